@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Tuning of the -adaptive-concurrency controller.
+const (
+	// adaptiveMinWindow is the fewest finished targets a failure rate is judged over.
+	adaptiveMinWindow = 20
+	// adaptiveSpikeMargin is how far a window's failure rate must rise above the baseline to count as a spike; halfway there, the limit holds.
+	adaptiveSpikeMargin = 0.1
+	// adaptiveBaselineWeight is the weight of each calm window in the baseline failure rate, an exponentially weighted average.
+	adaptiveBaselineWeight = 0.2
+)
+
+/*
+concurrencyController is -adaptive-concurrency: an AIMD limit on the targets probed at once, between min and max.
+The limit starts at min and doubles after every calm window (slow start) until the first spike, then grows by one per window; a spike halves it. A window is one limit's worth of finished targets, at least adaptiveMinWindow, and judged by its share of timeouts and dropped connections (see transientFailure) against the baseline share of earlier calm windows, so a target range where most addresses never answer does not read as congestion.
+*/
+type concurrencyController struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	min, max  int
+	limit     int
+	active    int
+	peak      int
+	slowStart bool
+	backoffs  int
+
+	baseline   float64
+	calibrated bool
+	done       int
+	failed     int
+}
+
+/*
+adaptiveSummary is how -adaptive-concurrency fared over a scan, for the scan summary.
+*/
+type adaptiveSummary struct {
+	Min      int `json:"min"`
+	Max      int `json:"max"`
+	Final    int `json:"final"`
+	Peak     int `json:"peak"`
+	Backoffs int `json:"backoffs"`
+}
+
+/*
+newConcurrencyController returns a controller starting at lo targets at once and never exceeding hi.
+*/
+func newConcurrencyController(lo, hi int) *concurrencyController {
+	c := &concurrencyController{min: lo, max: hi, limit: lo, peak: lo, slowStart: true}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+/*
+acquire blocks until the limit leaves room for one more target; a nil controller never blocks.
+*/
+func (c *concurrencyController) acquire() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for c.active >= c.limit {
+		c.cond.Wait()
+	}
+	c.active++
+	c.mu.Unlock()
+}
+
+/*
+release frees the slot acquire took, counts res toward the current window, and adjusts the limit when the window is full.
+*/
+func (c *concurrencyController) release(res Result) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	c.done++
+	if transientFailure(res) {
+		c.failed++
+	}
+	if c.done >= max(c.limit, adaptiveMinWindow) {
+		c.adjust()
+	}
+	c.cond.Broadcast()
+}
+
+/*
+adjust ends the current window: halves the limit on a spike, holds it on a rise short of one, and otherwise grows it and folds the window into the baseline.
+Function-level comment: the first window only sets the baseline. Called with mu held.
+*/
+func (c *concurrencyController) adjust() {
+	rate := float64(c.failed) / float64(c.done)
+	c.done, c.failed = 0, 0
+	switch {
+	case !c.calibrated:
+		c.baseline, c.calibrated = rate, true
+	case rate > c.baseline+adaptiveSpikeMargin:
+		c.limit = max(c.min, c.limit/2)
+		c.slowStart = false
+		c.backoffs++
+		return
+	case rate > c.baseline+adaptiveSpikeMargin/2:
+		return
+	default:
+		c.baseline += adaptiveBaselineWeight * (rate - c.baseline)
+	}
+	if c.slowStart {
+		c.limit = min(c.max, c.limit*2)
+	} else {
+		c.limit = min(c.max, c.limit+1)
+	}
+	c.peak = max(c.peak, c.limit)
+}
+
+/*
+summary reports the controller's bounds, its limit now and at its highest, and how often it backed off; nil when -adaptive-concurrency is off.
+*/
+func (c *concurrencyController) summary() *adaptiveSummary {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &adaptiveSummary{Min: c.min, Max: c.max, Final: c.limit, Peak: c.peak, Backoffs: c.backoffs}
+}
+
+/*
+String describes the controller's state for the SIGUSR1 dump.
+*/
+func (c *concurrencyController) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("limit %d (adaptive %d-%d, peak %d, %d backoffs), %d in use, baseline failure rate %.2f", c.limit, c.min, c.max, c.peak, c.backoffs, c.active, c.baseline)
+}
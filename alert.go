@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultAlertTemplate renders a watchChange when -alert-template is not given.
+const defaultAlertTemplate = "{{.Target}}: {{.Field}} changed from {{.Old}} to {{.New}}"
+
+/*
+alertSink delivers one rendered alert message.
+*/
+type alertSink interface {
+	name() string
+	send(subject, message string) error
+}
+
+/*
+slackSink posts alerts to a Slack incoming webhook.
+*/
+type slackSink struct {
+	url  string
+	http *http.Client
+}
+
+/*
+name identifies the sink in error messages.
+*/
+func (s *slackSink) name() string {
+	return "slack"
+}
+
+/*
+send posts the message as the webhook's text.
+*/
+func (s *slackSink) send(subject, message string) error {
+	body, _ := json.Marshal(map[string]string{"text": message})
+	resp, err := s.http.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s", resp.Status)
+	}
+	return nil
+}
+
+/*
+smtpSink mails alerts through an SMTP relay.
+*/
+type smtpSink struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+/*
+newSMTPSink parses smtp://[user:password@]host[:port]?from=addr&to=addr[,addr...]; the port defaults to 25.
+*/
+func newSMTPSink(spec string) (*smtpSink, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme != "smtp" || u.Hostname() == "" {
+		return nil, fmt.Errorf("want smtp://[user:password@]host[:port]?from=...&to=..., got %q", spec)
+	}
+	s := &smtpSink{addr: u.Host, from: u.Query().Get("from"), to: splitList(u.Query().Get("to"))}
+	if u.Port() == "" {
+		s.addr = u.Hostname() + ":25"
+	}
+	if s.from == "" || len(s.to) == 0 {
+		return nil, fmt.Errorf("smtp alert sink needs from= and to= query parameters")
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		s.auth = smtp.PlainAuth("", u.User.Username(), password, u.Hostname())
+	}
+	return s, nil
+}
+
+/*
+name identifies the sink in error messages.
+*/
+func (s *smtpSink) name() string {
+	return "smtp"
+}
+
+/*
+send mails the message with subject to every recipient.
+*/
+func (s *smtpSink) send(subject, message string) error {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		s.from, strings.Join(s.to, ", "), subject, time.Now().Format(time.RFC1123Z), message)
+	return smtp.SendMail(s.addr, s.auth, s.from, s.to, []byte(msg))
+}
+
+/*
+alertRate limits how many alerts one sink sends within a sliding window.
+*/
+type alertRate struct {
+	max    int
+	window time.Duration
+}
+
+/*
+parseAlertRate parses "<n>/<unit>" with unit s, m, or h, e.g. "10/h".
+*/
+func parseAlertRate(spec string) (alertRate, error) {
+	n, unit, ok := strings.Cut(spec, "/")
+	max, err := strconv.Atoi(n)
+	windows := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	if !ok || err != nil || max < 1 || windows[unit] == 0 {
+		return alertRate{}, fmt.Errorf("invalid alert rate %q (want N/s, N/m, or N/h, e.g. 10/h)", spec)
+	}
+	return alertRate{max: max, window: windows[unit]}, nil
+}
+
+/*
+limitedSink is a sink with its own rate window.
+*/
+type limitedSink struct {
+	sink    alertSink
+	sent    []time.Time
+	dropped int
+}
+
+/*
+allow reports whether another alert fits in the sink's window at now, recording it when it does.
+*/
+func (ls *limitedSink) allow(rate alertRate, now time.Time) bool {
+	keep := ls.sent[:0]
+	for _, t := range ls.sent {
+		if now.Sub(t) < rate.window {
+			keep = append(keep, t)
+		}
+	}
+	ls.sent = keep
+	if len(ls.sent) >= rate.max {
+		return false
+	}
+	ls.sent = append(ls.sent, now)
+	return true
+}
+
+/*
+alerter renders watch-mode changes with a template and delivers them to every sink from a background goroutine.
+Alerts over a sink's rate are dropped and counted; the count is reported with the next alert that gets through.
+*/
+type alerter struct {
+	tmpl   *template.Template
+	rate   alertRate
+	sinks  []*limitedSink
+	queue  chan watchChange
+	done   chan struct{}
+	errors io.Writer
+}
+
+/*
+newAlerter builds an alerter for sinks using the text/template in tmpl (defaultAlertTemplate when empty).
+*/
+func newAlerter(sinks []alertSink, tmpl string, rate alertRate, errOut io.Writer) (*alerter, error) {
+	if tmpl == "" {
+		tmpl = defaultAlertTemplate
+	}
+	t, err := template.New("alert").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	a := &alerter{tmpl: t, rate: rate, queue: make(chan watchChange, webhookQueueSize), done: make(chan struct{}), errors: errOut}
+	for _, s := range sinks {
+		a.sinks = append(a.sinks, &limitedSink{sink: s})
+	}
+	go a.run()
+	return a, nil
+}
+
+/*
+alert queues a change for delivery.
+*/
+func (a *alerter) alert(c watchChange) {
+	a.queue <- c
+}
+
+/*
+close waits for queued alerts to be delivered; alert must not be called afterwards.
+*/
+func (a *alerter) close() {
+	close(a.queue)
+	<-a.done
+}
+
+/*
+run renders and delivers queued changes until the queue is closed, then reports alerts still suppressed.
+*/
+func (a *alerter) run() {
+	defer close(a.done)
+	for c := range a.queue {
+		var buf bytes.Buffer
+		if err := a.tmpl.Execute(&buf, c); err != nil {
+			fmt.Fprintf(a.errors, "alert: template: %v\n", err)
+			continue
+		}
+		subject := fmt.Sprintf("mysql_scout: %s changed on %s", c.Field, c.Target)
+		for _, ls := range a.sinks {
+			if !ls.allow(a.rate, time.Now()) {
+				ls.dropped++
+				continue
+			}
+			message := buf.String()
+			if ls.dropped > 0 {
+				message += fmt.Sprintf("\n(%d earlier alerts suppressed by rate limit)", ls.dropped)
+				ls.dropped = 0
+			}
+			if err := ls.sink.send(subject, message); err != nil {
+				fmt.Fprintf(a.errors, "alert: %s: %v\n", ls.sink.name(), err)
+			}
+		}
+	}
+	for _, ls := range a.sinks {
+		if ls.dropped > 0 {
+			fmt.Fprintf(a.errors, "alert: %s: %d alerts suppressed by rate limit\n", ls.sink.name(), ls.dropped)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// maxResultLine bounds one JSON result line read by analyze; verbose records with banners and certificates can be long.
+const maxResultLine = 1 << 20
+
+// analyzeBarWidth is the length of the longest histogram bar in analyze's text output.
+const analyzeBarWidth = 40
+
+// charsetNames names the handshake character_set ids servers commonly announce (the server's default collation).
+var charsetNames = map[uint8]string{
+	8:   "latin1_swedish_ci",
+	28:  "gbk_chinese_ci",
+	33:  "utf8mb3_general_ci",
+	45:  "utf8mb4_general_ci",
+	46:  "utf8mb4_bin",
+	63:  "binary",
+	83:  "utf8mb3_bin",
+	224: "utf8mb4_unicode_ci",
+	255: "utf8mb4_0900_ai_ci",
+}
+
+/*
+analysis holds the histograms analyze builds from result records.
+*/
+type analysis struct {
+	Results        int            `json:"results"`
+	MySQL          int            `json:"mysql"`
+	Skipped        int            `json:"skipped_lines,omitempty"`
+	ServerVersions map[string]int `json:"server_versions"`
+	VersionSeries  map[string]int `json:"version_series"`
+	Capabilities   map[string]int `json:"capabilities"`
+	Charsets       map[string]int `json:"charsets"`
+	TLS            map[string]int `json:"tls"`
+	AuthPlugins    map[string]int `json:"auth_plugins"`
+	Providers      map[string]int `json:"providers"`
+	BuildVariants  map[string]int `json:"build_variants"`
+	Platforms      map[string]int `json:"platforms"`
+	CompatWarnings map[string]int `json:"compat_warnings"`
+	Fingerprints   map[string]int `json:"handshake_fingerprints"`
+	Statuses       map[string]int `json:"statuses"`
+}
+
+/*
+newAnalysis returns an empty analysis.
+*/
+func newAnalysis() *analysis {
+	return &analysis{
+		ServerVersions: make(map[string]int),
+		VersionSeries:  make(map[string]int),
+		Capabilities:   make(map[string]int),
+		Charsets:       make(map[string]int),
+		TLS:            make(map[string]int),
+		AuthPlugins:    make(map[string]int),
+		Providers:      make(map[string]int),
+		BuildVariants:  make(map[string]int),
+		Platforms:      make(map[string]int),
+		CompatWarnings: make(map[string]int),
+		Fingerprints:   make(map[string]int),
+		Statuses:       make(map[string]int),
+	}
+}
+
+/*
+add counts one result record.
+Function-level comment: capabilities, charsets, and auth plugins are only present in full-detail output (-v, -fields, or a non-JSON format), so records without them are left out of those histograms.
+*/
+func (a *analysis) add(res Result) {
+	a.Results++
+	status, _ := res.Status()
+	a.Statuses[string(status)]++
+	if res.Provider != "" {
+		a.Providers[res.Provider]++
+	}
+	info := res.HandshakeInfo
+	if !res.MySQL || info == nil {
+		return
+	}
+	a.MySQL++
+	a.ServerVersions[info.ServerVersion]++
+	if res.BuildVariant != "" {
+		a.BuildVariants[res.BuildVariant]++
+	}
+	if res.PlatformGuess != "" {
+		a.Platforms[res.PlatformGuess]++
+	}
+	for _, w := range res.CompatWarnings {
+		a.CompatWarnings[w.Client+" "+w.Affected]++
+	}
+	series := "unknown"
+	if v := info.Version; v != nil {
+		series = fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	a.VersionSeries[productName(info.ServerVersion)+" "+series]++
+	if info.AuthPluginName != "" {
+		a.AuthPlugins[info.AuthPluginName]++
+	}
+	if info.Fingerprint != "" {
+		a.Fingerprints[info.Fingerprint+" ("+info.ServerVersion+")"]++
+	}
+	if info.CharacterSet != 0 {
+		name, ok := charsetNames[info.CharacterSet]
+		if !ok {
+			name = "id " + strconv.Itoa(int(info.CharacterSet))
+		}
+		a.Charsets[name]++
+	}
+	for _, f := range capabilityFlagNames {
+		if info.CapabilityFlags&f.bit != 0 {
+			a.Capabilities[f.name]++
+		}
+	}
+	if posture := tlsPosture(res); posture != "" {
+		a.TLS[posture]++
+	}
+}
+
+/*
+tlsPosture describes a MySQL result's TLS support: the negotiated version when -tls ran, "handshake failed", or whether CLIENT_SSL was "offered"; "" when the capabilities were not collected.
+*/
+func tlsPosture(res Result) string {
+	switch {
+	case res.TLS != nil && res.TLS.Error == "":
+		return res.TLS.Version
+	case res.TLS != nil:
+		return "handshake failed"
+	case res.HandshakeInfo == nil || res.CapabilityFlags == 0:
+		return ""
+	case res.CapabilityFlags&mysqlprobe.ClientSSL != 0:
+		return "offered"
+	}
+	return "not offered"
+}
+
+/*
+read adds every JSON line of r, counting lines that do not decode as results as skipped.
+Function-level comment: gzip and zstd input (as written with -compress) is decompressed transparently.
+*/
+func (a *analysis) read(r io.Reader) error {
+	r, err := decompressedReader(r)
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxResultLine)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var res Result
+		if json.Unmarshal([]byte(line), &res) != nil {
+			a.Skipped++
+			continue
+		}
+		a.add(res)
+	}
+	return sc.Err()
+}
+
+/*
+writeText prints each histogram, limited to its top entries, with proportional bars.
+*/
+func (a *analysis) writeText(w io.Writer, top int) error {
+	fmt.Fprintf(w, "results: %d (%d MySQL)\n", a.Results, a.MySQL)
+	if a.Skipped > 0 {
+		fmt.Fprintf(w, "skipped lines: %d\n", a.Skipped)
+	}
+	sections := []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"status", a.Statuses},
+		{"server versions", a.ServerVersions},
+		{"version series", a.VersionSeries},
+		{"auth plugins", a.AuthPlugins},
+		{"charsets", a.Charsets},
+		{"tls", a.TLS},
+		{"capabilities", a.Capabilities},
+		{"providers", a.Providers},
+		{"build variants", a.BuildVariants},
+		{"platforms", a.Platforms},
+		{"client compatibility warnings", a.CompatWarnings},
+		{"handshake fingerprints", a.Fingerprints},
+	}
+	for _, s := range sections {
+		if len(s.counts) == 0 {
+			continue
+		}
+		if err := writeHistogram(w, s.title, s.counts, top); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+writeHistogram prints one titled histogram of the top entries (all when top <= 0), most frequent first.
+*/
+func writeHistogram(w io.Writer, title string, counts map[string]int, top int) error {
+	keys := sortedByCount(counts)
+	if top > 0 && len(keys) > top {
+		fmt.Fprintf(w, "\n%s (top %d of %d)\n", title, top, len(keys))
+		keys = keys[:top]
+	} else {
+		fmt.Fprintf(w, "\n%s\n", title)
+	}
+	width, maxCount := 0, counts[keys[0]]
+	for _, k := range keys {
+		width = max(width, len(k))
+	}
+	for _, k := range keys {
+		bar := strings.Repeat("#", max(1, counts[k]*analyzeBarWidth/maxCount))
+		if _, err := fmt.Fprintf(w, "  %-*s  %6d  %s\n", width, k, counts[k], bar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+sortedByCount returns the keys of counts, most frequent first with ties broken by key.
+*/
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+/*
+runAnalyze implements the analyze subcommand: histograms over previously written JSON results.
+Function-level comment: reads each file argument ("-" or none for stdin) and prints text histograms, or the full counts as JSON with -format json; returns the process exit code.
+*/
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	top := fs.Int("top", 10, "Show only the N most frequent entries of each histogram in text output (0 = all)")
+	format := fs.String("format", "text", "Output format: text (histograms) or json (all counts)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout analyze [-top N] [-format text|json] results.ndjson...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "invalid -format %q (want text or json)\n", *format)
+		return 2
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	a := newAnalysis()
+	for _, path := range paths {
+		var err error
+		if path == "-" {
+			err = a.read(os.Stdin)
+		} else {
+			err = readAnalysisFile(a, path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "analyze %s: %v\n", path, err)
+			return 1
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(a); err != nil {
+			fmt.Fprintf(os.Stderr, "analyze: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := a.writeText(os.Stdout, *top); err != nil {
+		fmt.Fprintf(os.Stderr, "analyze: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+/*
+readAnalysisFile adds the results in the file at path to a.
+*/
+func readAnalysisFile(a *analysis, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return a.read(f)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+blockTracker counts "host blocked / not allowed" refusals (ERR 1129/1130) per destination network and throttles networks past a threshold.
+Once a network is throttled, further connections into it are either refused outright (stop) or paced through a per-subnet rate limiter (slow), so the scanner stops adding to the servers' max_connect_errors counters.
+*/
+type blockTracker struct {
+	bits      int
+	threshold int
+	slow      *subnetLimiter
+	events    io.Writer
+
+	mu     sync.Mutex
+	counts map[netip.Prefix]int
+}
+
+/*
+newBlockTracker parses -block-action and returns the tracker, or nil when threshold is 0 (tracking disabled).
+Function-level comment: action is "stop" to skip the network's remaining targets or "<n>/s" to slow it to n connections per second; IPv4 networks are grouped by bits and IPv6 by /64, as -subnet-rate does.
+*/
+func newBlockTracker(threshold, bits int, action string, events io.Writer) (*blockTracker, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("threshold must not be negative")
+	}
+	if bits < 0 || bits > 32 {
+		return nil, fmt.Errorf("invalid prefix length %d", bits)
+	}
+	if threshold == 0 {
+		return nil, nil
+	}
+	b := &blockTracker{bits: bits, threshold: threshold, events: events, counts: make(map[netip.Prefix]int)}
+	if action != "stop" {
+		n, isRate := strings.CutSuffix(action, "/s")
+		rate, err := strconv.ParseFloat(n, 64)
+		if !isRate || err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid action %q (want stop or a rate such as 0.2/s)", action)
+		}
+		b.slow = newSubnetLimiter(subnetRateSpec{bits: bits, perSecond: rate})
+	}
+	return b, nil
+}
+
+/*
+network maps a destination address to the network its refusals are counted under.
+*/
+func (b *blockTracker) network(addr netip.Addr) netip.Prefix {
+	bits := b.bits
+	if addr.Is6() {
+		bits = subnetV6Bits
+	}
+	p, _ := addr.Prefix(bits)
+	return p
+}
+
+/*
+admit decides whether a connection into addr's network may proceed and returns the release func.
+Function-level comment: networks below the threshold are not affected; throttled ones either return an error (stop) or wait for the slow limiter. A nil tracker admits everything.
+*/
+func (b *blockTracker) admit(addr netip.Addr) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	network := b.network(addr)
+	b.mu.Lock()
+	n := b.counts[network]
+	b.mu.Unlock()
+	if n < b.threshold {
+		return func() {}, nil
+	}
+	if b.slow == nil {
+		return nil, fmt.Errorf("not probed: %s throttled after %d blocked-host errors", network, n)
+	}
+	return b.slow.acquire(addr), nil
+}
+
+/*
+record counts res against addr's network when the server refused our host, reporting the moment the network crosses the threshold.
+*/
+func (b *blockTracker) record(addr netip.Addr, res Result) {
+	if b == nil || res.ServerError == nil || !res.ServerError.HostRefused() {
+		return
+	}
+	network := b.network(addr)
+	b.mu.Lock()
+	b.counts[network]++
+	crossed := b.counts[network] == b.threshold
+	b.mu.Unlock()
+	if crossed {
+		action := "stopping probes into it"
+		if b.slow != nil {
+			action = fmt.Sprintf("slowing to %g connections/s", b.slow.spec.perSecond)
+		}
+		fmt.Fprintf(b.events, "throttle: %s returned %d blocked-host errors (1129/1130), last from %s; %s\n", network, b.threshold, addr, action)
+	}
+}
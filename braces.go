@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBraceSpecs bounds how many specs a single brace expression may expand to; each may still be a CIDR.
+const maxBraceSpecs = 1 << 16
+
+/*
+expandBraces expands the brace groups in a target spec, as a shell would: a comma list, db-{primary,replica}.example.com, or an inclusive numeric range, db{01..20}.prod.example.com or 10.0.{1..4}.0/24.
+Function-level comment: several groups expand to every combination, leftmost varying slowest. A range counts down when its first end is larger, and is zero-padded to the wider end when either end has a leading zero (01..20 gives 01, 02, ... 20). Nested groups, a group without a comma or .., and unbalanced braces are errors rather than literal text, since no host name contains a brace; a spec without braces is returned as is.
+*/
+func expandBraces(spec string) ([]string, error) {
+	if !strings.ContainsAny(spec, "{}") {
+		return []string{spec}, nil
+	}
+	var parts [][]string
+	total := 1
+	rest := spec
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			parts = append(parts, []string{rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("invalid target %q: unbalanced }", spec)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			if end < 0 {
+				return nil, fmt.Errorf("invalid target %q: unbalanced {", spec)
+			}
+			return nil, fmt.Errorf("invalid target %q: nested braces are not supported", spec)
+		}
+		end += open + 1
+		alts, err := braceAlternatives(rest[open+1 : end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", spec, err)
+		}
+		if total *= len(alts); total > maxBraceSpecs {
+			return nil, fmt.Errorf("target %q expands to more than %d specs", spec, maxBraceSpecs)
+		}
+		parts = append(parts, []string{rest[:open]}, alts)
+		rest = rest[end+1:]
+	}
+	specs := []string{""}
+	for _, alts := range parts {
+		next := make([]string, 0, len(specs)*len(alts))
+		for _, prefix := range specs {
+			for _, a := range alts {
+				next = append(next, prefix+a)
+			}
+		}
+		specs = next
+	}
+	return specs, nil
+}
+
+/*
+braceAlternatives returns what the body of one brace group stands for: its comma-separated items, or the numbers of an N..M range.
+*/
+func braceAlternatives(body string) ([]string, error) {
+	if strings.Contains(body, ",") {
+		return strings.Split(body, ","), nil
+	}
+	lo, hi, ok := strings.Cut(body, "..")
+	if !ok {
+		return nil, fmt.Errorf("brace group {%s} is neither a comma list nor a range", body)
+	}
+	from, err1 := strconv.Atoi(lo)
+	to, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || from < 0 || to < 0 {
+		return nil, fmt.Errorf("brace range {%s} must be two non-negative integers", body)
+	}
+	step := 1
+	if from > to {
+		step = -1
+	}
+	// Compared without the +1 so a range spanning all of int cannot overflow.
+	if (to-from)*step >= maxBraceSpecs {
+		return nil, fmt.Errorf("brace range {%s} has more than %d values", body, maxBraceSpecs)
+	}
+	width := 0
+	if (len(lo) > 1 && lo[0] == '0') || (len(hi) > 1 && hi[0] == '0') {
+		width = max(len(lo), len(hi))
+	}
+	var alts []string
+	for i := from; ; i += step {
+		alts = append(alts, fmt.Sprintf("%0*d", width, i))
+		if i == to {
+			break
+		}
+	}
+	return alts, nil
+}
+
+/*
+splitBraceAware splits list at commas outside brace groups, trimming spaces and dropping empty items, so "db{1,2},db3" is db{1,2} and db3.
+*/
+func splitBraceAware(list string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '{':
+				depth++
+				continue
+			case '}':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if item := strings.TrimSpace(list[start:i]); item != "" {
+			out = append(out, item)
+		}
+		start = i + 1
+	}
+	return out
+}
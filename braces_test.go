@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"db1.example.com", []string{"db1.example.com"}},
+		{"db-{primary,replica}.x", []string{"db-primary.x", "db-replica.x"}},
+		{"db{08..11}.x", []string{"db08.x", "db09.x", "db10.x", "db11.x"}},
+		{"db{3..1}.x", []string{"db3.x", "db2.x", "db1.x"}},
+		{"{a,b}{1..2}", []string{"a1", "a2", "b1", "b2"}},
+	}
+	for _, tt := range tests {
+		got, err := expandBraces(tt.spec)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, %v; want %q", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandBracesRejects(t *testing.T) {
+	for _, spec := range []string{
+		"db{1,2",
+		"db}1",
+		"db{{1,2}}",
+		"db{1}",
+		"db{a..b}",
+		"db{0..65536}.x",
+		"db{0..9223372036854775807}.x",
+		"db{9223372036854775807..0}.x",
+		"{0..255}.{0..255}.{0..1}.x",
+	} {
+		if got, err := expandBraces(spec); err == nil {
+			t.Errorf("expandBraces(%q) = %d specs, want an error", spec, len(got))
+		}
+	}
+}
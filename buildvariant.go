@@ -0,0 +1,88 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// Build variants reported as build_variant: who built the server binary.
+const (
+	buildOracleCommunity  = "oracle-community"
+	buildOracleEnterprise = "oracle-enterprise"
+	buildPercona          = "percona-server"
+	buildPerconaCluster   = "percona-xtradb-cluster"
+	buildMariaDB          = "mariadb"
+	buildDebian           = "debian"
+	buildUbuntu           = "ubuntu"
+)
+
+// perconaSuffix matches Percona's release number after the upstream version: "28" (Server), "27.1" (XtraDB Cluster 8.0), "31.65" (XtraDB Cluster 5.7, with the Galera version), optionally followed by -log.
+var perconaSuffix = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:-log)?$`)
+
+// debianPackageSuffix matches Debian's package revisions, e.g. "0+deb12u1" or "1debian11".
+var debianPackageSuffix = regexp.MustCompile(`\+deb\d|debian\d`)
+
+// oracleBuildSuffixes are the suffixes Oracle's own community binaries report, depending on logging and debug options.
+var oracleBuildSuffixes = map[string]bool{"": true, "log": true, "debug": true, "debug-log": true, "valgrind": true}
+
+/*
+classifyBuildVariant identifies who built the server binary from its handshake, with the evidence.
+Function-level comment: the version suffix decides: MariaDB's own repositories add +maria~, distro packages their Debian or Ubuntu package revision, Oracle's enterprise builds -enterprise or -commercial, and Percona its release number (with a nonzero second part for XtraDB Cluster); Oracle's community binaries add nothing but -log or -debug, unless the capability flags clear CLIENT_LONG_PASSWORD, which is MariaDB hiding its name. The default auth plugin is noted when it differs from the release's stock default, since it hints at a repackaged or reconfigured server. Unrecognized suffixes (cloud services, forks) yield "" rather than a guess.
+*/
+func classifyBuildVariant(info *mysqlprobe.HandshakeInfo) (string, []string) {
+	if info == nil || info.Version == nil {
+		return "", nil
+	}
+	suffix := info.Version.Suffix
+	lower := strings.ToLower(suffix)
+	mariaDB := strings.Contains(strings.ToLower(info.ServerVersion), "mariadb")
+
+	var variant, why string
+	switch {
+	case strings.Contains(lower, "+maria~"):
+		variant, why = buildMariaDB, "version suffix "+suffix+" is a MariaDB repository package"
+	case strings.Contains(lower, "ubuntu"):
+		variant, why = buildUbuntu, "version suffix "+suffix+" is an Ubuntu package revision"
+	case debianPackageSuffix.MatchString(lower):
+		variant, why = buildDebian, "version suffix "+suffix+" is a Debian package revision"
+	case mariaDB:
+		variant, why = buildMariaDB, "server version names MariaDB"
+	case strings.Contains(lower, "enterprise") || strings.Contains(lower, "commercial"):
+		variant, why = buildOracleEnterprise, "version suffix "+suffix+" marks an Oracle enterprise build"
+	case perconaSuffix.MatchString(suffix) && strings.HasSuffix(info.ServerVersion, "-"+suffix):
+		m := perconaSuffix.FindStringSubmatch(suffix)
+		if m[2] != "" && m[2] != "0" {
+			variant, why = buildPerconaCluster, "version suffix "+suffix+" is a Percona XtraDB Cluster release number"
+		} else {
+			variant, why = buildPercona, "version suffix "+suffix+" is a Percona Server release number"
+		}
+	case oracleBuildSuffixes[lower] && info.CapabilityFlags&mysqlprobe.ClientProtocol41 != 0 && info.CapabilityFlags&mysqlprobe.ClientLongPassword == 0:
+		variant, why = buildMariaDB, "capability flags clear CLIENT_LONG_PASSWORD, as MariaDB 10.2+ does"
+	case oracleBuildSuffixes[lower]:
+		variant, why = buildOracleCommunity, "no vendor suffix on the version, as in Oracle's community binaries"
+	default:
+		return "", nil
+	}
+	evidence := []string{why}
+	if plugin, stock := info.AuthPluginName, stockAuthPlugin(info.Version, mariaDB || variant == buildMariaDB); plugin != "" && stock != "" && plugin != stock {
+		evidence = append(evidence, "default auth plugin "+plugin+" instead of the release's stock "+stock)
+	}
+	return variant, evidence
+}
+
+/*
+stockAuthPlugin returns the default auth plugin a release ships with, or "" for releases before plugin negotiation.
+*/
+func stockAuthPlugin(v *mysqlprobe.VersionInfo, mariaDB bool) string {
+	switch {
+	case mariaDB:
+		return "mysql_native_password"
+	case v.Major >= 8:
+		return "caching_sha2_password"
+	case v.Major == 5 && v.Minor >= 5:
+		return "mysql_native_password"
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// censysSearchAPI is the Censys Search API v2 host search endpoint -censys-query pages through.
+const censysSearchAPI = "https://search.censys.io/api/v2/hosts/search"
+
+// censysPageSize is the hosts requested per page, the API's maximum.
+const censysPageSize = 100
+
+// censysRetries bounds how often a rate-limited (429) page request is retried, waiting censysBackoff, doubled each time, or the server's Retry-After.
+const (
+	censysRetries = 3
+	censysBackoff = 2 * time.Second
+)
+
+/*
+censysService is one service of a host in a Censys search hit.
+*/
+type censysService struct {
+	Port              int    `json:"port"`
+	ServiceName       string `json:"service_name"`
+	TransportProtocol string `json:"transport_protocol"`
+}
+
+/*
+censysSearchPage is the body of one host search response.
+*/
+type censysSearchPage struct {
+	Code   int    `json:"code"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Result struct {
+		Total int `json:"total"`
+		Hits  []struct {
+			IP       string          `json:"ip"`
+			Name     string          `json:"name"`
+			Services []censysService `json:"services"`
+		} `json:"hits"`
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	} `json:"result"`
+}
+
+/*
+censysClient queries the Censys Search API with an API ID and secret.
+*/
+type censysClient struct {
+	base       string
+	id, secret string
+	http       *http.Client
+}
+
+/*
+newCensysClient returns a client for the search endpoint at base, authenticating with CENSYS_API_ID and CENSYS_API_SECRET from the environment.
+*/
+func newCensysClient(base string) (*censysClient, error) {
+	id, secret := os.Getenv("CENSYS_API_ID"), os.Getenv("CENSYS_API_SECRET")
+	if id == "" || secret == "" {
+		return nil, errors.New("set CENSYS_API_ID and CENSYS_API_SECRET to the API credentials from your Censys account")
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid API URL %q", base)
+	}
+	return &censysClient{base: base, id: id, secret: secret, http: &http.Client{Timeout: time.Minute}}, nil
+}
+
+/*
+loadCensysTargets runs query against the host search and returns the matching services as probe targets.
+Function-level comment: pages through the results with the API's cursor until they run out or limit hosts (0 = no limit) have been read. Each hit is a host with all its services; only TCP services whose name is in services (case-insensitive; all when empty) are kept, so a query on other fields still yields just the MySQL ports. The IP is dialed and a virtual host's name is carried as the hostname; the first target of each host carries its UDP probes.
+*/
+func loadCensysTargets(ctx context.Context, c *censysClient, query string, services []string, limit int) ([]target, error) {
+	wanted := make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[strings.ToUpper(s)] = true
+	}
+	var targets []target
+	seen := make(map[string]bool)
+	hosts, cursor := 0, ""
+	for {
+		page, err := c.search(ctx, query, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range page.Result.Hits {
+			if limit > 0 && hosts == limit {
+				return targets, nil
+			}
+			hosts++
+			first := true
+			for _, s := range h.Services {
+				if !strings.EqualFold(s.TransportProtocol, "tcp") || (len(wanted) > 0 && !wanted[strings.ToUpper(s.ServiceName)]) {
+					continue
+				}
+				t := target{host: h.IP, port: s.Port, runUDP: first, hostname: h.Name}
+				if key := targetKey(t); !seen[key] {
+					seen[key] = true
+					first = false
+					targets = append(targets, t)
+				}
+			}
+		}
+		cursor = page.Result.Links.Next
+		if cursor == "" || len(page.Result.Hits) == 0 {
+			return targets, nil
+		}
+	}
+}
+
+/*
+search fetches one page of host search results, starting at cursor ("" for the first page).
+Function-level comment: rate-limited requests are retried after the server's Retry-After, else a doubling backoff; other errors are returned with the API's message.
+*/
+func (c *censysClient) search(ctx context.Context, query, cursor string) (*censysSearchPage, error) {
+	params := url.Values{"q": {query}, "per_page": {strconv.Itoa(censysPageSize)}}
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	backoff := censysBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(c.id, c.secret)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "mysql_scout/"+version)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("censys search: %w", err)
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("censys search: %w", err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < censysRetries {
+			wait := backoff
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+				wait = time.Duration(secs) * time.Second
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff *= 2
+			continue
+		}
+		var page censysSearchPage
+		if err := json.Unmarshal(body, &page); err != nil && resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("censys search: decode response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			msg := page.Error
+			if msg == "" {
+				msg = strings.TrimSpace(string(body[:min(len(body), 200)]))
+			}
+			return nil, fmt.Errorf("censys search: %s: %s", resp.Status, msg)
+		}
+		return &page, nil
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["check"] = runCheck
+}
+
+// Exit codes of the check subcommand, as Nagios, Icinga, and Sensu read them.
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+// checkStates names the exit codes in the status line.
+var checkStates = [...]string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// checkNames are the expectations -critical can name.
+var checkNames = []string{"version", "min-version", "tls", "tls-min-version", "auth-plugin"}
+
+/*
+checkExpectations are the check subcommand's expected values; empty fields are not checked.
+*/
+type checkExpectations struct {
+	version       string
+	minVersion    *mysqlprobe.VersionInfo
+	tls           string
+	tlsMinVersion uint16
+	authPlugin    string
+	critical      map[string]bool
+}
+
+/*
+checkFinding is one expectation the server did not meet, and whether it is critical.
+*/
+type checkFinding struct {
+	name     string
+	message  string
+	critical bool
+}
+
+/*
+runCheck is the check subcommand.
+Function-level comment: probes one target like a scan would and compares its version, TLS posture, and default auth plugin against the expected values given, for use as a Nagios/Icinga/Sensu check. Prints one status line, "MYSQL <STATE> - <summary>", with performance data after a "|", and exits 0 (OK), 1 (WARNING: an expectation not listed in -critical was missed), 2 (CRITICAL: a -critical expectation was missed, or the target did not answer as MySQL), or 3 (UNKNOWN: invalid arguments).
+*/
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	host := fs.String("host", "", "Target to check: host, host:port, [IPv6]:port, or mysql://host[:port]")
+	port := fs.Int("port", 3306, "Port when -host has none")
+	timeout := fs.Duration("timeout", 5*time.Second, "Dial/read timeout")
+	version := fs.String("version", "", "Expected server version, matched by leading components: 8.0 accepts 8.0.36, 8.0.36 only that release")
+	minVersion := fs.String("min-version", "", "Lowest acceptable server version, e.g. 8.0.35")
+	tlsExpect := fs.String("tls", "", "Expected TLS posture: offered (the handshake advertises SSL) or not-offered")
+	tlsMin := fs.String("tls-min-version", "", "Continue into TLS and require at least this negotiated version (1.0-1.3); implies -tls offered")
+	authPlugin := fs.String("auth-plugin", "", "Expected default auth plugin, e.g. caching_sha2_password")
+	critical := fs.String("critical", "tls,tls-min-version", "Comma-separated expectations that are CRITICAL when missed instead of WARNING: "+strings.Join(checkNames, ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout check -host host[:port] [-version V] [-min-version V] [-tls offered|not-offered] [-tls-min-version V] [-auth-plugin name] [-critical list]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return checkUnknown
+	}
+	if *host == "" && fs.NArg() == 1 {
+		*host = fs.Arg(0)
+	} else if *host == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return checkUnknown
+	}
+	t, exp, err := parseCheckArgs(*host, *port, *version, *minVersion, *tlsExpect, *tlsMin, *authPlugin, *critical)
+	if err != nil {
+		fmt.Printf("MYSQL UNKNOWN - %v\n", err)
+		return checkUnknown
+	}
+	if *timeout <= 0 {
+		fmt.Println("MYSQL UNKNOWN - invalid -timeout: must be positive")
+		return checkUnknown
+	}
+
+	cfg := scanConfig{prober: mysqlprobe.NewMySQLProber, probe: mysqlprobe.Options{Timeout: *timeout, Verbose: true, TLS: exp.tlsMinVersion != 0}}
+	start := time.Now()
+	res := safeScanTarget(t, cfg, newHostLimiter(0), nil)
+	elapsed := time.Since(start)
+
+	state, summary := evaluateCheck(res, exp)
+	perf := fmt.Sprintf("time=%.3fs;;;0;%.3f", elapsed.Seconds(), timeout.Seconds())
+	fmt.Printf("MYSQL %s - %s: %s|%s\n", checkStates[state], net.JoinHostPort(t.host, strconv.Itoa(t.port)), summary, perf)
+	return state
+}
+
+/*
+parseCheckArgs validates the check subcommand's arguments into the target to probe and the expectations to hold it to.
+*/
+func parseCheckArgs(host string, port int, version, minVersion, tlsExpect, tlsMin, authPlugin, critical string) (target, checkExpectations, error) {
+	var exp checkExpectations
+	spec, err := parseTargetSpec(host)
+	if err != nil {
+		return target{}, exp, err
+	}
+	if strings.Contains(spec.host, "/") {
+		return target{}, exp, fmt.Errorf("invalid -host %q: check takes one target, not a network", host)
+	}
+	t := target{host: spec.host, port: port, protocol: spec.protocol}
+	switch {
+	case len(spec.ports) > 1:
+		return target{}, exp, fmt.Errorf("invalid -host %q: check takes one port", host)
+	case len(spec.ports) == 1:
+		t.port = spec.ports[0]
+	}
+	if t.port < 1 || t.port > 65535 {
+		return target{}, exp, fmt.Errorf("invalid port %d", t.port)
+	}
+	if t.protocol != "" && t.protocol != "mysql" {
+		return target{}, exp, fmt.Errorf("invalid -host %q: check only speaks the classic MySQL protocol", host)
+	}
+	t.protocol = ""
+
+	if version != "" && mysqlprobe.ParseServerVersion(version) == nil {
+		return target{}, exp, fmt.Errorf("invalid -version %q", version)
+	}
+	exp.version = version
+	if minVersion != "" {
+		if exp.minVersion = mysqlprobe.ParseServerVersion(minVersion); exp.minVersion == nil {
+			return target{}, exp, fmt.Errorf("invalid -min-version %q", minVersion)
+		}
+	}
+	switch tlsExpect {
+	case "", "offered", "not-offered":
+		exp.tls = tlsExpect
+	default:
+		return target{}, exp, fmt.Errorf("invalid -tls %q (want offered or not-offered)", tlsExpect)
+	}
+	if exp.tlsMinVersion, err = mysqlprobe.ParseTLSVersion(tlsMin); err != nil {
+		return target{}, exp, fmt.Errorf("invalid -tls-min-version: %v", err)
+	}
+	if exp.tlsMinVersion != 0 && exp.tls == "not-offered" {
+		return target{}, exp, fmt.Errorf("-tls-min-version contradicts -tls not-offered")
+	}
+	exp.authPlugin = authPlugin
+	exp.critical = make(map[string]bool)
+	for _, name := range splitList(critical) {
+		known := false
+		for _, n := range checkNames {
+			known = known || n == name
+		}
+		if !known {
+			return target{}, exp, fmt.Errorf("invalid -critical %q (have %s)", name, strings.Join(checkNames, ", "))
+		}
+		exp.critical[name] = true
+	}
+	return t, exp, nil
+}
+
+/*
+evaluateCheck turns a probe result into the check's state and the summary for its status line.
+Function-level comment: a target that did not answer with a MySQL handshake is CRITICAL whatever the expectations; otherwise the state is the worst of the missed expectations, and the summary names the server and every miss.
+*/
+func evaluateCheck(res Result, exp checkExpectations) (int, string) {
+	if !res.MySQL || res.HandshakeInfo == nil {
+		status, err := res.Status()
+		reason := string(status)
+		if err != nil {
+			reason += ": " + err.Error()
+		} else if res.Error != "" {
+			reason += ": " + res.Error
+		}
+		return checkCritical, "no MySQL handshake (" + reason + ")"
+	}
+	info := res.HandshakeInfo
+	var findings []checkFinding
+	miss := func(name, format string, a ...any) {
+		findings = append(findings, checkFinding{name: name, message: fmt.Sprintf(format, a...), critical: exp.critical[name]})
+	}
+	if exp.version != "" && !versionMatches(info.Version, exp.version) {
+		miss("version", "version %s, expected %s", info.ServerVersion, exp.version)
+	}
+	if exp.minVersion != nil && (info.Version == nil || compareVersions(info.Version, exp.minVersion) < 0) {
+		miss("min-version", "version %s below %d.%d.%d", info.ServerVersion, exp.minVersion.Major, exp.minVersion.Minor, exp.minVersion.Patch)
+	}
+	offered := info.CapabilityFlags&mysqlprobe.ClientSSL != 0
+	switch {
+	case (exp.tls == "offered" || exp.tlsMinVersion != 0) && !offered:
+		miss("tls", "TLS not offered")
+	case exp.tls == "not-offered" && offered:
+		miss("tls", "TLS offered")
+	case exp.tlsMinVersion != 0 && (res.TLS == nil || res.TLS.Error != ""):
+		reason := "no TLS handshake"
+		if res.TLS != nil {
+			reason = "TLS handshake failed: " + res.TLS.Error
+		}
+		miss("tls-min-version", "%s", reason)
+	case exp.tlsMinVersion != 0:
+		if v, err := mysqlprobe.ParseTLSVersion(res.TLS.Version); err != nil || v < exp.tlsMinVersion {
+			miss("tls-min-version", "%s negotiated, expected at least %s", res.TLS.Version, tls.VersionName(exp.tlsMinVersion))
+		}
+	}
+	if exp.authPlugin != "" && info.AuthPluginName != exp.authPlugin {
+		miss("auth-plugin", "auth plugin %s, expected %s", info.AuthPluginName, exp.authPlugin)
+	}
+
+	summary := productName(info.ServerVersion) + " " + info.ServerVersion
+	state := checkOK
+	for _, f := range findings {
+		if f.critical {
+			state = checkCritical
+		} else if state == checkOK {
+			state = checkWarning
+		}
+		summary += ", " + f.message
+	}
+	return state, summary
+}
+
+/*
+versionMatches reports whether v agrees with every component want gives: "8" matches any 8.x, "8.0" any 8.0.x, "8.0.36" only 8.0.36.
+*/
+func versionMatches(v *mysqlprobe.VersionInfo, want string) bool {
+	if v == nil {
+		return false
+	}
+	parts := strings.Split(want, ".")
+	got := []int{v.Major, v.Minor, v.Patch}
+	for i, p := range parts[:min(len(parts), len(got))] {
+		n, err := strconv.Atoi(p)
+		if err != nil || n != got[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"iter"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how often completed targets are flushed to the checkpoint file during a scan.
+const checkpointInterval = 5 * time.Second
+
+/*
+checkpointFile is the on-disk checkpoint format.
+Completed lists host:port keys whose results have already been written.
+*/
+type checkpointFile struct {
+	Completed []string  `json:"completed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+/*
+checkpoint tracks completed targets and periodically persists them so an interrupted scan can resume.
+*/
+type checkpoint struct {
+	mu    sync.Mutex
+	path  string
+	done  map[string]struct{}
+	dirty bool
+}
+
+/*
+targetKey is the identity of a target in checkpoints.
+*/
+func targetKey(t target) string {
+	return net.JoinHostPort(t.host, strconv.Itoa(t.port))
+}
+
+/*
+loadCheckpoint reads the set of completed targets from a checkpoint file.
+Function-level comment: a missing file is treated as an empty checkpoint so the same path can be used for the first run and for resumes.
+*/
+func loadCheckpoint(path string) (map[string]struct{}, error) {
+	done := make(map[string]struct{})
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cf checkpointFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return nil, err
+	}
+	for _, k := range cf.Completed {
+		done[k] = struct{}{}
+	}
+	return done, nil
+}
+
+/*
+newCheckpoint returns a checkpoint writing to path, seeded with already-completed targets.
+*/
+func newCheckpoint(path string, done map[string]struct{}) *checkpoint {
+	if done == nil {
+		done = make(map[string]struct{})
+	}
+	return &checkpoint{path: path, done: done}
+}
+
+/*
+pending filters out targets already recorded as completed.
+Function-level comment: keeps the original order of the remaining targets.
+*/
+func (c *checkpoint) pending(targets iter.Seq[target]) iter.Seq[target] {
+	return func(yield func(target) bool) {
+		for t := range targets {
+			c.mu.Lock()
+			_, done := c.done[targetKey(t)]
+			c.mu.Unlock()
+			if !done && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+/*
+markDone records that a target's result has been written.
+*/
+func (c *checkpoint) markDone(t target) {
+	c.mu.Lock()
+	c.done[targetKey(t)] = struct{}{}
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+/*
+flush writes the checkpoint if anything changed since the last flush.
+Function-level comment: writes to a temporary file in the same directory and renames it over the old checkpoint so a crash mid-write never leaves a truncated file.
+*/
+func (c *checkpoint) flush() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	cf := checkpointFile{Completed: make([]string, 0, len(c.done)), UpdatedAt: time.Now().UTC()}
+	for k := range c.done {
+		cf.Completed = append(cf.Completed, k)
+	}
+	c.dirty = false
+	c.mu.Unlock()
+	sort.Strings(cf.Completed)
+
+	data, err := json.Marshal(cf)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+/*
+flushEvery flushes the checkpoint on a fixed interval until stop is closed.
+Function-level comment: flush errors are passed to onErr and do not stop the loop, since the next tick may succeed.
+*/
+func (c *checkpoint) flushEvery(interval time.Duration, stop <-chan struct{}, onErr func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.flush(); err != nil {
+				onErr(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// Ports the cluster checks look at, at their MySQL defaults.
+const (
+	groupCommPort   = 33061 // Group Replication's XCom group communication endpoint
+	adminPort       = 33062 // admin_port, commonly enabled on InnoDB Cluster members
+	routerRWPort    = 6446  // MySQL Router classic protocol, read-write
+	routerROPort    = 6447  // MySQL Router classic protocol, read-only
+	routerXRWPort   = 6448  // MySQL Router X Protocol, read-write
+	routerXROPort   = 6449  // MySQL Router X Protocol, read-only
+	minGroupVersion = 50717 // Group Replication shipped in 5.7.17
+)
+
+/*
+clusterIndicator is one observation suggesting Group Replication, InnoDB Cluster, or MySQL Router.
+*/
+type clusterIndicator struct {
+	Name   string `json:"name"`
+	Port   int    `json:"port,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+/*
+clusterInfo collects the cluster-related indicators found for a host and the role they point to.
+Role is "group_member" (Group Replication / InnoDB Cluster member), "router" (MySQL Router in front of a cluster), "router+group_member", or empty when the indicators are too weak to call.
+*/
+type clusterInfo struct {
+	Role       string             `json:"role,omitempty"`
+	Indicators []clusterIndicator `json:"indicators,omitempty"`
+}
+
+/*
+checkCluster runs the Group Replication / InnoDB Cluster follow-up checks against ip for a host whose main probe produced res.
+Function-level comment: probes the group communication port (open is enough, XCom does not speak first), the admin port and Router's classic ports (which must answer with a MySQL handshake), and Router's X Protocol ports (which must answer CapabilitiesGet), and adds hints from the main result: a target on a Router default port and a version too old for Group Replication. Each follow-up connection waits for the destination and subnet limits like the main probe. Returns nil when nothing was found.
+*/
+func checkCluster(ip string, addr netip.Addr, res Result, opts mysqlprobe.Options, dests *hostLimiter, subnets *subnetLimiter) *clusterInfo {
+	// Only the target itself is logged in to; the follow-up ports just need their handshake.
+	opts.Credentials = nil
+	limited := func(f func()) {
+		defer dests.acquire(ip)()
+		defer subnets.acquire(addr)()
+		f()
+	}
+	hostPort := func(port int) string { return net.JoinHostPort(ip, strconv.Itoa(port)) }
+	info := &clusterInfo{}
+	add := func(name string, port int, detail string) {
+		info.Indicators = append(info.Indicators, clusterIndicator{Name: name, Port: port, Detail: detail})
+	}
+
+	var member, router bool
+	limited(func() {
+		if conn, err := opts.Connect("tcp", hostPort(groupCommPort)); err == nil {
+			conn.Close()
+			add("group_communication_port_open", groupCommPort, "")
+			member = true
+		}
+	})
+	classic := []struct {
+		port int
+		name string
+	}{
+		{adminPort, "admin_port"},
+		{routerRWPort, "router_rw_port"},
+		{routerROPort, "router_ro_port"},
+	}
+	for _, c := range classic {
+		if c.port == res.Port {
+			continue
+		}
+		var r mysqlprobe.Result
+		limited(func() { r = mysqlprobe.Probe(hostPort(c.port), opts) })
+		if !r.MySQL {
+			continue
+		}
+		add(c.name, c.port, r.HandshakeInfo.ServerVersion)
+		if c.port != adminPort {
+			router = true
+		}
+	}
+	for _, port := range []int{routerXRWPort, routerXROPort} {
+		var x *mysqlprobe.XInfo
+		limited(func() { x = mysqlprobe.ProbeX(hostPort(port), opts) })
+		if x.Detected {
+			add("router_x_port", port, strings.Join(x.AuthMechanisms, ","))
+			router = true
+		}
+	}
+
+	switch res.Port {
+	case routerRWPort, routerROPort:
+		if res.MySQL {
+			add("target_on_router_port", res.Port, "")
+			router = true
+		}
+	}
+	if hs := res.HandshakeInfo; hs != nil && hs.Version != nil && member {
+		if v := hs.Version; v.Major*10000+v.Minor*100+v.Patch < minGroupVersion {
+			add("version_predates_group_replication", res.Port, hs.ServerVersion)
+			member = false
+		}
+	}
+
+	switch {
+	case member && router:
+		info.Role = "router+group_member"
+	case member:
+		info.Role = "group_member"
+	case router:
+		info.Role = "router"
+	}
+	if len(info.Indicators) == 0 {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+compatWarning is one client library (and the versions or settings of it) that would fail to connect to the server, and why.
+*/
+type compatWarning struct {
+	Client   string `json:"client"`
+	Affected string `json:"affected"`
+	Reason   string `json:"reason"`
+}
+
+/*
+compatRule is one known client incompatibility: the clients it affects and the server posture that triggers it.
+*/
+type compatRule struct {
+	client   string
+	affected string
+	reason   string
+	match    func(info *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool
+}
+
+/*
+defaultPlugin returns a match func for a handshake proposing one of plugins.
+*/
+func defaultPlugin(plugins ...string) func(*mysqlprobe.HandshakeInfo, *mysqlprobe.TLSInfo) bool {
+	return func(info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		for _, p := range plugins {
+			if info.AuthPluginName == p {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+/*
+rsaPluginWithoutTLS matches a handshake proposing plugin from a server that does not offer TLS, so a full password exchange has to encrypt with the server's RSA key instead.
+*/
+func rsaPluginWithoutTLS(plugin string) func(*mysqlprobe.HandshakeInfo, *mysqlprobe.TLSInfo) bool {
+	return func(info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return info.AuthPluginName == plugin && info.CapabilityFlags&mysqlprobe.ClientSSL == 0
+	}
+}
+
+/*
+legacyTLSOnly matches a server whose TLS stops at 1.0 or 1.1: it negotiated one of them when -tls-min-version allowed it, or refused the TLS 1.2-1.3 offered by default.
+*/
+func legacyTLSOnly(_ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+	if ti == nil {
+		return false
+	}
+	return ti.Version == "TLS 1.0" || ti.Version == "TLS 1.1" || strings.Contains(ti.Error, "protocol version")
+}
+
+/*
+noProtocol41 matches a pre-4.1 server, which lacks the 4.1 protocol every current driver requires.
+*/
+func noProtocol41(info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+	return info.CapabilityFlags&mysqlprobe.ClientProtocol41 == 0
+}
+
+/*
+oldPasswordOnly matches a server proposing the pre-4.1 password hash, or without secure connection support and so without anything better.
+*/
+func oldPasswordOnly(info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+	return info.AuthPluginName == "mysql_old_password" || (info.AuthPluginName == "" && info.CapabilityFlags&mysqlprobe.ClientSecureConnection == 0)
+}
+
+// Reasons given in compat_warnings.
+const (
+	reasonNoCachingSHA2  = "no caching_sha2_password support, so the login fails with an unknown authentication method"
+	reasonCachingSHA2RSA = "TLS is not offered, so the first login of each account after a server restart must fetch the server's RSA public key, which this client will not do"
+	reasonSHA256RSA      = "TLS is not offered, so every sha256_password login must encrypt the password with the server's RSA public key, which this client will not fetch"
+	reasonNativeRemoved  = "mysql_native_password was removed from MySQL 9.0 clients, so accounts on it (the MariaDB default) cannot log in"
+	reasonMariaDBPlugin  = "the default plugin is MariaDB-only (ed25519 or PARSEC), which this client does not implement"
+	reasonOldPassword    = "the pre-4.1 password hash is refused by this client"
+	reasonNoProtocol41   = "the server predates the MySQL 4.1 protocol this client requires"
+	reasonLegacyTLS      = "the server offers only TLS 1.0/1.1, which this client no longer negotiates"
+)
+
+// Client libraries named in compat_warnings.
+const (
+	clientMysqlnd    = "PHP mysqlnd (mysqli, PDO_MySQL)"
+	clientConnectorJ = "MySQL Connector/J"
+	clientGoMySQL    = "go-sql-driver/mysql"
+	clientPyMySQL    = "PyMySQL"
+	clientLibmysql   = "libmysqlclient (mysql CLI, mysqlclient for Python, DBD::mysql)"
+	clientNodeMySQL  = "Node.js mysql (mysqljs)"
+	clientJDBC       = "any JDBC driver"
+)
+
+// compatRules are the client incompatibilities checked against every handshake, grouped by the server posture that triggers them.
+var compatRules = []compatRule{
+	{clientMysqlnd, "PHP before 7.4", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientConnectorJ, "before 5.1.46, and 8.0 before 8.0.9", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientGoMySQL, "before 1.4.0", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientPyMySQL, "before 0.9.0", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientLibmysql, "before 5.7.23", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientNodeMySQL, "all versions", reasonNoCachingSHA2, defaultPlugin("caching_sha2_password")},
+	{clientConnectorJ, "8.0 and later without allowPublicKeyRetrieval=true", reasonCachingSHA2RSA, rsaPluginWithoutTLS("caching_sha2_password")},
+	{clientLibmysql, "without --get-server-public-key or --server-public-key-path", reasonCachingSHA2RSA, rsaPluginWithoutTLS("caching_sha2_password")},
+	{clientPyMySQL, "without the cryptography package installed", reasonCachingSHA2RSA, rsaPluginWithoutTLS("caching_sha2_password")},
+	{clientConnectorJ, "without allowPublicKeyRetrieval=true", reasonSHA256RSA, rsaPluginWithoutTLS("sha256_password")},
+	{clientLibmysql, "without --get-server-public-key or --server-public-key-path", reasonSHA256RSA, rsaPluginWithoutTLS("sha256_password")},
+	{clientNodeMySQL, "all versions", "no sha256_password support", defaultPlugin("sha256_password")},
+	{clientLibmysql, "9.0 and later", reasonNativeRemoved, defaultPlugin("mysql_native_password")},
+	{clientLibmysql, "all versions", reasonMariaDBPlugin, defaultPlugin("client_ed25519", "parsec")},
+	{clientConnectorJ, "all versions", reasonMariaDBPlugin, defaultPlugin("client_ed25519", "parsec")},
+	{clientMysqlnd, "all versions", reasonMariaDBPlugin, defaultPlugin("client_ed25519", "parsec")},
+	{clientGoMySQL, "before 1.7.0 (ed25519), all versions (PARSEC)", reasonMariaDBPlugin, defaultPlugin("client_ed25519", "parsec")},
+	{clientMysqlnd, "PHP 5.4 and later", reasonOldPassword, oldPasswordOnly},
+	{clientGoMySQL, "without allowOldPasswords=true", reasonOldPassword, oldPasswordOnly},
+	{clientLibmysql, "5.7 and later", reasonOldPassword, oldPasswordOnly},
+	{clientMysqlnd, "all versions", reasonNoProtocol41, noProtocol41},
+	{clientGoMySQL, "all versions", reasonNoProtocol41, noProtocol41},
+	{clientConnectorJ, "5.1 and later", reasonNoProtocol41, noProtocol41},
+	{clientLibmysql, "8.0.28 and later", reasonLegacyTLS, legacyTLSOnly},
+	{clientConnectorJ, "8.0.28 and later", reasonLegacyTLS, legacyTLSOnly},
+	{clientJDBC, "on JDK 8u291, 11.0.11, and later, which disable TLS 1.0/1.1", reasonLegacyTLS, legacyTLSOnly},
+}
+
+/*
+classifyCompat lists the common client libraries and versions that would fail to connect to the server, from its handshake and, when the probe continued into TLS, the TLS outcome.
+Function-level comment: judged from the default auth plugin the handshake proposes, so accounts on another plugin may fare differently; TLS versions are only known with -tls.
+*/
+func classifyCompat(info *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) []compatWarning {
+	if info == nil {
+		return nil
+	}
+	var warnings []compatWarning
+	for _, r := range compatRules {
+		if r.match(info, ti) {
+			warnings = append(warnings, compatWarning{Client: r.client, Affected: r.affected, Reason: r.reason})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+/*
+compressor is a streaming compressor that can be flushed to a record boundary.
+*/
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+/*
+compressedWriter compresses output for -compress.
+With flushEach set, every write is flushed through so what has been checkpointed as written is decodable even if the scan is killed; otherwise data is flushed only on Close, which compresses best.
+*/
+type compressedWriter struct {
+	c         compressor
+	flushEach bool
+}
+
+/*
+newCompressedWriter wraps w in the named compression ("gzip" or "zstd").
+*/
+func newCompressedWriter(kind string, w io.Writer, flushEach bool) (*compressedWriter, error) {
+	var c compressor
+	switch kind {
+	case "gzip":
+		c = gzip.NewWriter(w)
+	case "zstd":
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		c = zw
+	default:
+		return nil, fmt.Errorf("unknown compression %q (want gzip or zstd)", kind)
+	}
+	return &compressedWriter{c: c, flushEach: flushEach}, nil
+}
+
+/*
+Write compresses p, flushing it through when flushEach is set.
+*/
+func (cw *compressedWriter) Write(p []byte) (int, error) {
+	n, err := cw.c.Write(p)
+	if err == nil && cw.flushEach {
+		err = cw.c.Flush()
+	}
+	return n, err
+}
+
+/*
+Close finishes the compressed stream; the underlying writer is left open.
+*/
+func (cw *compressedWriter) Close() error {
+	return cw.c.Close()
+}
+
+/*
+decompressedReader returns r's content, transparently decompressing gzip or zstd streams recognized by their magic bytes.
+*/
+func decompressedReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return br, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	subcommands["corpus"] = runCorpus
+}
+
+// Suffixes of a corpus entry's files: the raw handshake packet, as read off the wire, and its metadata.
+const (
+	corpusRawSuffix  = ".bin"
+	corpusMetaSuffix = ".json"
+)
+
+/*
+corpusEntry is the metadata saved beside one distinct handshake: what it parsed to, how the scan labeled it, and where and how often it was seen.
+*/
+type corpusEntry struct {
+	Fingerprint     string    `json:"handshake_fingerprint"`
+	File            string    `json:"file"`
+	Size            int       `json:"size"`
+	ServerVersion   string    `json:"server_version"`
+	ProtocolVersion uint8     `json:"protocol_version"`
+	CapabilityFlags uint32    `json:"capability_flags"`
+	CharacterSet    uint8     `json:"character_set"`
+	StatusFlags     uint16    `json:"status_flags"`
+	AuthPluginName  string    `json:"auth_plugin_name,omitempty"`
+	Anomalies       []string  `json:"anomalies,omitempty"`
+	Provider        string    `json:"provider,omitempty"`
+	BuildVariant    string    `json:"build_variant,omitempty"`
+	PlatformGuess   string    `json:"platform_guess,omitempty"`
+	Middleware      string    `json:"middleware,omitempty"`
+	FirstTarget     string    `json:"first_target"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+	Seen            int       `json:"seen"`
+	ScanIDs         []string  `json:"scan_ids,omitempty"`
+}
+
+/*
+handshakeCorpus saves every distinct raw handshake a scan reads under dir, one <fingerprint>.bin and <fingerprint>.json per handshake_fingerprint.
+Entries already in dir from earlier scans are extended rather than replaced, so repeated scans into one directory grow a single deduplicated corpus.
+*/
+type handshakeCorpus struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]*corpusEntry
+	added   int
+}
+
+/*
+newHandshakeCorpus creates dir if needed and returns a corpus writing into it.
+*/
+func newHandshakeCorpus(dir string) (*handshakeCorpus, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &handshakeCorpus{dir: dir, entries: make(map[string]*corpusEntry)}, nil
+}
+
+/*
+add records res's raw handshake, writing the packet the first time its fingerprint is seen and counting it otherwise.
+Function-level comment: results without a raw handshake (no MySQL, or results returned by -worker processes, which do not carry the bytes) are ignored. Metadata is written by close.
+*/
+func (c *handshakeCorpus) add(res Result) error {
+	if c == nil || res.RawHandshake == nil || res.HandshakeInfo == nil || res.HandshakeInfo.Fingerprint == "" {
+		return nil
+	}
+	info := res.HandshakeInfo
+	now := time.Now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[info.Fingerprint]
+	if !ok {
+		var err error
+		if e, err = c.load(info.Fingerprint); err != nil {
+			return err
+		}
+		if e == nil {
+			e = newCorpusEntry(res, now)
+			if err := os.WriteFile(filepath.Join(c.dir, e.File), res.RawHandshake, 0o644); err != nil {
+				return err
+			}
+			c.added++
+		}
+		c.entries[info.Fingerprint] = e
+	}
+	e.Seen++
+	e.LastSeen = now
+	if res.ScanID != "" && !slices.Contains(e.ScanIDs, res.ScanID) {
+		e.ScanIDs = append(e.ScanIDs, res.ScanID)
+	}
+	return nil
+}
+
+/*
+load reads the metadata an earlier scan saved for fingerprint, or returns nil when there is none.
+*/
+func (c *handshakeCorpus) load(fingerprint string) (*corpusEntry, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, fingerprint+corpusMetaSuffix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e corpusEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("%s: %w", fingerprint+corpusMetaSuffix, err)
+	}
+	return &e, nil
+}
+
+/*
+close writes the metadata of every handshake seen during the scan and reports how many were new to the corpus.
+*/
+func (c *handshakeCorpus) close() (added int, err error) {
+	if c == nil {
+		return 0, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for fp, e := range c.entries {
+		data, merr := json.MarshalIndent(e, "", "  ")
+		if merr != nil {
+			err = errors.Join(err, merr)
+			continue
+		}
+		err = errors.Join(err, os.WriteFile(filepath.Join(c.dir, fp+corpusMetaSuffix), append(data, '\n'), 0o644))
+	}
+	return c.added, err
+}
+
+/*
+newCorpusEntry builds the metadata of a handshake first seen in res.
+*/
+func newCorpusEntry(res Result, now time.Time) *corpusEntry {
+	info := res.HandshakeInfo
+	e := &corpusEntry{
+		Fingerprint:     info.Fingerprint,
+		File:            info.Fingerprint + corpusRawSuffix,
+		Size:            len(res.RawHandshake),
+		ServerVersion:   info.ServerVersion,
+		ProtocolVersion: info.ProtocolVersion,
+		CapabilityFlags: info.CapabilityFlags,
+		CharacterSet:    info.CharacterSet,
+		StatusFlags:     info.StatusFlags,
+		AuthPluginName:  info.AuthPluginName,
+		Provider:        res.Provider,
+		BuildVariant:    res.BuildVariant,
+		PlatformGuess:   res.PlatformGuess,
+		Middleware:      res.Middleware,
+		FirstTarget:     corpusTarget(res),
+		FirstSeen:       now,
+	}
+	for _, a := range info.Anomalies {
+		e.Anomalies = append(e.Anomalies, a.Code)
+	}
+	return e
+}
+
+/*
+corpusTarget names where a result came from: ip:port, host:port, or the pipe path.
+*/
+func corpusTarget(res Result) string {
+	switch {
+	case res.Pipe != "":
+		return res.Pipe
+	case res.TargetIP != "":
+		return net.JoinHostPort(res.TargetIP, strconv.Itoa(res.Port))
+	}
+	return net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+}
+
+/*
+runCorpus is the corpus subcommand.
+Function-level comment: "corpus export [scan flags] dir/" runs a scan with -corpus dir/, saving every distinct raw handshake with its metadata; the scan's results are printed as usual. The directory may also come before the flags.
+*/
+func runCorpus(args []string) int {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintln(os.Stderr, "usage: mysql_scout corpus export [scan flags] dir/")
+		return 2
+	}
+	args = args[1:]
+	var dir string
+	switch {
+	case len(args) > 0 && !strings.HasPrefix(args[0], "-"):
+		dir, args = args[0], args[1:]
+	case len(args) > 0 && !strings.HasPrefix(args[len(args)-1], "-"):
+		dir, args = args[len(args)-1], args[:len(args)-1]
+	}
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "usage: mysql_scout corpus export [scan flags] dir/")
+		return 2
+	}
+	return scanCommand("corpus export", append(slices.Clip(args), "-corpus", dir))
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// vaultScheme prefixes a -credentials value naming a Vault secret rather than a file.
+const vaultScheme = "vault://"
+
+// vaultTimeout bounds each request to Vault, and the KMS call that unwraps a credentials file.
+const vaultTimeout = 10 * time.Second
+
+// credentialFallback is the credentials document key matching every target no other key matches.
+const credentialFallback = "*"
+
+/*
+credentialEntry is one target's login in a credentials document: a user and either the password itself or the Vault secret to read it from at scan time.
+Vault is a secret path as the HTTP API names it (secret/data/mysql/monitor for KV version 2), optionally followed by #field to read the password from a field other than "password"; the secret's user or username field stands in for an empty User.
+*/
+type credentialEntry struct {
+	User     string `json:"user"`
+	Password string `json:"password,omitempty"`
+	Vault    string `json:"vault,omitempty"`
+}
+
+/*
+credentialPrefix is a credentials document key naming a network, with the entry for targets inside it.
+*/
+type credentialPrefix struct {
+	prefix netip.Prefix
+	entry  *credentialEntry
+}
+
+/*
+credentialStore is -credentials: per-target logins for authenticated mode, loaded from an age- or KMS-encrypted file or a Vault secret, with passwords held in Vault fetched when a target first needs them.
+The document maps targets to entries; a key is host:port, a host name or IP, a CIDR, or "*". A target takes the first key that matches, in that order, the narrowest CIDR winning.
+*/
+type credentialStore struct {
+	exact    map[string]*credentialEntry
+	prefixes []credentialPrefix
+	fallback *credentialEntry
+	vault    *vaultClient
+
+	mu      sync.Mutex
+	secrets map[string]*vaultSecret
+}
+
+/*
+vaultSecret is one Vault read, shared by every target whose entry names the same secret.
+*/
+type vaultSecret struct {
+	once  sync.Once
+	creds mysqlprobe.Credentials
+	err   error
+}
+
+/*
+loadCredentialStore reads the credentials document from source: a vault:// secret, or a file encrypted with age (to an identity in identityPath) or by AWS KMS.
+Function-level comment: a plaintext file is accepted only when it holds no passwords, only Vault references, so no password sits on disk in the clear. The AWS configuration and VAULT_ADDR/VAULT_TOKEN come from the environment, as the aws and vault CLIs read them.
+*/
+func loadCredentialStore(ctx context.Context, source, identityPath string) (*credentialStore, error) {
+	var vault *vaultClient
+	var doc map[string]*credentialEntry
+	if path, ok := strings.CutPrefix(source, vaultScheme); ok {
+		var err error
+		if vault, err = newVaultClient(); err != nil {
+			return nil, err
+		}
+		data, err := vault.read(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("%s: not a credentials document: %w", source, err)
+		}
+	} else {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		plain := json.Unmarshal(data, &doc) == nil
+		if !plain {
+			if data, err = decryptCredentials(ctx, data, identityPath); err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				return nil, fmt.Errorf("%s: not a credentials document: %w", source, err)
+			}
+		}
+		for key, e := range doc {
+			if plain && e != nil && e.Password != "" {
+				return nil, fmt.Errorf("%s holds a plaintext password (for %q): encrypt it with age or KMS, or keep the password in Vault", source, key)
+			}
+		}
+	}
+	s := &credentialStore{exact: make(map[string]*credentialEntry), vault: vault, secrets: make(map[string]*vaultSecret)}
+	for key, e := range doc {
+		if err := s.add(key, e); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		if e.Vault != "" && s.vault == nil {
+			var err error
+			if s.vault, err = newVaultClient(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	slices.SortFunc(s.prefixes, func(a, b credentialPrefix) int { return b.prefix.Bits() - a.prefix.Bits() })
+	return s, nil
+}
+
+/*
+add files one document entry under its key.
+*/
+func (s *credentialStore) add(key string, e *credentialEntry) error {
+	switch {
+	case e == nil || (e.Password == "" && e.Vault == ""):
+		return fmt.Errorf("credentials for %q have neither password nor vault", key)
+	case e.Password != "" && e.Vault != "":
+		return fmt.Errorf("credentials for %q have both password and vault", key)
+	case key == credentialFallback:
+		s.fallback = e
+		return nil
+	}
+	if prefix, err := netip.ParsePrefix(key); err == nil {
+		s.prefixes = append(s.prefixes, credentialPrefix{prefix.Masked(), e})
+		return nil
+	}
+	if host, port, err := net.SplitHostPort(key); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port in credentials key %q", key)
+		}
+		s.exact[credentialKey(host, p)] = e
+		return nil
+	}
+	s.exact[credentialKey(key, 0)] = e
+	return nil
+}
+
+/*
+credentialKey normalizes a host (name or IP) and optional port for lookups: names lowercased, IPs in canonical form.
+*/
+func credentialKey(host string, port int) string {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		host = addr.Unmap().String()
+	} else {
+		host = normalizeHostname(host)
+	}
+	if port == 0 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+/*
+lookup returns the login for a target, given as written (host), as probed (ip, "" for a pipe), and its port; nil when no key matches.
+Function-level comment: a Vault-backed entry is read on first use and cached for the rest of the scan, so a failing Vault fails every target sharing the secret with the same error.
+*/
+func (s *credentialStore) lookup(ctx context.Context, host, ip string, port int) (*mysqlprobe.Credentials, error) {
+	e := s.match(host, ip, port)
+	if e == nil {
+		return nil, nil
+	}
+	if e.Vault == "" {
+		return &mysqlprobe.Credentials{User: e.User, Password: e.Password}, nil
+	}
+	s.mu.Lock()
+	secret, ok := s.secrets[e.Vault]
+	if !ok {
+		secret = &vaultSecret{}
+		s.secrets[e.Vault] = secret
+	}
+	s.mu.Unlock()
+	secret.once.Do(func() {
+		secret.creds, secret.err = s.vault.credentials(ctx, e.Vault)
+	})
+	if secret.err != nil {
+		return nil, secret.err
+	}
+	creds := secret.creds
+	if e.User != "" {
+		creds.User = e.User
+	}
+	if creds.User == "" {
+		return nil, fmt.Errorf("vault secret %s has no user and the credentials entry names none", e.Vault)
+	}
+	return &creds, nil
+}
+
+/*
+match finds the entry for a target: host:port, then ip:port, the host, the IP, the narrowest CIDR holding the IP, and finally "*".
+*/
+func (s *credentialStore) match(host, ip string, port int) *credentialEntry {
+	keys := []string{credentialKey(host, port)}
+	if ip != "" {
+		keys = append(keys, credentialKey(ip, port))
+	}
+	keys = append(keys, credentialKey(host, 0))
+	if ip != "" {
+		keys = append(keys, credentialKey(ip, 0))
+	}
+	for _, k := range keys {
+		if e, ok := s.exact[k]; ok {
+			return e
+		}
+	}
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		addr = addr.Unmap().WithZone("")
+		for _, p := range s.prefixes {
+			if p.prefix.Contains(addr) {
+				return p.entry
+			}
+		}
+	}
+	return s.fallback
+}
+
+/*
+decryptCredentials decrypts a credentials file: age, binary or armored, with the identities in identityPath; anything else is taken as an AWS KMS ciphertext blob, binary or base64 as aws kms encrypt prints it.
+*/
+func decryptCredentials(ctx context.Context, data []byte, identityPath string) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	switch {
+	case bytes.HasPrefix(data, []byte(armor.Header)):
+		src = armor.NewReader(src)
+		fallthrough
+	case bytes.HasPrefix(data, []byte("age-encryption.org/")):
+		if identityPath == "" {
+			return nil, errors.New("age-encrypted: -credentials-identity is required")
+		}
+		f, err := os.Open(identityPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		ids, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("-credentials-identity: %w", err)
+		}
+		r, err := age.Decrypt(src, ids...)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+	blob := data
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		blob = decoded
+	}
+	ctx, cancel := context.WithTimeout(ctx, vaultTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: blob})
+	if err != nil {
+		return nil, fmt.Errorf("neither age-encrypted nor decryptable with KMS: %w", err)
+	}
+	return out.Plaintext, nil
+}
+
+/*
+vaultClient reads secrets over Vault's HTTP API, configured like the vault CLI: VAULT_ADDR, VAULT_TOKEN (else ~/.vault-token), VAULT_NAMESPACE, and VAULT_CACERT.
+*/
+type vaultClient struct {
+	addr      string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+/*
+newVaultClient configures a client from the environment; it fails without an address or token.
+*/
+func newVaultClient() (*vaultClient, error) {
+	c := &vaultClient{addr: strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"), token: os.Getenv("VAULT_TOKEN"), namespace: os.Getenv("VAULT_NAMESPACE")}
+	if c.addr == "" {
+		return nil, errors.New("vault: VAULT_ADDR is not set")
+	}
+	if c.token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				c.token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if c.token == "" {
+		return nil, errors.New("vault: no token in VAULT_TOKEN or ~/.vault-token")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if path := os.Getenv("VAULT_CACERT"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("vault: VAULT_CACERT: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("vault: VAULT_CACERT %s holds no certificates", path)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	c.http = &http.Client{Timeout: vaultTimeout, Transport: transport}
+	return c, nil
+}
+
+/*
+read returns the data of the secret at path, unwrapping KV version 2's data.data.
+*/
+func (c *vaultClient) read(ctx context.Context, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("vault: read %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault: read %s: %s", path, resp.Status)
+	}
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("vault: read %s: %w", path, err)
+	}
+	if inner, ok := body.Data["data"].(map[string]any); ok {
+		if _, v2 := body.Data["metadata"]; v2 {
+			return inner, nil
+		}
+	}
+	return body.Data, nil
+}
+
+/*
+credentials reads a login from the secret ref names: path#field, where field (default "password") holds the password and user or username the user.
+*/
+func (c *vaultClient) credentials(ctx context.Context, ref string) (mysqlprobe.Credentials, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok {
+		field = "password"
+	}
+	data, err := c.read(ctx, path)
+	if err != nil {
+		return mysqlprobe.Credentials{}, err
+	}
+	password, ok := data[field].(string)
+	if !ok {
+		return mysqlprobe.Credentials{}, fmt.Errorf("vault: secret %s has no string field %q", path, field)
+	}
+	user, _ := data["user"].(string)
+	if user == "" {
+		user, _ = data["username"].(string)
+	}
+	return mysqlprobe.Credentials{User: user, Password: password}, nil
+}
+
+/*
+apply sets opts.Credentials to the target's login; a nil store leaves -user's in place.
+Function-level comment: a target no key matches is probed without logging in, as is one whose login could not be read, the error returned for credentialFailure to report.
+*/
+func (s *credentialStore) apply(ctx context.Context, opts *mysqlprobe.Options, host, ip string, port int) error {
+	if s == nil {
+		return nil
+	}
+	creds, err := s.lookup(ctx, host, ip, port)
+	opts.Credentials = creds
+	return err
+}
+
+/*
+credentialFailure records under auth that a MySQL target was not logged in to because its credentials could not be read.
+*/
+func credentialFailure(res *Result, err error) {
+	if err != nil && res.MySQL && res.Auth == nil {
+		res.Auth = &mysqlprobe.AuthInfo{Error: "credentials: " + err.Error()}
+	}
+}
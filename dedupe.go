@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+)
+
+/*
+dedupeWriter is the -dedupe wrapper around another format: it holds every result until the scan ends, collapses results for the same address and port (one server reached through several hostnames) into one, and hands them on sorted by IP and port, so two runs over the same targets diff cleanly.
+*/
+type dedupeWriter struct {
+	next    resultWriter
+	results map[string]*Result
+}
+
+/*
+newDedupeWriter wraps next.
+*/
+func newDedupeWriter(next resultWriter) *dedupeWriter {
+	return &dedupeWriter{next: next, results: make(map[string]*Result)}
+}
+
+/*
+write holds res, merging it into an earlier result for the same address and port.
+Function-level comment: the merged record lists every hostname in hostnames and keeps the most informative of the results: one that detected MySQL over one that did not, and one that connected over one that failed; between equals, the first. hostname and target_host are the first of the names in sorted order.
+*/
+func (dw *dedupeWriter) write(res Result) error {
+	key := res.Pipe
+	if key == "" {
+		key = net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+	}
+	prev, ok := dw.results[key]
+	if !ok {
+		res.Hostnames = appendHostname(nil, res.Hostname)
+		dw.results[key] = &res
+		return nil
+	}
+	names := appendHostname(prev.Hostnames, res.Hostname)
+	if (res.MySQL && !prev.MySQL) || (res.OK && !prev.OK) {
+		*prev = res
+	}
+	prev.Hostnames = names
+	if len(names) > 0 {
+		sort.Strings(names)
+		prev.Hostname, prev.TargetHost = names[0], names[0]
+	}
+	return nil
+}
+
+/*
+appendHostname adds name to names unless it is empty or already there.
+*/
+func appendHostname(names []string, name string) []string {
+	if name == "" {
+		return names
+	}
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+/*
+flush writes the held results in address order (IPv4 before IPv6, then hosts that are not IP literals, then named pipes), by port within an address, and finishes the wrapped format.
+Function-level comment: hostnames is only kept when a result was reached through more than one name; hostname already says the rest.
+*/
+func (dw *dedupeWriter) flush() error {
+	sorted := make([]*Result, 0, len(dw.results))
+	for _, res := range dw.results {
+		if len(res.Hostnames) < 2 {
+			res.Hostnames = nil
+		}
+		sorted = append(sorted, res)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return resultLess(sorted[i], sorted[j]) })
+	for _, res := range sorted {
+		if err := dw.next.write(*res); err != nil {
+			return err
+		}
+	}
+	return flushWriter(dw.next)
+}
+
+/*
+resultLess orders results by address, then port, then pipe path.
+*/
+func resultLess(a, b *Result) bool {
+	aa, aerr := netip.ParseAddr(a.Host)
+	ba, berr := netip.ParseAddr(b.Host)
+	switch {
+	case (a.Host == "") != (b.Host == ""):
+		return a.Host != ""
+	case (aerr == nil) != (berr == nil):
+		return aerr == nil
+	case aerr == nil && aa != ba:
+		return aa.Less(ba)
+	case aerr != nil && a.Host != b.Host:
+		return a.Host < b.Host
+	case a.Port != b.Port:
+		return a.Port < b.Port
+	}
+	return a.Pipe < b.Pipe
+}
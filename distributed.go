@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+const (
+	// maxBatchAttempts is how many leases a batch gets before its targets are reported as failed.
+	maxBatchAttempts = 3
+	// workerPollInterval is how long a worker waits when every remaining batch is leased elsewhere.
+	workerPollInterval = 2 * time.Second
+	// maxWorkerErrors is how many consecutive coordinator request failures a worker tolerates before giving up.
+	maxWorkerErrors = 5
+	// coordinatorDrainGrace keeps the coordinator answering "done" briefly so polling workers exit cleanly.
+	coordinatorDrainGrace = 5 * time.Second
+)
+
+/*
+wireTarget is a target as exchanged between coordinator and workers.
+*/
+type wireTarget struct {
+	Host     string         `json:"host"`
+	Port     int            `json:"port"`
+	RunUDP   bool           `json:"run_udp,omitempty"`
+	Hostname string         `json:"hostname,omitempty"`
+	Source   string         `json:"source,omitempty"`
+	Protocol string         `json:"protocol,omitempty"`
+	Options  *targetOptions `json:"options,omitempty"`
+}
+
+/*
+newWireTarget converts t for a lease.
+*/
+func newWireTarget(t target) wireTarget {
+	return wireTarget{Host: t.host, Port: t.port, RunUDP: t.runUDP, Hostname: t.hostname, Source: t.source, Protocol: t.protocol, Options: t.opts}
+}
+
+/*
+target converts a leased target back for scanning.
+*/
+func (t wireTarget) target() target {
+	return target{host: t.Host, port: t.Port, runUDP: t.RunUDP, hostname: t.Hostname, source: t.Source, protocol: t.Protocol, opts: t.Options}
+}
+
+/*
+batchLease is what the coordinator hands a worker: a batch of targets plus the exclusions workers must enforce.
+*/
+type batchLease struct {
+	ID      int          `json:"id"`
+	Targets []wireTarget `json:"targets"`
+	Exclude []string     `json:"exclude,omitempty"`
+}
+
+/*
+batchResults is what a worker posts back for a finished batch.
+*/
+type batchResults struct {
+	Results []Result `json:"results"`
+}
+
+/*
+batchState tracks one batch on the coordinator.
+*/
+type batchState struct {
+	targets     []target
+	attempts    int
+	leased      bool
+	leasedUntil time.Time
+	done        bool
+}
+
+/*
+coordinator hands out target batches over HTTP, collects results, and re-leases batches whose worker went quiet.
+*/
+type coordinator struct {
+	mu           sync.Mutex
+	batches      []*batchState
+	queue        []int
+	remaining    int
+	leaseTimeout time.Duration
+	exclude      []string
+	emit         func(target, Result)
+	finished     chan struct{}
+}
+
+/*
+newCoordinator splits targets into batches of batchSize.
+Function-level comment: emit is called (serialized) for every result received, and once per target of a batch that exhausted its attempts.
+*/
+func newCoordinator(targets []target, batchSize int, leaseTimeout time.Duration, ex *exclusionList, emit func(target, Result)) *coordinator {
+	batchSize = max(batchSize, 1)
+	c := &coordinator{leaseTimeout: leaseTimeout, exclude: ex.entries(), emit: emit, finished: make(chan struct{})}
+	for start := 0; start < len(targets); start += batchSize {
+		end := min(start+batchSize, len(targets))
+		c.queue = append(c.queue, len(c.batches))
+		c.batches = append(c.batches, &batchState{targets: targets[start:end]})
+	}
+	c.remaining = len(c.batches)
+	if c.remaining == 0 {
+		close(c.finished)
+	}
+	return c
+}
+
+/*
+reclaimExpired requeues batches whose lease ran out, failing those that used up their attempts.
+Function-level comment: caller must hold c.mu.
+*/
+func (c *coordinator) reclaimExpired(now time.Time) {
+	for id, b := range c.batches {
+		if !b.leased || b.done || now.Before(b.leasedUntil) {
+			continue
+		}
+		b.leased = false
+		if b.attempts < maxBatchAttempts {
+			c.queue = append(c.queue, id)
+			continue
+		}
+		for _, t := range b.targets {
+			c.emit(t, Result{Host: t.host, Hostname: t.hostname, Port: t.port, TargetHost: t.name(), Result: mysqlprobe.Result{
+				Error: fmt.Sprintf("batch %d failed after %d attempts", id, b.attempts),
+			}, Scanner: scannerBuild})
+		}
+		c.finish(b)
+	}
+}
+
+/*
+finish marks a batch complete and signals when it was the last one.
+Function-level comment: caller must hold c.mu.
+*/
+func (c *coordinator) finish(b *batchState) {
+	b.done = true
+	if c.remaining--; c.remaining == 0 {
+		close(c.finished)
+	}
+}
+
+/*
+handleLease serves POST /lease.
+Function-level comment: answers 200 with a batch, 204 when every outstanding batch is leased to someone else, and 410 once the scan is complete.
+*/
+func (c *coordinator) handleLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c.mu.Lock()
+	c.reclaimExpired(time.Now())
+	if c.remaining == 0 {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	// Skip batches that a late submission completed while they sat requeued.
+	for len(c.queue) > 0 && c.batches[c.queue[0]].done {
+		c.queue = c.queue[1:]
+	}
+	if len(c.queue) == 0 {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	id := c.queue[0]
+	c.queue = c.queue[1:]
+	b := c.batches[id]
+	b.attempts++
+	b.leased = true
+	b.leasedUntil = time.Now().Add(c.leaseTimeout)
+	lease := batchLease{ID: id, Targets: make([]wireTarget, len(b.targets)), Exclude: c.exclude}
+	for i, t := range b.targets {
+		lease.Targets[i] = newWireTarget(t)
+	}
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lease)
+}
+
+/*
+matchResults pairs each result with the batch target it reports on.
+Function-level comment: fails when a result names a host:port outside the batch, or reports a target more often than the batch holds it; results missing for some targets are allowed, since a worker may drop them under -output-queue-policy drop.
+*/
+func (b *batchState) matchResults(results []Result) ([]target, error) {
+	pending := make(map[string][]target, len(b.targets))
+	for _, t := range b.targets {
+		key := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+		pending[key] = append(pending[key], t)
+	}
+	matched := make([]target, len(results))
+	for i, res := range results {
+		key := net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+		ts := pending[key]
+		if len(ts) == 0 {
+			return nil, fmt.Errorf("result for %s is not part of this batch", key)
+		}
+		matched[i], pending[key] = ts[0], ts[1:]
+	}
+	return matched, nil
+}
+
+/*
+handleResults serves POST /results?batch=ID.
+Function-level comment: the first complete submission for a batch wins; late duplicates from a worker whose lease expired get 409 and are discarded. Results for a batch that was never leased, or for targets the batch does not hold, are rejected with 400 before any of them is emitted.
+*/
+func (c *coordinator) handleResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Query().Get("batch"))
+	if err != nil || id < 0 || id >= len(c.batches) {
+		http.Error(w, "unknown batch", http.StatusBadRequest)
+		return
+	}
+	var body batchResults
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad results: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.batches[id]
+	if b.done {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	if b.attempts == 0 {
+		http.Error(w, fmt.Sprintf("batch %d was never leased", id), http.StatusBadRequest)
+		return
+	}
+	targets, err := b.matchResults(body.Results)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("batch %d: %v", id, err), http.StatusBadRequest)
+		return
+	}
+	for i, res := range body.Results {
+		c.emit(targets[i], res)
+	}
+	b.leased = false
+	c.finish(b)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+/*
+runCoordinator serves batches on listenAddr until every batch is done.
+Function-level comment: after the last batch completes it keeps answering for coordinatorDrainGrace so idle workers see 410 and exit, then shuts the server down.
+*/
+func runCoordinator(listenAddr string, c *coordinator) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lease", c.handleLease)
+	mux.HandleFunc("/results", c.handleResults)
+	srv := &http.Server{Addr: listenAddr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-c.finished:
+	}
+	time.Sleep(coordinatorDrainGrace)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+/*
+workerClient talks to a coordinator on behalf of a worker.
+*/
+type workerClient struct {
+	base string
+	http *http.Client
+}
+
+/*
+lease asks the coordinator for the next batch.
+Function-level comment: returns the batch (nil when none is available right now) and whether the scan is finished.
+*/
+func (wc *workerClient) lease() (*batchLease, bool, error) {
+	resp, err := wc.http.Post(wc.base+"/lease", "application/json", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var lease batchLease
+		if err := json.NewDecoder(resp.Body).Decode(&lease); err != nil {
+			return nil, false, fmt.Errorf("decode lease: %w", err)
+		}
+		return &lease, false, nil
+	case http.StatusNoContent:
+		return nil, false, nil
+	case http.StatusGone:
+		return nil, true, nil
+	default:
+		return nil, false, fmt.Errorf("lease: unexpected status %s", resp.Status)
+	}
+}
+
+/*
+submit posts a batch's results, retrying transient failures with exponential backoff.
+Function-level comment: a 409 means another worker already delivered this batch and is not an error.
+*/
+func (wc *workerClient) submit(id int, results []Result) error {
+	body, err := json.Marshal(batchResults{Results: results})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/results?batch=%d", wc.base, id)
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := wc.http.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			switch {
+			case resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusConflict:
+				return nil
+			case resp.StatusCode < 500:
+				return fmt.Errorf("submit batch %d: %s", id, resp.Status)
+			}
+			err = fmt.Errorf("submit batch %d: %s", id, resp.Status)
+		}
+		if attempt == maxBatchAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+/*
+runWorker pulls batches from the coordinator at baseURL, scans them with cfg, and pushes results back.
+Function-level comment: coordinator exclusions are merged into the local ones for each batch; the worker exits when the coordinator reports the scan finished, when -max-runtime has passed (after its current batch), or after maxWorkerErrors consecutive request failures.
+*/
+func runWorker(baseURL string, cfg scanConfig) error {
+	wc := &workerClient{base: strings.TrimSuffix(baseURL, "/"), http: &http.Client{Timeout: time.Minute}}
+	localExclusions := cfg.exclusions
+	failures := 0
+	for {
+		if cfg.deadlinePassed() {
+			return nil
+		}
+		lease, finished, err := wc.lease()
+		if err != nil {
+			if failures++; failures >= maxWorkerErrors {
+				return err
+			}
+			time.Sleep(workerPollInterval)
+			continue
+		}
+		failures = 0
+		if finished {
+			return nil
+		}
+		if lease == nil {
+			time.Sleep(workerPollInterval)
+			continue
+		}
+
+		// A leased batch is finished even past -max-runtime, so the coordinator need not re-lease it.
+		batchCfg := cfg
+		batchCfg.deadline = time.Time{}
+		if batchCfg.exclusions, err = mergeExclusions(localExclusions, lease.Exclude); err != nil {
+			return fmt.Errorf("batch %d exclusions: %w", lease.ID, err)
+		}
+		targets := make([]target, len(lease.Targets))
+		for i, t := range lease.Targets {
+			targets[i] = t.target()
+		}
+		results := make([]Result, 0, len(targets))
+		runScan(slices.Values(targets), batchCfg, func(_ target, res Result) {
+			results = append(results, res)
+		})
+		if err := wc.submit(lease.ID, results); err != nil {
+			fmt.Fprintf(os.Stderr, "worker: %v (batch will be re-leased)\n", err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func TestCoordinatorResults(t *testing.T) {
+	targets := []target{
+		{host: "192.0.2.1", port: 3306, hostname: "a.example"},
+		{host: "192.0.2.2", port: 3306},
+		{host: "192.0.2.3", port: 3306},
+		{host: "192.0.2.4", port: 3306},
+	}
+	var emitted []target
+	c := newCoordinator(targets, 2, time.Minute, &exclusionList{}, func(t target, _ Result) {
+		emitted = append(emitted, t)
+	})
+	lease := func() batchLease {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		c.handleLease(rec, httptest.NewRequest(http.MethodPost, "/lease", nil))
+		var l batchLease
+		if err := json.NewDecoder(rec.Body).Decode(&l); err != nil {
+			t.Fatalf("lease: %v", err)
+		}
+		return l
+	}
+	submit := func(batch string, hosts ...string) int {
+		t.Helper()
+		var body batchResults
+		for _, h := range hosts {
+			body.Results = append(body.Results, Result{Host: h, Port: 3306, Result: mysqlprobe.Result{Error: "refused"}})
+		}
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		c.handleResults(rec, httptest.NewRequest(http.MethodPost, "/results?batch="+batch, strings.NewReader(string(data))))
+		return rec.Code
+	}
+
+	if got := submit("0", "192.0.2.1", "192.0.2.2"); got != http.StatusBadRequest {
+		t.Errorf("results for an unleased batch: status %d, want 400", got)
+	}
+	if l := lease(); l.ID != 0 {
+		t.Fatalf("first lease is batch %d, want 0", l.ID)
+	}
+	if got := submit("0", "192.0.2.3", "192.0.2.4"); got != http.StatusBadRequest {
+		t.Errorf("another batch's targets: status %d, want 400", got)
+	}
+	if got := submit("0", "192.0.2.1", "192.0.2.1"); got != http.StatusBadRequest {
+		t.Errorf("a target reported twice: status %d, want 400", got)
+	}
+	if len(emitted) != 0 {
+		t.Fatalf("rejected submissions emitted %d results", len(emitted))
+	}
+	if got := submit("0", "192.0.2.1", "192.0.2.2"); got != http.StatusNoContent {
+		t.Fatalf("matching results: status %d, want 204", got)
+	}
+	if got := submit("0", "192.0.2.1", "192.0.2.2"); got != http.StatusConflict {
+		t.Errorf("duplicate submission: status %d, want 409", got)
+	}
+	if len(emitted) != 2 || emitted[0].hostname != "a.example" {
+		t.Errorf("emitted %+v, want the batch's own two targets", emitted)
+	}
+	if c.remaining != 1 {
+		t.Errorf("remaining = %d, want 1", c.remaining)
+	}
+}
+
+func TestLeaseRoundTrip(t *testing.T) {
+	want := target{host: "192.0.2.1", port: 33060, hostname: "db.example", source: "targets.txt", protocol: "mysqlx"}
+	c := newCoordinator([]target{want}, 1, time.Minute, &exclusionList{}, func(target, Result) {})
+	rec := httptest.NewRecorder()
+	c.handleLease(rec, httptest.NewRequest(http.MethodPost, "/lease", nil))
+	var l batchLease
+	if err := json.NewDecoder(rec.Body).Decode(&l); err != nil {
+		t.Fatalf("lease: %v", err)
+	}
+	if len(l.Targets) != 1 {
+		t.Fatalf("lease has %d targets, want 1", len(l.Targets))
+	}
+	if got := l.Targets[0].target(); got != want {
+		t.Errorf("worker target = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsFallbackTTL is how long an answer is cached when no record TTL was seen, e.g. for names answered from /etc/hosts or through a search domain.
+const dnsFallbackTTL = time.Minute
+
+/*
+dnsCache caches hostname lookups for the life of the process, each for its records' TTL or a fixed override.
+The stdlib resolver does not expose TTLs, so lookups go through Go's own resolver with a dial hook that reads the TTLs off the DNS responses as they arrive. A nil cache resolves every time.
+*/
+type dnsCache struct {
+	resolver *net.Resolver
+	override time.Duration
+	hits     atomic.Int64
+	misses   atomic.Int64
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	ttls    map[string]uint32 // lowest answer TTL per question name (FQDN) seen on the wire
+}
+
+/*
+dnsEntry is one cached lookup; done is closed once addrs and err are set, so concurrent misses for a name share one lookup.
+*/
+type dnsEntry struct {
+	done    chan struct{}
+	addrs   []netip.Addr
+	err     error
+	expires time.Time
+}
+
+/*
+newDNSCache returns a cache honoring record TTLs, or holding every answer for override when it is positive.
+*/
+func newDNSCache(override time.Duration) *dnsCache {
+	c := &dnsCache{override: override, entries: make(map[string]*dnsEntry), ttls: make(map[string]uint32)}
+	var d net.Dialer
+	c.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := d.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			sniff := &ttlSniffConn{Conn: conn, cache: c}
+			// Go's resolver frames queries by whether the conn is a PacketConn, so a UDP conn must stay one.
+			if pc, ok := conn.(net.PacketConn); ok {
+				return &ttlSniffPacketConn{ttlSniffConn: sniff, pc: pc}, nil
+			}
+			sniff.stream = true
+			return sniff, nil
+		},
+	}
+	return c
+}
+
+/*
+lookup resolves host to its addresses, from the cache while the answer is fresh.
+Function-level comment: failures are not cached, so a name that failed is looked up again next time.
+*/
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if c == nil {
+		return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	}
+	key := strings.ToLower(strings.TrimSuffix(host, "."))
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		select {
+		case <-e.done:
+			if e.err == nil && time.Now().Before(e.expires) {
+				c.mu.Unlock()
+				c.hits.Add(1)
+				return e.addrs, nil
+			}
+		default:
+			// Another target is resolving the same name; wait for its answer.
+			c.mu.Unlock()
+			c.hits.Add(1)
+			<-e.done
+			return e.addrs, e.err
+		}
+	}
+	e := &dnsEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	delete(c.ttls, key+".")
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	addrs, err := c.resolver.LookupNetIP(ctx, "ip", host)
+	ttl := c.override
+	c.mu.Lock()
+	if ttl <= 0 {
+		ttl = dnsFallbackTTL
+		if seen, ok := c.ttls[key+"."]; ok {
+			ttl = time.Duration(seen) * time.Second
+		}
+	}
+	e.addrs, e.err, e.expires = addrs, err, time.Now().Add(ttl)
+	if err != nil {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(e.done)
+	return addrs, err
+}
+
+/*
+noteResponse records the lowest answer TTL of a DNS response under its question name.
+Function-level comment: A and AAAA answers for the same name both land here, so the entry expires with whichever record expires first; responses without answers (NXDOMAIN, NODATA) are ignored.
+*/
+func (c *dnsCache) noteResponse(msg []byte) {
+	var p dnsmessage.Parser
+	if _, err := p.Start(msg); err != nil {
+		return
+	}
+	q, err := p.Question()
+	if err != nil {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	lowest, found := uint32(0), false
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			break
+		}
+		if !found || h.TTL < lowest {
+			lowest, found = h.TTL, true
+		}
+		if err := p.SkipAnswer(); err != nil {
+			break
+		}
+	}
+	if !found {
+		return
+	}
+	name := strings.ToLower(q.Name.String())
+	c.mu.Lock()
+	if prev, ok := c.ttls[name]; !ok || lowest < prev {
+		c.ttls[name] = lowest
+	}
+	c.mu.Unlock()
+}
+
+/*
+ttlSniffConn hands every DNS response read from a resolver connection to the cache; over TCP (stream) the 2-byte length-prefixed messages are reassembled first.
+*/
+type ttlSniffConn struct {
+	net.Conn
+	cache  *dnsCache
+	stream bool
+	buf    []byte
+}
+
+/*
+Read passes data through, noting the TTLs of each complete response.
+*/
+func (c *ttlSniffConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if !c.stream {
+		c.cache.noteResponse(p[:n])
+		return n, err
+	}
+	c.buf = append(c.buf, p[:n]...)
+	for len(c.buf) >= 2 {
+		size := int(c.buf[0])<<8 | int(c.buf[1])
+		if len(c.buf) < 2+size {
+			break
+		}
+		c.cache.noteResponse(c.buf[2 : 2+size])
+		c.buf = c.buf[2+size:]
+	}
+	return n, err
+}
+
+/*
+ttlSniffPacketConn is a ttlSniffConn over UDP, keeping the net.PacketConn methods.
+*/
+type ttlSniffPacketConn struct {
+	*ttlSniffConn
+	pc net.PacketConn
+}
+
+/*
+ReadFrom passes a datagram through, noting its TTLs.
+*/
+func (c *ttlSniffPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.pc.ReadFrom(p)
+	c.cache.noteResponse(p[:n])
+	return n, addr, err
+}
+
+/*
+WriteTo sends a datagram.
+*/
+func (c *ttlSniffPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return c.pc.WriteTo(p, addr)
+}
+
+/*
+counts returns the cache's hits and misses.
+*/
+func (c *dnsCache) counts() (hits, misses int64) {
+	if c == nil {
+		return 0, 0
+	}
+	return c.hits.Load(), c.misses.Load()
+}
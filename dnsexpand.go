@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// srvPrefix marks a -host entry that is expanded through a DNS SRV lookup, e.g. srv:_mysql._tcp.example.com.
+const srvPrefix = "srv:"
+
+/*
+lookupSRVTargets expands the srv: entries of specs into targets and returns the remaining specs untouched.
+Function-level comment: each SRV record becomes a target on the record's own port (so -port/-ports do not apply), tagged with the spec it came from. A failed lookup is reported on stderr and skipped so one stale record does not abort the whole scan.
+*/
+func lookupSRVTargets(ctx context.Context, specs []string) ([]string, []target) {
+	var rest []string
+	var targets []target
+	for _, spec := range specs {
+		name, ok := strings.CutPrefix(spec, srvPrefix)
+		if !ok {
+			rest = append(rest, spec)
+			continue
+		}
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: %v\n", spec, err)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			targets = append(targets, target{host: host, port: int(r.Port), runUDP: !seen[host], source: spec})
+			seen[host] = true
+		}
+	}
+	return rest, targets
+}
+
+/*
+expandRecords replaces every hostname target with one target per address the name resolves to, or with only the first address when firstOnly is set.
+Function-level comment: all addresses are used for round-robin names where each record may be a different server (-expand-dns); the first alone pins a name to the address it would have been dialed at, so -dedupe can tell which targets are the same server. Expanded targets keep the name as hostname and, unless they already have one, as their source; names that fail to resolve are kept as they are so the failure is reported when they are scanned. Each name is looked up once.
+*/
+func expandRecords(ctx context.Context, targets iter.Seq[target], dns *dnsCache, firstOnly bool) iter.Seq[target] {
+	return func(yield func(target) bool) {
+		cache := make(map[string][]netip.Addr)
+		for t := range targets {
+			if _, err := netip.ParseAddr(t.host); err == nil {
+				if !yield(t) {
+					return
+				}
+				continue
+			}
+			addrs, ok := cache[t.host]
+			if !ok {
+				addrs, _ = dns.lookup(ctx, t.host)
+				cache[t.host] = addrs
+			}
+			if len(addrs) == 0 {
+				if !yield(t) {
+					return
+				}
+				continue
+			}
+			if firstOnly {
+				addrs = addrs[:1]
+			}
+			for _, a := range addrs {
+				e := t
+				e.host = a.Unmap().String()
+				e.hostname = t.host
+				if e.source == "" {
+					e.source = t.host
+				}
+				if !yield(e) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// DRDA code points used by the EXCSAT exchange.
+const (
+	drdaEXCSAT   = 0x1041 // Exchange Server Attributes (request)
+	drdaEXCSATRD = 0x1443 // EXCSAT reply data
+	drdaEXTNAM   = 0x115e // external name
+	drdaSRVNAM   = 0x116d // server name
+	drdaSRVRLSLV = 0x115a // server product release level
+	drdaSRVCLSNM = 0x1147 // server class name
+	drdaMGRLVLLS = 0x1404 // manager-level list
+)
+
+// drdaExternalName is the EXTNAM the probe announces itself with.
+const drdaExternalName = "mysql_scout"
+
+// drdaManagerLevels are the (manager, level) pairs sent in MGRLVLLS: AGENT, SQLAM, RDB, SECMGR, CMNTCPIP, as Db2 clients offer them.
+var drdaManagerLevels = [][2]uint16{{0x1403, 7}, {0x2407, 7}, {0x240f, 7}, {0x1440, 7}, {0x1474, 5}}
+
+// maxDRDAReply bounds how much of the EXCSAT reply is read.
+const maxDRDAReply = 4096
+
+/*
+DRDAResult is the JSON record for the Db2 DRDA probe sent alongside the MySQL check.
+*/
+type DRDAResult struct {
+	Port         int    `json:"port"`
+	Detected     bool   `json:"detected"`
+	ServerClass  string `json:"server_class,omitempty"`
+	ReleaseLevel string `json:"release_level,omitempty"`
+	ExternalName string `json:"external_name,omitempty"`
+	ServerName   string `json:"server_name,omitempty"`
+	ReplyCode    string `json:"reply_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+/*
+runDRDAProbe performs the DRDA EXCSAT exchange against host:port and records the server attributes from EXCSATRD.
+Function-level comment: DRDA servers wait for the client, so this sends one EXCSAT request DSS and reads one reply DSS; a well-formed reply with another code point (e.g. a reply message rejecting the request) still counts as DRDA and is reported by its code point.
+*/
+func runDRDAProbe(host string, port int, opts mysqlprobe.Options) *DRDAResult {
+	res := &DRDAResult{Port: port}
+	conn, err := opts.Connect("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		res.Error = "dial failed: " + err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(opts.Timeout))
+	if _, err := conn.Write(buildEXCSAT()); err != nil {
+		res.Error = "write failed: " + err.Error()
+		return res
+	}
+	var header [6]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		res.Error = "read failed: " + err.Error()
+		return res
+	}
+	n := int(binary.BigEndian.Uint16(header[:2]))
+	if header[2] != 0xd0 || n < 10 || n > maxDRDAReply {
+		res.Error = "not a DRDA reply"
+		return res
+	}
+	body := make([]byte, n-6)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		res.Error = "read failed: " + err.Error()
+		return res
+	}
+	if err := parseEXCSATRD(body, res); err != nil {
+		res.Error = err.Error()
+	}
+	return res
+}
+
+/*
+buildEXCSAT builds the request DSS: a 6-byte DSS header (length, 0xD0 magic, request format, correlation ID 1) around the EXCSAT command with EXTNAM and MGRLVLLS.
+*/
+func buildEXCSAT() []byte {
+	params := drdaParam(drdaEXTNAM, ebcdicEncode(drdaExternalName))
+	levels := make([]byte, 0, 4*len(drdaManagerLevels))
+	for _, l := range drdaManagerLevels {
+		levels = binary.BigEndian.AppendUint16(levels, l[0])
+		levels = binary.BigEndian.AppendUint16(levels, l[1])
+	}
+	params = append(params, drdaParam(drdaMGRLVLLS, levels)...)
+	ddm := drdaParam(drdaEXCSAT, params)
+	dss := binary.BigEndian.AppendUint16(nil, uint16(6+len(ddm)))
+	dss = append(dss, 0xd0, 0x01, 0x00, 0x01)
+	return append(dss, ddm...)
+}
+
+/*
+drdaParam encodes one DDM object or parameter: 2-byte length (including the 4-byte header), 2-byte code point, data.
+*/
+func drdaParam(codePoint uint16, data []byte) []byte {
+	out := binary.BigEndian.AppendUint16(nil, uint16(4+len(data)))
+	out = binary.BigEndian.AppendUint16(out, codePoint)
+	return append(out, data...)
+}
+
+/*
+parseEXCSATRD decodes the DDM object following the reply's DSS header into res.
+Function-level comment: string parameters are EBCDIC, since no code page has been negotiated yet.
+*/
+func parseEXCSATRD(b []byte, res *DRDAResult) error {
+	if len(b) < 4 {
+		return fmt.Errorf("truncated DDM object")
+	}
+	res.Detected = true
+	n := int(binary.BigEndian.Uint16(b[:2]))
+	cp := binary.BigEndian.Uint16(b[2:4])
+	if cp != drdaEXCSATRD {
+		res.ReplyCode = fmt.Sprintf("0x%04x", cp)
+		return nil
+	}
+	if n < 4 || n > len(b) {
+		return fmt.Errorf("DDM object length %d out of range", n)
+	}
+	params := b[4:n]
+	for len(params) >= 4 {
+		pl := int(binary.BigEndian.Uint16(params[:2]))
+		if pl < 4 || pl > len(params) {
+			return fmt.Errorf("parameter length %d out of range", pl)
+		}
+		data := params[4:pl]
+		switch binary.BigEndian.Uint16(params[2:4]) {
+		case drdaSRVCLSNM:
+			res.ServerClass = ebcdicDecode(data)
+		case drdaSRVRLSLV:
+			res.ReleaseLevel = ebcdicDecode(data)
+		case drdaEXTNAM:
+			res.ExternalName = ebcdicDecode(data)
+		case drdaSRVNAM:
+			res.ServerName = ebcdicDecode(data)
+		}
+		params = params[pl:]
+	}
+	return nil
+}
+
+// ebcdicPrintable maps EBCDIC code page 037 bytes to the ASCII characters DRDA attribute strings use.
+var ebcdicPrintable = map[byte]byte{
+	0x40: ' ', 0x4b: '.', 0x4c: '<', 0x4d: '(', 0x4e: '+', 0x4f: '|', 0x50: '&', 0x5a: '!', 0x5b: '$',
+	0x5c: '*', 0x5d: ')', 0x5e: ';', 0x60: '-', 0x61: '/', 0x6b: ',', 0x6c: '%', 0x6d: '_', 0x6e: '>',
+	0x6f: '?', 0x79: '`', 0x7a: ':', 0x7b: '#', 0x7c: '@', 0x7d: '\'', 0x7e: '=', 0x7f: '"', 0xa1: '~',
+	0xba: '[', 0xbb: ']', 0xc0: '{', 0xd0: '}', 0xe0: '\\',
+}
+
+/*
+ebcdicByte maps one code page 037 byte to ASCII, or 0 when it has no printable ASCII equivalent.
+*/
+func ebcdicByte(c byte) byte {
+	switch {
+	case c >= 0x81 && c <= 0x89:
+		return 'a' + c - 0x81
+	case c >= 0x91 && c <= 0x99:
+		return 'j' + c - 0x91
+	case c >= 0xa2 && c <= 0xa9:
+		return 's' + c - 0xa2
+	case c >= 0xc1 && c <= 0xc9:
+		return 'A' + c - 0xc1
+	case c >= 0xd1 && c <= 0xd9:
+		return 'J' + c - 0xd1
+	case c >= 0xe2 && c <= 0xe9:
+		return 'S' + c - 0xe2
+	case c >= 0xf0 && c <= 0xf9:
+		return '0' + c - 0xf0
+	}
+	return ebcdicPrintable[c]
+}
+
+/*
+ebcdicDecode converts an EBCDIC attribute string to ASCII, trimming the padding blanks; unmappable bytes become '?'.
+*/
+func ebcdicDecode(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if a := ebcdicByte(c); a != 0 {
+			sb.WriteByte(a)
+		} else {
+			sb.WriteByte('?')
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+/*
+ebcdicEncode converts printable ASCII to code page 037; characters without a mapping become EBCDIC '?'.
+*/
+func ebcdicEncode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		enc := byte(0x6f)
+		for c := 0x40; c <= 0xff; c++ {
+			if ebcdicByte(byte(c)) == s[i] {
+				enc = byte(c)
+				break
+			}
+		}
+		out = append(out, enc)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+/*
+planStat is one derived figure (target counts and the like) reported by -dry-run.
+*/
+type planStat struct {
+	name  string
+	value string
+}
+
+/*
+printPlan writes the effective configuration and the final target list for -dry-run.
+Function-level comment: every flag is listed with its effective value, followed by the derived stats and one line per target giving the address scanTarget would dial or why it would be skipped. Hostnames are resolved so DNS-driven exclusions show up, but nothing is sent to the targets themselves.
+*/
+func printPlan(w io.Writer, flags *flag.FlagSet, stats []planStat, targets []target, ex *exclusionList) error {
+	bw := bufio.NewWriter(w)
+	flags.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(bw, "# -%s=%s\n", f.Name, f.Value)
+	})
+	for _, s := range stats {
+		fmt.Fprintf(bw, "# %s: %s\n", s.name, s.value)
+	}
+	skipped := 0
+	for _, t := range targets {
+		addr := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+		ip, err := resolveTarget(context.Background(), t.host, ex, nil)
+		if err != nil {
+			skipped++
+			fmt.Fprintf(bw, "%s\tskip (%v)\n", addr, err)
+			continue
+		}
+		fmt.Fprintf(bw, "%s\t%s\n", addr, ip)
+	}
+	fmt.Fprintf(bw, "# would probe %d targets, skip %d\n", len(targets)-skipped, skipped)
+	return bw.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+eolSchedule maps "major.minor" release series to their end-of-life date (end of community/premier support).
+Series not listed (e.g. releases newer than this table) are reported without an eol verdict rather than guessed.
+*/
+var eolSchedule = map[string]map[string]string{
+	"mysql": {
+		"5.0": "2012-01-09",
+		"5.1": "2013-12-31",
+		"5.5": "2018-12-31",
+		"5.6": "2021-02-28",
+		"5.7": "2023-10-31",
+		"8.0": "2026-04-30",
+		"8.1": "2023-10-25",
+		"8.2": "2024-01-16",
+		"8.3": "2024-04-30",
+		"8.4": "2032-04-30",
+		"9.0": "2024-10-15",
+		"9.1": "2025-01-21",
+		"9.2": "2025-04-15",
+		"9.3": "2025-07-22",
+	},
+	"mariadb": {
+		"5.5":   "2020-04-11",
+		"10.0":  "2019-03-31",
+		"10.1":  "2020-10-17",
+		"10.2":  "2022-05-23",
+		"10.3":  "2023-05-25",
+		"10.4":  "2024-06-18",
+		"10.5":  "2025-06-24",
+		"10.6":  "2026-07-06",
+		"10.7":  "2023-02-09",
+		"10.8":  "2023-05-20",
+		"10.9":  "2023-08-22",
+		"10.10": "2023-11-17",
+		"10.11": "2028-02-16",
+		"11.0":  "2024-06-06",
+		"11.1":  "2024-08-21",
+		"11.2":  "2024-11-21",
+		"11.3":  "2024-05-29",
+		"11.4":  "2029-05-29",
+		"11.8":  "2030-06-04",
+	},
+}
+
+/*
+versionProduct names the release line a server belongs to for EOL purposes.
+Function-level comment: MariaDB announces itself in the version string; everything else (including Percona and distro builds) follows the MySQL schedule.
+*/
+func versionProduct(serverVersion string) string {
+	if strings.Contains(strings.ToLower(serverVersion), "mariadb") {
+		return "mariadb"
+	}
+	return "mysql"
+}
+
+/*
+lookupEOL returns whether the server's release series is past end of life at now, and the EOL date.
+Function-level comment: ok is false when the version could not be parsed or the series is not in eolSchedule.
+*/
+func lookupEOL(info *mysqlprobe.HandshakeInfo, now time.Time) (eol bool, date string, ok bool) {
+	if info.Version == nil {
+		return false, "", false
+	}
+	series := fmt.Sprintf("%d.%d", info.Version.Major, info.Version.Minor)
+	date, ok = eolSchedule[versionProduct(info.ServerVersion)][series]
+	if !ok {
+		return false, "", false
+	}
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return false, "", false
+	}
+	return !now.Before(d), date, true
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"time"
+)
+
+// maxExecProbeOutput bounds what an external probe may print; longer output fails the probe rather than growing the result without limit.
+const maxExecProbeOutput = 1 << 20
+
+/*
+execProbe is one -probe-exec command: an external program run once per target that answered, with the target on stdin and a JSON value expected on stdout.
+The value is stored in the result's external object under the probe's name, the program's base name without extension.
+*/
+type execProbe struct {
+	name string
+	argv []string
+}
+
+/*
+execProbeFlag collects repeated -probe-exec commands.
+*/
+type execProbeFlag []execProbe
+
+/*
+String lists the commands for flag usage output.
+*/
+func (f *execProbeFlag) String() string {
+	cmds := make([]string, len(*f))
+	for i, p := range *f {
+		cmds[i] = strings.Join(p.argv, " ")
+	}
+	return strings.Join(cmds, "; ")
+}
+
+/*
+Set adds one command: a program path and its arguments separated by spaces (no shell quoting).
+Function-level comment: two probes whose programs share a base name would overwrite each other's output, so that is an error.
+*/
+func (f *execProbeFlag) Set(cmd string) error {
+	argv := strings.Fields(cmd)
+	if len(argv) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	name := strings.TrimSuffix(filepath.Base(argv[0]), filepath.Ext(argv[0]))
+	for _, p := range *f {
+		if p.name == name {
+			return fmt.Errorf("two probes named %q", name)
+		}
+	}
+	*f = append(*f, execProbe{name: name, argv: argv})
+	return nil
+}
+
+// externalResults holds each -probe-exec program's output, by probe name.
+type externalResults map[string]json.RawMessage
+
+/*
+execProbeInput is the JSON document an external probe reads on stdin: where to connect, and everything the scan learned about the target so far.
+*/
+type execProbeInput struct {
+	Host     string  `json:"host"`
+	IP       string  `json:"ip"`
+	Port     int     `json:"port"`
+	Hostname string  `json:"hostname,omitempty"`
+	Timeout  float64 `json:"timeout_seconds"`
+	Result   *Result `json:"result"`
+}
+
+/*
+run executes the probe for one target within timeout and returns its JSON output, or a {"error": ...} object saying why there is none.
+Function-level comment: the program is killed when the timeout passes or ctx is cancelled. A nonzero exit, output that is not JSON, or output over maxExecProbeOutput are failures; the error carries the first line of the program's stderr when it wrote one.
+*/
+func (p execProbe) run(ctx context.Context, in execProbeInput, timeout time.Duration) json.RawMessage {
+	stdin, err := json.Marshal(in)
+	if err != nil {
+		return execProbeError(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.argv[0], p.argv[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: maxExecProbeOutput}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: 4 << 10}
+	err = cmd.Run()
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return execProbeError(fmt.Errorf("timed out after %v", timeout))
+	case err != nil:
+		if line, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); line != "" {
+			err = fmt.Errorf("%w: %s", err, line)
+		}
+		return execProbeError(err)
+	case stdout.Len() >= maxExecProbeOutput:
+		return execProbeError(fmt.Errorf("output exceeds %d bytes", maxExecProbeOutput))
+	}
+	out := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(out) {
+		return execProbeError(fmt.Errorf("output is not JSON"))
+	}
+	return json.RawMessage(out)
+}
+
+/*
+execProbeError renders a probe failure as the {"error": ...} object stored in its place.
+*/
+func execProbeError(err error) json.RawMessage {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return b
+}
+
+/*
+limitedBuffer keeps the first limit bytes written to it and discards the rest, so a runaway probe cannot exhaust memory.
+*/
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+/*
+Write keeps what fits and reports everything as written, so the program is not killed by a broken pipe.
+*/
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if room := l.limit - l.buf.Len(); room > 0 {
+		l.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+/*
+pluginFlag collects repeated -plugin paths.
+*/
+type pluginFlag []string
+
+/*
+String lists the paths for flag usage output.
+*/
+func (f *pluginFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+/*
+Set adds one plugin path.
+*/
+func (f *pluginFlag) Set(path string) error {
+	*f = append(*f, path)
+	return nil
+}
+
+/*
+loadPlugins opens each Go plugin in paths; a plugin's init functions register its probers, sinks, and modules with mysqlprobe, which makes them available to -protocol, -output, and target URIs.
+Function-level comment: plugins only load on Linux, macOS, and FreeBSD in binaries built with cgo, and must be built with the same Go version and module versions as the binary.
+*/
+func loadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["fake-server"] = runFakeServer
+}
+
+/*
+runFakeServer is the fake-server subcommand.
+Function-level comment: serves one of the selftest's canned servers on -listen until interrupted, so scans, dashboards, and alert sinks can be exercised end to end without a real MySQL server.
+*/
+func runFakeServer(args []string) int {
+	fs := flag.NewFlagSet("fake-server", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:3306", "Address to listen on")
+	profile := fs.String("profile", selftestCases[0].name, "Canned server to play: "+fakeServerProfiles())
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout fake-server [-listen addr] [-profile name]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var first []byte
+	for _, c := range selftestCases {
+		if c.name == *profile {
+			first = c.first
+		}
+	}
+	if first == nil {
+		fmt.Fprintf(os.Stderr, "invalid -profile: %q (want one of %s)\n", *profile, fakeServerProfiles())
+		return 2
+	}
+	srv, err := mysqlprobe.ListenFakeServer(*listen, first)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fake-server: %v\n", err)
+		return 1
+	}
+	defer srv.Close()
+	fmt.Fprintf(os.Stderr, "fake-server: serving %s on %s\n", *profile, srv.Addr())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	return 0
+}
+
+/*
+fakeServerProfiles lists the -profile names fake-server accepts.
+*/
+func fakeServerProfiles() string {
+	names := make([]string, len(selftestCases))
+	for i, c := range selftestCases {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
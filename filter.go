@@ -0,0 +1,503 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+filterNode is one node of a compiled -filter expression, evaluated against a result's JSON fields.
+*/
+type filterNode interface {
+	eval(fields map[string]any) any
+}
+
+type (
+	filterLiteral struct{ v any }
+	filterField   struct{ path []string }
+	filterNot     struct{ x filterNode }
+	filterLogic   struct {
+		and  bool
+		l, r filterNode
+	}
+	filterCompare struct {
+		op   string
+		l, r filterNode
+		re   *regexp.Regexp
+	}
+)
+
+/*
+resultFilter is a compiled -filter expression.
+*/
+type resultFilter struct {
+	root filterNode
+}
+
+/*
+compileFilter parses a filter expression such as `mysql==true && version<"5.7"`.
+Function-level comment: supports && || ! and parentheses, comparisons == != < <= > >= and =~ (regexp), string/number/bool/null literals, and dotted field paths named after the JSON output (e.g. tls.version). An empty expression yields a nil filter that matches everything.
+*/
+func compileFilter(src string) (*resultFilter, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	toks, err := tokenizeFilter(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q at end of filter", p.toks[p.pos].text)
+	}
+	return &resultFilter{root: root}, nil
+}
+
+/*
+match reports whether res satisfies the filter.
+Function-level comment: the result is evaluated through its JSON form so field names match the output exactly; a nil filter matches everything.
+*/
+func (f *resultFilter) match(res Result) bool {
+	if f == nil {
+		return true
+	}
+	fields, err := resultFields(res)
+	if err != nil {
+		return false
+	}
+	return truthy(f.root.eval(fields))
+}
+
+/*
+selectOutput reports whether res satisfies the filter and returns it as output shows it: with the handshake trimmed to its basic fields unless fullDetail is set.
+Function-level comment: the filter runs before the trim, so it can test fields the trimmed output leaves out, such as auth_plugin and capability_flags.
+*/
+func (f *resultFilter) selectOutput(res Result, fullDetail bool) (Result, bool) {
+	ok := f.match(res)
+	if !fullDetail && res.HandshakeInfo != nil {
+		res.HandshakeInfo = res.HandshakeInfo.Basic()
+	}
+	return res, ok
+}
+
+/*
+fields lists the top-level result fields the filter reads; a nil filter reads none.
+*/
+func (f *resultFilter) fields() []string {
+	var names []string
+	var walk func(n filterNode)
+	walk = func(n filterNode) {
+		switch n := n.(type) {
+		case filterField:
+			names = append(names, n.path[0])
+		case filterNot:
+			walk(n.x)
+		case filterLogic:
+			walk(n.l)
+			walk(n.r)
+		case filterCompare:
+			walk(n.l)
+			walk(n.r)
+		}
+	}
+	if f != nil {
+		walk(f.root)
+	}
+	return names
+}
+
+/*
+filterToken is a lexical token of a filter expression; kind is one of ident, string, number, op.
+*/
+type filterToken struct {
+	kind string
+	text string
+}
+
+/*
+tokenizeFilter splits a filter expression into tokens.
+Function-level comment: strings may use single or double quotes with backslash escapes; identifiers may contain letters, digits, '_' and '.'.
+*/
+func tokenizeFilter(src string) ([]filterToken, error) {
+	var toks []filterToken
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != c; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			toks = append(toks, filterToken{"string", sb.String()})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, filterToken{"number", string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, filterToken{"ident", string(rs[i:j])})
+			i = j
+		default:
+			two := ""
+			if i+1 < len(rs) {
+				two = string(rs[i : i+2])
+			}
+			switch two {
+			case "&&", "||", "==", "!=", "<=", ">=", "=~":
+				toks = append(toks, filterToken{"op", two})
+				i += 2
+				continue
+			}
+			switch c {
+			case '(', ')', '!', '<', '>':
+				toks = append(toks, filterToken{"op", string(c)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return toks, nil
+}
+
+/*
+filterParser is a recursive-descent parser over filter tokens.
+*/
+type filterParser struct {
+	toks []filterToken
+	pos  int
+}
+
+/*
+peekOp reports whether the next token is one of the given operators, without consuming it.
+*/
+func (p *filterParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.toks) || p.toks[p.pos].kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.toks[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+/*
+parseOr parses a chain of || (lowest precedence).
+*/
+func (p *filterParser) parseOr() (filterNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("||"); !ok {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = filterLogic{and: false, l: l, r: r}
+	}
+}
+
+/*
+parseAnd parses a chain of &&.
+*/
+func (p *filterParser) parseAnd() (filterNode, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("&&"); !ok {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = filterLogic{and: true, l: l, r: r}
+	}
+}
+
+/*
+parseNot parses optional leading ! operators.
+*/
+func (p *filterParser) parseNot() (filterNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{x}, nil
+	}
+	return p.parseCompare()
+}
+
+/*
+parseCompare parses an operand optionally followed by one comparison; =~ patterns are compiled here so bad regexps fail at startup.
+*/
+func (p *filterParser) parseCompare() (filterNode, error) {
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOp("==", "!=", "<", "<=", ">", ">=", "=~")
+	if !ok {
+		return l, nil
+	}
+	p.pos++
+	r, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	cmp := filterCompare{op: op, l: l, r: r}
+	if op == "=~" {
+		lit, isLit := r.(filterLiteral)
+		pattern, isStr := lit.v.(string)
+		if !isLit || !isStr {
+			return nil, fmt.Errorf("=~ needs a string pattern on the right")
+		}
+		if cmp.re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return cmp, nil
+}
+
+/*
+parsePrimary parses a literal, a field path, or a parenthesized expression.
+*/
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case "string":
+		return filterLiteral{t.text}, nil
+	case "number":
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return filterLiteral{f}, nil
+	case "ident":
+		switch t.text {
+		case "true":
+			return filterLiteral{true}, nil
+		case "false":
+			return filterLiteral{false}, nil
+		case "null":
+			return filterLiteral{nil}, nil
+		}
+		return filterField{strings.Split(t.text, ".")}, nil
+	}
+	if t.text == "(" {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOp(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// eval implementations: literals return themselves, fields walk the JSON object, and the
+// logical/comparison nodes combine their operands.
+func (n filterLiteral) eval(map[string]any) any { return n.v }
+
+func (n filterField) eval(fields map[string]any) any {
+	var cur any = fields
+	for _, key := range n.path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+func (n filterNot) eval(fields map[string]any) any { return !truthy(n.x.eval(fields)) }
+
+func (n filterLogic) eval(fields map[string]any) any {
+	if n.and {
+		return truthy(n.l.eval(fields)) && truthy(n.r.eval(fields))
+	}
+	return truthy(n.l.eval(fields)) || truthy(n.r.eval(fields))
+}
+
+func (n filterCompare) eval(fields map[string]any) any {
+	l, r := n.l.eval(fields), n.r.eval(fields)
+	if n.op == "=~" {
+		s, ok := asVersionString(l)
+		if !ok {
+			s, ok = l.(string)
+		}
+		return ok && n.re.MatchString(s)
+	}
+	ordering := n.op != "==" && n.op != "!="
+	c, comparable := compareFilterValues(l, r, ordering && (versionOperand(n.l, l) || versionOperand(n.r, r)))
+	switch n.op {
+	case "==":
+		return comparable && c == 0
+	case "!=":
+		return !comparable || c != 0
+	case "<":
+		return comparable && c < 0
+	case "<=":
+		return comparable && c <= 0
+	case ">":
+		return comparable && c > 0
+	case ">=":
+		return comparable && c >= 0
+	}
+	return false
+}
+
+/*
+truthy maps an evaluated value to a boolean: null, false, 0 and "" are false.
+*/
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+/*
+asVersionString renders a nested version object ({"major","minor","patch"}) as "major.minor.patch".
+*/
+func asVersionString(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	major, ok := m["major"].(float64)
+	if !ok {
+		return "", false
+	}
+	minor, _ := m["minor"].(float64)
+	patch, _ := m["patch"].(float64)
+	return fmt.Sprintf("%d.%d.%d", int(major), int(minor), int(patch)), true
+}
+
+/*
+versionOperand reports whether a comparison operand is a version: a version object, or the server_version string.
+*/
+func versionOperand(n filterNode, v any) bool {
+	if _, ok := asVersionString(v); ok {
+		return true
+	}
+	f, ok := n.(filterField)
+	return ok && len(f.path) > 0 && f.path[len(f.path)-1] == "server_version"
+}
+
+/*
+compareFilterValues orders two values, returning -1/0/1 and whether they were comparable at all.
+Function-level comment: numbers compare numerically and strings exactly, byte by byte; with versions set (an ordering comparison on a version), strings that both look like dotted versions compare component by component (so "5.10" > "5.9"), ties broken on the whole string so 8.0.36-log sorts after 8.0.36. Booleans and null only compare for equality.
+*/
+func compareFilterValues(l, r any, versions bool) (int, bool) {
+	if lv, ok := asVersionString(l); ok {
+		l = lv
+	}
+	if rv, ok := asVersionString(r); ok {
+		r = rv
+	}
+	switch lx := l.(type) {
+	case float64:
+		if rx, ok := r.(float64); ok {
+			switch {
+			case lx < rx:
+				return -1, true
+			case lx > rx:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if rx, ok := r.(string); ok {
+			if !versions {
+				return strings.Compare(lx, rx), true
+			}
+			if lvi, rvi := mysqlprobe.ParseServerVersion(lx), mysqlprobe.ParseServerVersion(rx); lvi != nil && rvi != nil {
+				if c := compareVersions(lvi, rvi); c != 0 {
+					return c, true
+				}
+			}
+			return strings.Compare(lx, rx), true
+		}
+	case bool:
+		if rx, ok := r.(bool); ok {
+			if lx == rx {
+				return 0, true
+			}
+			return 1, true
+		}
+	case nil:
+		if r == nil {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+/*
+compareVersions orders two parsed versions by major, minor, then patch; suffixes are ignored.
+*/
+func compareVersions(a, b *mysqlprobe.VersionInfo) int {
+	for _, d := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		switch {
+		case d[0] < d[1]:
+			return -1
+		case d[0] > d[1]:
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func TestFilterComparisons(t *testing.T) {
+	res := Result{Host: "127.0.0.1"}
+	res.MySQL = true
+	res.HandshakeInfo = &mysqlprobe.HandshakeInfo{ServerVersion: "8.0.36-log", Version: mysqlprobe.ParseServerVersion("8.0.36-log")}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`host=="127.0.0.1"`, true},
+		{`host=="127.0.0.2"`, false},
+		{`host!="127.0.0.2"`, true},
+		{`server_version=="8.0.36-log"`, true},
+		{`server_version=="8.0.36"`, false},
+		{`server_version>"8.0.36"`, true},
+		{`server_version>="8.0.9"`, true},
+		{`server_version<"8.0.100"`, true},
+		{`version>"8.0.9"`, true},
+		{`version<"5.7"`, false},
+		{`version=="8.0.36"`, true},
+		{`host<"127.0.0.10"`, true},
+	}
+	for _, tt := range tests {
+		f, err := compileFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("compileFilter(%q): %v", tt.expr, err)
+		}
+		if got := f.match(res); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestFilterSeesTrimmedFields(t *testing.T) {
+	res := Result{Host: "127.0.0.1"}
+	res.MySQL = true
+	res.HandshakeInfo = &mysqlprobe.HandshakeInfo{ServerVersion: "8.0.36", AuthPluginName: "mysql_native_password", CapabilityFlags: mysqlprobe.ClientProtocol41}
+	f, err := compileFilter(`auth_plugin=="mysql_native_password" && capability_flags>0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := f.selectOutput(res, false)
+	if !ok {
+		t.Errorf("filter on fields Basic() drops did not match")
+	}
+	if out.HandshakeInfo.AuthPluginName != "" {
+		t.Errorf("output handshake not trimmed: auth plugin %q", out.HandshakeInfo.AuthPluginName)
+	}
+	if res.HandshakeInfo.AuthPluginName == "" {
+		t.Errorf("selectOutput trimmed the caller's handshake")
+	}
+	if got := f.fields(); !slices.Equal(got, []string{"auth_plugin", "capability_flags"}) {
+		t.Errorf("fields() = %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// integrationPassword is the root password the containers are started with.
+const integrationPassword = "scout-integration"
+
+var (
+	integrationWait = flag.Duration("integration.wait", 3*time.Minute, "How long to wait for each container to accept connections")
+	integrationKeep = flag.Bool("integration.keep", false, "Leave the containers running for inspection")
+)
+
+/*
+integrationServer is a server image the integration run starts and what scanning it must report.
+*/
+type integrationServer struct {
+	name     string
+	image    string
+	env      string // root password variable
+	major    int
+	minor    int
+	mariaDB  bool
+	plugin   string
+	tlsByDef bool // the image generates a certificate and offers TLS out of the box
+}
+
+// integrationServers are the releases parser changes are validated against.
+var integrationServers = []integrationServer{
+	{"mysql-5.7", "mysql:5.7", "MYSQL_ROOT_PASSWORD", 5, 7, false, "mysql_native_password", true},
+	{"mysql-8.0", "mysql:8.0", "MYSQL_ROOT_PASSWORD", 8, 0, false, "caching_sha2_password", true},
+	{"mysql-8.4", "mysql:8.4", "MYSQL_ROOT_PASSWORD", 8, 4, false, "caching_sha2_password", true},
+	{"mariadb-11.4", "mariadb:11.4", "MARIADB_ROOT_PASSWORD", 11, 4, true, "mysql_native_password", true},
+}
+
+/*
+TestIntegration starts each server image with docker on an ephemeral host port, waits until it answers with a handshake, then scans it twice through the normal pipeline, in plaintext and with TLS, logging in as root both times, and checks the parsed version, auth plugin, TLS negotiation, and login.
+Function-level comment: one subtest per server, so -run TestIntegration/mysql-8.4 tests a single release. Containers are removed afterwards unless -integration.keep is given; without docker the test is skipped.
+*/
+func TestIntegration(t *testing.T) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker not found in PATH")
+	}
+	for _, srv := range integrationServers {
+		t.Run(srv.name, func(t *testing.T) {
+			for _, err := range runIntegrationServer(t, srv, *integrationWait, *integrationKeep) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+/*
+runIntegrationServer starts srv's container, removed when t ends, scans it, and returns every failed check.
+*/
+func runIntegrationServer(t *testing.T, srv integrationServer, wait time.Duration, keep bool) []error {
+	id, err := docker("run", "-d", "-p", "127.0.0.1::3306", "-e", srv.env+"="+integrationPassword, srv.image)
+	if err != nil {
+		return []error{fmt.Errorf("start %s: %w", srv.image, err)}
+	}
+	if keep {
+		t.Logf("container %.12s", id)
+	} else {
+		t.Cleanup(func() { docker("rm", "-f", id) })
+	}
+	mapped, err := docker("port", id, "3306/tcp")
+	if err != nil {
+		return []error{fmt.Errorf("port mapping: %w", err)}
+	}
+	// docker port may list several bindings, one per line.
+	host, portStr, err := net.SplitHostPort(strings.Fields(mapped)[0])
+	if err != nil {
+		return []error{fmt.Errorf("port mapping %q: %w", mapped, err)}
+	}
+	port, err := parsePortList(portStr)
+	if err != nil {
+		return []error{fmt.Errorf("port mapping %q: %w", mapped, err)}
+	}
+	tgt := target{host: host, port: port[0]}
+	if err := waitForHandshake(tgt, wait); err != nil {
+		return []error{err}
+	}
+
+	creds := &mysqlprobe.Credentials{User: "root", Password: integrationPassword}
+	var errs []error
+	for _, useTLS := range []bool{false, true} {
+		mode := "plaintext"
+		if useTLS {
+			mode = "tls"
+		}
+		cfg := scanConfig{prober: mysqlprobe.NewMySQLProber, probe: mysqlprobe.Options{Timeout: 10 * time.Second, Verbose: true, TLS: useTLS, Credentials: creds}}
+		res := safeScanTarget(tgt, cfg, newHostLimiter(0), nil)
+		for _, err := range verifyIntegration(srv, &res, useTLS) {
+			errs = append(errs, fmt.Errorf("%s: %w", mode, err))
+		}
+	}
+	return errs
+}
+
+/*
+waitForHandshake polls t until it sends a MySQL handshake or wait runs out.
+Function-level comment: the official images run a temporary server with networking disabled while initializing, so connections are refused or reset until the real server is up.
+*/
+func waitForHandshake(t target, wait time.Duration) error {
+	cfg := scanConfig{prober: mysqlprobe.NewMySQLProber, probe: mysqlprobe.Options{Timeout: 5 * time.Second}}
+	deadline := time.Now().Add(wait)
+	for {
+		res := safeScanTarget(t, cfg, newHostLimiter(0), nil)
+		if res.MySQL {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no handshake within %v: %s", wait, res.Error)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+/*
+verifyIntegration checks one scan of srv and returns every mismatch.
+*/
+func verifyIntegration(srv integrationServer, res *Result, useTLS bool) []error {
+	info := res.HandshakeInfo
+	if !res.MySQL || info == nil {
+		return []error{fmt.Errorf("no MySQL handshake: %s", res.Error)}
+	}
+	var errs []error
+	if v := info.Version; v == nil || v.Major != srv.major || v.Minor != srv.minor {
+		errs = append(errs, fmt.Errorf("parsed version %+v from %q, want %d.%d.x", v, info.ServerVersion, srv.major, srv.minor))
+	}
+	if got := strings.Contains(info.ServerVersion, "MariaDB"); got != srv.mariaDB {
+		errs = append(errs, fmt.Errorf("server version %q: MariaDB %t, want %t", info.ServerVersion, got, srv.mariaDB))
+	}
+	if info.AuthPluginName != srv.plugin {
+		errs = append(errs, fmt.Errorf("auth plugin %q, want %q", info.AuthPluginName, srv.plugin))
+	}
+	if len(info.Anomalies) > 0 {
+		errs = append(errs, fmt.Errorf("unexpected anomaly %s: %s", info.Anomalies[0].Code, info.Anomalies[0].Description))
+	}
+	if supports := info.CapabilityFlags&mysqlprobe.ClientSSL != 0; supports != srv.tlsByDef {
+		errs = append(errs, fmt.Errorf("CLIENT_SSL %t, want %t", supports, srv.tlsByDef))
+	}
+	switch {
+	case useTLS && srv.tlsByDef && (res.TLS == nil || res.TLS.Error != "" || res.TLS.Version == ""):
+		errs = append(errs, fmt.Errorf("TLS not negotiated: %+v", res.TLS))
+	case !useTLS && res.TLS != nil:
+		errs = append(errs, errors.New("TLS attempted without -tls"))
+	}
+	if a := res.Auth; a == nil || !a.OK {
+		errs = append(errs, fmt.Errorf("root login failed: %+v", a))
+	} else if a.Stats == nil || !a.Stats.PingOK {
+		errs = append(errs, fmt.Errorf("no COM_PING after login: %+v", a.Stats))
+	}
+	return errs
+}
+
+/*
+docker runs the docker CLI and returns its trimmed stdout, or an error carrying its stderr.
+*/
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
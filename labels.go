@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+labelFlag collects repeated -label key=value flags.
+*/
+type labelFlag map[string]string
+
+/*
+String renders the labels as sorted key=value pairs for flag usage output.
+*/
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+/*
+Set adds one key=value label; the key must be non-empty.
+*/
+func (l labelFlag) Set(kv string) error {
+	key, value, ok := strings.Cut(kv, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", kv)
+	}
+	l[key] = value
+	return nil
+}
+
+/*
+mergeLabels combines the global -label set with a result's per-target labels, per-target values winning.
+Function-level comment: returns nil when both are empty so results without labels carry no labels field.
+*/
+func mergeLabels(global, target map[string]string) map[string]string {
+	if len(global) == 0 {
+		return target
+	}
+	merged := make(map[string]string, len(global)+len(target))
+	for k, v := range global {
+		merged[k] = v
+	}
+	for k, v := range target {
+		merged[k] = v
+	}
+	return merged
+}
@@ -1,278 +1,1089 @@
 package main
 
 import (
-	"encoding/binary"
-	"encoding/hex"
-	"errors"
+	"context"
+	"crypto"
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"net"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /*
-HandshakeInfo holds the fields we extract from the MySQL handshake packet.
+Result is the JSON record printed for a probed target.
+The probe's fields, including the handshake when MySQL was detected, are flattened into the top level.
 */
-type HandshakeInfo struct {
-	ProtocolVersion  uint8
-	ServerVersion    string
-	ConnectionID     uint32
-	CapabilityFlags  uint32
-	CharacterSet     uint8
-	StatusFlags      uint16
-	AuthPluginName   string
-	RawFirstBytesHex string
-	Notes            []string
+type Result struct {
+	Host       string            `json:"host,omitempty"`
+	Hostname   string            `json:"hostname,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	TargetHost string            `json:"target_host,omitempty"`
+	TargetIP   string            `json:"target_ip,omitempty"`
+	Port       int               `json:"port,omitempty"`
+	Pipe       string            `json:"pipe,omitempty"`
+	Probe      string            `json:"probe,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Label      string            `json:"label,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	ScanID     string            `json:"scan_id,omitempty"`
+	RunLabel   string            `json:"run_label,omitempty"`
+	mysqlprobe.Result
+	EOL                *bool             `json:"eol,omitempty"`
+	EOLDate            string            `json:"eol_date,omitempty"`
+	BuildVariant       string            `json:"build_variant,omitempty"`
+	BuildEvidence      []string          `json:"build_variant_evidence,omitempty"`
+	PlatformGuess      string            `json:"platform_guess,omitempty"`
+	PlatformEvidence   []string          `json:"platform_evidence,omitempty"`
+	WeakAuth           bool              `json:"weak_auth,omitempty"`
+	WeakAuthReason     string            `json:"weak_auth_reason,omitempty"`
+	CompatWarnings     []compatWarning   `json:"compat_warnings,omitempty"`
+	Provider           string            `json:"provider,omitempty"`
+	ProviderEvidence   []string          `json:"provider_evidence,omitempty"`
+	Middleware         string            `json:"middleware,omitempty"`
+	MiddlewareEvidence []string          `json:"middleware_evidence,omitempty"`
+	Intermediary       bool              `json:"intermediary,omitempty"`
+	TimingDiff         *timingDiffResult `json:"timing_diff,omitempty"`
+	InlineSuspected    bool              `json:"inline_device_suspected,omitempty"`
+	InlineEvidence     []string          `json:"inline_device_evidence,omitempty"`
+	UDP                []UDPResult       `json:"udp,omitempty"`
+	MySQLX             *mysqlprobe.XInfo `json:"mysqlx,omitempty"`
+	Cluster            *clusterInfo      `json:"cluster,omitempty"`
+	DB2                *DRDAResult       `json:"db2,omitempty"`
+	Sampling           *samplingResult   `json:"sampling,omitempty"`
+	External           externalResults   `json:"external,omitempty"`
+	Scanner            *buildMetadata    `json:"scanner,omitempty"`
+	Attempts           int               `json:"attempts,omitempty"`
+	SecondPass         bool              `json:"second_pass,omitempty"`
+	ErrorType          string            `json:"error_type,omitempty"`
+	Stack              string            `json:"stack,omitempty"`
+
+	// span is the target's trace span, so writing the result can be traced under it.
+	span trace.SpanContext
+}
+
+// subcommands maps a first argument to its entry point, which returns the exit code; a first argument that is a flag starts a scan.
+var subcommands = map[string]func(args []string) int{
+	"analyze": runAnalyze,
+	"scan":    runScanCommand,
+	"serve":   runServe,
 }
 
 /*
-readWithDeadline reads into the provided buffer from conn, applying a read deadline.
-Function-level comment: sets a read deadline and performs a single Read call; returns bytes read or an error.
+main is the program entrypoint.
+Function-level comment: runs the subcommand named by the first argument; arguments starting with a flag are a scan, as before the CLI had subcommands, so existing command lines keep working.
 */
-func readWithDeadline(conn net.Conn, buf []byte, timeout time.Duration) (int, error) {
-	_ = conn.SetReadDeadline(time.Now().Add(timeout))
-	return conn.Read(buf)
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		run, ok := subcommands[args[0]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown subcommand %q\n", args[0])
+			printSubcommands(os.Stderr)
+			os.Exit(2)
+		}
+		os.Exit(run(args[1:]))
+	}
+	os.Exit(runScanCommand(args))
 }
 
 /*
-parseNullTerminated extracts a NUL-terminated string from byte slice starting at start.
-Function-level comment: finds the next 0x00, returns the string and the position after the terminator or an error if none found.
+printSubcommands lists the subcommands compiled into this binary.
 */
-func parseNullTerminated(b []byte, start int) (val string, next int, err error) {
-	i := start
-	for i < len(b) && b[i] != 0x00 {
-		i++
-	}
-	if i >= len(b) {
-		return "", 0, errors.New("unterminated string")
+func printSubcommands(w io.Writer) {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
 	}
-	return string(b[start:i]), i + 1, nil
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage: mysql_scout <subcommand> [flags]\nsubcommands: %s\nrun mysql_scout <subcommand> -h for its flags; flags without a subcommand are a scan\n", strings.Join(names, ", "))
 }
 
 /*
-parseHandshake interprets the first MySQL packet payload and fills HandshakeInfo.
-Function-level comment: given a full packet (header+payload), parse fields per MySQL protocol v10 where possible;
-it is defensive about truncated payloads and returns partial info or an error when parsing cannot proceed.
+runScanCommand is the scan subcommand, and what the binary runs when given flags only.
 */
-func parseHandshake(b []byte) (*HandshakeInfo, error) {
-	if len(b) < 4 {
-		return nil, errors.New("short read (no packet header)")
-	}
-	payloadLen := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
-	seq := b[3]
-	_ = seq
+func runScanCommand(args []string) int {
+	return scanCommand("scan", args)
+}
 
-	if len(b) < 4+payloadLen {
-		return nil, errors.New("short read (payload incomplete)")
-	}
-	p := b[4 : 4+payloadLen]
+/*
+runServe is the serve subcommand: a scan run as the coordinator of a distributed scan, handing target batches to -worker scans (scan -coordinator with -listen).
+*/
+func runServe(args []string) int {
+	return scanCommand("serve", args)
+}
 
-	info := &HandshakeInfo{
-		RawFirstBytesHex: hex.EncodeToString(b[:min(len(b), 64)]),
+/*
+scanCommand parses the scan flags in args and runs the scan they describe.
+Function-level comment: parse flags, dial the target TCP address, read the first packet, parse the handshake, and print JSON-style results indicating whether MySQL was detected and details when available. name is the subcommand (scan or serve); serve takes the coordinator address as -listen and requires it.
+*/
+func scanCommand(name string, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mysql_scout %s [flags]\n", name)
+		fs.PrintDefaults()
 	}
-
-	if len(p) < 1 {
-		return nil, errors.New("payload too small for protocol version")
+	host := fs.String("host", "127.0.0.1", "Targets, comma-separated: host/IP/CIDR, host:port[,port...], mysql://host[:port] or mysqlx://host[:port], or srv:<name> for an SRV lookup; brace groups expand to lists and ranges, e.g. db{01..20}.example.com or 10.0.{1..4}.0/24")
+	port := fs.Int("port", 3306, "Target TCP port; without it, the -protocol's default port")
+	protocol := fs.String("protocol", mysqlprobe.ModuleName, "Protocol to probe targets with, from the registered probers (mysql)")
+	ports := fs.String("ports", "", "Comma-separated TCP ports to probe on every host (overrides -port)")
+	expandDNS := fs.Bool("expand-dns", false, "Probe every A/AAAA record of each hostname target instead of the first usable one (round-robin names)")
+	targetsFile := fs.String("targets-file", "", "Read targets from this file (- for stdin) instead of -host: one `spec [timeout=10s retries=3 tls=true label=name key=value...]` or JSON object per line, with specs as in -host; gzip and zstd files are decompressed, and files are streamed rather than loaded")
+	globalLabels := labelFlag{}
+	runLabel := fs.String("run-label", "", "Free-form name for this run (e.g. a job or shard), stamped on every result and the summary as run_label next to the generated scan_id")
+	var execProbes execProbeFlag
+	fs.Var(&execProbes, "probe-exec", "Run this program (path and space-separated arguments) for every target that answered, with the target and its result as JSON on stdin, and store the JSON it prints under external.<program name> (repeatable)")
+	execTimeout := fs.Duration("probe-exec-timeout", 10*time.Second, "With -probe-exec, kill a program still running after this long")
+	var plugins pluginFlag
+	fs.Var(&plugins, "plugin", "Load this Go plugin (.so) at startup; its init functions register extra probers, sinks, and modules (repeatable; Linux, macOS, and FreeBSD builds with cgo)")
+	fs.Var(globalLabels, "label", "Attach key=value to every result's labels (repeatable; -targets-file columns win per target)")
+	fromNmap := fs.String("from-nmap", "", "Take targets from nmap -oX output instead of -host (every open TCP port, see -nmap-services)")
+	nmapServices := fs.String("nmap-services", "", "With -from-nmap, keep only ports nmap labeled with these services, e.g. mysql,unknown")
+	fromMasscan := fs.String("from-masscan", "", "Take targets from masscan -oJ output instead of -host, keeping open ports listed in -port/-ports")
+	censysQuery := fs.String("censys-query", "", "Take targets from this Censys Search query instead of -host, e.g. 'services.service_name: MYSQL and location.country: DE' (credentials from CENSYS_API_ID/CENSYS_API_SECRET)")
+	censysServices := fs.String("censys-services", "MYSQL", "With -censys-query, keep only the matching hosts' TCP services with these Censys service names (empty = every TCP service)")
+	censysLimit := fs.Int("censys-limit", 1000, "With -censys-query, stop after this many matching hosts, to bound API quota use (0 = all)")
+	censysAPI := fs.String("censys-api", censysSearchAPI, "With -censys-query, the host search endpoint to query")
+	pipe := fs.String("pipe", "", `Probe the MySQL server on this Windows named pipe instead of -host, e.g. \\.\pipe\MySQL, for local scans where TCP is disabled (Windows only)`)
+	timeout := fs.Duration("timeout", 3*time.Second, "Dial/read timeout")
+	retries := fs.Int("retries", 0, "Retry a target this many times after a timeout or dropped connection")
+	secondPass := fs.Bool("second-pass", false, "Hold back targets that still failed with a timeout or dropped connection and probe them again once the main sweep finishes")
+	verbose := fs.Bool("v", false, "Verbose output (dump hex preview, timing trace)")
+	bannerFallback := fs.Bool("banner-fallback", false, "On non-MySQL responses, record a generic banner (probing silent services with a newline / HTTP GET)")
+	mysqlxPort := fs.Int("mysqlx-port", 0, "Also probe the X Protocol (CapabilitiesGet) on this port of every host, usually 33060, reporting capabilities, TLS, and auth mechanisms under mysqlx (0 = off)")
+	clusterChecks := fs.Bool("cluster-checks", false, "Also check each host for Group Replication / InnoDB Cluster and MySQL Router (ports 33061, 33062, 6446-6449, plus version hints), reporting indicators under cluster")
+	middlewareChecks := fs.Bool("middleware-checks", false, "Also check each host's ProxySQL admin port (6032), MySQL Router REST API (8443), MaxScale REST API (8989), and HAProxy stats page (8404), and retry targets that sent nothing behind a PROXY protocol header, to tell a proxy's handshake from the backend's (see middleware)")
+	db2Port := fs.Int("db2-port", 0, "Also send a DRDA EXCSAT to this port of every host, usually 50000, reporting Db2's server class, release level, and external name under db2 (0 = off)")
+	samples := fs.Int("samples", 0, "Open this many extra handshake-only connections to each MySQL target and report connection ID deltas, churn rate, restarts, load-balanced backends, and connect/handshake latency under sampling (0 = off)")
+	sampleInterval := fs.Duration("sample-interval", 500*time.Millisecond, "With -samples, time between sample connections")
+	timingDiffDelay := fs.Duration("timing-diff", 0, "Probe each MySQL target twice more, reading the banner at once and after staying silent this long (e.g. 2s, below the server's connect_timeout), and compare connect and banner timing and outcomes to set inline_device_suspected when a firewall, IDS/IPS, or rate-based blocking sits in the path (0 = off)")
+	udp := fs.String("udp", "", "Comma-separated UDP probes to also run against each host (memcached, dns)")
+	concurrency := fs.Int("concurrency", 50, "Maximum targets probed at once")
+	adaptiveConcurrency := fs.Bool("adaptive-concurrency", false, "Adjust the targets probed at once between -min-concurrency and -concurrency: doubling while timeouts and dropped connections stay at their usual rate, then growing by one per round, and halving when they spike")
+	minConcurrency := fs.Int("min-concurrency", 10, "With -adaptive-concurrency, the fewest targets probed at once, and where the scan starts")
+	outputQueue := fs.Int("output-queue", 0, "Results that may wait for a slow output sink before -output-queue-policy applies (0 = -concurrency)")
+	queuePolicy := fs.String("output-queue-policy", "block", "When the output queue is full: block (pause probing until the sink catches up) or drop (discard the result, count it as dropped_results, and keep scanning)")
+	hostParallelism := fs.Int("host-parallelism", 0, "Maximum simultaneous connections to the same host (0 = limited only by -concurrency)")
+	maxConnsPerIP := fs.Int("max-conns-per-ip", 0, "Maximum simultaneous connections to any one destination IP, however many targets (ports, hostnames) lead to it (0 = unlimited)")
+	checkpointPath := fs.String("checkpoint", "", "Record completed targets in this JSON file while scanning")
+	resumePath := fs.String("resume", "", "Skip targets already completed in this checkpoint file (and keep checkpointing to it unless -checkpoint is set)")
+	excludeFile := fs.String("exclude-file", "", "File of CIDRs, IPs, and hostnames that must never be contacted (one per line, # comments)")
+	shard := fs.String("shard", "", "Scan only shard k of n (\"k/n\", 0-based) of the expanded targets, for splitting work across instances")
+	ttl := fs.Int("ttl", 0, "IP TTL / IPv6 hop limit for outgoing packets (0 = system default)")
+	dscp := fs.Int("dscp", 0, "DSCP value (0-63) to mark outgoing packets with (0 = unmarked)")
+	tcpKeepAlive := fs.Duration("tcp-keepalive", 0, "TCP keepalive period (0 = Go default of 15s, negative disables keepalives)")
+	tcpLinger := fs.Int("tcp-linger", -1, "SO_LINGER seconds on close; 0 resets connections instead of a FIN handshake (-1 = OS default)")
+	iface := fs.String("interface", "", "Send probes out of this network interface, e.g. eth1 or wg0 (SO_BINDTODEVICE on Linux, the interface's source address elsewhere)")
+	tcpNoDelay := fs.Bool("tcp-nodelay", true, "Disable Nagle's algorithm (TCP_NODELAY)")
+	tcpClose := fs.String("tcp-close", "", "How probe connections end: fin (orderly close, the OS default) or rst (abortive, same as -tcp-linger 0)")
+	tcpFastOpen := fs.Bool("tcp-fastopen", false, "Send TCP Fast Open SYNs (TCP_FASTOPEN_CONNECT, Linux only): a cookie request, or the cached cookie")
+	dialRetries := fs.Int("dial-retries", 0, "Retry a TCP connect that times out this many times, before -retries counts a failed probe")
+	dialBackoff := fs.Duration("dial-backoff", 0, "Wait before the first -dial-retries retry, doubling after each")
+	sendProxyProtocol := fs.String("send-proxy-protocol", "", "Start every TCP connection with a PROXY protocol header, v1 (text) or v2 (binary), for load balancer frontends that drop connections without one")
+	tlsProbe := fs.Bool("tls", false, "When the server offers SSL, continue into TLS and record the certificate")
+	tlsCert := fs.String("tls-cert", "", "With -tls, PEM client certificate to present when a server requests one (mutual TLS / REQUIRE X509); needs -tls-key")
+	tlsKey := fs.String("tls-key", "", "PEM private key for -tls-cert")
+	tlsWrapped := fs.Bool("tls-wrapped", false, "When a server stays silent or sends TLS records first, retry it on a new connection that starts with a TLS handshake, for proxies wrapping the port in TLS without an SSLRequest; such servers are reported with tls_wrapped and their certificate")
+	downgradeTest := fs.Bool("downgrade-test", false, "On an extra connection, log in as a pre-4.1 client with no capabilities (no CLIENT_PROTOCOL_41, no SSL) and record in downgrade whether the server proceeds, errors, or disconnects")
+	followUpWait := fs.Duration("follow-up-wait", 0, "After a handshake, keep reading this long for packets the server sends unprompted (some proxies and honeypots do) and record them, parsed or raw, in additional_packets; 0 disables")
+	enumAuthPlugins := fs.Bool("enum-auth-plugins", false, "Without credentials, advertise each of mysql_native_password, caching_sha2_password, sha256_password, mysql_clear_password, client_ed25519, and dialog (PAM) on an extra connection and record in auth_plugin_matrix whether the server switches plugin, errors, or accepts")
+	detectTLSPolicy := fs.Bool("detect-tls-policy", false, "For servers offering SSL, try both TLS and a plaintext login on extra connections and record tls_policy: optional, required, or unsupported (heuristic without -user: MySQL only enforces require_secure_transport after a valid password)")
+	tlsMinVersion := fs.String("tls-min-version", "", "With -tls, lowest TLS version to offer: 1.0, 1.1, 1.2, or 1.3 (default 1.2); e.g. -tls-max-version 1.1 finds servers still accepting TLS 1.0/1.1")
+	tlsMaxVersion := fs.String("tls-max-version", "", "With -tls, highest TLS version to offer (default 1.3)")
+	tlsCiphers := fs.String("tls-ciphers", "", "With -tls, comma-separated cipher suites to offer, by Go name (e.g. TLS_RSA_WITH_3DES_EDE_CBC_SHA); TLS 1.3 suites are fixed, so this caps the version at 1.2 unless -tls-max-version is set")
+	sni := fs.String("sni", "", "With -tls, server name to send as SNI (none by default, like MySQL clients); proxies terminating TLS often route on it")
+	alpn := fs.String("alpn", "", "With -tls, comma-separated ALPN protocols to offer; the one selected is recorded as tls.alpn")
+	user := fs.String("user", "", "Authenticated mode: log in as this user after the handshake and report COM_PING/COM_STATISTICS figures under auth (password from -password-file or MYSQL_PWD)")
+	passwordFile := fs.String("password-file", "", "With -user, read the password from this file (default: the MYSQL_PWD environment variable)")
+	credentialsSource := fs.String("credentials", "", "Authenticated mode with per-target logins: read them from this JSON file, encrypted with age or AWS KMS, or from a Vault secret (vault://secret/data/path); entries may also name a Vault secret holding the password, read at scan time")
+	credentialsIdentity := fs.String("credentials-identity", "", "With an age-encrypted -credentials file, age identity file to decrypt it with")
+	queryVars := fs.String("query-vars", "", "With -user or -credentials, also read these server variables, from a fixed allowlist: version_comment, ssl_cipher, require_secure_transport, default_authentication_plugin, or all")
+	enumSchemas := fs.Bool("enum-schemas", false, "With -user or -credentials, list the schemas the user can see (SHOW DATABASES) under auth.schemas, for audits")
+	schemaRedact := fs.String("schema-redact", "none", "With -enum-schemas, how to report schema names: none, hash (short SHA-256 of non-system names), or count (no names)")
+	sshJumpSpec := fs.String("ssh-jump", "", "Tunnel every TCP probe through this SSH bastion (user@host[:port]), authenticating with ssh-agent and -ssh-key")
+	sshKey := fs.String("ssh-key", "", "With -ssh-jump, private key file to authenticate with (default: ~/.ssh/id_ed25519, id_ecdsa, id_rsa when present)")
+	sshKnownHosts := fs.String("ssh-known-hosts", "", "With -ssh-jump, known_hosts file the bastion's host key must be listed in (default ~/.ssh/known_hosts)")
+	blockThreshold := fs.Int("block-threshold", 0, "Throttle a network after this many \"host blocked/not allowed\" errors (ERR 1129/1130) from it (0 = never)")
+	blockPrefix := fs.Int("block-prefix", 24, "IPv4 prefix length networks are grouped by for -block-threshold (IPv6 uses /64)")
+	blockAction := fs.String("block-action", "stop", "What to do with a throttled network: stop (skip its remaining targets) or a rate such as 0.2/s")
+	subnetRate := fs.String("subnet-rate", "", "Limit connections into any one subnet: \"prefix:N/s\" per second or \"prefix:N\" concurrent (e.g. 24:2/s; IPv6 groups by /64)")
+	format := fs.String("format", "json", "Output format: json (one object per line), csv, pretty (aligned, colored on a terminal), table (fixed-width, printed at the end), parquet (use with -o), zgrab2 (zgrab2 envelope), or nmap-xml (nmap -oX schema)")
+	outputPath := fs.String("o", "", "Write results to this file instead of stdout")
+	compress := fs.String("compress", "", "Compress output written to -o or stdout: gzip or zstd")
+	signKey := fs.String("sign-key", "", "Sign the -o file when the scan ends with this PEM private key (Ed25519, ECDSA, or RSA), writing a detached signature to the file's name plus .sig (check it with the verify subcommand)")
+	outputURL := fs.String("output", "", "Send results to this sink URL instead of stdout/-o: gzipped NDJSON chunks under an object storage prefix (s3://bucket/prefix/ or gs://bucket/prefix/), NDJSON to file:///path or stdout:, one RFC 5424 message per detection to syslog://host[:port][?transport=udp|tcp|tls], or any scheme registered with mysqlprobe.RegisterSink")
+	outputChunkSize := fs.Int("output-chunk-size", defaultOutputChunkSize>>20, "With -output, MiB of NDJSON (before compression) per uploaded object")
+	gcsChunkSize := fs.Int("gcs-chunk-size", defaultGCSUploadChunkSize>>20, "With -output gs://, MiB sent per resumable upload request (0 = single-request uploads)")
+	fieldList := fs.String("fields", "", "Comma-separated output fields to keep, e.g. host,port,server_version,auth_plugin (dots reach nested fields)")
+	redact := fs.Bool("redact", false, "Mask results for sharing outside the organization: the last octet of IPv4 addresses (the host bits past /64 of IPv6), hostnames and certificate names replaced by hashes, raw packet hex, auth salt, and schema names dropped")
+	dedupe := fs.Bool("dedupe", false, "Hold results until the scan ends, merge targets that reached the same IP:port under different hostnames into one record listing them all in hostnames, and write them sorted by IP and port")
+	filterExpr := fs.String("filter", "", "Only print results matching this expression, e.g. 'mysql==true && version<\"5.7\"'")
+	coordinatorFlag, coordinatorHelp := "coordinator", "Run as coordinator: serve target batches to workers on this listen address (e.g. :8700); same as the serve subcommand"
+	if name == "serve" {
+		coordinatorFlag, coordinatorHelp = "listen", "Address to serve target batches to workers on (e.g. :8700)"
+	}
+	coordinatorAddr := fs.String(coordinatorFlag, "", coordinatorHelp)
+	workerURL := fs.String("worker", "", "Run as worker: pull batches from the coordinator at this URL (e.g. http://coord:8700)")
+	batchSize := fs.Int("batch-size", 256, "Targets per batch handed to a worker (coordinator mode)")
+	leaseTimeout := fs.Duration("lease-timeout", 5*time.Minute, "Time a worker has to return a batch before it is re-leased (coordinator mode)")
+	webhookURL := fs.String("webhook", "", "POST a JSON event to this URL for every MySQL server detected (after -filter) and every -watch change, retrying failures with backoff")
+	watch := fs.Duration("watch", 0, "Rescan the targets every interval and report changes of server version, TLS posture, or auth plugin (0 = scan once)")
+	watchCount := fs.Int("watch-count", 0, "With -watch, stop after this many rounds (0 = until interrupted)")
+	alertSlack := fs.String("alert-slack", "", "With -watch, post change alerts to this Slack incoming-webhook URL")
+	alertSMTP := fs.String("alert-smtp", "", "With -watch, mail change alerts via smtp://[user:password@]host[:port]?from=...&to=a,b")
+	alertTemplate := fs.String("alert-template", "", "Go text/template for alert messages over .Target .Field .Old .New .Time .Result (default \""+defaultAlertTemplate+"\")")
+	alertRateSpec := fs.String("alert-rate", "10/h", "Maximum alerts each sink sends per window (N/s, N/m, or N/h); extra alerts are dropped and counted")
+	noRecover := fs.Bool("no-recover", false, "Let a panic while probing a target crash the scan instead of recording it as an internal_error result (for debugging)")
+	maxPayload := fs.Int("max-payload", mysqlprobe.DefaultMaxPayload, "Largest first-packet payload read in full, in bytes; a longer packet is read up to this limit and reported as truncated")
+	captureBytes := fs.Int("capture-bytes", 0, "Deprecated: use -max-payload; the first-packet buffer size, payload plus the 4-byte header")
+	manifestDir := fs.String("manifest", "", "Write a reproducibility manifest into this directory: manifest.json with the scan ID, build, every flag's value, timing, summary, and the -o file's SHA-256, and targets.txt with the exact target list as a -targets-file")
+	summaryPath := fs.String("summary", "", "Write the end-of-scan summary (counts by version, auth plugin, and error type) as JSON to this file instead of printing it to stderr")
+	statsdAddr := fs.String("statsd", "", "Send per-target counters and timings to this statsd/DogStatsD agent over UDP, e.g. 127.0.0.1:8125")
+	statsdPrefix := fs.String("statsd-prefix", "mysql_scout.", "With -statsd, prefix of every metric name")
+	statsdTags := fs.String("statsd-tags", "", "With -statsd, comma-separated key:value tags added to every metric, e.g. env:prod,team:secops")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "Trace each target (dial, first-packet read, parse, TLS, output) with OpenTelemetry and export the spans over OTLP/HTTP to this collector URL, e.g. http://localhost:4318")
+	traceSampleRatio := fs.Float64("trace-sample-ratio", 1, "With -otlp-endpoint, fraction of targets to trace (0-1)")
+	pprofAddr := fs.String("pprof-addr", "", "Serve net/http/pprof profiles (CPU, heap, goroutines) on this address, e.g. 127.0.0.1:6060, while the scan runs")
+	maxRuntime := fs.Duration("max-runtime", 0, "Stop starting new targets once the scan has run this long (e.g. 2h), let those in flight finish, and count the rest as skipped_deadline in the summary (0 = no limit)")
+	dnsCacheTTL := fs.Duration("dns-cache-ttl", 0, "Cache hostname lookups for this long instead of each answer's record TTL (0 = honor record TTLs, negative = no cache)")
+	recordDir := fs.String("record", "", "Save the raw bytes of every target's probe connection under this directory, one <ip>_<port>.session.json per target, for the replay subcommand")
+	corpusDir := fs.String("corpus", "", "Save every distinct raw handshake under this directory, one <handshake_fingerprint>.bin with a .json of its metadata and labels, for fingerprint research and fuzzing seeds; rescanning into the same directory extends it (see the corpus export subcommand)")
+	showVersion := fs.Bool("version", false, "Print the version, git commit, build date, and Go version, then exit (same as the version subcommand)")
+	dryRun := fs.Bool("dry-run", false, "Expand targets, apply exclusions, sharding, and -resume, then print the plan and effective settings without probing anything")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "%s: unexpected arguments: %s\n", name, strings.Join(fs.Args(), " "))
+		return 2
+	}
+	if err := loadPlugins(plugins); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -plugin: %v\n", err)
+		return 2
+	}
+	if *execTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "invalid -probe-exec-timeout: must be positive")
+		return 2
+	}
+	if name == "serve" && *coordinatorAddr == "" {
+		fmt.Fprintln(os.Stderr, "serve: -listen is required")
+		return 2
+	}
+	if *showVersion {
+		fmt.Println(scannerBuild)
+		return 0
 	}
-	info.ProtocolVersion = p[0]
-	i := 1
 
-	sv, next, err := parseNullTerminated(p, i)
+	udpNames, err := parseUDPProbeList(*udp)
 	if err != nil {
-		return nil, fmt.Errorf("server version parse error: %w", err)
+		fmt.Fprintf(os.Stderr, "invalid -udp: %v\n", err)
+		os.Exit(2)
 	}
-	info.ServerVersion = sv
-	i = next
-
-	if i+4 > len(p) {
-		return nil, errors.New("payload too small for connection id")
+	var resolver *dnsCache
+	if *dnsCacheTTL >= 0 {
+		resolver = newDNSCache(*dnsCacheTTL)
 	}
-	info.ConnectionID = binary.LittleEndian.Uint32(p[i : i+4])
-	i += 4
-
-	if i+8+1 > len(p) {
-		return nil, errors.New("payload too small for auth data part 1")
+	newProber, ok := mysqlprobe.LookupProber(*protocol)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid -protocol: want one of %s, got %q\n", strings.Join(mysqlprobe.ProberNames(), ", "), *protocol)
+		os.Exit(2)
 	}
-	i += 8
-	i += 1
-
-	if i+2 > len(p) {
-		return nil, errors.New("payload too small for capability flags (lower)")
+	portSet := false
+	fs.Visit(func(f *flag.Flag) { portSet = portSet || f.Name == "port" })
+	if !portSet {
+		*port = newProber(mysqlprobe.Options{}).DefaultPort()
 	}
-	capLower := binary.LittleEndian.Uint16(p[i : i+2])
-	i += 2
-
-	if i >= len(p) {
-		info.CapabilityFlags = uint32(capLower)
-		return info, nil
+	portList := []int{*port}
+	if *ports != "" {
+		if portList, err = parsePortList(*ports); err != nil || len(portList) == 0 {
+			fmt.Fprintf(os.Stderr, "invalid -ports: %v\n", err)
+			os.Exit(2)
+		}
 	}
 
-	if i+1+2+2 > len(p) {
-		info.CapabilityFlags = uint32(capLower)
-		return info, nil
+	socketOpts := mysqlprobe.SocketOptions{TTL: *ttl, DSCP: *dscp, KeepAlive: *tcpKeepAlive, NoDelay: tcpNoDelay, Interface: *iface, FastOpen: *tcpFastOpen, DialRetries: *dialRetries, DialBackoff: *dialBackoff}
+	if *tcpLinger >= 0 {
+		socketOpts.Linger = tcpLinger
 	}
-	info.CharacterSet = p[i]
-	i += 1
-
-	info.StatusFlags = binary.LittleEndian.Uint16(p[i : i+2])
-	i += 2
-
-	capUpper := binary.LittleEndian.Uint16(p[i : i+2])
-	i += 2
-
-	info.CapabilityFlags = uint32(capLower) | (uint32(capUpper) << 16)
-
-	var authDataLen uint8
-	if (info.CapabilityFlags & (1 << 19)) != 0 {
-		if i >= len(p) {
-			return info, nil
+	switch *tcpClose {
+	case "":
+	case "fin", "rst":
+		if socketOpts.Linger != nil && (*tcpLinger == 0) != (*tcpClose == "rst") {
+			fmt.Fprintf(os.Stderr, "invalid -tcp-close: %s contradicts -tcp-linger %d\n", *tcpClose, *tcpLinger)
+			os.Exit(2)
 		}
-		authDataLen = p[i]
-		i += 1
-	} else {
-		if i < len(p) {
-			authDataLen = p[i]
-			i += 1
+		// Without SO_LINGER the kernel closes with a FIN, so only rst needs a setting.
+		if *tcpClose == "rst" {
+			socketOpts.Linger = new(int)
 		}
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -tcp-close: want fin or rst, got %q\n", *tcpClose)
+		os.Exit(2)
 	}
-
-	if i+10 <= len(p) {
-		i += 10
+	if err := socketOpts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid socket options: %v\n", err)
+		os.Exit(2)
+	}
+	proxyProtocol, err := mysqlprobe.ParseProxyProtocol(*sendProxyProtocol)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -send-proxy-protocol: %v\n", err)
+		os.Exit(2)
 	}
 
-	if authDataLen > 0 && i < len(p) {
-		need := int(authDataLen) - 8
-		if need < 0 {
-			need = 0
-		}
-		if need > 0 {
-			if i+need <= len(p) {
-				i += need
-			} else {
-				i = len(p)
+	var exclusions *exclusionList
+	if *excludeFile != "" {
+		if exclusions, err = loadExclusions(*excludeFile); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -exclude-file: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var clientCert *tls.Certificate
+	if *tlsCert != "" || *tlsKey != "" {
+		if *tlsCert == "" || *tlsKey == "" || !(*tlsProbe || *tlsWrapped) {
+			fmt.Fprintln(os.Stderr, "-tls-cert and -tls-key must be given together, with -tls or -tls-wrapped")
+			os.Exit(2)
+		}
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -tls-cert/-tls-key: %v\n", err)
+			os.Exit(2)
+		}
+		clientCert = &cert
+	}
+	var tlsPolicy mysqlprobe.TLSPolicy
+	if *tlsMinVersion != "" || *tlsMaxVersion != "" || *tlsCiphers != "" || *sni != "" || *alpn != "" {
+		if !*tlsProbe && !*tlsWrapped {
+			fmt.Fprintln(os.Stderr, "-tls-min-version, -tls-max-version, -tls-ciphers, -sni, and -alpn need -tls or -tls-wrapped")
+			os.Exit(2)
+		}
+		tlsPolicy.ServerName, tlsPolicy.ALPN = *sni, splitList(*alpn)
+		if tlsPolicy.MinVersion, err = mysqlprobe.ParseTLSVersion(*tlsMinVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -tls-min-version: %v\n", err)
+			os.Exit(2)
+		}
+		if tlsPolicy.MaxVersion, err = mysqlprobe.ParseTLSVersion(*tlsMaxVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -tls-max-version: %v\n", err)
+			os.Exit(2)
+		}
+		if tlsPolicy.MaxVersion != 0 && tlsPolicy.MinVersion > tlsPolicy.MaxVersion {
+			fmt.Fprintln(os.Stderr, "invalid -tls-min-version: above -tls-max-version")
+			os.Exit(2)
+		}
+		if tlsPolicy.CipherSuites, err = mysqlprobe.ParseCipherSuites(*tlsCiphers); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -tls-ciphers: %v\n", err)
+			os.Exit(2)
+		}
+		if len(tlsPolicy.CipherSuites) > 0 && tlsPolicy.MinVersion == tls.VersionTLS13 {
+			fmt.Fprintln(os.Stderr, "invalid -tls-ciphers: TLS 1.3 cipher suites cannot be chosen")
+			os.Exit(2)
+		}
+	}
+	var creds *mysqlprobe.Credentials
+	if *user != "" {
+		creds = &mysqlprobe.Credentials{User: *user, Password: os.Getenv("MYSQL_PWD")}
+		if *passwordFile != "" {
+			data, err := os.ReadFile(*passwordFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid -password-file: %v\n", err)
+				os.Exit(2)
 			}
+			creds.Password = strings.TrimRight(string(data), "\r\n")
 		}
+	} else if *passwordFile != "" || ((*queryVars != "" || *enumSchemas) && *credentialsSource == "") {
+		fmt.Fprintln(os.Stderr, "-password-file needs -user, and -query-vars and -enum-schemas need -user or -credentials")
+		os.Exit(2)
 	}
-
-	if i < len(p) {
-		if name, _, err := parseNullTerminated(p, i); err == nil {
-			info.AuthPluginName = name
+	var credentials *credentialStore
+	if *credentialsSource != "" {
+		if *user != "" {
+			fmt.Fprintln(os.Stderr, "-credentials and -user are mutually exclusive")
+			os.Exit(2)
+		}
+		credentials, err = loadCredentialStore(context.Background(), *credentialsSource, *credentialsIdentity)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -credentials: %v\n", err)
+			os.Exit(2)
 		}
+	} else if *credentialsIdentity != "" {
+		fmt.Fprintln(os.Stderr, "-credentials-identity needs -credentials")
+		os.Exit(2)
 	}
-
-	return info, nil
-}
-
-/*
-grabFirstPacket reads the initial MySQL packet (header + payload) from conn.
-Function-level comment: reads the 4-byte MySQL packet header to determine payload length and then reads the payload; returns raw header+payload or partial data on timeout/error.
-*/
-func grabFirstPacket(conn net.Conn, overallTimeout time.Duration) ([]byte, error) {
-	header := make([]byte, 4)
-	if _, err := readWithDeadline(conn, header, overallTimeout); err != nil {
-		return nil, fmt.Errorf("read header: %w", err)
-	}
-	payloadLen := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
-	if payloadLen <= 0 || payloadLen > 100000 {
-		return append(header, []byte{}...), nil
-	}
-	payload := make([]byte, payloadLen)
-	read := 0
-	for read < payloadLen {
-		n, err := readWithDeadline(conn, payload[read:], overallTimeout)
-		if n > 0 {
-			read += n
+	if err := mysqlprobe.ValidateSchemaRedaction(*schemaRedact); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -schema-redact: %v\n", err)
+		os.Exit(2)
+	}
+	variables, err := mysqlprobe.ParseQueryVariables(*queryVars)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -query-vars: %v\n", err)
+		os.Exit(2)
+	}
+	if *sshJumpSpec != "" && *udp != "" {
+		fmt.Fprintln(os.Stderr, "-udp cannot be used with -ssh-jump (UDP cannot be tunneled)")
+		os.Exit(2)
+	}
+	if *mysqlxPort < 0 || *mysqlxPort > 65535 {
+		fmt.Fprintln(os.Stderr, "invalid -mysqlx-port: must be 0-65535")
+		os.Exit(2)
+	}
+	if *db2Port < 0 || *db2Port > 65535 {
+		fmt.Fprintln(os.Stderr, "invalid -db2-port: must be 0-65535")
+		os.Exit(2)
+	}
+	if *samples < 0 || *sampleInterval < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -samples/-sample-interval: must not be negative")
+		os.Exit(2)
+	}
+	if *timingDiffDelay < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -timing-diff: must not be negative")
+		os.Exit(2)
+	}
+	if *adaptiveConcurrency && (*minConcurrency < 1 || *minConcurrency > *concurrency) {
+		fmt.Fprintf(os.Stderr, "invalid -min-concurrency %d: want 1 to -concurrency (%d)\n", *minConcurrency, *concurrency)
+		os.Exit(2)
+	}
+	if *captureBytes != 0 {
+		maxPayloadSet := false
+		fs.Visit(func(f *flag.Flag) { maxPayloadSet = maxPayloadSet || f.Name == "max-payload" })
+		if maxPayloadSet {
+			fmt.Fprintln(os.Stderr, "invalid -capture-bytes: use -max-payload alone")
+			os.Exit(2)
+		}
+		*maxPayload = *captureBytes - 4
+	}
+	if *followUpWait < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -follow-up-wait: must not be negative")
+		os.Exit(2)
+	}
+	if *maxPayload < 60 {
+		fmt.Fprintln(os.Stderr, "invalid -max-payload: must be at least 60")
+		os.Exit(2)
+	}
+	if *maxConnsPerIP < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -max-conns-per-ip: must not be negative")
+		os.Exit(2)
+	}
+	if *retries < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -retries: must not be negative")
+		os.Exit(2)
+	}
+	sources := 0
+	for _, src := range []string{*targetsFile, *fromMasscan, *fromNmap, *censysQuery} {
+		if src != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		fmt.Fprintln(os.Stderr, "-targets-file, -from-masscan, -from-nmap, and -censys-query are mutually exclusive")
+		os.Exit(2)
+	}
+	if *pipe != "" {
+		hostSet := false
+		fs.Visit(func(f *flag.Flag) { hostSet = hostSet || f.Name == "host" || f.Name == "ports" || f.Name == "port" })
+		switch {
+		case !mysqlprobe.CanDialPipe:
+			fmt.Fprintln(os.Stderr, "invalid -pipe: named pipes are only supported on Windows")
+			os.Exit(2)
+		case hostSet || sources > 0:
+			fmt.Fprintln(os.Stderr, "-pipe probes a single local server and cannot be combined with -host, -port(s), or other target sources")
+			os.Exit(2)
+		}
+	}
+	if *censysLimit < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -censys-limit: must not be negative")
+		os.Exit(2)
+	}
+	if *dryRun && *workerURL != "" {
+		fmt.Fprintln(os.Stderr, "-dry-run cannot be used with -worker (targets come from the coordinator)")
+		os.Exit(2)
+	}
+	if *watch < 0 || *watchCount < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -watch/-watch-count: must not be negative")
+		os.Exit(2)
+	}
+	if *watch > 0 {
+		switch {
+		case *coordinatorAddr != "" || *workerURL != "":
+			fmt.Fprintln(os.Stderr, "-watch cannot be used with -coordinator or -worker")
+			os.Exit(2)
+		case *checkpointPath != "" || *resumePath != "":
+			fmt.Fprintln(os.Stderr, "-watch cannot be used with -checkpoint or -resume")
+			os.Exit(2)
+		case *dedupe:
+			fmt.Fprintln(os.Stderr, "-watch cannot be used with -dedupe (a round's results are written as they come)")
+			os.Exit(2)
+		case *format == "table" || *format == "parquet" || *format == "nmap-xml":
+			fmt.Fprintf(os.Stderr, "-watch needs a streaming format (json, csv, pretty, zgrab2), not %s\n", *format)
+			os.Exit(2)
+		}
+	} else if *alertSlack != "" || *alertSMTP != "" {
+		fmt.Fprintln(os.Stderr, "-alert-slack and -alert-smtp need -watch")
+		os.Exit(2)
+	}
+	var signer crypto.Signer
+	if *signKey != "" {
+		switch {
+		case *outputPath == "":
+			fmt.Fprintln(os.Stderr, "-sign-key needs -o (the signature covers the output file)")
+			os.Exit(2)
+		case *watch > 0:
+			fmt.Fprintln(os.Stderr, "-sign-key cannot be used with -watch (the output is never finished)")
+			os.Exit(2)
+		}
+		if signer, err = loadSigningKey(*signKey); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -sign-key: %v\n", err)
+			os.Exit(2)
 		}
+	}
+	if *dedupe && (*checkpointPath != "" || *resumePath != "") {
+		// Results held for sorting would be marked done in the checkpoint before they were written.
+		fmt.Fprintln(os.Stderr, "-dedupe cannot be used with -checkpoint or -resume")
+		os.Exit(2)
+	}
+	if *checkpointPath != "" || *resumePath != "" {
+		switch *format {
+		case "table", "parquet", "nmap-xml":
+			// These formats hold rows until the scan ends, so an interrupted scan would checkpoint rows it never wrote.
+			fmt.Fprintf(os.Stderr, "-format %s cannot be used with -checkpoint or -resume\n", *format)
+			os.Exit(2)
+		}
+	}
+	var sinks []alertSink
+	if *alertSlack != "" {
+		sinks = append(sinks, &slackSink{url: *alertSlack, http: &http.Client{Timeout: 30 * time.Second}})
+	}
+	if *alertSMTP != "" {
+		s, err := newSMTPSink(*alertSMTP)
 		if err != nil {
-			return append(header, payload[:read]...), nil
+			fmt.Fprintf(os.Stderr, "invalid -alert-smtp: %v\n", err)
+			os.Exit(2)
 		}
+		sinks = append(sinks, s)
 	}
-	return append(header, payload...), nil
-}
-
-/*
-min is a small helper utility.
-Function-level comment: returns the smaller of two integers.
-*/
-func min(a, b int) int {
-	if a < b {
-		return a
+	alertRate, err := parseAlertRate(*alertRateSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -alert-rate: %v\n", err)
+		os.Exit(2)
 	}
-	return b
-}
-
-/*
-escape performs a minimal JSON-safe escaping for a string.
-Function-level comment: escapes backslashes, quotes, and common control characters for safe inline JSON printing.
-*/
-func escape(s string) string {
-	out := make([]byte, 0, len(s)+8)
-	for i := 0; i < len(s); i++ {
-		switch s[i] {
-		case '\\', '"':
-			out = append(out, '\\', s[i])
-		case '\n':
-			out = append(out, '\\', 'n')
-		case '\r':
-			out = append(out, '\\', 'r')
-		case '\t':
-			out = append(out, '\\', 't')
-		default:
-			out = append(out, s[i])
-		}
-	}
-	return string(out)
-}
-
-/*
-main is the program entrypoint.
-Function-level comment: parse flags, dial the target TCP address, read the first packet, parse the handshake, and print JSON-style results indicating whether MySQL was detected and details when available.
-*/
-func main() {
-	host := flag.String("host", "127.0.0.1", "Target host/IP")
-	port := flag.Int("port", 3306, "Target TCP port")
-	timeout := flag.Duration("timeout", 3*time.Second, "Dial/read timeout")
-	verbose := flag.Bool("v", false, "Verbose output (dump hex preview)")
-	flag.Parse()
-
-	addr := fmt.Sprintf("%s:%d", *host, *port)
-	dialer := net.Dialer{Timeout: *timeout}
-	conn, err := dialer.Dial("tcp", addr)
+	if *coordinatorAddr != "" && *workerURL != "" {
+		fmt.Fprintln(os.Stderr, "-coordinator and -worker are mutually exclusive")
+		os.Exit(2)
+	}
+	subnetSpec, err := parseSubnetRate(*subnetRate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -subnet-rate: %v\n", err)
+		os.Exit(2)
+	}
+	blocks, err := newBlockTracker(*blockThreshold, *blockPrefix, *blockAction, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -block-threshold/-block-action: %v\n", err)
+		os.Exit(2)
+	}
+	filter, err := compileFilter(*filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -filter: %v\n", err)
+		os.Exit(2)
+	}
+	shardSel, err := parseShard(*shard)
 	if err != nil {
-		fmt.Printf("{\"ok\":false,\"mysql\":false,\"error\":\"dial failed: %s\"}\n", escape(err.Error()))
-		os.Exit(0)
+		fmt.Fprintf(os.Stderr, "invalid -shard: %v\n", err)
+		os.Exit(2)
+	}
+	hostSpecs, srvTargets := lookupSRVTargets(context.Background(), splitTargetList(*host))
+	hostTargets, err := specTargets(hostSpecs, portList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -host: %v\n", err)
+		os.Exit(2)
 	}
-	defer conn.Close()
 
-	first, err := grabFirstPacket(conn, *timeout)
-	if err != nil || len(first) < 4 {
+	// Projected and non-JSON output pick their own columns, so keep the full handshake for them to choose from; the probe only does its verbose work (timing trace, first bytes) when a column shows it.
+	fullDetail := *verbose || *fieldList != "" || *format != "json"
+	// A filter on a verbose-only field needs the verbose probe to fill it in, even when output never shows it.
+	verboseProbe := *verbose || needsVerboseProbe(*format, splitList(*fieldList)) || slices.ContainsFunc(filter.fields(), func(f string) bool {
+		return slices.Contains(verboseProbeFields, f)
+	})
+	if *maxRuntime < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -max-runtime: must not be negative")
+		os.Exit(2)
+	}
+	if *outputQueue < 0 {
+		fmt.Fprintln(os.Stderr, "invalid -output-queue: must not be negative")
+		os.Exit(2)
+	}
+	if *queuePolicy != "block" && *queuePolicy != "drop" {
+		fmt.Fprintf(os.Stderr, "invalid -output-queue-policy %q: want block or drop\n", *queuePolicy)
+		os.Exit(2)
+	}
+	var deadline time.Time
+	if *maxRuntime > 0 {
+		deadline = time.Now().Add(*maxRuntime)
+	}
+	var recorder *sessionRecorder
+	if *recordDir != "" && !*dryRun {
+		if recorder, err = newSessionRecorder(*recordDir, *protocol); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -record: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var corpus *handshakeCorpus
+	if *corpusDir != "" && !*dryRun {
+		if corpus, err = newHandshakeCorpus(*corpusDir); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -corpus: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	var statsd *statsdClient
+	if *statsdAddr != "" && !*dryRun {
+		tags, err := parseStatsdTags(*statsdTags)
 		if err != nil {
-			fmt.Printf("{\"ok\":false,\"mysql\":false,\"error\":\"read failed: %s\"}\n", escape(err.Error()))
-		} else {
-			fmt.Printf("{\"ok\":false,\"mysql\":false,\"error\":\"no data from server\"}\n")
+			fmt.Fprintf(os.Stderr, "invalid -statsd-tags: %v\n", err)
+			os.Exit(2)
+		}
+		if statsd, err = newStatsdClient(*statsdAddr, *statsdPrefix, tags); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -statsd: %v\n", err)
+			os.Exit(2)
+		}
+		defer statsd.close()
+	}
+	cfg := scanConfig{
+		concurrency:     *concurrency,
+		hostParallelism: *hostParallelism,
+		perIPLimit:      *maxConnsPerIP,
+		prober:          newProber,
+		recorder:        recorder,
+		stats:           newRuntimeStats(),
+		statsd:          statsd,
+		deadline:        deadline,
+		dns:             resolver,
+		probe:           mysqlprobe.Options{Timeout: *timeout, Verbose: verboseProbe, BannerFallback: *bannerFallback, TLS: *tlsProbe, ClientCert: clientCert, TLSPolicy: tlsPolicy, DetectTLSRequirement: *detectTLSPolicy, DowngradeTest: *downgradeTest, TLSWrapped: *tlsWrapped, EnumAuthPlugins: *enumAuthPlugins, FollowUpWait: *followUpWait, Credentials: creds, Variables: variables, EnumSchemas: *enumSchemas, SchemaRedaction: *schemaRedact, Socket: socketOpts, ProxyProtocol: proxyProtocol, KeepRawHandshake: corpus != nil, Buffers: mysqlprobe.NewBufferPool(4 + *maxPayload)},
+		retries:         *retries,
+		secondPass:      *secondPass,
+		udpProbes:       udpNames,
+		mysqlxPort:      *mysqlxPort,
+		db2Port:         *db2Port,
+		clusterChecks:   *clusterChecks,
+		middleware:      *middlewareChecks,
+		samples:         *samples,
+		sampleInterval:  *sampleInterval,
+		timingDiffDelay: *timingDiffDelay,
+		credentials:     credentials,
+		exclusions:      exclusions,
+		subnetRate:      subnetSpec,
+		blocks:          blocks,
+		noRecover:       *noRecover,
+		execProbes:      execProbes,
+		execTimeout:     *execTimeout,
+		outputQueue:     *outputQueue,
+		dropResults:     *queuePolicy == "drop",
+	}
+	if *sshJumpSpec != "" && !*dryRun {
+		jump, err := newSSHJump(*sshJumpSpec, *sshKey, *sshKnownHosts, socketOpts, *timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -ssh-jump: %v\n", err)
+			os.Exit(2)
+		}
+		defer jump.close()
+		cfg.probe.Dial = jump.dial
+	}
+	if *adaptiveConcurrency {
+		cfg.adaptive = newConcurrencyController(*minConcurrency, *concurrency)
+		cfg.stats.adaptive = cfg.adaptive
+	}
+	if *otlpEndpoint != "" && !*dryRun {
+		if *traceSampleRatio < 0 || *traceSampleRatio > 1 {
+			fmt.Fprintln(os.Stderr, "invalid -trace-sample-ratio: must be between 0 and 1")
+			os.Exit(2)
+		}
+		shutdown, err := setupTracing(context.Background(), *otlpEndpoint, *traceSampleRatio)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -otlp-endpoint: %v\n", err)
+			os.Exit(2)
 		}
-		return
+		defer func() {
+			if err := shutdown(context.Background()); err != nil {
+				fmt.Fprintf(os.Stderr, "trace export: %v\n", err)
+			}
+		}()
+	}
+	if *pprofAddr != "" && !*dryRun {
+		addr, err := startPprof(*pprofAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -pprof-addr: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "pprof: serving on http://%s/debug/pprof/\n", addr)
+	}
+	if *workerURL != "" {
+		if err := runWorker(*workerURL, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+			os.Exit(1)
+		}
+		return 0
 	}
 
-	info, perr := parseHandshake(first)
-	if perr != nil {
-		if *verbose {
-			fmt.Printf("{\"ok\":true,\"mysql\":false,\"reason\":\"%s\",\"first_bytes_hex\":\"%s\"}\n", escape(perr.Error()), hex.EncodeToString(first[:min(len(first), 64)]))
+	targets := append(hostTargets, srvTargets...)
+	if *pipe != "" {
+		targets = []target{{host: *pipe, pipe: true}}
+	}
+	if *fromMasscan != "" {
+		if targets, err = loadMasscanTargets(*fromMasscan, portList); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -from-masscan: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	// A -targets-file on disk is streamed, not loaded, unless the target list must be held in memory anyway.
+	var stream *targetStream
+	if *targetsFile != "" {
+		if *targetsFile == "-" {
+			targets, err = loadTargetsFile(*targetsFile, portList)
 		} else {
-			fmt.Printf("{\"ok\":true,\"mysql\":false}\n")
+			stream, err = openTargetStream(*targetsFile, portList)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -targets-file: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *fromNmap != "" {
+		if targets, err = loadNmapTargets(*fromNmap, splitList(*nmapServices)); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -from-nmap: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *censysQuery != "" {
+		client, err := newCensysClient(*censysAPI)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -censys-query: %v\n", err)
+			os.Exit(2)
+		}
+		if targets, err = loadCensysTargets(context.Background(), client, *censysQuery, splitList(*censysServices), *censysLimit); err != nil {
+			fmt.Fprintf(os.Stderr, "-censys-query: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "censys: %d targets matched %q\n", len(targets), *censysQuery)
+	}
+	seq := slices.Values(targets)
+	if stream != nil {
+		seq = stream.all
+	}
+	if *expandDNS || *dedupe {
+		seq = expandRecords(context.Background(), seq, resolver, !*expandDNS)
+	}
+	var dropped int
+	seq = applyShard(filterExcluded(seq, exclusions, &dropped), shardSel)
+	inMemory := stream == nil || *dryRun || *coordinatorAddr != ""
+	if inMemory {
+		targets = slices.Collect(seq)
+		seq = slices.Values(targets)
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "skipping %d excluded targets\n", dropped)
 		}
-		return
 	}
+	sharded := targets
 
-	if !*verbose {
-		fmt.Printf("{\"ok\":true,\"mysql\":true,\"server_version\":\"%s\",\"protocol\":%d,\"connection_id\":%d}\n",
-			escape(info.ServerVersion), info.ProtocolVersion, info.ConnectionID)
-		return
+	var cp *checkpoint
+	if *resumePath != "" {
+		done, err := loadCheckpoint(*resumePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot resume from %s: %v\n", *resumePath, err)
+			os.Exit(2)
+		}
+		path := *checkpointPath
+		if path == "" {
+			path = *resumePath
+		}
+		cp = newCheckpoint(path, done)
+		seq = cp.pending(seq)
+	} else if *checkpointPath != "" {
+		cp = newCheckpoint(*checkpointPath, nil)
+	}
+	if *dryRun {
+		targets = slices.Collect(seq)
+		stats := []planStat{
+			{"excluded before resolution", strconv.Itoa(dropped)},
+			{"after sharding", strconv.Itoa(len(sharded))},
+			{"already completed", strconv.Itoa(len(sharded) - len(targets))},
+		}
+		if err := printPlan(os.Stdout, fs, stats, targets, exclusions); err != nil {
+			fmt.Fprintf(os.Stderr, "dry run: %v\n", err)
+			os.Exit(1)
+		}
+		return 0
+	}
+	if cp != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		logErr := func(err error) { fmt.Fprintf(os.Stderr, "checkpoint write failed: %v\n", err) }
+		go cp.flushEvery(checkpointInterval, stop, logErr)
+		go func() {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+			<-sig
+			if err := cp.flush(); err != nil {
+				logErr(err)
+			}
+			os.Exit(130)
+		}()
 	}
 
-	fmt.Printf("{\"ok\":true,\"mysql\":true,\"protocol\":%d,\"server_version\":\"%s\",\"connection_id\":%d,"+
-		"\"capability_flags\":%d,\"character_set\":%d,\"status_flags\":%d,\"auth_plugin\":\"%s\",\"preview_hex\":\"%s\"}\n",
-		info.ProtocolVersion, escape(info.ServerVersion), info.ConnectionID,
-		info.CapabilityFlags, info.CharacterSet, info.StatusFlags, escape(info.AuthPluginName), info.RawFirstBytesHex)
-}
\ No newline at end of file
+	var dest io.Writer = os.Stdout
+	var upload *chunkUploader
+	var digest *digestWriter
+	var sink mysqlprobe.OutputSink
+	appending := false
+	if *outputURL != "" {
+		switch {
+		case *outputPath != "":
+			fmt.Fprintln(os.Stderr, "-o and -output are mutually exclusive")
+			os.Exit(2)
+		case *format != "json":
+			fmt.Fprintln(os.Stderr, "-output writes NDJSON and needs -format json")
+			os.Exit(2)
+		case *outputChunkSize < 1:
+			fmt.Fprintln(os.Stderr, "invalid -output-chunk-size: must be at least 1 MiB")
+			os.Exit(2)
+		case *gcsChunkSize < 0:
+			fmt.Fprintln(os.Stderr, "invalid -gcs-chunk-size: must not be negative")
+			os.Exit(2)
+		}
+		if u, err := url.Parse(*outputURL); err == nil && (u.Scheme == "s3" || u.Scheme == "gs") {
+			if upload, err = newObjectSink(context.Background(), *outputURL, *outputChunkSize<<20, *gcsChunkSize<<20); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid -output: %v\n", err)
+				os.Exit(2)
+			}
+			dest = upload
+		} else {
+			if *fieldList != "" {
+				fmt.Fprintln(os.Stderr, "-fields does not apply to -output sinks other than s3:// and gs://")
+				os.Exit(2)
+			}
+			if *dedupe || *redact {
+				fmt.Fprintln(os.Stderr, "-dedupe and -redact do not apply to -output sinks other than s3:// and gs://")
+				os.Exit(2)
+			}
+			if sink, err = mysqlprobe.OpenSink(*outputURL); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid -output: %v\n", err)
+				os.Exit(2)
+			}
+		}
+	} else if *outputPath != "" {
+		// A resumed scan adds to what the interrupted run wrote instead of starting the file over.
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *resumePath != "" {
+			flags = os.O_RDWR | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(*outputPath, flags, 0o666)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot create output: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		if fi, err := f.Stat(); err == nil {
+			appending = fi.Size() > 0
+		}
+		dest = f
+		if signer != nil {
+			digest = newDigestWriter(f)
+			if appending {
+				if err := digest.absorb(f); err != nil {
+					fmt.Fprintf(os.Stderr, "cannot read output: %v\n", err)
+					os.Exit(2)
+				}
+			}
+			dest = digest
+		}
+	} else if *format == "parquet" && isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "-format parquet needs -o or a redirected stdout")
+		os.Exit(2)
+	}
+	var compressed *compressedWriter
+	if *compress != "" {
+		switch {
+		case *outputURL != "":
+			fmt.Fprintln(os.Stderr, "-compress does not apply to -output (uploads are always gzipped, other sinks store records their own way)")
+			os.Exit(2)
+		case *format == "parquet":
+			fmt.Fprintln(os.Stderr, "-compress does not apply to -format parquet (parquet pages are already compressed)")
+			os.Exit(2)
+		case *outputPath == "" && isTerminal(os.Stdout):
+			fmt.Fprintln(os.Stderr, "-compress needs -o or a redirected stdout")
+			os.Exit(2)
+		}
+		// With a checkpoint, flush every record so results marked done are never stuck in the compressor.
+		if compressed, err = newCompressedWriter(*compress, dest, cp != nil); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -compress: %v\n", err)
+			os.Exit(2)
+		}
+		dest = compressed
+	}
+	if sink == nil {
+		out, err := newResultWriter(*format, splitList(*fieldList), dest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -format: %v\n", err)
+			os.Exit(2)
+		}
+		// The interrupted run already wrote the CSV header.
+		if cw, ok := out.(*csvWriter); ok && appending {
+			cw.wroteHeader = true
+		}
+		if *redact {
+			out = redactWriter{next: out}
+		}
+		if *dedupe {
+			out = newDedupeWriter(out)
+		}
+		fs := &formatSink{out: out}
+		if compressed != nil {
+			fs.closers = append(fs.closers, compressed)
+		}
+		if upload != nil {
+			fs.closers = append(fs.closers, upload)
+		}
+		sink = fs
+	}
+	var webhook *webhookNotifier
+	if *webhookURL != "" {
+		if u, err := url.Parse(*webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "invalid -webhook: want an http(s) URL, got %q\n", *webhookURL)
+			os.Exit(2)
+		}
+		webhook = newWebhookNotifier(*webhookURL, os.Stderr)
+	}
+	var watcher *watcher
+	var alerts *alerter
+	if *watch > 0 {
+		watcher = newWatcher()
+		if len(sinks) > 0 {
+			if alerts, err = newAlerter(sinks, *alertTemplate, alertRate, os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid -alert-template: %v\n", err)
+				os.Exit(2)
+			}
+		}
+	}
+	summary := newScanSummary()
+	summary.ScanID, summary.RunLabel = newScanID(), *runLabel
+	emit := func(t target, res Result) {
+		res.Labels = mergeLabels(globalLabels, res.Labels)
+		res.ScanID, res.RunLabel = summary.ScanID, summary.RunLabel
+		summary.add(res)
+		for _, c := range watcher.observe(res) {
+			fmt.Fprintf(os.Stderr, "watch: %s: %s changed from %q to %q\n", c.Target, c.Field, c.Old, c.New)
+			if alerts != nil {
+				alerts.alert(c)
+			}
+			if webhook != nil {
+				webhook.notify(webhookEvent{Event: "change", Timestamp: c.Time, Result: c.Result, Change: &c})
+			}
+		}
+		if err := corpus.add(res); err != nil {
+			fmt.Fprintf(os.Stderr, "corpus: %v\n", err)
+		}
+		if res, ok := filter.selectOutput(res, fullDetail); ok {
+			span := startOutputSpan(res)
+			if err := sink.Write(res); err != nil {
+				fmt.Fprintf(os.Stderr, "write result: %v\n", err)
+			}
+			// Records marked done in the checkpoint must not sit in a sink's buffer.
+			if cp != nil {
+				if err := sink.Flush(); err != nil {
+					fmt.Fprintf(os.Stderr, "write result: %v\n", err)
+				}
+			}
+			span.End()
+			if webhook != nil && res.MySQL {
+				webhook.notify(webhookEvent{Event: "detection", Timestamp: time.Now().UTC(), Result: &res})
+			}
+		}
+		if cp != nil {
+			cp.markDone(t)
+		}
+	}
+	var manifest *scanManifest
+	if *manifestDir != "" {
+		if manifest, err = newScanManifest(*manifestDir, name, fs, seq, summary); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -manifest: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *coordinatorAddr != "" {
+		coord := newCoordinator(slices.Collect(seq), *batchSize, *leaseTimeout, exclusions, emit)
+		if err := runCoordinator(*coordinatorAddr, coord); err != nil {
+			fmt.Fprintf(os.Stderr, "coordinator: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// kill -USR1 <pid> prints progress and the longest-running targets to stderr.
+		stop := make(chan struct{})
+		go cfg.stats.dumpOnSignal(stop)
+		if *watch > 0 {
+			summary.SkippedDeadline = runWatch(seq, cfg, emit, *watch, *watchCount)
+		} else {
+			summary.SkippedDeadline = runScan(seq, cfg, emit)
+		}
+		close(stop)
+		if stream != nil && dropped > 0 {
+			fmt.Fprintf(os.Stderr, "skipped %d excluded targets\n", dropped)
+		}
+		if summary.SkippedDeadline > 0 {
+			fmt.Fprintf(os.Stderr, "max-runtime of %v reached: %d targets skipped\n", *maxRuntime, summary.SkippedDeadline)
+		}
+	}
+	// The file was checked before the scan, so this is one changed or truncated while being read.
+	exitCode := 0
+	if stream != nil && stream.err() != nil {
+		fmt.Fprintf(os.Stderr, "-targets-file: %v\n", stream.err())
+		exitCode = 1
+	}
+	if err := sink.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "write results: %v\n", err)
+	} else if digest != nil {
+		if err := writeOutputSignature(*outputPath, digest, signer, summary.ScanID); err != nil {
+			fmt.Fprintf(os.Stderr, "sign results: %v\n", err)
+		}
+	}
+	if added, err := corpus.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "write corpus: %v\n", err)
+	} else if corpus != nil {
+		fmt.Fprintf(os.Stderr, "corpus: %d new handshakes saved to %s\n", added, *corpusDir)
+	}
+	summary.DNSCacheHits, summary.DNSCacheMisses = resolver.counts()
+	summary.OutputQueuePeak, summary.DroppedResults = cfg.stats.outputCounts()
+	summary.AdaptiveConcurrency = cfg.adaptive.summary()
+	summary.finish()
+	statsd.scanDone(summary)
+	if manifest != nil {
+		if err := manifest.finish(*manifestDir, summary, *outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "write manifest: %v\n", err)
+		}
+	}
+	if alerts != nil {
+		alerts.close()
+	}
+	if webhook != nil {
+		webhook.close()
+	}
+	if *summaryPath != "" {
+		if err := summary.writeFile(*summaryPath); err != nil {
+			fmt.Fprintf(os.Stderr, "write summary: %v\n", err)
+		}
+	} else if summary.Targets > 1 || summary.SkippedDeadline > 0 || summary.DroppedResults > 0 {
+		summary.writeText(os.Stderr)
+	}
+	if cp != nil {
+		if err := cp.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "checkpoint write failed: %v\n", err)
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"iter"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files written into the -manifest directory.
+const (
+	manifestFile        = "manifest.json"
+	manifestTargetsFile = "targets.txt"
+)
+
+// manifestReplayDrops are the flags left out of a manifest's replay_args: those that chose the targets, which targets.txt now lists, and those naming files the replay should not overwrite.
+var manifestReplayDrops = map[string]bool{
+	"host":            true,
+	"targets-file":    true,
+	"from-nmap":       true,
+	"nmap-services":   true,
+	"from-masscan":    true,
+	"censys-query":    true,
+	"censys-services": true,
+	"censys-limit":    true,
+	"censys-api":      true,
+	"expand-dns":      true,
+	"shard":           true,
+	"checkpoint":      true,
+	"resume":          true,
+	"manifest":        true,
+	"o":               true,
+	"output":          true,
+	"summary":         true,
+	"sign-key":        true,
+}
+
+/*
+scanManifest is manifest.json in the -manifest directory: what a scan was run with and what it produced, for reproducing it or accounting for it later.
+The target list itself is in targets.txt next to it, in -targets-file format, after DNS expansion, exclusions, sharding, and -resume were applied.
+*/
+type scanManifest struct {
+	Version         int               `json:"version"`
+	ScanID          string            `json:"scan_id"`
+	RunLabel        string            `json:"run_label,omitempty"`
+	Scanner         *buildMetadata    `json:"scanner"`
+	ScannerHost     string            `json:"scanner_host,omitempty"`
+	Command         string            `json:"command"`
+	Flags           map[string]string `json:"flags"`
+	FlagsSet        []string          `json:"flags_set"`
+	ReplayArgs      []string          `json:"replay_args"`
+	Targets         int               `json:"targets"`
+	TargetsFile     string            `json:"targets_file"`
+	TargetsSHA256   string            `json:"targets_sha256"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+	DurationSeconds float64           `json:"duration_seconds,omitempty"`
+	Output          *manifestOutput   `json:"output,omitempty"`
+	Summary         *scanSummary      `json:"summary,omitempty"`
+}
+
+/*
+manifestOutput identifies the -o file a scan wrote.
+*/
+type manifestOutput struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+/*
+newScanManifest starts the manifest of a scan about to probe targets, writing targets.txt into dir (created if needed) and recording every flag's effective value.
+Function-level comment: targets is read through once here, ahead of the scan, so a streamed -targets-file is read an extra time. Passwords embedded in URL flag values are masked. Pipe targets cannot be listed in a targets file, so a -pipe scan keeps -pipe in replay_args instead.
+*/
+func newScanManifest(dir, command string, fs *flag.FlagSet, targets iter.Seq[target], summary *scanSummary) (*scanManifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	m := &scanManifest{
+		Version:     1,
+		ScanID:      summary.ScanID,
+		RunLabel:    summary.RunLabel,
+		Scanner:     scannerBuild,
+		Command:     command,
+		Flags:       make(map[string]string),
+		FlagsSet:    []string{},
+		TargetsFile: manifestTargetsFile,
+		StartedAt:   time.Now().UTC(),
+	}
+	m.ScannerHost, _ = os.Hostname()
+	fs.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = redactURLPassword(f.Value.String())
+	})
+	fs.Visit(func(f *flag.Flag) {
+		m.FlagsSet = append(m.FlagsSet, f.Name)
+		if !manifestReplayDrops[f.Name] {
+			m.ReplayArgs = append(m.ReplayArgs, "-"+f.Name+"="+m.Flags[f.Name])
+		}
+	})
+
+	f, err := os.Create(filepath.Join(dir, manifestTargetsFile))
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	w := bufio.NewWriter(f)
+	listed := 0
+	for t := range targets {
+		m.Targets++
+		if t.pipe {
+			continue
+		}
+		line := targetLine(t) + "\n"
+		w.WriteString(line)
+		h.Write([]byte(line))
+		listed++
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	m.TargetsSHA256 = hex.EncodeToString(h.Sum(nil))
+	if listed > 0 {
+		m.ReplayArgs = append(m.ReplayArgs, "-targets-file="+manifestTargetsFile)
+	}
+	return m, m.write(dir)
+}
+
+/*
+finish records the end of the scan in dir's manifest.json: its timing, summary, and the size and hash of the -o file (outputPath, when set).
+Function-level comment: the manifest is still written when the output file cannot be hashed, without output.
+*/
+func (m *scanManifest) finish(dir string, summary *scanSummary, outputPath string) error {
+	now := time.Now().UTC()
+	m.FinishedAt = &now
+	m.DurationSeconds = now.Sub(m.StartedAt).Seconds()
+	m.Summary = summary
+	var hashErr error
+	if outputPath != "" {
+		size, sum, err := hashFile(outputPath)
+		if err == nil {
+			m.Output = &manifestOutput{Path: outputPath, Size: size, SHA256: sum}
+		}
+		hashErr = err
+	}
+	if err := m.write(dir); err != nil {
+		return err
+	}
+	return hashErr
+}
+
+/*
+write (re)writes manifest.json in dir.
+Function-level comment: it is written when the scan starts, without finished_at, and again when it ends, so an interrupted scan still leaves a manifest behind.
+*/
+func (m *scanManifest) write(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFile), append(data, '\n'), 0o644)
+}
+
+/*
+targetLine renders t as a -targets-file line that reads back as the same target: host:port, with the scheme when a URI picked the protocol, followed by its per-target options.
+Function-level comment: a target whose label or label values contain whitespace is written as a JSON line instead, since the plain form splits on it.
+*/
+func targetLine(t target) string {
+	addr := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+	if t.protocol != "" {
+		addr = t.protocol + "://" + addr
+	}
+	o := t.opts
+	if o == nil {
+		return addr
+	}
+	fields := []string{addr}
+	if o.Timeout > 0 {
+		fields = append(fields, "timeout="+o.Timeout.String())
+	}
+	if o.Retries != nil {
+		fields = append(fields, "retries="+strconv.Itoa(*o.Retries))
+	}
+	if o.TLS != nil {
+		fields = append(fields, "tls="+strconv.FormatBool(*o.TLS))
+	}
+	if o.Label != "" {
+		fields = append(fields, "label="+o.Label)
+	}
+	keys := make([]string, 0, len(o.Labels))
+	for k := range o.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fields = append(fields, k+"="+o.Labels[k])
+	}
+	line := strings.Join(fields, " ")
+	if len(strings.Fields(line)) == len(fields) {
+		return line
+	}
+	host := t.host
+	if t.protocol != "" {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		host = t.protocol + "://" + host
+	}
+	spec := targetSpec{Host: host, Port: t.port, Retries: o.Retries, TLS: o.TLS, Label: o.Label, Labels: o.Labels}
+	if o.Timeout > 0 {
+		spec.Timeout = o.Timeout.String()
+	}
+	data, _ := json.Marshal(spec)
+	return string(data)
+}
+
+/*
+redactURLPassword masks the password in a URL flag value, e.g. an -output or -alert sink with credentials in it; other values are returned unchanged.
+*/
+func redactURLPassword(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
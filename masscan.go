@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// maxMasscanLine bounds one line of masscan output; records with grabbed banners can be long.
+const maxMasscanLine = 1 << 20
+
+/*
+masscanRecord is one host entry of masscan's -oJ output.
+*/
+type masscanRecord struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port   int    `json:"port"`
+		Proto  string `json:"proto"`
+		Status string `json:"status"`
+	} `json:"ports"`
+}
+
+/*
+loadMasscanTargets reads masscan -oJ output and returns the open TCP ports among ports as probe targets.
+Function-level comment: masscan writes one record per line inside a JSON array and its files are often not valid JSON as a whole (trailing commas, truncated by an interrupted sweep), so each line is decoded on its own and unparseable lines are skipped. Duplicates are dropped, and the first target of each host carries its UDP probes.
+*/
+func loadMasscanTargets(path string, ports []int) ([]target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	wanted := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		wanted[p] = true
+	}
+	var targets []target
+	seen := make(map[string]bool)
+	hasUDP := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxMasscanLine)
+	for sc.Scan() {
+		line := bytes.Trim(bytes.TrimSpace(sc.Bytes()), "[],")
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var rec masscanRecord
+		if json.Unmarshal(line, &rec) != nil || rec.IP == "" {
+			continue
+		}
+		for _, p := range rec.Ports {
+			if p.Proto != "tcp" || p.Status != "open" || !wanted[p.Port] {
+				continue
+			}
+			t := target{host: rec.IP, port: p.Port, runUDP: !hasUDP[rec.IP]}
+			if key := targetKey(t); !seen[key] {
+				seen[key] = true
+				hasUDP[rec.IP] = true
+				targets = append(targets, t)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return targets, nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// Ports the middleware checks look at, at ProxySQL's, MySQL Router's, MaxScale's, and HAProxy's defaults.
+const (
+	proxySQLAdminPort    = 6032
+	proxySQLFrontendPort = 6033
+	routerRWSplitPort    = 6450
+	routerRESTPort       = 8443
+	maxScaleRWPort       = 4006
+	maxScaleROPort       = 4008
+	maxScaleRESTPort     = 8989
+	haproxyStatsPort     = 8404
+	// routerRESTPath is served without authentication by MySQL Router's REST API and names the product.
+	routerRESTPath = "/api/20190715/swagger.json"
+	// maxScaleRESTPath answers 401 with a realm naming MaxScale when asked without credentials.
+	maxScaleRESTPath = "/v1/maxscale"
+	// haproxyStatsPath is where HAProxy's documented stats frontend serves its report.
+	haproxyStatsPath = "/stats"
+	// maxRESTBody caps how much of the REST response is read while fingerprinting.
+	maxRESTBody = 64 << 10
+)
+
+// middlewareConfidenceThreshold is the score a middleware product needs before it is reported.
+const middlewareConfidenceThreshold = 2
+
+// A handshake slower than proxyBannerRatio times the TCP connect, and by at least proxyBannerMinMillis, suggests a proxy that dials its backend only once it has accepted the client.
+const (
+	proxyBannerRatio     = 4
+	proxyBannerMinMillis = 1.0
+)
+
+/*
+middlewareObservation is what the fingerprinting sees for a target: its port, handshake, and -samples timing, whether it accepted the connection and closed it unanswered, plus, with -middleware-checks, what the host's admin interfaces answered and whether the target answers after a PROXY protocol header.
+*/
+type middlewareObservation struct {
+	port         int
+	info         *mysqlprobe.HandshakeInfo
+	sampling     *samplingResult
+	silentClose  bool
+	adminVersion string
+	routerREST   bool
+	maxScaleREST bool
+	haproxyStats bool
+	proxyHeader  bool
+}
+
+/*
+newMiddlewareObservation collects what classifyMiddleware looks at from a target's result.
+*/
+func newMiddlewareObservation(res *Result, port int) middlewareObservation {
+	o := middlewareObservation{port: port, info: res.HandshakeInfo, sampling: res.Sampling}
+	status, _ := res.Status()
+	o.silentClose = status == mysqlprobe.StatusConnectionClosed && res.FirstBytesHex == ""
+	return o
+}
+
+/*
+unanswered reports whether the target took the connection but sent nothing, which is what a listener waiting for a PROXY protocol header does.
+*/
+func unanswered(res *Result) bool {
+	switch status, _ := res.Status(); status {
+	case mysqlprobe.StatusConnectionClosed, mysqlprobe.StatusIOTimeout:
+		return res.FirstBytesHex == ""
+	}
+	return false
+}
+
+/*
+middlewareSignal is one heuristic pointing at a proxy in front of the real server.
+As with providers, strong signals (weight 2) stand alone and weak ones (weight 1) must be corroborated.
+*/
+type middlewareSignal struct {
+	product  string
+	weight   int
+	evidence string
+	match    func(o middlewareObservation) bool
+}
+
+var middlewareSignals = []middlewareSignal{
+	{"proxysql", 2, "server version names ProxySQL", func(o middlewareObservation) bool {
+		return o.info != nil && strings.Contains(strings.ToLower(o.info.ServerVersion), "proxysql")
+	}},
+	{"proxysql", 2, "admin interface on 6032 identifies as ProxySQL", func(o middlewareObservation) bool {
+		return strings.Contains(strings.ToLower(o.adminVersion), "proxysql")
+	}},
+	{"proxysql", 1, "MySQL handshake on ProxySQL's admin port 6032", func(o middlewareObservation) bool {
+		return o.adminVersion != "" && !strings.Contains(strings.ToLower(o.adminVersion), "proxysql")
+	}},
+	{"proxysql", 1, "target on ProxySQL's default port 6033", func(o middlewareObservation) bool {
+		return o.info != nil && o.port == proxySQLFrontendPort
+	}},
+	{"proxysql", 1, "server version is ProxySQL's default 5.5.30", func(o middlewareObservation) bool {
+		return o.info != nil && o.info.ServerVersion == "5.5.30"
+	}},
+	{"mysql_router", 2, "REST API on 8443 identifies as MySQL Router", func(o middlewareObservation) bool {
+		return o.routerREST
+	}},
+	{"mysql_router", 1, "target on a MySQL Router default port (6446/6447/6450)", func(o middlewareObservation) bool {
+		return o.info != nil && (o.port == routerRWPort || o.port == routerROPort || o.port == routerRWSplitPort)
+	}},
+	{"maxscale", 2, "server version names MaxScale, as its fallback version string (e.g. 5.5.5-10.2.12 2.2.9-maxscale) does", func(o middlewareObservation) bool {
+		return o.info != nil && strings.Contains(strings.ToLower(o.info.ServerVersion), "maxscale")
+	}},
+	{"maxscale", 2, "REST API on 8989 identifies as MaxScale", func(o middlewareObservation) bool {
+		return o.maxScaleREST
+	}},
+	{"maxscale", 1, "target on a MaxScale default listener port (4006/4008)", func(o middlewareObservation) bool {
+		return o.info != nil && (o.port == maxScaleRWPort || o.port == maxScaleROPort)
+	}},
+	{"haproxy", 2, "stats page on 8404 identifies as HAProxy", func(o middlewareObservation) bool {
+		return o.haproxyStats
+	}},
+	{"haproxy", 2, "answered only after a PROXY protocol header, as an HAProxy accept-proxy listener does", func(o middlewareObservation) bool {
+		return o.proxyHeader
+	}},
+	{"haproxy", 1, "accepted the connection and closed it without a handshake, as HAProxy does when no backend passes its mysql-check", func(o middlewareObservation) bool {
+		return o.silentClose
+	}},
+	{"haproxy", 1, "-samples reached several backends, as behind HAProxy's balancing", func(o middlewareObservation) bool {
+		return o.sampling != nil && o.sampling.MultipleBackends
+	}},
+	{"haproxy", 1, "handshakes arrived well after the TCP connect, as when a proxy dials the backend only after accepting", func(o middlewareObservation) bool {
+		if o.sampling == nil || o.sampling.Latency == nil {
+			return false
+		}
+		l := o.sampling.Latency
+		return l.Banner.Median >= proxyBannerRatio*l.Connect.Median && l.Banner.Median-l.Connect.Median >= proxyBannerMinMillis
+	}},
+}
+
+/*
+classifyMiddleware scores ProxySQL, MySQL Router, MaxScale, and HAProxy from an observation, and whether some intermediary answers in place of the database.
+Function-level comment: returns the highest-scoring product and the evidence behind it, or "" when none reaches middlewareConfidenceThreshold or two products tie, as classifyProvider does. The signals of all products together can still reach the threshold without naming one; the intermediary is then reported without a product, with all the evidence.
+*/
+func classifyMiddleware(o middlewareObservation) (string, []string, bool) {
+	scores := make(map[string]int)
+	evidence := make(map[string][]string)
+	total := 0
+	var all []string
+	for _, s := range middlewareSignals {
+		if s.match(o) {
+			scores[s.product] += s.weight
+			evidence[s.product] = append(evidence[s.product], s.evidence)
+			total += s.weight
+			all = append(all, s.evidence)
+		}
+	}
+	best, bestScore, tied := "", 0, false
+	for p, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = p, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied || bestScore < middlewareConfidenceThreshold {
+		if total >= middlewareConfidenceThreshold {
+			return "", all, true
+		}
+		return "", nil, false
+	}
+	return best, evidence[best], true
+}
+
+/*
+probeMiddlewareAdmin checks ip's ProxySQL admin port, MySQL Router REST API, MaxScale REST API, and HAProxy stats page for -middleware-checks, recording what they answered in o.
+Function-level comment: the connections wait for the destination and subnet limits like the main probe.
+*/
+func probeMiddlewareAdmin(o *middlewareObservation, ip string, addr netip.Addr, opts mysqlprobe.Options, dests *hostLimiter, subnets *subnetLimiter) {
+	// Only the target itself is logged in to; the follow-up ports just need their handshake.
+	opts.Credentials = nil
+	limited := func(f func()) {
+		defer dests.acquire(ip)()
+		defer subnets.acquire(addr)()
+		f()
+	}
+	limited(func() {
+		if admin := mysqlprobe.Probe(net.JoinHostPort(ip, strconv.Itoa(proxySQLAdminPort)), opts); admin.MySQL {
+			o.adminVersion = admin.HandshakeInfo.ServerVersion
+		}
+	})
+	limited(func() {
+		_, body, ok := middlewareHTTPGet("https://"+net.JoinHostPort(ip, strconv.Itoa(routerRESTPort))+routerRESTPath, opts)
+		o.routerREST = ok && strings.Contains(body, "MySQL Router")
+	})
+	limited(func() {
+		header, body, _ := middlewareHTTPGet("http://"+net.JoinHostPort(ip, strconv.Itoa(maxScaleRESTPort))+maxScaleRESTPath, opts)
+		o.maxScaleREST = strings.Contains(strings.ToLower(header.Get("WWW-Authenticate")+body), "maxscale")
+	})
+	limited(func() {
+		_, body, ok := middlewareHTTPGet("http://"+net.JoinHostPort(ip, strconv.Itoa(haproxyStatsPort))+haproxyStatsPath, opts)
+		o.haproxyStats = ok && strings.Contains(body, "HAProxy")
+	})
+}
+
+/*
+proxyHeaderAnswer reports whether hostPort sends a MySQL handshake once a connection opens with a PROXY protocol v1 header, for -middleware-checks on targets that sent nothing without one.
+*/
+func proxyHeaderAnswer(hostPort string, opts mysqlprobe.Options) bool {
+	opts.Credentials = nil
+	opts.ProxyProtocol = mysqlprobe.ProxyProtocolV1
+	return mysqlprobe.Probe(hostPort, opts).MySQL
+}
+
+/*
+middlewareHTTPGet fetches url for the middleware checks and returns the response headers, the start of the body, and whether the status was 200.
+Function-level comment: certificates are not verified (Router ships a self-signed one), and connections are opened like a probe's so tunnels and socket options apply. A failed request returns empty headers and body.
+*/
+func middlewareHTTPGet(url string, opts mysqlprobe.Options) (http.Header, string, bool) {
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+				return opts.Connect(network, addr)
+			},
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return http.Header{}, "", false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxRESTBody))
+	return resp.Header, string(body), resp.StatusCode == http.StatusOK
+}
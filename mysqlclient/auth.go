@@ -0,0 +1,269 @@
+package mysqlclient
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Auth plugins; Auth answers the first three.
+const (
+	NativePasswordPlugin = "mysql_native_password"
+	CachingSHA2Plugin    = "caching_sha2_password"
+	ClearPasswordPlugin  = "mysql_clear_password"
+	OldPasswordPlugin    = "mysql_old_password"
+	SHA256PasswordPlugin = "sha256_password"
+)
+
+// caching_sha2_password AuthMoreData status bytes and the public key request.
+const (
+	sha2FastAuthOK       = 3
+	sha2FullAuthRequired = 4
+	sha2RequestPublicKey = 2
+)
+
+// maxAuthRounds is how many server packets Conn.Authenticate lets an authentication take (switch, more data, key, OK).
+const maxAuthRounds = 6
+
+/*
+State is where an authentication exchange stands.
+*/
+type State int
+
+// The states of an Auth. StateOK and StateRefused are final.
+const (
+	// StateStart: nothing sent yet; Start builds the HandshakeResponse41.
+	StateStart State = iota
+	// StateAuthSent: the HandshakeResponse41 or an auth switch reply is out; the server answers with OK, ERR, a switch, or more data.
+	StateAuthSent
+	// StateFastAuth: caching_sha2_password accepted the scramble from its cache; an OK follows.
+	StateFastAuth
+	// StatePublicKeyRequested: caching_sha2_password needs the full password and, without TLS, its RSA public key was requested.
+	StatePublicKeyRequested
+	// StatePasswordSent: the full password went out, in clear over TLS or RSA-encrypted; OK or ERR follows.
+	StatePasswordSent
+	// StateOK: the server let the client in.
+	StateOK
+	// StateRefused: the server answered with an ERR packet (see Auth.Refusal).
+	StateRefused
+)
+
+var stateNames = [...]string{"start", "auth_sent", "fast_auth", "public_key_requested", "password_sent", "ok", "refused"}
+
+func (s State) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+/*
+Auth is the client side of a MySQL 4.1 authentication as a state machine: Start gives the HandshakeResponse41 to send, and Next takes each server packet and gives the reply to send, if any, until Done.
+It does no I/O, so it runs over a Conn (see Conn.Authenticate), a TLS session, or a recorded exchange alike. It answers mysql_native_password, caching_sha2_password (fast and full authentication, the latter with the server's RSA key when not secure), and mysql_clear_password (only when secure, so a server cannot talk the client into sending the password in the open); an unsupported default plugin is answered with a mysql_native_password scramble, as other clients do, leaving the server to switch.
+*/
+type Auth struct {
+	User     string
+	Password string
+	// Secure says the session is already in TLS.
+	Secure bool
+
+	serverCaps uint32
+	plugin     string
+	salt       []byte
+	state      State
+	refusal    *ErrPacket
+}
+
+/*
+NewAuth returns the authentication of user with password against a server whose handshake announced serverCaps, the default plugin, and salt (both scramble parts).
+*/
+func NewAuth(serverCaps uint32, plugin string, salt []byte, user, password string, secure bool) *Auth {
+	if plugin != CachingSHA2Plugin && plugin != ClearPasswordPlugin {
+		plugin = NativePasswordPlugin
+	}
+	return &Auth{User: user, Password: password, Secure: secure, serverCaps: serverCaps, plugin: plugin, salt: salt}
+}
+
+/*
+Plugin is the auth plugin in use: the one the first response was computed for, or the last the server switched to.
+*/
+func (a *Auth) Plugin() string { return a.plugin }
+
+/*
+State is where the exchange stands.
+*/
+func (a *Auth) State() State { return a.state }
+
+/*
+Done reports whether the exchange has ended, with the client let in or refused.
+*/
+func (a *Auth) Done() bool { return a.state == StateOK || a.state == StateRefused }
+
+/*
+Refusal is the ERR packet that ended the exchange in StateRefused, nil otherwise.
+*/
+func (a *Auth) Refusal() *ErrPacket { return a.refusal }
+
+/*
+Start returns the HandshakeResponse41 payload to send as the first client packet.
+Function-level comment: fails when the server does not speak protocol 4.1 or the plugin cannot be answered.
+*/
+func (a *Auth) Start() ([]byte, error) {
+	if a.state != StateStart {
+		return nil, fmt.Errorf("auth already started (%s)", a.state)
+	}
+	if a.serverCaps&ClientProtocol41 == 0 {
+		return nil, errors.New("server does not support protocol 4.1 authentication")
+	}
+	resp, err := Scramble(a.plugin, a.Password, a.salt, a.Secure)
+	if err != nil {
+		return nil, err
+	}
+	a.state = StateAuthSent
+	return HandshakeResponse41(a.serverCaps, a.Secure, a.User, resp, a.plugin), nil
+}
+
+/*
+Next advances the exchange with the server packet p (a payload) and returns the payload to send next, or nil when the client sends nothing and awaits another packet or is done.
+Function-level comment: an OK or ERR ends the exchange; an auth switch is answered for the new plugin and salt; caching_sha2_password's AuthMoreData is answered per its status byte or, when it carries the public key, with the encrypted password. Anything else, including the pre-4.1 old-password request, is an error that leaves the state unchanged.
+*/
+func (a *Auth) Next(p []byte) ([]byte, error) {
+	if len(p) == 0 {
+		return nil, errors.New("empty packet during authentication")
+	}
+	if a.state == StateStart || a.Done() {
+		return nil, fmt.Errorf("no packet expected in state %s", a.state)
+	}
+	switch {
+	case p[0] == HeaderOK:
+		a.state = StateOK
+		return nil, nil
+	case p[0] == HeaderErr:
+		e, err := ParseErr(p)
+		if err != nil {
+			return nil, err
+		}
+		a.state, a.refusal = StateRefused, e
+		return nil, nil
+	case p[0] == HeaderAuthSwitch:
+		sw, err := ParseAuthSwitch(p)
+		if err != nil {
+			return nil, err
+		}
+		a.plugin = sw.Plugin
+		if sw.OldPassword {
+			return nil, errors.New("server asked for the pre-4.1 password hash")
+		}
+		a.salt = sw.Data
+		reply, err := Scramble(a.plugin, a.Password, a.salt, a.Secure)
+		if err != nil {
+			return nil, err
+		}
+		a.state = StateAuthSent
+		return reply, nil
+	case p[0] == HeaderMoreData && a.plugin == CachingSHA2Plugin && len(p) == 2:
+		switch p[1] {
+		case sha2FastAuthOK:
+			a.state = StateFastAuth
+			return nil, nil
+		case sha2FullAuthRequired:
+			if a.Secure {
+				a.state = StatePasswordSent
+				return append([]byte(a.Password), 0), nil
+			}
+			a.state = StatePublicKeyRequested
+			return []byte{sha2RequestPublicKey}, nil
+		}
+		return nil, fmt.Errorf("unexpected caching_sha2_password status %d", p[1])
+	case p[0] == HeaderMoreData && a.plugin == CachingSHA2Plugin:
+		reply, err := EncryptPassword(p[1:], a.Password, a.salt)
+		if err != nil {
+			return nil, err
+		}
+		a.state = StatePasswordSent
+		return reply, nil
+	}
+	return nil, fmt.Errorf("unexpected packet 0x%02x during authentication", p[0])
+}
+
+/*
+Scramble computes the auth response for plugin from password and the server's salt.
+Function-level comment: mysql_clear_password is only answered when secure, so a server cannot talk the client into sending the password in the open.
+*/
+func Scramble(plugin, password string, salt []byte, secure bool) ([]byte, error) {
+	switch plugin {
+	case NativePasswordPlugin:
+		return ScrambleNative(password, salt), nil
+	case CachingSHA2Plugin:
+		return ScrambleSHA2(password, salt), nil
+	case ClearPasswordPlugin:
+		if !secure {
+			return nil, errors.New("server asked for mysql_clear_password without TLS; password not sent")
+		}
+		return append([]byte(password), 0), nil
+	}
+	return nil, fmt.Errorf("unsupported auth plugin %q", plugin)
+}
+
+/*
+ScrambleNative is mysql_native_password's response: SHA1(password) XOR SHA1(salt + SHA1(SHA1(password))), empty for an empty password.
+*/
+func ScrambleNative(password string, salt []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	h1 := sha1.Sum([]byte(password))
+	h2 := sha1.Sum(h1[:])
+	h3 := sha1.Sum(append(append([]byte(nil), salt...), h2[:]...))
+	for i := range h1 {
+		h1[i] ^= h3[i]
+	}
+	return h1[:]
+}
+
+/*
+ScrambleSHA2 is caching_sha2_password's fast-auth response: SHA256(password) XOR SHA256(SHA256(SHA256(password)) + salt), empty for an empty password.
+*/
+func ScrambleSHA2(password string, salt []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	h1 := sha256.Sum256([]byte(password))
+	h2 := sha256.Sum256(h1[:])
+	h3 := sha256.Sum256(append(h2[:], salt...))
+	for i := range h1 {
+		h1[i] ^= h3[i]
+	}
+	return h1[:]
+}
+
+/*
+EncryptPassword answers caching_sha2_password's full authentication without TLS: the NUL-terminated password XORed with the salt, RSA-OAEP encrypted with the server's PEM public key.
+*/
+func EncryptPassword(keyPEM []byte, password string, salt []byte) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("server public key is not PEM")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("server public key: %w", err)
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("server public key is not RSA")
+	}
+	if len(salt) == 0 {
+		return nil, errors.New("handshake carried no salt")
+	}
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= salt[i%len(salt)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, plain, nil)
+}
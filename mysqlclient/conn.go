@@ -0,0 +1,183 @@
+package mysqlclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MaxPacket bounds a packet Conn reads; auth exchanges, status replies, and the rows of small queries fit well within it.
+const MaxPacket = 1 << 20
+
+// MaxQueryRows bounds how many rows of a result set Query keeps; the rest are read and dropped.
+const MaxQueryRows = 10000
+
+var errTruncatedRow = errors.New("truncated result set row")
+
+/*
+Conn reads and writes MySQL packets on a connection, tracking the sequence ID and applying a timeout to each operation.
+*/
+type Conn struct {
+	conn    net.Conn
+	seq     byte
+	timeout time.Duration
+}
+
+/*
+NewConn returns a Conn on conn whose next packet written has sequence ID seq: 1 right after the server's handshake, 2 after an SSLRequest and the TLS handshake.
+*/
+func NewConn(conn net.Conn, seq byte, timeout time.Duration) *Conn {
+	return &Conn{conn: conn, seq: seq, timeout: timeout}
+}
+
+/*
+WritePacket sends payload as one packet with the next sequence ID.
+*/
+func (c *Conn) WritePacket(payload []byte) error {
+	pkt := make([]byte, 4, 4+len(payload))
+	pkt[0], pkt[1], pkt[2], pkt[3] = byte(len(payload)), byte(len(payload)>>8), byte(len(payload)>>16), c.seq
+	c.seq++
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(append(pkt, payload...))
+	return err
+}
+
+/*
+ReadPacket receives one packet's payload and continues the sequence from its ID.
+Function-level comment: an empty packet or one over MaxPacket is an error.
+*/
+func (c *Conn) ReadPacket() ([]byte, error) {
+	var header [4]byte
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return nil, err
+	}
+	n := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if n == 0 || n > MaxPacket {
+		return nil, fmt.Errorf("packet length %d out of range", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, payload); err != nil {
+		return nil, err
+	}
+	c.seq = header[3] + 1
+	return payload, nil
+}
+
+/*
+Command starts a new command phase exchange: the sequence resets and payload is sent as packet 0.
+*/
+func (c *Conn) Command(payload ...byte) error {
+	c.seq = 0
+	return c.WritePacket(payload)
+}
+
+/*
+Authenticate runs a, which must not have started, to completion over c: it sends the HandshakeResponse41 and answers the server until a reaches StateOK or StateRefused.
+Function-level comment: a read or write failure, a protocol error from a, or more than maxAuthRounds server packets end the exchange with an error; a refusal is not an error (see Auth.Refusal).
+*/
+func (c *Conn) Authenticate(a *Auth) error {
+	out, err := a.Start()
+	if err != nil {
+		return err
+	}
+	if err := c.WritePacket(out); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	for range maxAuthRounds {
+		p, err := c.ReadPacket()
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		if out, err = a.Next(p); err != nil {
+			return err
+		}
+		if a.Done() {
+			return nil
+		}
+		if out == nil {
+			continue
+		}
+		if err := c.WritePacket(out); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+	}
+	return errors.New("authentication did not finish")
+}
+
+/*
+Query runs sql with COM_QUERY and returns the text result set's rows, NULL values as nil.
+Function-level comment: the session does not negotiate CLIENT_DEPRECATE_EOF, so column definitions and rows each end with an EOF packet; an ERR reply is returned as an *ErrPacket, a statement without a result set yields no rows, and rows past MaxQueryRows are dropped.
+*/
+func (c *Conn) Query(sql string) ([][]*string, error) {
+	if err := c.Command(append([]byte{ComQuery}, sql...)...); err != nil {
+		return nil, err
+	}
+	p, err := c.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	switch p[0] {
+	case HeaderErr:
+		return nil, ReplyError(p)
+	case HeaderOK:
+		return nil, nil
+	}
+	columns, n := LenEncInt(p)
+	if n == 0 || columns == 0 {
+		return nil, fmt.Errorf("bad column count")
+	}
+	for {
+		if p, err = c.ReadPacket(); err != nil {
+			return nil, err
+		}
+		if IsEOF(p) {
+			break
+		}
+	}
+	var rows [][]*string
+	for {
+		if p, err = c.ReadPacket(); err != nil {
+			return nil, err
+		}
+		if IsEOF(p) {
+			return rows, nil
+		}
+		if p[0] == HeaderErr {
+			return nil, ReplyError(p)
+		}
+		if len(rows) == MaxQueryRows {
+			continue
+		}
+		row := make([]*string, 0, columns)
+		for range columns {
+			if len(p) > 0 && p[0] == 0xfb {
+				row, p = append(row, nil), p[1:]
+				continue
+			}
+			l, n := LenEncInt(p)
+			if n == 0 || uint64(len(p)-n) < l {
+				return nil, errTruncatedRow
+			}
+			v := string(p[n : n+int(l)])
+			row, p = append(row, &v), p[n+int(l):]
+		}
+		rows = append(rows, row)
+	}
+}
+
+/*
+ReplyError describes a reply that should have been OK: the *ErrPacket for an ERR packet (or why it could not be decoded), otherwise the packet type.
+*/
+func ReplyError(p []byte) error {
+	if p[0] != HeaderErr {
+		return fmt.Errorf("unexpected reply 0x%x", p[0])
+	}
+	e, err := ParseErr(p)
+	if err != nil {
+		return err
+	}
+	return e
+}
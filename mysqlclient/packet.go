@@ -0,0 +1,210 @@
+/*
+Package mysqlclient is the client side of the MySQL client/server protocol, without the network: builders for the packets a client sends (SSLRequest, HandshakeResponse41 and 320, commands), parsers for the server's replies (OK, ERR, AuthSwitchRequest, AuthMoreData, EOF), password scrambles, and Auth, a state machine that walks an authentication exchange one server packet at a time.
+Conn adds packet framing over a net.Conn for callers that want it; Auth and the parsers work on payloads from any transport.
+*/
+package mysqlclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Capability flags a client sends or checks in the server's handshake.
+const (
+	ClientLongPassword     = 1 << 0
+	ClientProtocol41       = 1 << 9
+	ClientSSL              = 1 << 11
+	ClientTransactions     = 1 << 13
+	ClientSecureConnection = 1 << 15
+	ClientPluginAuth       = 1 << 19
+)
+
+// First bytes of the server replies the client tells apart. An EOF packet and an AuthSwitchRequest share 0xfe; which one it is depends on the phase.
+const (
+	HeaderOK         = 0x00
+	HeaderMoreData   = 0x01
+	HeaderAuthSwitch = 0xfe
+	HeaderEOF        = 0xfe
+	HeaderErr        = 0xff
+)
+
+// Commands of the command phase.
+const (
+	ComQuit       = 0x01
+	ComQuery      = 0x03
+	ComStatistics = 0x09
+	ComPing       = 0x0e
+)
+
+// DefaultCharset is the collation clients announce: utf8mb4_general_ci, which every server since 5.5 knows.
+const DefaultCharset = 45
+
+// maxPacketSize is the max packet size clients announce in the SSLRequest and HandshakeResponse41.
+const maxPacketSize = 1 << 24
+
+/*
+ErrPacket is an ERR packet: the error code, the SQL state when the server sent one, and the message.
+*/
+type ErrPacket struct {
+	Code     uint16 `json:"code"`
+	SQLState string `json:"sql_state,omitempty"`
+	Message  string `json:"message"`
+}
+
+/*
+Error formats the server error as "server error <code>: <message>".
+*/
+func (e *ErrPacket) Error() string {
+	return fmt.Sprintf("server error %d: %s", e.Code, e.Message)
+}
+
+/*
+ParseErr decodes an ERR packet payload (starting at the 0xff marker).
+Function-level comment: servers send pre-handshake errors without knowing the client's capabilities, so the "#" SQL state marker is optional.
+*/
+func ParseErr(p []byte) (*ErrPacket, error) {
+	if len(p) < 3 || p[0] != HeaderErr {
+		return nil, errors.New("truncated error packet")
+	}
+	e := &ErrPacket{Code: binary.LittleEndian.Uint16(p[1:3])}
+	msg := p[3:]
+	if len(msg) >= 6 && msg[0] == '#' {
+		e.SQLState, msg = string(msg[1:6]), msg[6:]
+	}
+	e.Message = string(msg)
+	return e, nil
+}
+
+/*
+OKPacket is an OK packet of a 4.1 session.
+*/
+type OKPacket struct {
+	AffectedRows uint64
+	LastInsertID uint64
+	StatusFlags  uint16
+	Warnings     uint16
+	Info         string
+}
+
+/*
+ParseOK decodes an OK packet payload (starting at the 0x00 marker).
+*/
+func ParseOK(p []byte) (*OKPacket, error) {
+	if len(p) == 0 || p[0] != HeaderOK {
+		return nil, errors.New("not an OK packet")
+	}
+	ok := &OKPacket{}
+	i := 1
+	for _, dst := range []*uint64{&ok.AffectedRows, &ok.LastInsertID} {
+		v, n := LenEncInt(p[i:])
+		if n == 0 {
+			return nil, errors.New("truncated OK packet")
+		}
+		*dst, i = v, i+n
+	}
+	if len(p) < i+4 {
+		return ok, nil
+	}
+	ok.StatusFlags = binary.LittleEndian.Uint16(p[i:])
+	ok.Warnings = binary.LittleEndian.Uint16(p[i+2:])
+	ok.Info = string(p[i+4:])
+	return ok, nil
+}
+
+/*
+AuthSwitch is an AuthSwitchRequest: the plugin the server wants and its data (a new salt). A bare 0xfe is the pre-4.1 request for the old password hash, with OldPassword set and no plugin.
+*/
+type AuthSwitch struct {
+	Plugin      string
+	Data        []byte
+	OldPassword bool
+}
+
+/*
+ParseAuthSwitch decodes an AuthSwitchRequest payload (starting at the 0xfe marker).
+*/
+func ParseAuthSwitch(p []byte) (*AuthSwitch, error) {
+	if len(p) == 0 || p[0] != HeaderAuthSwitch {
+		return nil, errors.New("not an auth switch request")
+	}
+	if len(p) == 1 {
+		return &AuthSwitch{Plugin: OldPasswordPlugin, OldPassword: true}, nil
+	}
+	name, data, _ := bytes.Cut(p[1:], []byte{0})
+	return &AuthSwitch{Plugin: string(name), Data: bytes.TrimSuffix(data, []byte{0})}, nil
+}
+
+/*
+IsEOF reports whether p is an EOF packet (0xfe marker, shorter than a length-encoded 8-byte integer row).
+*/
+func IsEOF(p []byte) bool {
+	return len(p) > 0 && p[0] == HeaderEOF && len(p) < 9
+}
+
+/*
+LenEncInt decodes a length-encoded integer at the start of b, returning it and its size, or size 0 when b is too short or starts with a NULL/ERR marker.
+*/
+func LenEncInt(b []byte) (uint64, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	switch b[0] {
+	case 0xfc:
+		if len(b) >= 3 {
+			return uint64(binary.LittleEndian.Uint16(b[1:3])), 3
+		}
+	case 0xfd:
+		if len(b) >= 4 {
+			return uint64(b[1]) | uint64(b[2])<<8 | uint64(b[3])<<16, 4
+		}
+	case 0xfe:
+		if len(b) >= 9 {
+			return binary.LittleEndian.Uint64(b[1:9]), 9
+		}
+	case 0xfb, 0xff:
+	default:
+		return uint64(b[0]), 1
+	}
+	return 0, 0
+}
+
+/*
+SSLRequest builds the SSLRequest payload a client sends, as packet 1, in place of its HandshakeResponse to switch to TLS: caps (with ClientSSL added), max packet size, charset, and 23 zero bytes of filler.
+*/
+func SSLRequest(caps uint32) []byte {
+	p := binary.LittleEndian.AppendUint32(make([]byte, 0, 32), caps|ClientSSL)
+	p = binary.LittleEndian.AppendUint32(p, maxPacketSize)
+	p = append(p, DefaultCharset)
+	return append(p, make([]byte, 23)...)
+}
+
+/*
+HandshakeResponse41 builds the HandshakeResponse41 payload: the capabilities a client of this package sends, limited to serverCaps (ClientSSL added when secure, for a session already in TLS), max packet size, charset, 23 bytes of filler, the user, the length-prefixed auth response, and the plugin name.
+*/
+func HandshakeResponse41(serverCaps uint32, secure bool, user string, authResp []byte, plugin string) []byte {
+	caps := uint32(ClientLongPassword|ClientProtocol41|ClientTransactions|ClientSecureConnection|ClientPluginAuth) & serverCaps
+	if secure {
+		caps |= ClientSSL
+	}
+	p := binary.LittleEndian.AppendUint32(nil, caps)
+	p = binary.LittleEndian.AppendUint32(p, maxPacketSize)
+	p = append(p, DefaultCharset)
+	p = append(p, make([]byte, 23)...)
+	p = append(append(p, user...), 0)
+	p = append(append(p, byte(len(authResp))), authResp...)
+	if caps&ClientPluginAuth != 0 {
+		p = append(append(p, plugin...), 0)
+	}
+	return p
+}
+
+/*
+HandshakeResponse320 builds a pre-4.1 HandshakeResponse320 payload: 2 bytes of capabilities (none), a 3-byte max packet size, the user, and an empty auth response.
+*/
+func HandshakeResponse320(user string) []byte {
+	p := binary.LittleEndian.AppendUint16(nil, 0)
+	p = append(p, 0xff, 0xff, 0xff)
+	return append(append(p, user...), 0)
+}
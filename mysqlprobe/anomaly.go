@@ -0,0 +1,170 @@
+package mysqlprobe
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Anomaly severities: SeverityError breaks the protocol, SeverityWarning is legal framing no genuine server of the version sends, SeverityInfo is merely unusual.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Anomaly codes reported in Anomaly.Code.
+const (
+	AnomalySequenceNonzero       = "sequence_nonzero"
+	AnomalyFillerNonzero         = "filler_nonzero"
+	AnomalyReservedNonzero       = "reserved_bytes_nonzero"
+	AnomalyCharsetInvalid        = "charset_invalid"
+	AnomalyCharsetVersion        = "charset_version_mismatch"
+	AnomalyAuthDataLength        = "auth_data_length_mismatch"
+	AnomalyAuthPluginUnterminate = "auth_plugin_unterminated"
+	AnomalyTrailingBytes         = "trailing_bytes"
+	AnomalyVersionUnprintable    = "version_unprintable"
+	AnomalyPluginUnprintable     = "auth_plugin_unprintable"
+)
+
+// pluginAuthDataLength is the auth-plugin-data length a server with CLIENT_PLUGIN_AUTH announces: the 20-byte scramble and its NUL.
+const pluginAuthDataLength = saltLength + 1
+
+/*
+Anomaly is a handshake detail that a genuine server of the announced version would not produce: a misconfiguration, a fork, or an emulation (honeypot) getting it wrong.
+Offset is the byte offset of the offending field in the packet, header included; Hex carries the offending bytes where they are the evidence.
+*/
+type Anomaly struct {
+	Code        string `json:"code"`
+	Severity    string `json:"severity"`
+	Offset      *int   `json:"offset,omitempty"`
+	Description string `json:"description"`
+	Hex         string `json:"hex,omitempty"`
+}
+
+/*
+newAnomaly builds an Anomaly at offset in the packet.
+*/
+func newAnomaly(code, severity string, offset int, format string, args ...any) Anomaly {
+	return Anomaly{Code: code, Severity: severity, Offset: &offset, Description: fmt.Sprintf(format, args...)}
+}
+
+/*
+anomalies runs every validation check over the parsed packet and returns what they found, in packet order.
+Function-level comment: framing checks (sequence ID, unprintable bytes in the version or plugin name, the filler after the first scramble part, the auth-plugin-data length, the plugin name's terminator, bytes after it) apply to any handshake; the reserved bytes and character set are judged against the announced version. Checks for fields the packet did not reach are skipped.
+*/
+func (h *Handshake) anomalies(info *HandshakeInfo) []Anomaly {
+	var out []Anomaly
+	if h.raw[3] != 0 {
+		out = append(out, newAnomaly(AnomalySequenceNonzero, SeverityError, 3, "handshake has sequence ID %d, not 0", h.raw[3]))
+	}
+	if _, changed := SanitizeText(h.serverVersion); changed {
+		a := newAnomaly(AnomalyVersionUnprintable, SeverityWarning, 5, "server version has control characters or invalid UTF-8")
+		a.Hex = hex.EncodeToString(h.serverVersion[:min(len(h.serverVersion), 32)])
+		out = append(out, a)
+	}
+	if h.fillerAt > 0 && h.raw[h.fillerAt] != 0 {
+		a := newAnomaly(AnomalyFillerNonzero, SeverityWarning, h.fillerAt, "filler byte after the first scramble part is 0x%02x, not 0", h.raw[h.fillerAt])
+		out = append(out, a)
+	}
+	if h.extended {
+		out = append(out, checkCharset(info, h.charsetAt)...)
+	}
+	if h.authLenAt > 0 && h.CapabilityFlags&ClientPluginAuth != 0 && h.raw[h.authLenAt] != pluginAuthDataLength {
+		out = append(out, newAnomaly(AnomalyAuthDataLength, SeverityWarning, h.authLenAt, "auth-plugin-data length is %d, not %d", h.raw[h.authLenAt], pluginAuthDataLength))
+	}
+	if h.reserved != nil {
+		out = append(out, checkReserved(info, h.reserved, h.reservedAt)...)
+	}
+	if _, changed := SanitizeText(h.authPlugin); changed {
+		a := newAnomaly(AnomalyPluginUnprintable, SeverityWarning, h.pluginAt, "auth plugin name has control characters or invalid UTF-8")
+		a.Hex = hex.EncodeToString(h.authPlugin[:min(len(h.authPlugin), 32)])
+		out = append(out, a)
+	}
+	if h.pluginUnterminated > 0 {
+		out = append(out, newAnomaly(AnomalyAuthPluginUnterminate, SeverityInfo, h.pluginUnterminated, "auth plugin name has no NUL terminator"))
+	}
+	if h.trailingAt > 0 {
+		a := newAnomaly(AnomalyTrailingBytes, SeverityWarning, h.trailingAt, "%d bytes after the auth plugin name", len(h.raw)-h.trailingAt)
+		a.Hex = hex.EncodeToString(h.raw[h.trailingAt:min(len(h.raw), h.trailingAt+32)])
+		out = append(out, a)
+	}
+	return out
+}
+
+/*
+collationRange is a block of collation IDs sharing the release that introduced them.
+*/
+type collationRange struct {
+	first, last uint8
+	family      string
+	since       int // major*10000 + minor*100 + patch
+	mysqlOnly   bool
+}
+
+// collationsByRelease lists collation ID blocks newer than 4.1, where the character set byte was introduced, with the first MySQL release that has them.
+// MariaDB shares the 5.5-era IDs but never added MySQL 8.0's, so those are MySQL-only.
+var collationsByRelease = []collationRange{
+	{45, 46, "utf8mb4", 50503, false},
+	{224, 247, "utf8mb4", 50503, false},
+	{54, 56, "utf16", 50503, false},
+	{101, 124, "utf16", 50503, false},
+	{60, 61, "utf32", 50503, false},
+	{160, 183, "utf32", 50503, false},
+	{248, 250, "gb18030", 50704, true},
+	{255, 255, "utf8mb4_0900", 80001, true},
+}
+
+/*
+checkCharset cross-checks the announced character set (the server's default collation ID, at offset) against the collations the parsed version can have.
+Function-level comment: ID 0 is never a MySQL collation; otherwise only blocks with a known introducing release are judged, so unknown builds and pre-4.1 servers yield nothing. MariaDB versions are compared against the MySQL release their collations came from.
+*/
+func checkCharset(info *HandshakeInfo, offset int) []Anomaly {
+	v := info.Version
+	if v == nil || v.Major*10000+v.Minor*100 < 40100 {
+		return nil
+	}
+	release := v.Major*10000 + v.Minor*100 + v.Patch
+	mariaDB := strings.Contains(info.ServerVersion, "MariaDB")
+	// MariaDB's collation IDs above 255 (e.g. uca1400's) are truncated into the byte, so 0 is only impossible for MySQL.
+	if info.CharacterSet == 0 && !mariaDB {
+		return []Anomaly{newAnomaly(AnomalyCharsetInvalid, SeverityError, offset, "character set 0 is not a collation ID")}
+	}
+	if mariaDB && release >= 100000 {
+		// MariaDB 10+ descends from MySQL 5.5/5.6.
+		release = 50600
+	}
+	for _, r := range collationsByRelease {
+		if info.CharacterSet < r.first || info.CharacterSet > r.last {
+			continue
+		}
+		if r.mysqlOnly && mariaDB {
+			return []Anomaly{newAnomaly(AnomalyCharsetVersion, SeverityWarning, offset, "collation %d (%s) exists only in MySQL, not MariaDB", info.CharacterSet, r.family)}
+		}
+		if release < r.since {
+			return []Anomaly{newAnomaly(AnomalyCharsetVersion, SeverityWarning, offset, "collation %d (%s) needs %d.%d.%d or later, server is %s", info.CharacterSet, r.family, r.since/10000, r.since/100%100, r.since%100, info.ServerVersion)}
+		}
+	}
+	return nil
+}
+
+// mariaDBCapsOffset is where MariaDB puts its extended capability flags inside the reserved bytes, when it clears CLIENT_LONG_PASSWORD (CLIENT_MYSQL) to say so.
+const mariaDBCapsOffset = 6
+
+/*
+checkReserved verifies the 10 reserved bytes (at offset) after the upper capability flags, which the protocol requires to be zero.
+Function-level comment: MariaDB legitimately fills the last four with its extended capabilities, so for MariaDB (by version string or a cleared CLIENT_LONG_PASSWORD) only the first six must be zero. Nonzero content is reported with the bytes' hex.
+*/
+func checkReserved(info *HandshakeInfo, reserved []byte, offset int) []Anomaly {
+	checked := reserved
+	if strings.Contains(info.ServerVersion, "MariaDB") || info.CapabilityFlags&ClientLongPassword == 0 {
+		checked = reserved[:mariaDBCapsOffset]
+	}
+	if len(bytes.Trim(checked, "\x00")) == 0 {
+		return nil
+	}
+	a := newAnomaly(AnomalyReservedNonzero, SeverityWarning, offset, "reserved filler after the capability flags is not zero")
+	a.Hex = hex.EncodeToString(reserved)
+	return []Anomaly{a}
+}
@@ -0,0 +1,75 @@
+package mysqlprobe
+
+import (
+	"slices"
+	"testing"
+)
+
+// Offsets into a FakeHandshake packet, header included, for a server version of n bytes.
+func fillerAt(n int) int  { return 4 + 1 + n + 1 + 4 + 8 }
+func charsetAt(n int) int { return fillerAt(n) + 3 }
+func authLenAt(n int) int { return fillerAt(n) + 8 }
+
+// reframe rebuilds pkt's header after its payload was changed.
+func reframe(pkt []byte) []byte { return fakePacket(pkt[4:]) }
+
+// mutate returns a copy of pkt with f applied.
+func mutate(pkt []byte, f func(p []byte) []byte) []byte { return f(slices.Clone(pkt)) }
+
+func TestHandshakeAnomalies(t *testing.T) {
+	mysql8 := FakeHandshake("8.0.36", 0xdfffffff, 255, "caching_sha2_password")
+	mariaDB := FakeHandshake("5.5.5-10.11.6-MariaDB", 0xa0fff7fe, 45, "mysql_native_password")
+	n8, nMaria := len("8.0.36"), len("5.5.5-10.11.6-MariaDB")
+	tests := []struct {
+		name     string
+		pkt      []byte
+		code     string
+		severity string
+		offset   int
+		also     []string // anomalies the first one causes further on
+	}{
+		{"clean mysql 8.0", mysql8, "", "", 0, nil},
+		{"clean mariadb", mariaDB, "", "", 0, nil},
+		{"sequence id", mutate(mysql8, func(p []byte) []byte { p[3] = 1; return p }), AnomalySequenceNonzero, SeverityError, 3, nil},
+		{"filler", mutate(mysql8, func(p []byte) []byte { p[fillerAt(n8)] = 0x41; return p }), AnomalyFillerNonzero, SeverityWarning, fillerAt(n8), nil},
+		{"charset 0", FakeHandshake("8.0.36", 0xdfffffff, 0, "caching_sha2_password"), AnomalyCharsetInvalid, SeverityError, charsetAt(n8), nil},
+		{"charset newer than version", FakeHandshake("5.7.44", 0xdfffffff, 255, "mysql_native_password"), AnomalyCharsetVersion, SeverityWarning, charsetAt(len("5.7.44")), nil},
+		{"mysql-only charset on mariadb", FakeHandshake("5.5.5-10.11.6-MariaDB", 0xa0fff7fe, 255, "mysql_native_password"), AnomalyCharsetVersion, SeverityWarning, charsetAt(nMaria), nil},
+		{"auth data length", mutate(mysql8, func(p []byte) []byte { p[authLenAt(n8)] = 20; return p }), AnomalyAuthDataLength, SeverityWarning, authLenAt(n8), []string{AnomalyTrailingBytes}},
+		{"reserved bytes", mutate(mysql8, func(p []byte) []byte { p[authLenAt(n8)+10] = 1; return p }), AnomalyReservedNonzero, SeverityWarning, authLenAt(n8) + 1, nil},
+		{"mariadb extended capabilities", mutate(mariaDB, func(p []byte) []byte { p[authLenAt(nMaria)+1+mariaDBCapsOffset] = 0x1d; return p }), "", "", 0, nil},
+		{"unterminated plugin", mutate(mysql8, func(p []byte) []byte { return reframe(p[:len(p)-1]) }), AnomalyAuthPluginUnterminate, SeverityInfo, len(mysql8) - len("caching_sha2_password\x00"), nil},
+		{"trailing bytes", mutate(mysql8, func(p []byte) []byte { return reframe(append(p, "extra"...)) }), AnomalyTrailingBytes, SeverityWarning, len(mysql8), nil},
+		{"unprintable version", FakeHandshake("8.0.36\x1b[31m", 0xdfffffff, 255, "caching_sha2_password"), AnomalyVersionUnprintable, SeverityWarning, 5, nil},
+		{"unprintable plugin", FakeHandshake("8.0.36", 0xdfffffff, 255, "caching\x07sha2"), AnomalyPluginUnprintable, SeverityWarning, authLenAt(n8) + 1 + 10 + 13, nil},
+	}
+	for _, tt := range tests {
+		info, err := ParseHandshake(tt.pkt)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.code == "" {
+			if len(info.Anomalies) > 0 {
+				t.Errorf("%s: unexpected anomalies %+v", tt.name, info.Anomalies)
+			}
+			continue
+		}
+		var codes []string
+		for _, a := range info.Anomalies {
+			codes = append(codes, a.Code)
+		}
+		if want := append([]string{tt.code}, tt.also...); !slices.Equal(codes, want) {
+			t.Errorf("%s: got anomalies %v, want %v", tt.name, codes, want)
+			continue
+		}
+		a := info.Anomalies[0]
+		if a.Code != tt.code || a.Severity != tt.severity || a.Offset == nil || *a.Offset != tt.offset || a.Description == "" {
+			offset := -1
+			if a.Offset != nil {
+				offset = *a.Offset
+			}
+			t.Errorf("%s: got %s/%s at %d (%q), want %s/%s at %d", tt.name, a.Code, a.Severity, offset, a.Description, tt.code, tt.severity, tt.offset)
+		}
+	}
+}
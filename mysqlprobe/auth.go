@@ -0,0 +1,71 @@
+package mysqlprobe
+
+import (
+	"encoding/hex"
+	"net"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+/*
+Credentials are the account the probe logs in with in authenticated mode.
+*/
+type Credentials struct {
+	User     string
+	Password string
+}
+
+/*
+AuthInfo is the outcome of logging in after the handshake, and what was collected over the session.
+*/
+type AuthInfo struct {
+	User        string       `json:"user"`
+	Plugin      string       `json:"plugin,omitempty"`
+	OK          bool         `json:"ok"`
+	ServerError *ServerError `json:"server_error,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Stats       *ServerStats `json:"stats,omitempty"`
+	// Variables holds the allowlisted server variables read with Options.Variables (nil for NULL); VariableErrors says why any could not be read.
+	Variables      map[string]*string `json:"variables,omitempty"`
+	VariableErrors map[string]string  `json:"variable_errors,omitempty"`
+	Schemas        *SchemaList        `json:"schemas,omitempty"`
+}
+
+/*
+authenticatedSession logs in over conn, whose next client packet has sequence seq, and collects what authenticated mode reports.
+Function-level comment: secure says conn is already TLS, which allows sending the password in clear when the server asks for it. The session ends with COM_QUIT.
+*/
+func authenticatedSession(conn net.Conn, seq byte, info *HandshakeInfo, secure bool, opts Options) *AuthInfo {
+	pc := mysqlclient.NewConn(conn, seq, opts.Timeout)
+	ai := authenticate(pc, info, secure, opts.Credentials)
+	if !ai.OK {
+		return ai
+	}
+	ai.Stats = collectStats(pc)
+	if len(opts.Variables) > 0 {
+		ai.Variables, ai.VariableErrors = readVariables(pc, opts.Variables)
+	}
+	if opts.EnumSchemas {
+		ai.Schemas = listSchemas(pc, opts.SchemaRedaction)
+	}
+	_ = pc.Command(mysqlclient.ComQuit)
+	return ai
+}
+
+/*
+authenticate logs in as creds over pc, whose next packet is the HandshakeResponse41, with mysqlclient's Auth.
+Function-level comment: failures are reported in AuthInfo rather than returned; a refusal carries the server's ERR packet.
+*/
+func authenticate(pc *mysqlclient.Conn, info *HandshakeInfo, secure bool, creds *Credentials) *AuthInfo {
+	salt, _ := hex.DecodeString(info.AuthPluginData)
+	a := mysqlclient.NewAuth(info.CapabilityFlags, info.AuthPluginName, salt, creds.User, creds.Password, secure)
+	err := pc.Authenticate(a)
+	ai := &AuthInfo{User: creds.User, Plugin: a.Plugin(), OK: a.State() == mysqlclient.StateOK}
+	switch {
+	case err != nil:
+		ai.Error = err.Error()
+	case a.Refusal() != nil:
+		ai.ServerError = (*ServerError)(a.Refusal())
+	}
+	return ai
+}
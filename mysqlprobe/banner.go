@@ -0,0 +1,97 @@
+package mysqlprobe
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// maxBannerBytes caps how much of a non-MySQL banner is kept for the generic_banner field.
+	maxBannerBytes = 512
+	// bannerIdleTimeout is how long the banner grab waits for more bytes once the server has started talking.
+	bannerIdleTimeout = 250 * time.Millisecond
+)
+
+/*
+bannerProbe is a payload written to a silent service to coax out a banner.
+*/
+type bannerProbe struct {
+	name    string
+	payload []byte
+}
+
+// bannerProbes are tried in order when the server sends nothing on its own.
+var bannerProbes = []bannerProbe{
+	{name: "newline", payload: []byte("\r\n")},
+	{name: "http_get", payload: []byte("GET / HTTP/1.0\r\n\r\n")},
+}
+
+/*
+drainBanner appends whatever the server sends to buf until it goes quiet, closes, or buf reaches maxBannerBytes.
+Function-level comment: waits up to timeout for the first bytes, then keeps reading with a short idle deadline so chatty services don't stall the scan.
+*/
+func drainBanner(conn net.Conn, buf []byte, timeout time.Duration) []byte {
+	chunk := make([]byte, maxBannerBytes)
+	wait := timeout
+	if len(buf) > 0 {
+		wait = bannerIdleTimeout
+	}
+	for len(buf) < maxBannerBytes {
+		n, err := ReadWithDeadline(conn, chunk[:maxBannerBytes-len(buf)], wait)
+		buf = append(buf, chunk[:n]...)
+		if err != nil {
+			break
+		}
+		wait = bannerIdleTimeout
+	}
+	return buf
+}
+
+/*
+grabGenericBanner collects a banner from a service that did not answer like MySQL.
+Function-level comment: starts from the bytes already read, drains anything else the server sent, and if it sent nothing writes each bannerProbe in turn; returns the banner and the name of the probe that produced it ("" when the server spoke first).
+*/
+func grabGenericBanner(conn net.Conn, initial []byte, timeout time.Duration) ([]byte, string) {
+	buf := make([]byte, 0, maxBannerBytes)
+	buf = append(buf, initial[:min(len(initial), maxBannerBytes)]...)
+	if buf = drainBanner(conn, buf, timeout); len(buf) > 0 {
+		return buf, ""
+	}
+	for _, p := range bannerProbes {
+		if _, err := conn.Write(p.payload); err != nil {
+			return nil, ""
+		}
+		if buf = drainBanner(conn, buf, timeout); len(buf) > 0 {
+			return buf, p.name
+		}
+	}
+	return nil, ""
+}
+
+/*
+PrintableBanner renders raw banner bytes as readable text.
+Function-level comment: keeps printable ASCII plus CR/LF/TAB and replaces every other byte with a \xNN escape so the banner stays readable in JSON output.
+*/
+func PrintableBanner(b []byte) string {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		if (c >= 0x20 && c < 0x7f) || c == '\n' || c == '\r' || c == '\t' {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, fmt.Sprintf("\\x%02x", c)...)
+	}
+	return string(out)
+}
+
+/*
+min is a small helper utility.
+Function-level comment: returns the smaller of two integers.
+*/
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package mysqlprobe
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// canBindToDevice reports whether sockets can be pinned to an interface with SO_BINDTODEVICE.
+const canBindToDevice = true
+
+/*
+bindToDevice pins fd to the named interface with SO_BINDTODEVICE, so traffic egresses through it whatever the routing table prefers.
+*/
+func bindToDevice(fd uintptr, iface string) error {
+	if err := syscall.BindToDevice(int(fd), iface); err != nil {
+		return fmt.Errorf("bind to interface %s: %w", iface, err)
+	}
+	return nil
+}
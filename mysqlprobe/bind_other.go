@@ -0,0 +1,15 @@
+//go:build !linux
+
+package mysqlprobe
+
+import "errors"
+
+// canBindToDevice reports whether sockets can be pinned to an interface with SO_BINDTODEVICE.
+const canBindToDevice = false
+
+/*
+bindToDevice is not available on this platform; Dial binds to the interface's address instead.
+*/
+func bindToDevice(fd uintptr, iface string) error {
+	return errors.New("SO_BINDTODEVICE is not supported on this platform")
+}
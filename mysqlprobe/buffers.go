@@ -0,0 +1,57 @@
+package mysqlprobe
+
+import "sync"
+
+// DefaultMaxPayload is the default largest first-packet payload read in full.
+// Real handshakes are around 100 bytes; a packet announcing more is read up to the limit, reported as truncated, and not parsed as a handshake.
+const DefaultMaxPayload = 16 << 10
+
+// DefaultCaptureBytes is the default size of the first-packet buffer: the 4-byte header plus DefaultMaxPayload.
+const DefaultCaptureBytes = 4 + DefaultMaxPayload
+
+// defaultBuffers serves probes whose Options carry no BufferPool.
+var defaultBuffers = NewBufferPool(DefaultCaptureBytes)
+
+/*
+BufferPool recycles first-packet buffers of one size across probes, so scans of many targets do not allocate (and collect) a buffer per connection.
+A nil *BufferPool is valid and shares a package-wide pool of DefaultCaptureBytes buffers.
+*/
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+/*
+NewBufferPool returns a pool of size-byte buffers; sizes under 4 bytes (one packet header) use DefaultCaptureBytes.
+*/
+func NewBufferPool(size int) *BufferPool {
+	if size < 4 {
+		size = DefaultCaptureBytes
+	}
+	p := &BufferPool{size: size}
+	p.pool.New = func() any {
+		buf := make([]byte, size)
+		return &buf
+	}
+	return p
+}
+
+/*
+get returns a buffer from the pool.
+*/
+func (p *BufferPool) get() *[]byte {
+	if p == nil {
+		p = defaultBuffers
+	}
+	return p.pool.Get().(*[]byte)
+}
+
+/*
+put returns buf to the pool; nothing may reference it afterwards.
+*/
+func (p *BufferPool) put(buf *[]byte) {
+	if p == nil {
+		p = defaultBuffers
+	}
+	p.pool.Put(buf)
+}
@@ -0,0 +1,75 @@
+package mysqlprobe
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// testHandshake is a MySQL 8.0 initial handshake packet as servers send it.
+var testHandshake = FakeHandshake("8.0.36", 0xdfffffff, 255, "caching_sha2_password")
+
+/*
+replayConn is a net.Conn that serves the same bytes on every Reset, so benchmarks measure buffering rather than the network.
+*/
+type replayConn struct {
+	net.Conn
+	data []byte
+	off  int
+}
+
+/*
+Reset rewinds the connection to the start of its data.
+*/
+func (c *replayConn) Reset() {
+	c.off = 0
+}
+
+/*
+Read copies the next bytes of data into p.
+*/
+func (c *replayConn) Read(p []byte) (int, error) {
+	n := copy(p, c.data[c.off:])
+	c.off += n
+	return n, nil
+}
+
+/*
+SetReadDeadline is a no-op; replayed reads never block.
+*/
+func (c *replayConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+/*
+BenchmarkFirstPacket reads a handshake into a newly allocated capture buffer per connection, as without pooling, and into a pooled one, as Probe does.
+*/
+func BenchmarkFirstPacket(b *testing.B) {
+	pool := NewBufferPool(DefaultCaptureBytes)
+	buffers := []struct {
+		name string
+		get  func() *[]byte
+		put  func(*[]byte)
+	}{
+		{"fresh-buffer", func() *[]byte { buf := make([]byte, DefaultCaptureBytes); return &buf }, func(*[]byte) {}},
+		{"pooled", pool.get, pool.put},
+	}
+	for _, bb := range buffers {
+		b.Run(bb.name, func(b *testing.B) {
+			conn := &replayConn{data: testHandshake}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				conn.Reset()
+				buf := bb.get()
+				first, err := grabFirstPacket(conn, time.Second, *buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(first) != len(testHandshake) {
+					b.Fatalf("read %d bytes, want %d", len(first), len(testHandshake))
+				}
+				bb.put(buf)
+			}
+		})
+	}
+}
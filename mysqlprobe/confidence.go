@@ -0,0 +1,34 @@
+package mysqlprobe
+
+import "math"
+
+// saltLength is the length of the auth-plugin-data a 4.1+ server sends: 8 bytes in part 1 and 12 in part 2.
+const saltLength = 20
+
+/*
+DetectionConfidence scores how closely a parsed handshake follows the protocol, from 0 to 1: the fraction of invariants it meets.
+Function-level comment: the invariants are a header whose length matches the packet read and whose sequence ID is 0, protocol version 10, a version string that parses as major.minor.patch, sane capability bits (protocol 4.1 and secure connection, with an auth plugin named exactly when CLIENT_PLUGIN_AUTH is set), and a 20-byte salt. The lenient parser accepts handshakes that miss several of these, which is what a low score flags.
+*/
+func DetectionConfidence(first []byte, info *HandshakeInfo) float64 {
+	if info == nil || len(first) < 4 {
+		return 0
+	}
+	payloadLen := int(first[0]) | int(first[1])<<8 | int(first[2])<<16
+	caps := info.CapabilityFlags
+	checks := []bool{
+		payloadLen == len(first)-4,
+		first[3] == 0,
+		info.ProtocolVersion == 10,
+		info.Version != nil,
+		caps&ClientProtocol41 != 0 && caps&ClientSecureConnection != 0,
+		(caps&ClientPluginAuth != 0) == (info.AuthPluginName != ""),
+		len(info.AuthPluginData) == 2*saltLength,
+	}
+	met := 0
+	for _, ok := range checks {
+		if ok {
+			met++
+		}
+	}
+	return math.Round(float64(met)/float64(len(checks))*100) / 100
+}
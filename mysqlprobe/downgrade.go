@@ -0,0 +1,72 @@
+package mysqlprobe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// Downgrade test outcomes reported in DowngradeInfo.Outcome.
+const (
+	DowngradeProceeded    = "proceeded"
+	DowngradeError        = "error"
+	DowngradeDisconnected = "disconnected"
+)
+
+// downgradeCheckUser is the account the legacy login names; it is not expected to exist.
+const downgradeCheckUser = "mysqlprobe_downgrade_check"
+
+/*
+DowngradeInfo is how a server answered a login with minimal, pre-4.1 client capabilities.
+LegacyAccepted is set when the server handled the login on the legacy 3.20 path: it let the client in, asked for the old password hash, or judged the credentials instead of refusing the protocol.
+*/
+type DowngradeInfo struct {
+	Outcome        string       `json:"outcome,omitempty"`
+	LegacyAccepted bool         `json:"legacy_accepted"`
+	Detail         string       `json:"detail,omitempty"`
+	ServerError    *ServerError `json:"server_error,omitempty"`
+	Error          string       `json:"error,omitempty"`
+}
+
+/*
+testDowngrade logs in to the server at addr on a connection of its own with a HandshakeResponse320 carrying no capabilities at all (no CLIENT_PROTOCOL_41, no SSL), and records whether the server proceeds, errors, or disconnects.
+Function-level comment: the response names downgradeCheckUser with an empty password, so nothing secret is sent in the clear. Servers since MySQL 5.7 refuse such clients with ER_NOT_SUPPORTED_AUTH_MODE or a bad handshake error; an OK, an old-password request, or an access-denied verdict means the legacy path is still open.
+*/
+func testDowngrade(addr string, opts Options) *DowngradeInfo {
+	conn, _, err := dialHandshake(addr, opts)
+	if err != nil {
+		return &DowngradeInfo{Error: err.Error()}
+	}
+	defer conn.Close()
+	pc := mysqlclient.NewConn(conn, 1, opts.Timeout)
+	if err := pc.WritePacket(mysqlclient.HandshakeResponse320(downgradeCheckUser)); err != nil {
+		return &DowngradeInfo{Error: "write failed: " + err.Error()}
+	}
+	p, err := pc.ReadPacket()
+	switch {
+	case err != nil && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)):
+		return &DowngradeInfo{Outcome: DowngradeDisconnected, Detail: "server closed the connection on the legacy login"}
+	case err != nil:
+		return &DowngradeInfo{Error: "read failed: " + err.Error()}
+	case p[0] == mysqlclient.HeaderOK:
+		return &DowngradeInfo{Outcome: DowngradeProceeded, LegacyAccepted: true, Detail: "server accepted the legacy login"}
+	case p[0] == mysqlclient.HeaderAuthSwitch && len(p) == 1:
+		return &DowngradeInfo{Outcome: DowngradeProceeded, LegacyAccepted: true, Detail: "server asked for the pre-4.1 password hash"}
+	case p[0] == mysqlclient.HeaderAuthSwitch:
+		return &DowngradeInfo{Outcome: DowngradeProceeded, LegacyAccepted: true, Detail: "server switched the legacy client to an auth plugin it cannot have"}
+	case p[0] == mysqlclient.HeaderErr:
+		serr, err := parseErrPacket(p)
+		if err != nil {
+			return &DowngradeInfo{Outcome: DowngradeError, Error: err.Error()}
+		}
+		di := &DowngradeInfo{Outcome: DowngradeError, ServerError: serr, Detail: "server refused the legacy protocol"}
+		if class := serr.Class(); class == ErrorClassAccessDenied || class == ErrorClassAccountLocked || class == ErrorClassPasswordExpired {
+			di.LegacyAccepted, di.Detail = true, "server judged the legacy login's credentials instead of refusing the protocol"
+		}
+		return di
+	}
+	return &DowngradeInfo{Outcome: DowngradeProceeded, Detail: fmt.Sprintf("unexpected packet 0x%02x after the legacy login", p[0])}
+}
@@ -0,0 +1,63 @@
+package mysqlprobe
+
+import "strings"
+
+// Error classes reported as server_error_class, so consumers can group refusals without their own code table.
+const (
+	ErrorClassHostBlocked        = "host_blocked"
+	ErrorClassHostNotAllowed     = "host_not_allowed"
+	ErrorClassTooManyConnections = "too_many_connections"
+	ErrorClassResourceLimit      = "resource_limit"
+	ErrorClassAccessDenied       = "access_denied"
+	ErrorClassAccountLocked      = "account_locked"
+	ErrorClassPasswordExpired    = "password_expired"
+	ErrorClassSecureTransport    = "secure_transport_required"
+	ErrorClassAuthUnsupported    = "auth_unsupported"
+	ErrorClassBadHandshake       = "bad_handshake"
+	ErrorClassShuttingDown       = "shutting_down"
+	ErrorClassConnectionRejected = "connection_rejected"
+	ErrorClassOther              = "other"
+)
+
+// errorClasses maps the error codes servers commonly send before or during login to their class.
+var errorClasses = map[uint16]string{
+	ErrHostIsBlocked:           ErrorClassHostBlocked,
+	ErrHostNotPrivileged:       ErrorClassHostNotAllowed,
+	1040:                       ErrorClassTooManyConnections, // ER_CON_COUNT_ERROR
+	1203:                       ErrorClassTooManyConnections, // ER_TOO_MANY_USER_CONNECTIONS
+	1226:                       ErrorClassResourceLimit,      // ER_USER_LIMIT_REACHED
+	1044:                       ErrorClassAccessDenied,       // ER_DBACCESS_DENIED_ERROR
+	1045:                       ErrorClassAccessDenied,       // ER_ACCESS_DENIED_ERROR
+	1698:                       ErrorClassAccessDenied,       // ER_ACCESS_DENIED_NO_PASSWORD_ERROR
+	3118:                       ErrorClassAccountLocked,      // ER_ACCOUNT_HAS_BEEN_LOCKED
+	4151:                       ErrorClassAccountLocked,      // MariaDB ER_ACCOUNT_HAS_BEEN_LOCKED
+	1862:                       ErrorClassPasswordExpired,    // ER_MUST_CHANGE_PASSWORD_LOGIN
+	ErrSecureTransportRequired: ErrorClassSecureTransport,
+	1251:                       ErrorClassAuthUnsupported, // ER_NOT_SUPPORTED_AUTH_MODE
+	1043:                       ErrorClassBadHandshake,    // ER_HANDSHAKE_ERROR
+	1053:                       ErrorClassShuttingDown,    // ER_SERVER_SHUTDOWN
+}
+
+/*
+ErrorClass maps a server error code, or failing that its SQL state, to a human-readable class.
+Function-level comment: unknown codes fall back on the SQL state class (28 is an authorization failure, 08 a rejected connection) and then to ErrorClassOther.
+*/
+func ErrorClass(code uint16, sqlState string) string {
+	if class, ok := errorClasses[code]; ok {
+		return class
+	}
+	switch {
+	case strings.HasPrefix(sqlState, "28"):
+		return ErrorClassAccessDenied
+	case strings.HasPrefix(sqlState, "08"):
+		return ErrorClassConnectionRejected
+	}
+	return ErrorClassOther
+}
+
+/*
+Class returns the error's class (see ErrorClass).
+*/
+func (e *ServerError) Class() string {
+	return ErrorClass(e.Code, e.SQLState)
+}
@@ -0,0 +1,97 @@
+package mysqlprobe
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+/*
+FakeServer is a loopback listener that answers every connection with a canned first packet and hangs up, standing in for a MySQL server in benchmarks and self-tests.
+*/
+type FakeServer struct {
+	ln net.Listener
+}
+
+/*
+StartFakeServer listens on an ephemeral 127.0.0.1 port and serves first, typically built with FakeHandshake or FakeErrPacket, to each client.
+*/
+func StartFakeServer(first []byte) (*FakeServer, error) {
+	return ListenFakeServer("127.0.0.1:0", first)
+}
+
+/*
+ListenFakeServer is StartFakeServer on a chosen address.
+*/
+func ListenFakeServer(addr string, first []byte) (*FakeServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write(first)
+			conn.Close()
+		}
+	}()
+	return &FakeServer{ln: ln}, nil
+}
+
+/*
+Addr returns the host:port the server listens on.
+*/
+func (s *FakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+/*
+Close stops accepting connections.
+*/
+func (s *FakeServer) Close() error {
+	return s.ln.Close()
+}
+
+/*
+FakeHandshake builds a protocol v10 handshake packet with connection ID 11 and a fixed scramble; an empty plugin omits the plugin fields as pre-5.5 servers do.
+*/
+func FakeHandshake(version string, caps uint32, charset uint8, plugin string) []byte {
+	p := []byte{10}
+	p = append(p, version...)
+	p = append(p, 0)
+	p = binary.LittleEndian.AppendUint32(p, 11)
+	p = append(p, "\x1f\x2e\x3d\x4c\x5b\x6a\x79\x08"...)
+	p = append(p, 0)
+	p = binary.LittleEndian.AppendUint16(p, uint16(caps))
+	p = append(p, charset)
+	p = binary.LittleEndian.AppendUint16(p, 2)
+	p = binary.LittleEndian.AppendUint16(p, uint16(caps>>16))
+	p = append(p, 21)
+	p = append(p, make([]byte, 10)...)
+	p = append(p, "\x11\x22\x33\x44\x55\x66\x77\x08\x19\x2a\x3b\x4c"...)
+	p = append(p, 0)
+	if plugin != "" {
+		p = append(p, plugin...)
+		p = append(p, 0)
+	}
+	return fakePacket(p)
+}
+
+/*
+FakeErrPacket builds the ERR packet a server sends in place of the handshake.
+*/
+func FakeErrPacket(code uint16, msg string) []byte {
+	p := []byte{0xff}
+	p = binary.LittleEndian.AppendUint16(p, code)
+	return fakePacket(append(p, msg...))
+}
+
+/*
+fakePacket prefixes a payload with its header (length and sequence 0).
+*/
+func fakePacket(payload []byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), 0}, payload...)
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package mysqlprobe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// canFastOpen reports whether client sockets can use TCP Fast Open.
+const canFastOpen = true
+
+// tcpFastOpenConnect is TCP_FASTOPEN_CONNECT (Linux 4.11+), which the syscall package does not define.
+const tcpFastOpenConnect = 30
+
+/*
+setFastOpen enables TCP_FASTOPEN_CONNECT on fd: the SYN carries a Fast Open cookie request, or the cached cookie and any first data.
+*/
+func setFastOpen(fd uintptr) error {
+	if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpFastOpenConnect, 1); err != nil {
+		return fmt.Errorf("set TCP_FASTOPEN_CONNECT: %w", err)
+	}
+	return nil
+}
+
+/*
+startFastOpen sends the SYN of a connection whose connect was deferred.
+Function-level comment: with a cookie cached for the server, Linux returns from connect without sending anything and waits for data to put in the SYN; MySQL servers speak first, so a probe would wait forever. An empty sendto starts the handshake (a plain write of nothing is a no-op that never reaches TCP); on a connection that was not deferred it sends nothing.
+*/
+func startFastOpen(tc *net.TCPConn) error {
+	rc, err := tc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Write(func(fd uintptr) bool {
+		serr = syscall.Sendto(int(fd), nil, 0, nil)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if serr != nil && !errors.Is(serr, syscall.EINPROGRESS) {
+		return fmt.Errorf("start fast open handshake: %w", serr)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package mysqlprobe
+
+import (
+	"errors"
+	"net"
+)
+
+// canFastOpen reports whether client sockets can use TCP Fast Open.
+const canFastOpen = false
+
+/*
+setFastOpen is not available on this platform; Validate rejects FastOpen before a dial gets here.
+*/
+func setFastOpen(fd uintptr) error {
+	return errors.New("TCP Fast Open is only supported on Linux")
+}
+
+/*
+startFastOpen is not needed on this platform.
+*/
+func startFastOpen(tc *net.TCPConn) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+package mysqlprobe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+/*
+canonical renders the handshake fields that stay the same across connections to one server build, in a fixed order:
+protocol, server version, capability flags, character set, status flags, salt length, reserved bytes, and auth plugin.
+The salt's content and the connection ID change on every connection and are left out; the salt's length and the reserved bytes are kept, since implementations differ in both.
+*/
+func (h *Handshake) canonical() string {
+	return fmt.Sprintf("p=%d;v=%s;caps=%08x;cs=%d;st=%04x;salt=%d;res=%x;plugin=%s",
+		h.ProtocolVersion, h.serverVersion, h.CapabilityFlags, h.CharacterSet, h.StatusFlags,
+		len(h.salt1)+len(h.salt2), h.reserved, h.authPlugin)
+}
+
+/*
+fingerprint hashes the canonical handshake: the first 16 bytes of its SHA-256, in hex, so identical server builds share a value across hosts and scans.
+*/
+func (h *Handshake) fingerprint() string {
+	sum := sha256.Sum256([]byte(h.canonical()))
+	return hex.EncodeToString(sum[:16])
+}
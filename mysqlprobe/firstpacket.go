@@ -0,0 +1,65 @@
+package mysqlprobe
+
+import "bytes"
+
+// First packet classes reported as first_packet_class when a server does not open with a handshake.
+const (
+	FirstPacketErr           = "err_packet"
+	FirstPacketOK            = "ok_packet"
+	FirstPacketHTTP          = "http_response"
+	FirstPacketTLSAlert      = "tls_alert"
+	FirstPacketTLSHandshake  = "tls_handshake"
+	FirstPacketText          = "text_banner"
+	FirstPacketBinaryUnknown = "binary_unknown"
+	// FirstPacketTarpit is a first packet that stalled after its first bytes, whatever they were.
+	FirstPacketTarpit = "tarpit"
+)
+
+/*
+ClassifyFirstPacket labels the first bytes a server sent when they are not a MySQL handshake: a MySQL ERR or OK packet, an HTTP response, a TLS record (the server expected a ClientHello), a text banner from another service, or unrecognized binary.
+Function-level comment: MySQL packets are recognized by a plausible header (no more bytes than its payload length announces) and the ERR (0xff) or OK (0x00) marker; other services are recognized by their own framing first, since their bytes also decode as some MySQL header.
+*/
+func ClassifyFirstPacket(b []byte) string {
+	if bytes.HasPrefix(b, []byte("HTTP")) {
+		return FirstPacketHTTP
+	}
+	// A TLS record header: content type, then major version 3 and a minor version up to TLS 1.3's.
+	if len(b) >= 3 && b[1] == 0x03 && b[2] <= 0x04 {
+		switch b[0] {
+		case 0x15:
+			return FirstPacketTLSAlert
+		case 0x16:
+			return FirstPacketTLSHandshake
+		}
+	}
+	if len(b) >= 5 {
+		n := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		if n > 0 && len(b) <= 4+n {
+			switch b[4] {
+			case 0xff:
+				return FirstPacketErr
+			case 0x00:
+				return FirstPacketOK
+			}
+		}
+	}
+	if isText(b) {
+		return FirstPacketText
+	}
+	return FirstPacketBinaryUnknown
+}
+
+/*
+isText reports whether b is printable ASCII with line breaks and tabs, as line-based protocols (SSH, SMTP, FTP, Redis errors) greet.
+*/
+func isText(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if (c < 0x20 || c >= 0x7f) && c != '\n' && c != '\r' && c != '\t' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package mysqlprobe
+
+import (
+	"encoding/hex"
+	"net"
+	"time"
+)
+
+// Limits on what Options.FollowUpWait collects: bytes read after the handshake, packets reported, and bytes of each kept as hex.
+const (
+	maxFollowUpBytes   = 4 << 10
+	maxFollowUpPackets = 8
+	followUpHexBytes   = 256
+)
+
+/*
+AdditionalPacket is data a server sent unprompted after its handshake, as some proxies and honeypots do (see Options.FollowUpWait).
+A whole MySQL packet records its sequence ID and payload length, and is parsed when it is an ERR packet or another handshake; data that does not frame as a MySQL packet is kept raw, with Length its byte count. Class labels either like a first packet (see ClassifyFirstPacket), and Hex holds up to 256 bytes of it.
+*/
+type AdditionalPacket struct {
+	Sequence    *uint8         `json:"sequence,omitempty"`
+	Length      int            `json:"length"`
+	Raw         bool           `json:"raw,omitempty"`
+	Class       string         `json:"class"`
+	ServerError *ServerError   `json:"server_error,omitempty"`
+	Handshake   *HandshakeInfo `json:"handshake,omitempty"`
+	Hex         string         `json:"hex"`
+}
+
+/*
+readFollowUp reads whatever the server sends on conn within wait of the handshake, up to maxFollowUpBytes, and splits it into packets.
+Function-level comment: the read always takes the full wait unless the server closes the connection or fills the limit first; the deadline is cleared afterwards for the rest of the session.
+*/
+func readFollowUp(conn net.Conn, wait time.Duration) []AdditionalPacket {
+	buf := make([]byte, maxFollowUpBytes)
+	_ = conn.SetReadDeadline(time.Now().Add(wait))
+	got := 0
+	for got < len(buf) {
+		n, err := conn.Read(buf[got:])
+		got += n
+		if err != nil {
+			break
+		}
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	return splitFollowUp(buf[:got])
+}
+
+/*
+splitFollowUp frames b as consecutive MySQL packets, keeping anything from the first byte that does not frame (a short or oversized header, or a zero length) as one raw packet.
+*/
+func splitFollowUp(b []byte) []AdditionalPacket {
+	var pkts []AdditionalPacket
+	for len(b) > 0 && len(pkts) < maxFollowUpPackets {
+		n := 0
+		if len(b) >= 4 {
+			n = int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		}
+		if n == 0 || 4+n > len(b) {
+			pkts = append(pkts, AdditionalPacket{Length: len(b), Raw: true, Class: ClassifyFirstPacket(b), Hex: hex.EncodeToString(b[:min(len(b), followUpHexBytes)])})
+			break
+		}
+		p := b[:4+n]
+		seq := p[3]
+		ap := AdditionalPacket{Sequence: &seq, Length: n, Class: ClassifyFirstPacket(p), Hex: hex.EncodeToString(p[:min(len(p), followUpHexBytes)])}
+		switch p[4] {
+		case 0xff:
+			ap.ServerError, _ = parseErrPacket(p[4:])
+		case 0x0a:
+			ap.Handshake, _ = ParseHandshake(p)
+		}
+		pkts = append(pkts, ap)
+		b = b[4+n:]
+	}
+	return pkts
+}
@@ -0,0 +1,96 @@
+package mysqlprobe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+/*
+FrameError reports a packet that ended early: how many of its bytes arrived before the server closed the connection, the read went idle past its deadline, or the read failed otherwise.
+Want is the 4-byte header until it arrived, then the header plus the payload length it announced (capped at the buffer). Err is the underlying error, so errors.As still finds a net.Error.
+*/
+type FrameError struct {
+	Got  int
+	Want int
+	Err  error
+}
+
+func (e *FrameError) Error() string {
+	switch {
+	case e.Closed():
+		return fmt.Sprintf("connection closed after %d of %d bytes", e.Got, e.Want)
+	case e.Timeout():
+		return fmt.Sprintf("idle timeout after %d of %d bytes", e.Got, e.Want)
+	}
+	return fmt.Sprintf("%v after %d of %d bytes", e.Err, e.Got, e.Want)
+}
+
+func (e *FrameError) Unwrap() error { return e.Err }
+
+/*
+Closed reports whether the server closed the connection before the packet was complete.
+*/
+func (e *FrameError) Closed() bool {
+	return errors.Is(e.Err, io.EOF) || errors.Is(e.Err, io.ErrUnexpectedEOF)
+}
+
+/*
+Timeout reports whether the connection went idle past a read deadline before the packet was complete.
+*/
+func (e *FrameError) Timeout() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
+/*
+frameReader reads from conn with a fresh read deadline before every read.
+Function-level comment: the first byte may take up to timeout to arrive; each later read gets timeout too, but never past timeout after the first byte, so a server sending a byte just before each deadline cannot hold the reader indefinitely. It records how many reads returned data and when the first and last bytes arrived, the evidence for a tarpit.
+*/
+type frameReader struct {
+	conn        net.Conn
+	timeout     time.Duration
+	first, last time.Time
+	reads       int
+}
+
+func (r *frameReader) Read(p []byte) (int, error) {
+	deadline := time.Now().Add(r.timeout)
+	if !r.first.IsZero() && r.first.Add(r.timeout).Before(deadline) {
+		deadline = r.first.Add(r.timeout)
+	}
+	_ = r.conn.SetReadDeadline(deadline)
+	n, err := r.conn.Read(p)
+	if n > 0 {
+		r.last = time.Now()
+		if r.first.IsZero() {
+			r.first = r.last
+		}
+		r.reads++
+	}
+	return n, err
+}
+
+/*
+readFull reads exactly len(p) bytes with io.ReadFull's semantics: io.EOF if nothing was read, io.ErrUnexpectedEOF if the connection closed partway.
+Function-level comment: calling io.ReadFull itself would move the reader to the heap through the io.Reader interface, an allocation per probe.
+*/
+func (r *frameReader) readFull(p []byte) (int, error) {
+	got := 0
+	for got < len(p) {
+		n, err := r.Read(p[got:])
+		got += n
+		if err != nil {
+			if got >= len(p) {
+				return got, nil
+			}
+			if err == io.EOF && got > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return got, err
+		}
+	}
+	return got, nil
+}
@@ -0,0 +1,354 @@
+package mysqlprobe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// Capability flags from the MySQL client/server protocol that the probe inspects or sends (see mysqlclient).
+const (
+	ClientLongPassword     = mysqlclient.ClientLongPassword
+	ClientProtocol41       = mysqlclient.ClientProtocol41
+	ClientSSL              = mysqlclient.ClientSSL
+	ClientSecureConnection = mysqlclient.ClientSecureConnection
+	ClientPluginAuth       = mysqlclient.ClientPluginAuth
+)
+
+// Server error codes a server sends instead of the handshake when it refuses the client's host.
+const (
+	ErrHostIsBlocked     = 1129 // too many connection errors; unblock with FLUSH HOSTS
+	ErrHostNotPrivileged = 1130 // host is not allowed to connect
+)
+
+/*
+ServerError is an ERR packet the server sent in place of the handshake.
+*/
+type ServerError struct {
+	Code     uint16 `json:"code"`
+	SQLState string `json:"sql_state,omitempty"`
+	Message  string `json:"message"`
+}
+
+/*
+Error formats the server error as "server error <code>: <message>".
+*/
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error %d: %s", e.Code, e.Message)
+}
+
+/*
+HostRefused reports whether the server refused the client's host (ERR 1129 or 1130).
+*/
+func (e *ServerError) HostRefused() bool {
+	return e.Code == ErrHostIsBlocked || e.Code == ErrHostNotPrivileged
+}
+
+/*
+parseErrPacket decodes an ERR packet payload (starting at the 0xff marker) with mysqlclient.ParseErr.
+*/
+func parseErrPacket(p []byte) (*ServerError, error) {
+	e, err := mysqlclient.ParseErr(p)
+	return (*ServerError)(e), err
+}
+
+/*
+HandshakeInfo holds the fields we extract from the MySQL handshake packet.
+*/
+type HandshakeInfo struct {
+	ProtocolVersion  uint8        `json:"protocol"`
+	ServerVersion    string       `json:"server_version"`
+	ServerVersionHex string       `json:"server_version_hex,omitempty"`
+	Version          *VersionInfo `json:"version,omitempty"`
+	ConnectionID     uint32       `json:"connection_id"`
+	CapabilityFlags  uint32       `json:"capability_flags,omitempty"`
+	CharacterSet     uint8        `json:"character_set,omitempty"`
+	StatusFlags      uint16       `json:"status_flags,omitempty"`
+	AuthPluginName   string       `json:"auth_plugin,omitempty"`
+	AuthPluginData   string       `json:"auth_plugin_data,omitempty"`
+	SaltEntropy      *float64     `json:"salt_entropy,omitempty"`
+	RawFirstBytesHex string       `json:"preview_hex,omitempty"`
+	Anomalies        []Anomaly    `json:"anomalies,omitempty"`
+	Fingerprint      string       `json:"handshake_fingerprint,omitempty"`
+}
+
+/*
+setAuthPluginData records the handshake's auth-plugin-data (the scramble/salt) as hex with its entropy score.
+Function-level comment: entropy is Shannon entropy in bits per byte, rounded to 3 decimals; a healthy 20-byte random salt scores around 4, while constant or patterned salts score far lower.
+*/
+func (info *HandshakeInfo) setAuthPluginData(salt []byte) {
+	info.AuthPluginData = hex.EncodeToString(salt)
+	e := math.Round(shannonEntropy(salt)*1000) / 1000
+	info.SaltEntropy = &e
+}
+
+/*
+shannonEntropy computes the Shannon entropy of b in bits per byte.
+Function-level comment: returns 0 for empty input.
+*/
+func shannonEntropy(b []byte) float64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, c := range b {
+		counts[c]++
+	}
+	h := 0.0
+	for _, n := range counts {
+		if n == 0 {
+			continue
+		}
+		p := float64(n) / float64(len(b))
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+/*
+ReadWithDeadline reads into the provided buffer from conn, applying a read deadline.
+Function-level comment: sets a read deadline and performs a single Read call; returns bytes read or an error.
+*/
+func ReadWithDeadline(conn net.Conn, buf []byte, timeout time.Duration) (int, error) {
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	return conn.Read(buf)
+}
+
+// Parse errors are package-level values so a failed parse allocates nothing.
+var (
+	errNoHeader          = errors.New("short read (no packet header)")
+	errPayloadIncomplete = errors.New("short read (payload incomplete)")
+	errNoProtocol        = errors.New("payload too small for protocol version")
+	errServerVersion     = errors.New("server version parse error: unterminated string")
+	errNoConnectionID    = errors.New("payload too small for connection id")
+	errNoAuthData1       = errors.New("payload too small for auth data part 1")
+	errNoCapabilities    = errors.New("payload too small for capability flags (lower)")
+)
+
+/*
+Handshake is a parsed view over a raw handshake packet: fixed-size fields are decoded in place, while the server version, auth plugin name, and auth-plugin-data stay sub-slices of the packet and become strings only when asked for.
+It aliases the packet, so it is only valid while that buffer is (for Probe, until its pooled buffer is returned); call Info for a copy that outlives it.
+*/
+type Handshake struct {
+	ProtocolVersion uint8
+	ConnectionID    uint32
+	CapabilityFlags uint32
+	CharacterSet    uint8
+	StatusFlags     uint16
+
+	raw           []byte
+	extended      bool // the packet went past the lower capability flags (charset, status, upper flags)
+	serverVersion []byte
+	salt1, salt2  []byte
+	reserved      []byte
+	authPlugin    []byte
+
+	// Offsets into raw of the fields the anomaly checks inspect, 0 when the packet did not reach them.
+	fillerAt, charsetAt, authLenAt, reservedAt int
+	pluginAt, pluginUnterminated, trailingAt   int
+}
+
+/*
+ParseHandshakeView parses a full packet (header+payload) per MySQL protocol v10 without allocating.
+Function-level comment: it is defensive about truncated payloads: once the lower capability flags are read, a short packet yields the fields seen so far rather than an error. An ERR packet is returned as a *ServerError (the only allocating path).
+*/
+func ParseHandshakeView(b []byte) (Handshake, error) {
+	var h Handshake
+	if len(b) < 4 {
+		return h, errNoHeader
+	}
+	payloadLen := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	if len(b) < 4+payloadLen {
+		return h, errPayloadIncomplete
+	}
+	h.raw = b
+	p := b[4 : 4+payloadLen]
+
+	if len(p) < 1 {
+		return h, errNoProtocol
+	}
+	if p[0] == 0xff {
+		serr, err := parseErrPacket(p)
+		if err != nil {
+			return h, err
+		}
+		return h, serr
+	}
+	h.ProtocolVersion = p[0]
+	i := 1
+
+	end := bytes.IndexByte(p[i:], 0x00)
+	if end < 0 {
+		return h, errServerVersion
+	}
+	h.serverVersion = p[i : i+end]
+	i += end + 1
+
+	if i+4 > len(p) {
+		return h, errNoConnectionID
+	}
+	h.ConnectionID = binary.LittleEndian.Uint32(p[i : i+4])
+	i += 4
+
+	if i+8+1 > len(p) {
+		return h, errNoAuthData1
+	}
+	h.salt1 = p[i : i+8]
+	h.fillerAt = 4 + i + 8
+	i += 8 + 1
+
+	if i+2 > len(p) {
+		return h, errNoCapabilities
+	}
+	capLower := binary.LittleEndian.Uint16(p[i : i+2])
+	i += 2
+	h.CapabilityFlags = uint32(capLower)
+
+	if i+1+2+2 > len(p) {
+		return h, nil
+	}
+	h.CharacterSet = p[i]
+	h.charsetAt = 4 + i
+	h.StatusFlags = binary.LittleEndian.Uint16(p[i+1 : i+3])
+	capUpper := binary.LittleEndian.Uint16(p[i+3 : i+5])
+	i += 5
+	h.CapabilityFlags |= uint32(capUpper) << 16
+	h.extended = true
+
+	var authDataLen uint8
+	if h.CapabilityFlags&ClientPluginAuth != 0 {
+		if i >= len(p) {
+			return h, nil
+		}
+		authDataLen = p[i]
+		h.authLenAt = 4 + i
+		i++
+	} else if i < len(p) {
+		authDataLen = p[i]
+		h.authLenAt = 4 + i
+		i++
+	}
+
+	if i+10 <= len(p) {
+		h.reserved = p[i : i+10]
+		h.reservedAt = 4 + i
+		i += 10
+	}
+
+	if need := int(authDataLen) - 8; need > 0 && i < len(p) {
+		end := min(i+need, len(p))
+		part2 := p[i:end]
+		if len(part2) > 0 && part2[len(part2)-1] == 0x00 {
+			part2 = part2[:len(part2)-1]
+		}
+		h.salt2 = part2
+		i = end
+	}
+
+	if i < len(p) {
+		if end := bytes.IndexByte(p[i:], 0x00); end >= 0 {
+			h.authPlugin = p[i : i+end]
+			h.pluginAt = 4 + i
+			if i+end+1 < len(p) {
+				h.trailingAt = 4 + i + end + 1
+			}
+		} else if h.CapabilityFlags&ClientPluginAuth != 0 {
+			h.pluginUnterminated = 4 + i
+		}
+	}
+	return h, nil
+}
+
+/*
+ServerVersion returns the server version string as sent; Info sanitizes it (see SanitizeText).
+*/
+func (h *Handshake) ServerVersion() string {
+	return string(h.serverVersion)
+}
+
+/*
+AuthPluginName returns the announced auth plugin as sent, or "" when the packet did not carry one; Info sanitizes it.
+*/
+func (h *Handshake) AuthPluginName() string {
+	return string(h.authPlugin)
+}
+
+/*
+AppendAuthPluginData appends the auth-plugin-data (both scramble parts) to dst.
+*/
+func (h *Handshake) AppendAuthPluginData(dst []byte) []byte {
+	return append(append(dst, h.salt1...), h.salt2...)
+}
+
+/*
+Info copies the view into a HandshakeInfo that no longer references the packet, adding the derived fields (parsed version, anomalies, salt hex and entropy, fingerprint, preview hex).
+Function-level comment: the version string and auth plugin name are sanitized with SanitizeText, so output stays printable whatever the server sent; a version that had to be changed keeps its exact bytes in ServerVersionHex, and either one changed is reported as an anomaly.
+*/
+func (h *Handshake) Info() *HandshakeInfo {
+	info := &HandshakeInfo{
+		ProtocolVersion:  h.ProtocolVersion,
+		ConnectionID:     h.ConnectionID,
+		CapabilityFlags:  h.CapabilityFlags,
+		CharacterSet:     h.CharacterSet,
+		StatusFlags:      h.StatusFlags,
+		RawFirstBytesHex: hex.EncodeToString(h.raw[:min(len(h.raw), 64)]),
+	}
+	var versionChanged bool
+	info.ServerVersion, versionChanged = SanitizeText(h.serverVersion)
+	if versionChanged {
+		info.ServerVersionHex = hex.EncodeToString(h.serverVersion)
+	}
+	info.AuthPluginName, _ = SanitizeText(h.authPlugin)
+	info.Version = ParseServerVersion(info.ServerVersion)
+	info.Anomalies = h.anomalies(info)
+	var salt [64]byte
+	info.setAuthPluginData(h.AppendAuthPluginData(salt[:0]))
+	info.Fingerprint = h.fingerprint()
+	return info
+}
+
+/*
+ParseHandshake interprets the first MySQL packet and returns its fields as a HandshakeInfo.
+Function-level comment: parses with ParseHandshakeView and copies the result out with Info, so the packet buffer may be reused afterwards. An ERR packet is returned as a *ServerError.
+*/
+func ParseHandshake(b []byte) (*HandshakeInfo, error) {
+	h, err := ParseHandshakeView(b)
+	if err != nil {
+		return nil, err
+	}
+	return h.Info(), nil
+}
+
+/*
+grabFirstPacket reads the initial MySQL packet (header + payload) from conn.
+Function-level comment: reads the 4-byte MySQL packet header to determine payload length and then reads the payload into buf; returns raw header+payload (a prefix of buf), or a *FrameError when the packet ended early. A payload larger than buf is cut off at its end, which callers treat as not a handshake. See readFirstPacket for the deadlines.
+*/
+func grabFirstPacket(conn net.Conn, overallTimeout time.Duration, buf []byte) ([]byte, error) {
+	first, _, _, err := readFirstPacket(conn, overallTimeout, buf)
+	if err != nil {
+		return nil, err
+	}
+	return first, nil
+}
+
+/*
+Basic returns the subset of handshake fields kept in non-verbose output; anomalies are kept, since they are the point of looking.
+*/
+func (info *HandshakeInfo) Basic() *HandshakeInfo {
+	return &HandshakeInfo{
+		ProtocolVersion:  info.ProtocolVersion,
+		ServerVersion:    info.ServerVersion,
+		ServerVersionHex: info.ServerVersionHex,
+		Version:          info.Version,
+		ConnectionID:     info.ConnectionID,
+		Anomalies:        info.Anomalies,
+		Fingerprint:      info.Fingerprint,
+	}
+}
@@ -0,0 +1,49 @@
+package mysqlprobe
+
+import "testing"
+
+// testPayloads are first packets representative of what a scan meets; fails marks those that must not parse.
+var testPayloads = []struct {
+	name   string
+	packet []byte
+	fails  bool
+}{
+	{"mysql-8.0", testHandshake, false},
+	{"mysql-5.7", FakeHandshake("5.7.44-log", 0xc1ffffff, 33, "mysql_native_password"), false},
+	{"mariadb-10.11", FakeHandshake("5.5.5-10.11.6-MariaDB-0+deb12u1", 0xa0fff7fe, 45, "mysql_native_password"), false},
+	{"mysql-5.1-no-plugin-auth", FakeHandshake("5.1.73", 0x0000f7ff, 8, ""), false},
+	{"truncated", testHandshake[:4+1+7+4+9+2], true},
+	{"err-1130", FakeErrPacket(ErrHostNotPrivileged, "Host '192.0.2.1' is not allowed to connect to this MySQL server"), true},
+}
+
+/*
+BenchmarkParseHandshakeView parses each payload into a Handshake view.
+*/
+func BenchmarkParseHandshakeView(b *testing.B) {
+	for _, p := range testPayloads {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseHandshakeView(p.packet); (err != nil) != p.fails {
+					b.Fatalf("parse error %v, want failure %t", err, p.fails)
+				}
+			}
+		})
+	}
+}
+
+/*
+BenchmarkParseHandshake parses each payload into a full HandshakeInfo, as Probe does.
+*/
+func BenchmarkParseHandshake(b *testing.B) {
+	for _, p := range testPayloads {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseHandshake(p.packet); (err != nil) != p.fails {
+					b.Fatalf("parse error %v, want failure %t", err, p.fails)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package mysqlprobe
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModuleName is the name the MySQL module registers under, and the key zgrab2 files its results under.
+const ModuleName = "mysql"
+
+/*
+ScanStatus is the outcome of one scan, using zgrab2's status vocabulary.
+*/
+type ScanStatus string
+
+// Scan statuses reported by Scanner.Scan and Result.Status.
+const (
+	StatusSuccess           ScanStatus = "success"
+	StatusConnectionRefused ScanStatus = "connection-refused"
+	StatusConnectionTimeout ScanStatus = "connection-timeout"
+	StatusConnectionClosed  ScanStatus = "connection-closed"
+	StatusIOTimeout         ScanStatus = "io-timeout"
+	StatusProtocolError     ScanStatus = "protocol-error"
+	StatusApplicationError  ScanStatus = "application-error"
+	StatusUnknownError      ScanStatus = "unknown-error"
+)
+
+/*
+ScanTarget is one host to scan, shaped like zgrab2's target: an IP and/or a domain, with an optional per-target port.
+*/
+type ScanTarget struct {
+	IP     net.IP
+	Domain string
+	Port   *uint
+	Tag    string
+}
+
+/*
+ScanFlags is the flag set a module parses its options into.
+*/
+type ScanFlags interface {
+	Help() string
+	Validate(args []string) error
+}
+
+/*
+Scanner probes targets with one module's configuration.
+The method set matches zgrab2's Scanner so the MySQL module can be adapted into a zgrab2 build, or driven directly by other scanners.
+*/
+type Scanner interface {
+	Init(flags ScanFlags) error
+	InitPerSender(senderID int) error
+	GetName() string
+	GetTrigger() string
+	Protocol() string
+	Scan(t ScanTarget) (ScanStatus, any, error)
+}
+
+/*
+ScanModule creates flags and scanners for one protocol.
+*/
+type ScanModule interface {
+	NewFlags() any
+	NewScanner() Scanner
+	Description() string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ScanModule)
+)
+
+/*
+RegisterModule makes a module available under name.
+Function-level comment: like database/sql drivers, registering the same name twice or a nil module panics.
+*/
+func RegisterModule(name string, m ScanModule) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if m == nil {
+		panic("mysqlprobe: RegisterModule module is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("mysqlprobe: RegisterModule called twice for " + name)
+	}
+	registry[name] = m
+}
+
+/*
+LookupModule returns the module registered under name.
+*/
+func LookupModule(name string) (ScanModule, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	m, ok := registry[name]
+	return m, ok
+}
+
+/*
+ModuleNames lists the registered module names in sorted order.
+*/
+func ModuleNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterModule(ModuleName, new(Module))
+}
+
+/*
+Flags are the MySQL module's options; the struct tags follow the go-flags conventions zgrab2 parses module flags with.
+*/
+type Flags struct {
+	Name           string        `long:"name" description:"Name the results are filed under"`
+	Port           uint          `short:"p" long:"port" default:"3306" description:"TCP port to probe when the target has none"`
+	Timeout        time.Duration `short:"t" long:"timeout" default:"3s" description:"Dial/read timeout"`
+	Trigger        string        `long:"trigger" description:"Only scan targets carrying this tag"`
+	TLS            bool          `long:"tls" description:"When the server offers SSL, continue into TLS and record the certificate"`
+	TLSCert        string        `long:"tls-cert" description:"PEM client certificate to present when the server requests one (with --tls-key)"`
+	TLSKey         string        `long:"tls-key" description:"PEM private key for --tls-cert"`
+	TLSMinVersion  string        `long:"tls-min-version" description:"Lowest TLS version to offer (1.0, 1.1, 1.2, 1.3)"`
+	TLSMaxVersion  string        `long:"tls-max-version" description:"Highest TLS version to offer (1.0, 1.1, 1.2, 1.3)"`
+	TLSCiphers     string        `long:"tls-ciphers" description:"Comma-separated cipher suites to offer, up to TLS 1.2"`
+	SNI            string        `long:"sni" description:"Server name to send in the TLS ClientHello"`
+	ALPN           string        `long:"alpn" description:"Comma-separated ALPN protocols to offer"`
+	TLSWrapped     bool          `long:"tls-wrapped" description:"Retry silent servers, and servers sending TLS records, with a TLS handshake first (tls_wrapped)"`
+	DetectTLS      bool          `long:"detect-tls-policy" description:"Classify whether SSL-capable servers require TLS (tls_policy), with extra connections"`
+	Downgrade      bool          `long:"downgrade-test" description:"Try a pre-4.1 login with no client capabilities on an extra connection and record the answer (downgrade)"`
+	EnumPlugins    bool          `long:"enum-auth-plugins" description:"Advertise each common client auth plugin on an extra connection and record the answers (auth_plugin_matrix)"`
+	FollowUpWait   time.Duration `long:"follow-up-wait" description:"After a handshake, keep reading this long for unprompted packets (additional_packets)"`
+	BannerFallback bool          `long:"banner-fallback" description:"On non-MySQL responses, record a generic banner"`
+	Verbose        bool          `long:"verbose" description:"Keep the raw first bytes of unparseable responses"`
+}
+
+/*
+Help returns extra usage text for the module.
+*/
+func (f *Flags) Help() string {
+	return ""
+}
+
+/*
+Validate checks the flags after parsing; positional arguments are not accepted.
+*/
+func (f *Flags) Validate(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	if f.Port == 0 || f.Port > 65535 {
+		return fmt.Errorf("invalid port %d", f.Port)
+	}
+	if f.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	if (f.TLSCert == "") != (f.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must be given together")
+	}
+	if _, err := f.tlsPolicy(); err != nil {
+		return err
+	}
+	return nil
+}
+
+/*
+tlsPolicy parses the TLS version, cipher, SNI, and ALPN flags.
+*/
+func (f *Flags) tlsPolicy() (TLSPolicy, error) {
+	var p TLSPolicy
+	var err error
+	if p.MinVersion, err = ParseTLSVersion(f.TLSMinVersion); err != nil {
+		return p, fmt.Errorf("tls-min-version: %w", err)
+	}
+	if p.MaxVersion, err = ParseTLSVersion(f.TLSMaxVersion); err != nil {
+		return p, fmt.Errorf("tls-max-version: %w", err)
+	}
+	if p.CipherSuites, err = ParseCipherSuites(f.TLSCiphers); err != nil {
+		return p, fmt.Errorf("tls-ciphers: %w", err)
+	}
+	p.ServerName = f.SNI
+	for _, proto := range strings.Split(f.ALPN, ",") {
+		if proto = strings.TrimSpace(proto); proto != "" {
+			p.ALPN = append(p.ALPN, proto)
+		}
+	}
+	return p, nil
+}
+
+/*
+Module is the MySQL handshake prober as a pluggable scan module.
+*/
+type Module struct{}
+
+/*
+NewFlags returns the module's flags with their defaults filled in.
+*/
+func (m *Module) NewFlags() any {
+	return &Flags{Name: ModuleName, Port: 3306, Timeout: 3 * time.Second}
+}
+
+/*
+NewScanner returns an uninitialized MySQL scanner; call Init before Scan.
+*/
+func (m *Module) NewScanner() Scanner {
+	return new(MySQLScanner)
+}
+
+/*
+Description describes the module for usage output.
+*/
+func (m *Module) Description() string {
+	return "Read the MySQL server handshake (version, capabilities, auth plugin) and optionally continue into TLS"
+}
+
+/*
+MySQLScanner runs Probe against targets using the module's flags.
+*/
+type MySQLScanner struct {
+	config     *Flags
+	clientCert *tls.Certificate
+	tlsPolicy  TLSPolicy
+}
+
+/*
+Init stores the parsed flags, loads the client certificate, if any, and parses the TLS policy; flags must come from Module.NewFlags.
+*/
+func (s *MySQLScanner) Init(flags ScanFlags) error {
+	f, ok := flags.(*Flags)
+	if !ok {
+		return fmt.Errorf("mysqlprobe: unexpected flags type %T", flags)
+	}
+	s.config = f
+	if f.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(f.TLSCert, f.TLSKey)
+		if err != nil {
+			return fmt.Errorf("mysqlprobe: load client certificate: %w", err)
+		}
+		s.clientCert = &cert
+	}
+	policy, err := f.tlsPolicy()
+	if err != nil {
+		return fmt.Errorf("mysqlprobe: %w", err)
+	}
+	s.tlsPolicy = policy
+	return nil
+}
+
+/*
+InitPerSender is a no-op; the scanner keeps no per-goroutine state.
+*/
+func (s *MySQLScanner) InitPerSender(senderID int) error {
+	return nil
+}
+
+/*
+GetName returns the name results are filed under.
+*/
+func (s *MySQLScanner) GetName() string {
+	if s.config.Name == "" {
+		return ModuleName
+	}
+	return s.config.Name
+}
+
+/*
+GetTrigger returns the tag targets must carry to be scanned ("" for all).
+*/
+func (s *MySQLScanner) GetTrigger() string {
+	return s.config.Trigger
+}
+
+/*
+Protocol names the protocol the scanner speaks.
+*/
+func (s *MySQLScanner) Protocol() string {
+	return ModuleName
+}
+
+/*
+Scan probes one target and returns its status, a *Result, and the error behind a non-success status.
+Function-level comment: the target's own port wins over the configured one; the IP is preferred over the domain so a resolved target is not looked up again.
+*/
+func (s *MySQLScanner) Scan(t ScanTarget) (ScanStatus, any, error) {
+	port := s.config.Port
+	if t.Port != nil {
+		port = *t.Port
+	}
+	host := t.Domain
+	if t.IP != nil {
+		host = t.IP.String()
+	}
+	res := Probe(net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)), Options{
+		Timeout:              s.config.Timeout,
+		Verbose:              s.config.Verbose,
+		BannerFallback:       s.config.BannerFallback,
+		TLS:                  s.config.TLS,
+		ClientCert:           s.clientCert,
+		TLSPolicy:            s.tlsPolicy,
+		DetectTLSRequirement: s.config.DetectTLS,
+		DowngradeTest:        s.config.Downgrade,
+		TLSWrapped:           s.config.TLSWrapped,
+		EnumAuthPlugins:      s.config.EnumPlugins,
+		FollowUpWait:         s.config.FollowUpWait,
+	})
+	status, err := res.Status()
+	return status, &res, err
+}
+
+/*
+Status maps the result onto zgrab2's status vocabulary, with the error behind any non-success status.
+Function-level comment: something that answered but not as MySQL is a protocol-error, as zgrab2's mysql module would report it; an ERR packet in place of the handshake is an application-error.
+*/
+func (r *Result) Status() (ScanStatus, error) {
+	switch {
+	case r.ServerError != nil:
+		return StatusApplicationError, r.ServerError
+	case r.MySQL, r.XProtocol != nil && r.XProtocol.Detected:
+		return StatusSuccess, nil
+	case r.OK:
+		if r.Reason != "" {
+			return StatusProtocolError, errors.New(r.Reason)
+		}
+		return StatusProtocolError, errors.New("not a MySQL handshake")
+	}
+	e := r.Error
+	switch {
+	case strings.Contains(e, "connection refused"):
+		return StatusConnectionRefused, errors.New(e)
+	case strings.HasPrefix(e, "dial failed") && strings.Contains(e, "timeout"):
+		return StatusConnectionTimeout, errors.New(e)
+	case strings.HasPrefix(e, "read failed") && strings.Contains(e, "timeout"):
+		return StatusIOTimeout, errors.New(e)
+	case strings.Contains(e, "EOF"), strings.Contains(e, "connection reset"), strings.Contains(e, "connection closed"), e == "no data from server":
+		return StatusConnectionClosed, errors.New(e)
+	}
+	return StatusUnknownError, errors.New(e)
+}
@@ -0,0 +1,413 @@
+package mysqlprobe
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// X Protocol message types used by the probe (Mysqlx.ClientMessages / Mysqlx.ServerMessages).
+const (
+	xClientCapabilitiesGet = 1
+	xServerError           = 1
+	xServerCapabilities    = 2
+	xServerNotice          = 11
+)
+
+// maxXFrame bounds an X Protocol frame we are willing to read; capability lists are a few hundred bytes.
+const maxXFrame = 64 << 10
+
+// maxXNotices is how many notices (e.g. the server hello) are skipped before giving up on a capabilities reply.
+const maxXNotices = 4
+
+/*
+XInfo is what the X Protocol (mysqlx) endpoint advertised in reply to CapabilitiesGet.
+*/
+type XInfo struct {
+	Detected       bool           `json:"detected"`
+	TLS            bool           `json:"tls"`
+	AuthMechanisms []string       `json:"auth_mechanisms,omitempty"`
+	Capabilities   map[string]any `json:"capabilities,omitempty"`
+	ServerError    *ServerError   `json:"server_error,omitempty"`
+	Error          string         `json:"error,omitempty"`
+}
+
+/*
+ProbeX connects to an X Protocol endpoint at addr and records the capabilities it advertises.
+Function-level comment: the X Protocol client speaks first, so this sends Mysqlx.Connection.CapabilitiesGet, skips notices the server sends on connect, and decodes the Capabilities reply; an Mysqlx.Error reply still counts as detected. Dialing follows opts as Probe does; failures are reported in XInfo.Error.
+*/
+func ProbeX(addr string, opts Options) *XInfo {
+	conn, err := opts.Connect("tcp", addr)
+	if err != nil {
+		return &XInfo{Error: "dial failed: " + err.Error()}
+	}
+	defer conn.Close()
+	return probeXConn(conn, opts.Timeout)
+}
+
+/*
+probeXConn runs the CapabilitiesGet exchange of ProbeX on an open connection.
+*/
+func probeXConn(conn net.Conn, timeout time.Duration) *XInfo {
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{1, 0, 0, 0, xClientCapabilitiesGet}); err != nil {
+		return &XInfo{Error: "write failed: " + err.Error()}
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	for range maxXNotices + 1 {
+		typ, payload, err := readXFrame(conn)
+		if err != nil {
+			return &XInfo{Error: "read failed: " + err.Error()}
+		}
+		switch typ {
+		case xServerNotice:
+			continue
+		case xServerError:
+			serr, err := parseXError(payload)
+			if err != nil {
+				return &XInfo{Detected: true, Error: err.Error()}
+			}
+			return &XInfo{Detected: true, ServerError: serr}
+		case xServerCapabilities:
+			info, err := parseXCapabilities(payload)
+			if err != nil {
+				return &XInfo{Detected: true, Error: err.Error()}
+			}
+			return info
+		default:
+			return &XInfo{Error: fmt.Sprintf("unexpected X Protocol message type %d", typ)}
+		}
+	}
+	return &XInfo{Detected: true, Error: "no capabilities after server notices"}
+}
+
+// XModuleName is the name the X Protocol prober is registered under.
+const XModuleName = "mysqlx"
+
+/*
+xProber is ProbeX as a Prober, for targets given as mysqlx://host.
+*/
+type xProber struct {
+	timeout time.Duration
+}
+
+/*
+NewXProber returns the X Protocol Prober; it is registered as "mysqlx".
+*/
+func NewXProber(opts Options) Prober {
+	return &xProber{timeout: opts.Timeout}
+}
+
+/*
+Name returns "mysqlx".
+*/
+func (p *xProber) Name() string {
+	return XModuleName
+}
+
+/*
+DefaultPort returns the X Protocol's 33060.
+*/
+func (p *xProber) DefaultPort() int {
+	return 33060
+}
+
+/*
+Probe sends CapabilitiesGet on conn and reports the reply under Result.XProtocol.
+Function-level comment: an endpoint that answered as X Protocol (capabilities or an X error) is OK; anything else returns XInfo's error so the Result's status says why.
+*/
+func (p *xProber) Probe(_ context.Context, conn net.Conn) (Result, error) {
+	info := probeXConn(conn, p.timeout)
+	if !info.Detected {
+		return Result{XProtocol: info}, errors.New(info.Error)
+	}
+	return Result{OK: true, XProtocol: info}, nil
+}
+
+/*
+readXFrame reads one X Protocol frame: a 4-byte little-endian length (covering the type byte), the message type, and the protobuf payload.
+Function-level comment: an implausible length means the service is not speaking the X Protocol, e.g. a classic MySQL handshake arriving on the port.
+*/
+func readXFrame(conn net.Conn) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.LittleEndian.Uint32(header[:4])
+	if n == 0 || n > maxXFrame {
+		return 0, nil, fmt.Errorf("not an X Protocol frame (length %d)", n)
+	}
+	payload := make([]byte, n-1)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+/*
+parseXCapabilities decodes Mysqlx.Connection.Capabilities and lifts out TLS support and the authentication mechanisms.
+*/
+func parseXCapabilities(b []byte) (*XInfo, error) {
+	fields, err := parsePB(b)
+	if err != nil {
+		return nil, fmt.Errorf("capabilities: %w", err)
+	}
+	info := &XInfo{Detected: true, Capabilities: make(map[string]any)}
+	for _, f := range fields {
+		if f.num != 1 || f.wire != pbBytes {
+			continue
+		}
+		capFields, err := parsePB(f.data)
+		if err != nil {
+			return nil, fmt.Errorf("capability: %w", err)
+		}
+		var name string
+		var value any
+		for _, cf := range capFields {
+			switch {
+			case cf.num == 1 && cf.wire == pbBytes:
+				name = string(cf.data)
+			case cf.num == 2 && cf.wire == pbBytes:
+				if value, err = decodeXAny(cf.data); err != nil {
+					return nil, fmt.Errorf("capability %s: %w", name, err)
+				}
+			}
+		}
+		if name == "" {
+			continue
+		}
+		info.Capabilities[name] = value
+		switch name {
+		case "tls":
+			info.TLS, _ = value.(bool)
+		case "authentication.mechanisms":
+			list, _ := value.([]any)
+			for _, m := range list {
+				if s, ok := m.(string); ok {
+					info.AuthMechanisms = append(info.AuthMechanisms, s)
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+/*
+parseXError decodes Mysqlx.Error into a ServerError (code, sql_state, msg).
+*/
+func parseXError(b []byte) (*ServerError, error) {
+	fields, err := parsePB(b)
+	if err != nil {
+		return nil, fmt.Errorf("error message: %w", err)
+	}
+	serr := &ServerError{}
+	for _, f := range fields {
+		switch {
+		case f.num == 2 && f.wire == pbVarint:
+			serr.Code = uint16(f.varint)
+		case f.num == 3 && f.wire == pbBytes:
+			serr.Message = string(f.data)
+		case f.num == 4 && f.wire == pbBytes:
+			serr.SQLState = string(f.data)
+		}
+	}
+	return serr, nil
+}
+
+// Mysqlx.Datatypes.Any and Scalar type tags.
+const (
+	xAnyScalar = 1
+	xAnyObject = 2
+	xAnyArray  = 3
+
+	xScalarSint   = 1
+	xScalarUint   = 2
+	xScalarNull   = 3
+	xScalarOctets = 4
+	xScalarDouble = 5
+	xScalarFloat  = 6
+	xScalarBool   = 7
+	xScalarString = 8
+)
+
+/*
+decodeXAny converts a Mysqlx.Datatypes.Any into a JSON-friendly Go value: scalars become numbers, bools, strings, or nil; arrays []any; objects map[string]any.
+*/
+func decodeXAny(b []byte) (any, error) {
+	fields, err := parsePB(b)
+	if err != nil {
+		return nil, err
+	}
+	var typ uint64
+	var body []byte
+	for _, f := range fields {
+		if f.num == 1 && f.wire == pbVarint {
+			typ = f.varint
+		} else if f.wire == pbBytes && f.num >= 2 && f.num <= 4 {
+			body = f.data
+		}
+	}
+	switch typ {
+	case xAnyScalar:
+		return decodeXScalar(body)
+	case xAnyArray:
+		items, err := parsePB(body)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]any, 0, len(items))
+		for _, it := range items {
+			if it.num != 1 || it.wire != pbBytes {
+				continue
+			}
+			v, err := decodeXAny(it.data)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case xAnyObject:
+		flds, err := parsePB(body)
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]any, len(flds))
+		for _, fld := range flds {
+			if fld.num != 1 || fld.wire != pbBytes {
+				continue
+			}
+			kv, err := parsePB(fld.data)
+			if err != nil {
+				return nil, err
+			}
+			var key string
+			var val any
+			for _, e := range kv {
+				switch {
+				case e.num == 1 && e.wire == pbBytes:
+					key = string(e.data)
+				case e.num == 2 && e.wire == pbBytes:
+					if val, err = decodeXAny(e.data); err != nil {
+						return nil, err
+					}
+				}
+			}
+			obj[key] = val
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unknown Any type %d", typ)
+}
+
+/*
+decodeXScalar converts a Mysqlx.Datatypes.Scalar; octets and strings keep only their value, dropping content type and collation.
+*/
+func decodeXScalar(b []byte) (any, error) {
+	fields, err := parsePB(b)
+	if err != nil {
+		return nil, err
+	}
+	var typ uint64
+	for _, f := range fields {
+		if f.num == 1 && f.wire == pbVarint {
+			typ = f.varint
+		}
+	}
+	for _, f := range fields {
+		switch {
+		case typ == xScalarSint && f.num == 2 && f.wire == pbVarint:
+			return int64(f.varint>>1) ^ -int64(f.varint&1), nil
+		case typ == xScalarUint && f.num == 3 && f.wire == pbVarint:
+			return f.varint, nil
+		case typ == xScalarDouble && f.num == 6 && f.wire == pbFixed64:
+			return math.Float64frombits(f.varint), nil
+		case typ == xScalarFloat && f.num == 7 && f.wire == pbFixed32:
+			return float64(math.Float32frombits(uint32(f.varint))), nil
+		case typ == xScalarBool && f.num == 8 && f.wire == pbVarint:
+			return f.varint != 0, nil
+		case (typ == xScalarOctets && f.num == 5 || typ == xScalarString && f.num == 9) && f.wire == pbBytes:
+			inner, err := parsePB(f.data)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range inner {
+				if v.num == 1 && v.wire == pbBytes {
+					return string(v.data), nil
+				}
+			}
+			return "", nil
+		}
+	}
+	if typ == xScalarNull {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("scalar type %d without a value", typ)
+}
+
+// Protobuf wire types.
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+	pbFixed32 = 5
+)
+
+/*
+pbField is one decoded protobuf field: varint and fixed-width values in varint, length-delimited ones in data.
+*/
+type pbField struct {
+	num    int
+	wire   int
+	varint uint64
+	data   []byte
+}
+
+var errPBTruncated = errors.New("truncated protobuf message")
+
+/*
+parsePB splits a protobuf message into its fields without a schema; data slices alias b.
+*/
+func parsePB(b []byte) ([]pbField, error) {
+	var fields []pbField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errPBTruncated
+		}
+		b = b[n:]
+		f := pbField{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case pbVarint:
+			if f.varint, n = binary.Uvarint(b); n <= 0 {
+				return nil, errPBTruncated
+			}
+			b = b[n:]
+		case pbFixed64:
+			if len(b) < 8 {
+				return nil, errPBTruncated
+			}
+			f.varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case pbFixed32:
+			if len(b) < 4 {
+				return nil, errPBTruncated
+			}
+			f.varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case pbBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, errPBTruncated
+			}
+			f.data, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", f.wire)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
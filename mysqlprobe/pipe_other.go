@@ -0,0 +1,19 @@
+//go:build !windows
+
+package mysqlprobe
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// CanDialPipe reports whether DialPipe can reach named pipes on this platform.
+const CanDialPipe = false
+
+/*
+DialPipe is not available on this platform: named pipes are a Windows transport.
+*/
+func DialPipe(path string, timeout time.Duration) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package mysqlprobe
+
+import (
+	"net"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// CanDialPipe reports whether DialPipe can reach named pipes on this platform.
+const CanDialPipe = true
+
+/*
+DialPipe connects to the named pipe at path (e.g. \\.\pipe\MySQL), waiting up to timeout for a free pipe instance.
+Function-level comment: the connection supports deadlines like a TCP one, so Probe and the prober read from it unchanged; use it as Options.Dial for servers started with --named-pipe.
+*/
+func DialPipe(path string, timeout time.Duration) (net.Conn, error) {
+	return winio.DialPipe(path, &timeout)
+}
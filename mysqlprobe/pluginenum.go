@@ -0,0 +1,107 @@
+package mysqlprobe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// Outcomes of one auth plugin attempt, reported in PluginAttempt.Outcome.
+const (
+	PluginAccepted     = "accepted"
+	PluginMoreData     = "more_data"
+	PluginSwitched     = "auth_switch"
+	PluginError        = "error"
+	PluginDisconnected = "disconnected"
+)
+
+// pluginCheckUser is the account the plugin attempts name; it is not expected to exist.
+const pluginCheckUser = "mysqlprobe_plugin_check"
+
+// enumAuthPlugins are the client plugins EnumAuthPlugins advertises, one connection each: MySQL's built-in ones, MariaDB's ed25519, and the dialog plugin PAM logins use.
+var enumAuthPlugins = []string{mysqlclient.NativePasswordPlugin, mysqlclient.CachingSHA2Plugin, mysqlclient.SHA256PasswordPlugin, mysqlclient.ClearPasswordPlugin, "client_ed25519", "dialog"}
+
+/*
+PluginMatrix is how a server answered HandshakeResponses advertising each client auth plugin.
+Supported lists the plugins the server carried on with instead of switching away from: it let the client in, asked for more data, or judged the credentials.
+*/
+type PluginMatrix struct {
+	Plugins   []PluginAttempt `json:"plugins,omitempty"`
+	Supported []string        `json:"supported,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+/*
+PluginAttempt is the server's first answer to a HandshakeResponse advertising Plugin.
+*/
+type PluginAttempt struct {
+	Plugin      string       `json:"plugin"`
+	Outcome     string       `json:"outcome,omitempty"`
+	SwitchTo    string       `json:"switch_to,omitempty"`
+	ServerError *ServerError `json:"server_error,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+/*
+enumeratePlugins advertises each of enumAuthPlugins in turn, on connections of their own, and records the server's answer to each.
+Function-level comment: every attempt logs in as pluginCheckUser with an empty auth response, so no credentials are needed or sent. Servers pick the plugin by account, and for unknown accounts most pretend one exists with the default plugin, so the matrix shows which plugins the server is willing to continue with for that account, not every plugin it has loaded. A server without CLIENT_PLUGIN_AUTH cannot be asked.
+*/
+func enumeratePlugins(addr string, info *HandshakeInfo, opts Options) *PluginMatrix {
+	if info.CapabilityFlags&ClientPluginAuth == 0 {
+		return &PluginMatrix{Error: "server does not support CLIENT_PLUGIN_AUTH"}
+	}
+	m := &PluginMatrix{}
+	for _, plugin := range enumAuthPlugins {
+		a := tryPlugin(addr, plugin, opts)
+		if a.Outcome == PluginAccepted || a.Outcome == PluginMoreData || (a.Outcome == PluginSwitched && a.SwitchTo == plugin) ||
+			(a.ServerError != nil && a.ServerError.Class() == ErrorClassAccessDenied) {
+			m.Supported = append(m.Supported, plugin)
+		}
+		m.Plugins = append(m.Plugins, a)
+	}
+	return m
+}
+
+/*
+tryPlugin sends one HandshakeResponse41 advertising plugin and classifies the first packet the server answers with.
+*/
+func tryPlugin(addr, plugin string, opts Options) PluginAttempt {
+	a := PluginAttempt{Plugin: plugin}
+	conn, hs, err := dialHandshake(addr, opts)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer conn.Close()
+	pc := mysqlclient.NewConn(conn, 1, opts.Timeout)
+	if err := pc.WritePacket(mysqlclient.HandshakeResponse41(hs.CapabilityFlags, false, pluginCheckUser, nil, plugin)); err != nil {
+		a.Error = "write failed: " + err.Error()
+		return a
+	}
+	p, err := pc.ReadPacket()
+	switch {
+	case err != nil && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)):
+		a.Outcome = PluginDisconnected
+	case err != nil:
+		a.Error = "read failed: " + err.Error()
+	case p[0] == mysqlclient.HeaderOK:
+		a.Outcome = PluginAccepted
+	case p[0] == mysqlclient.HeaderMoreData:
+		a.Outcome = PluginMoreData
+	case p[0] == mysqlclient.HeaderAuthSwitch:
+		a.Outcome = PluginSwitched
+		sw, _ := mysqlclient.ParseAuthSwitch(p)
+		a.SwitchTo = sw.Plugin
+	case p[0] == mysqlclient.HeaderErr:
+		a.Outcome = PluginError
+		if a.ServerError, err = parseErrPacket(p); err != nil {
+			a.Error = err.Error()
+		}
+	default:
+		a.Error = fmt.Sprintf("unexpected packet 0x%02x", p[0])
+	}
+	return a
+}
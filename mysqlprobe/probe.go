@@ -0,0 +1,302 @@
+/*
+Package mysqlprobe reads and classifies the initial handshake of MySQL-compatible servers.
+It is used directly through Probe, plugged into a multi-protocol scanner through Module, or driven as one Prober among others.
+*/
+package mysqlprobe
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+Result is what a single probe learned about a TCP service.
+The handshake fields are flattened into the top level of the JSON when MySQL was detected.
+*/
+type Result struct {
+	OK    bool `json:"ok"`
+	MySQL bool `json:"mysql"`
+	// Confidence scores a MySQL detection by the protocol invariants the handshake met (see DetectionConfidence).
+	Confidence    float64      `json:"confidence,omitempty"`
+	Error         string       `json:"error,omitempty"`
+	Reason        string       `json:"reason,omitempty"`
+	FirstBytesHex string       `json:"first_bytes_hex,omitempty"`
+	GenericBanner string       `json:"generic_banner,omitempty"`
+	BannerProbe   string       `json:"banner_probe,omitempty"`
+	ServerError   *ServerError `json:"server_error,omitempty"`
+	// ServerErrorClass is ServerError's class (see ErrorClass), e.g. host_blocked or too_many_connections.
+	ServerErrorClass string `json:"server_error_class,omitempty"`
+	// FirstPacketClass labels a first packet that was not a handshake (see ClassifyFirstPacket), or FirstPacketTarpit.
+	FirstPacketClass string `json:"first_packet_class,omitempty"`
+	// Tarpit is set when the first packet stalled after its first bytes or dripped in slowly (see readFirstPacket).
+	Tarpit *TarpitInfo `json:"tarpit,omitempty"`
+	// Truncated is set when the first packet's header announced more payload than the buffer holds (see Options.Buffers); the packet was read up to the limit, and AdvertisedLength is the payload length the header announced.
+	Truncated        bool `json:"truncated,omitempty"`
+	AdvertisedLength int  `json:"advertised_length,omitempty"`
+	// AdditionalPackets holds what the server sent unprompted after its handshake, with Options.FollowUpWait.
+	AdditionalPackets []AdditionalPacket `json:"additional_packets,omitempty"`
+	*HandshakeInfo
+	TLS *TLSInfo `json:"tls,omitempty"`
+	// TLSWrapped is set when the port speaks TLS from the first byte, without an SSLRequest: confirmed by a handshake with Options.TLSWrapped (TLS then holds the certificate), or inferred from TLS records sent unprompted.
+	TLSWrapped bool `json:"tls_wrapped,omitempty"`
+	// TLSRequirement is set by Options.DetectTLSRequirement: TLSOptional, TLSRequired, or TLSUnsupported, with the observation behind it.
+	TLSRequirement         string    `json:"tls_policy,omitempty"`
+	TLSRequirementEvidence string    `json:"tls_policy_evidence,omitempty"`
+	Auth                   *AuthInfo `json:"auth,omitempty"`
+	// Downgrade is set by Options.DowngradeTest: how the server answered a client with minimal capabilities.
+	Downgrade *DowngradeInfo `json:"downgrade,omitempty"`
+	// AuthPlugins is set by Options.EnumAuthPlugins: the server's answer to each client auth plugin.
+	AuthPlugins *PluginMatrix `json:"auth_plugin_matrix,omitempty"`
+	// XProtocol is set by the mysqlx prober: what an X Protocol endpoint advertised.
+	XProtocol *XInfo `json:"mysqlx,omitempty"`
+	// Timing traces the probe's stages with Options.Verbose: connect, first_byte, banner_complete, and tls, plus dns when the caller resolved a name (see TimingPhase).
+	Timing []TimingPhase `json:"timing,omitempty"`
+	// RawHandshake is the handshake packet as received, header included, with Options.KeepRawHandshake; it is not part of the JSON.
+	RawHandshake []byte `json:"-"`
+}
+
+/*
+Options carries the per-probe settings.
+*/
+type Options struct {
+	Timeout        time.Duration
+	Verbose        bool
+	BannerFallback bool
+	TLS            bool
+	// ClientCert is presented when a server continued into TLS requests a client certificate (mutual TLS); nil sends none.
+	ClientCert *tls.Certificate
+	// TLSPolicy limits the TLS versions and cipher suites offered and sets SNI and ALPN; the zero value offers Go's defaults with neither.
+	TLSPolicy TLSPolicy
+	// TLSWrapped retries a server that stays silent or sends TLS records with a TLS handshake first, for proxies that wrap the port in TLS (see Result.TLSWrapped).
+	TLSWrapped bool
+	Socket     SocketOptions
+	// Dial, when set, opens the probe's TCP connection instead of Socket.Dial (e.g. through a tunnel); the conn must support read deadlines.
+	Dial func(network, address string, timeout time.Duration) (net.Conn, error)
+	// ProxyProtocol, when ProxyProtocolV1 or ProxyProtocolV2, prepends a PROXY protocol header to every TCP connection, for load balancer frontends that drop connections without one.
+	ProxyProtocol int
+	// DetectTLSRequirement classifies whether the server requires TLS by trying it and a plaintext login on connections of their own (see Result.TLSRequirement).
+	DetectTLSRequirement bool
+	// DowngradeTest logs in with a pre-4.1 client and no capabilities on a connection of its own, to find servers still taking the legacy 3.20 path (see Result.Downgrade).
+	DowngradeTest bool
+	// EnumAuthPlugins advertises each common client auth plugin in a HandshakeResponse of its own, without credentials (see Result.AuthPlugins).
+	EnumAuthPlugins bool
+	// FollowUpWait, when positive, keeps reading this long after a handshake for packets the server sends unprompted, before any TLS or login (see Result.AdditionalPackets).
+	FollowUpWait time.Duration
+	// Credentials, when set, switch on authenticated mode: after the handshake (and TLS, if negotiated) the probe logs in and collects server status.
+	Credentials *Credentials
+	// Variables names allowlisted server variables (see ParseQueryVariables) to read once logged in.
+	Variables []string
+	// EnumSchemas lists the schemas visible to the logged-in user, redacted per SchemaRedaction (RedactNone, RedactHash, RedactCount).
+	EnumSchemas     bool
+	SchemaRedaction string
+	// KeepRawHandshake copies a parsed handshake packet into Result.RawHandshake, e.g. to build a corpus of them.
+	KeepRawHandshake bool
+	// Buffers supplies the first-packet buffer, whose size less the 4-byte header is the largest payload read in full (see Result.Truncated); nil uses a shared pool of DefaultCaptureBytes buffers.
+	Buffers *BufferPool
+}
+
+/*
+Connect opens a connection the way the probes do: with Dial when set, otherwise with the socket options, and within Timeout.
+Function-level comment: with ProxyProtocol, a TCP connection starts with the PROXY protocol header before the caller sees it.
+*/
+func (o Options) Connect(network, addr string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if o.Dial != nil {
+		conn, err = o.Dial(network, addr, o.Timeout)
+	} else {
+		conn, err = o.Socket.Dial(network, addr, o.Timeout)
+	}
+	if err != nil || o.ProxyProtocol == 0 || network != "tcp" {
+		return conn, err
+	}
+	if err := sendProxyHeader(o.ProxyProtocol, conn, o.Timeout); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+/*
+Probe connects to addr and classifies the service from its first packet.
+Function-level comment: dials TCP, reads and parses the initial handshake (optionally continuing into TLS and, with Credentials, logging in), and falls back to a generic banner grab when enabled; failures are reported inside the Result rather than returned, including an ERR packet sent in place of the handshake (ServerError). The full handshake is returned; callers trim it for non-verbose output.
+*/
+func Probe(addr string, opts Options) Result {
+	return ProbeWith(context.Background(), NewMySQLProber(opts), addr, opts)
+}
+
+/*
+mysqlProber is the MySQL handshake probe as a Prober.
+*/
+type mysqlProber struct {
+	opts Options
+}
+
+/*
+NewMySQLProber returns the MySQL Prober configured by opts; it is registered as "mysql".
+*/
+func NewMySQLProber(opts Options) Prober {
+	return &mysqlProber{opts: opts}
+}
+
+/*
+Name returns "mysql".
+*/
+func (p *mysqlProber) Name() string {
+	return ModuleName
+}
+
+/*
+DefaultPort returns MySQL's 3306.
+*/
+func (p *mysqlProber) DefaultPort() int {
+	return 3306
+}
+
+/*
+Probe reads and classifies the first packet on conn, as described for Probe.
+Function-level comment: an error is returned only when nothing at all was learned (the read failed or the server sent nothing); everything else, including non-MySQL answers, is a Result. Reading the first packet, parsing it, and the TLS continuation are traced as children of the span in ctx, if any, and with Options.Verbose timed into Result.Timing.
+*/
+func (p *mysqlProber) Probe(ctx context.Context, conn net.Conn) (res Result, err error) {
+	opts := p.opts
+	buf := opts.Buffers.get()
+	defer opts.Buffers.put(buf)
+	_, span := startSpan(ctx, "mysqlprobe.read_first_packet")
+	readStart := time.Now()
+	first, tarpit, firstByte, err := readFirstPacket(conn, opts.Timeout, *buf)
+	span.SetAttributes(attribute.Int("mysql.first_packet.bytes", len(first)))
+	endSpan(span, err)
+	if opts.Verbose {
+		read := readPhases(readStart, firstByte, time.Now(), err)
+		// Prepended on every return, ahead of the tls stage continueSession records.
+		defer func() { res.Timing = append(read, res.Timing...) }()
+	}
+	if opts.TLSWrapped && tlsWrappedCandidate(first, err) {
+		if res, ok := probeTLSWrapped(conn.RemoteAddr().String(), opts); ok {
+			return res, nil
+		}
+	}
+	if tarpit != nil && !tarpit.Complete {
+		res := Result{OK: true, Reason: "tarpit: " + tarpit.Evidence, FirstPacketClass: FirstPacketTarpit, Tarpit: tarpit}
+		if opts.Verbose {
+			res.FirstBytesHex = hex.EncodeToString(first[:min(len(first), 64)])
+		}
+		return res, nil
+	}
+	if len(first) < 4 {
+		if opts.BannerFallback {
+			if banner, probe := grabGenericBanner(conn, first, opts.Timeout); len(banner) > 0 {
+				return Result{OK: true, GenericBanner: PrintableBanner(banner), BannerProbe: probe}, nil
+			}
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("read failed: %w", err)
+		}
+		return Result{}, errors.New("no data from server")
+	}
+
+	_, span = startSpan(ctx, "mysqlprobe.parse")
+	info, perr := ParseHandshake(first)
+	if info != nil {
+		span.SetAttributes(attribute.String("mysql.server_version", info.ServerVersion))
+	}
+	endSpan(span, perr)
+	advertised, truncated := truncatedPacket(first, len(*buf))
+	if truncated {
+		perr = truncationError(advertised, len(first)-4)
+	} else if err != nil {
+		perr = err
+	}
+	var serr *ServerError
+	if errors.As(perr, &serr) {
+		return Result{OK: true, Reason: serr.Error(), ServerError: serr, ServerErrorClass: serr.Class(), FirstPacketClass: FirstPacketErr, Tarpit: tarpit}, nil
+	}
+	if perr != nil {
+		res := Result{OK: true, FirstPacketClass: ClassifyFirstPacket(first), Tarpit: tarpit, Truncated: truncated}
+		if truncated {
+			res.AdvertisedLength = advertised
+		}
+		res.TLSWrapped = res.FirstPacketClass == FirstPacketTLSAlert || res.FirstPacketClass == FirstPacketTLSHandshake
+		if opts.BannerFallback {
+			banner, _ := grabGenericBanner(conn, first, opts.Timeout)
+			res.Reason = perr.Error()
+			res.GenericBanner = PrintableBanner(banner)
+			res.FirstPacketClass = ClassifyFirstPacket(banner)
+		} else if opts.Verbose {
+			res.Reason = perr.Error()
+			res.FirstBytesHex = hex.EncodeToString(first[:min(len(first), 64)])
+		}
+		return res, nil
+	}
+
+	res = Result{OK: true, MySQL: true, HandshakeInfo: info, Confidence: DetectionConfidence(first, info), Tarpit: tarpit}
+	if opts.KeepRawHandshake {
+		// first is in a pooled buffer that is reused once Probe returns.
+		res.RawHandshake = bytes.Clone(first)
+	}
+	if opts.FollowUpWait > 0 {
+		res.AdditionalPackets = readFollowUp(conn, opts.FollowUpWait)
+	}
+	continueSession(ctx, conn, info, &res, opts)
+	return res, nil
+}
+
+/*
+FollowUp runs the checks that need connections of their own (DetectTLSRequirement, DowngradeTest, EnumAuthPlugins) against the MySQL server at addr, recording them in res.
+Function-level comment: ProbeWith runs it for the MySQL prober once the probe's own connection is closed. Callers that hold connections to limits can instead probe with those options off and call FollowUp themselves, with a Dial that waits for the limits.
+*/
+func FollowUp(addr string, res *Result, opts Options) {
+	info := res.HandshakeInfo
+	if info == nil {
+		return
+	}
+	if opts.DetectTLSRequirement {
+		res.TLSRequirement, res.TLSRequirementEvidence = detectTLSRequirement(addr, info, res, opts)
+	}
+	if opts.DowngradeTest {
+		res.Downgrade = testDowngrade(addr, opts)
+	}
+	if opts.EnumAuthPlugins {
+		res.AuthPlugins = enumeratePlugins(addr, info, opts)
+	}
+}
+
+/*
+continueSession takes a parsed handshake on conn into TLS and, with Credentials, a login, as opts ask, recording both in res; the TLS handshake gets a span under ctx's.
+*/
+func continueSession(ctx context.Context, conn net.Conn, info *HandshakeInfo, res *Result, opts Options) {
+	session, seq := conn, byte(1)
+	if opts.TLS && info.CapabilityFlags&ClientSSL != 0 {
+		var tc net.Conn
+		_, span := startSpan(ctx, "mysqlprobe.tls")
+		start := time.Now()
+		tc, res.TLS = continueTLS(conn, info, opts.Timeout, opts.ClientCert, opts.TLSPolicy)
+		if opts.Verbose {
+			res.Timing = append(res.Timing, NewTimingPhase(PhaseTLS, start, time.Now(), tc == nil))
+		}
+		var terr error
+		if res.TLS != nil && res.TLS.Error != "" {
+			terr = errors.New(res.TLS.Error)
+		} else if res.TLS != nil {
+			span.SetAttributes(attribute.String("tls.protocol.version", res.TLS.Version))
+		}
+		endSpan(span, terr)
+		if tc == nil {
+			if opts.Credentials != nil {
+				res.Auth = &AuthInfo{User: opts.Credentials.User, Error: "not attempted: TLS handshake failed"}
+			}
+			return
+		}
+		session, seq = tc, 2
+	}
+	if opts.Credentials != nil {
+		res.Auth = authenticatedSession(session, seq, info, session != conn, opts)
+	}
+}
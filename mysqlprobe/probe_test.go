@@ -0,0 +1,102 @@
+package mysqlprobe
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*
+fakeServer starts a FakeServer answering every connection with pkt.
+Function-level comment: returns the listen address and a func that shuts the server down.
+*/
+func fakeServer(tb testing.TB, pkt []byte) (string, func()) {
+	srv, err := StartFakeServer(pkt)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv.Addr(), func() { srv.Close() }
+}
+
+/*
+BenchmarkProbe runs the full dial/read/parse pipeline against loopback servers sending a MySQL 8.0 handshake and refusing the host with ERR 1130.
+*/
+func BenchmarkProbe(b *testing.B) {
+	servers := []struct {
+		name  string
+		first []byte
+		ok    func(Result) bool
+	}{
+		{"loopback", testHandshake, func(r Result) bool { return r.MySQL }},
+		{"loopback-err", FakeErrPacket(ErrHostNotPrivileged, "Host '127.0.0.1' is not allowed to connect to this MySQL server"), func(r Result) bool { return r.ServerError != nil }},
+	}
+	for _, s := range servers {
+		b.Run(s.name, func(b *testing.B) {
+			addr, stop := fakeServer(b, s.first)
+			defer stop()
+			opts := Options{Timeout: time.Second}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if res := Probe(addr, opts); !s.ok(res) {
+					b.Fatalf("unexpected result: %+v", res)
+				}
+			}
+		})
+	}
+}
+
+/*
+countingConn reports its Close to the dialer that opened it.
+*/
+type countingConn struct {
+	net.Conn
+	closed func()
+	once   sync.Once
+}
+
+/*
+Close reports the first close to the dialer.
+*/
+func (c *countingConn) Close() error {
+	c.once.Do(c.closed)
+	return c.Conn.Close()
+}
+
+/*
+TestFollowUpAfterClose checks that the checks needing connections of their own only dial once the probe's connection is closed.
+*/
+func TestFollowUpAfterClose(t *testing.T) {
+	addr, stop := fakeServer(t, testHandshake)
+	defer stop()
+	var mu sync.Mutex
+	open, peak, dials := 0, 0, 0
+	opts := Options{Timeout: time.Second, DetectTLSRequirement: true, DowngradeTest: true, EnumAuthPlugins: true}
+	opts.Dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		conn, err := net.DialTimeout(network, address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		open++
+		dials++
+		peak = max(peak, open)
+		mu.Unlock()
+		return &countingConn{Conn: conn, closed: func() {
+			mu.Lock()
+			open--
+			mu.Unlock()
+		}}, nil
+	}
+	res := Probe(addr, opts)
+	if !res.MySQL || res.Downgrade == nil || res.AuthPlugins == nil {
+		t.Fatalf("follow-up checks did not run: %+v", res)
+	}
+	if dials < 3 {
+		t.Errorf("%d connections, want the probe's and the checks' own", dials)
+	}
+	if peak != 1 {
+		t.Errorf("%d connections open at once, want 1", peak)
+	}
+}
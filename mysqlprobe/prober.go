@@ -0,0 +1,110 @@
+package mysqlprobe
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+/*
+Prober speaks one protocol over a connection the caller has opened.
+Probe reports what the service said as a Result, using the generic fields (OK, GenericBanner, Reason) when the protocol is not MySQL; it returns an error only when nothing was learned, which callers record as the Result's Error. The context is cancelled when the caller gives up, which also interrupts blocked reads.
+*/
+type Prober interface {
+	Name() string
+	DefaultPort() int
+	Probe(ctx context.Context, conn net.Conn) (Result, error)
+}
+
+/*
+ProberFactory returns a Prober configured with the scan's options (timeouts, TLS, credentials) for one target.
+*/
+type ProberFactory func(opts Options) Prober
+
+var (
+	proberMu sync.RWMutex
+	probers  = make(map[string]ProberFactory)
+)
+
+/*
+RegisterProber makes a prober available under name.
+Function-level comment: as with RegisterModule, registering the same name twice or a nil factory panics.
+*/
+func RegisterProber(name string, f ProberFactory) {
+	proberMu.Lock()
+	defer proberMu.Unlock()
+	if f == nil {
+		panic("mysqlprobe: RegisterProber factory is nil")
+	}
+	if _, dup := probers[name]; dup {
+		panic("mysqlprobe: RegisterProber called twice for " + name)
+	}
+	probers[name] = f
+}
+
+/*
+LookupProber returns the factory registered under name.
+*/
+func LookupProber(name string) (ProberFactory, bool) {
+	proberMu.RLock()
+	defer proberMu.RUnlock()
+	f, ok := probers[name]
+	return f, ok
+}
+
+/*
+ProberNames lists the registered prober names in sorted order.
+*/
+func ProberNames() []string {
+	proberMu.RLock()
+	defer proberMu.RUnlock()
+	names := make([]string, 0, len(probers))
+	for name := range probers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+ProbeWith connects to addr the way Probe does and hands the connection to p.
+Function-level comment: dial failures and errors from p become the Result's Error; when ctx is cancelled the connection's deadline is moved to now so p's reads return. The dial is traced as a child of the span in ctx, if any, and with opts.Verbose timed as the connect stage of Result.Timing. For the MySQL prober, the checks of FollowUp run once the connection is closed.
+*/
+func ProbeWith(ctx context.Context, p Prober, addr string, opts Options) Result {
+	_, span := startSpan(ctx, "mysqlprobe.dial", attribute.String("server.address", addr))
+	start := time.Now()
+	conn, err := opts.Connect("tcp", addr)
+	endSpan(span, err)
+	var connect []TimingPhase
+	if opts.Verbose {
+		connect = []TimingPhase{NewTimingPhase(PhaseConnect, start, time.Now(), err != nil)}
+	}
+	if err != nil {
+		return Result{Error: "dial failed: " + err.Error(), Timing: connect}
+	}
+	res, err := func() (Result, error) {
+		defer conn.Close()
+		stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+		defer stop()
+		return p.Probe(ctx, conn)
+	}()
+	if err != nil && res.Error == "" {
+		res.Error = err.Error()
+	}
+	if _, ok := p.(*mysqlProber); ok {
+		FollowUp(addr, &res, opts)
+	}
+	if connect != nil {
+		res.Timing = append(connect, res.Timing...)
+	}
+	return res
+}
+
+func init() {
+	RegisterProber(ModuleName, NewMySQLProber)
+	RegisterProber(XModuleName, NewXProber)
+}
@@ -0,0 +1,87 @@
+package mysqlprobe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// PROXY protocol versions for Options.ProxyProtocol.
+const (
+	ProxyProtocolV1 = 1
+	ProxyProtocolV2 = 2
+)
+
+// proxyV2Signature opens every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+/*
+ParseProxyProtocol maps a -send-proxy-protocol value (v1, v2, or "" for none) to its Options.ProxyProtocol version.
+*/
+func ParseProxyProtocol(s string) (int, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "v1", "1":
+		return ProxyProtocolV1, nil
+	case "v2", "2":
+		return ProxyProtocolV2, nil
+	}
+	return 0, fmt.Errorf("unknown PROXY protocol version %q (want v1 or v2)", s)
+}
+
+/*
+proxyHeader builds the PROXY protocol header of the given version for conn, naming its local address as the client and its remote address as the server.
+Function-level comment: a connection whose ends are not both TCP addresses of the same family, as through some tunnels, gets the v1 UNKNOWN or v2 LOCAL form, which tells the receiver to use the connection's own addresses.
+*/
+func proxyHeader(version int, conn net.Conn) []byte {
+	src, srcOK := conn.LocalAddr().(*net.TCPAddr)
+	dst, dstOK := conn.RemoteAddr().(*net.TCPAddr)
+	known := srcOK && dstOK && (src.IP.To4() != nil) == (dst.IP.To4() != nil)
+	if version == ProxyProtocolV1 {
+		if !known {
+			return []byte("PROXY UNKNOWN\r\n")
+		}
+		family := "TCP6"
+		if src.IP.To4() != nil {
+			family = "TCP4"
+		}
+		return fmt.Appendf(nil, "PROXY %s %s %s %d %d\r\n", family, src.IP, dst.IP, src.Port, dst.Port)
+	}
+
+	h := append([]byte{}, proxyV2Signature...)
+	if !known {
+		// LOCAL command, unspecified family, no addresses.
+		return append(h, 0x20, 0x00, 0x00, 0x00)
+	}
+	var addrs []byte
+	family := byte(0x21) // TCP over IPv6
+	if s4, d4 := src.IP.To4(), dst.IP.To4(); s4 != nil {
+		family = 0x11 // TCP over IPv4
+		addrs = append(append(addrs, s4...), d4...)
+	} else {
+		addrs = append(append(addrs, src.IP.To16()...), dst.IP.To16()...)
+	}
+	addrs = binary.BigEndian.AppendUint16(addrs, uint16(src.Port))
+	addrs = binary.BigEndian.AppendUint16(addrs, uint16(dst.Port))
+	h = append(h, 0x21, family)
+	h = binary.BigEndian.AppendUint16(h, uint16(len(addrs)))
+	return append(h, addrs...)
+}
+
+/*
+sendProxyHeader writes the PROXY protocol header of the given version to a freshly opened conn, within timeout.
+Function-level comment: a failed write closes conn, so the caller's dial fails as a whole.
+*/
+func sendProxyHeader(version int, conn net.Conn, timeout time.Duration) error {
+	if timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		defer conn.SetWriteDeadline(time.Time{})
+	}
+	if _, err := conn.Write(proxyHeader(version, conn)); err != nil {
+		conn.Close()
+		return fmt.Errorf("send PROXY protocol v%d header: %w", version, err)
+	}
+	return nil
+}
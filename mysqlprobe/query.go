@@ -0,0 +1,81 @@
+package mysqlprobe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// queryVariables is the allowlist of server variables authenticated mode may read, mapped to the fixed statement that reads each one.
+// Names from flags are looked up here and never interpolated into SQL.
+var queryVariables = map[string]string{
+	"version_comment":               "SELECT @@version_comment",
+	"ssl_cipher":                    "SELECT @@ssl_cipher",
+	"require_secure_transport":      "SELECT @@require_secure_transport",
+	"default_authentication_plugin": "SELECT @@default_authentication_plugin",
+}
+
+/*
+QueryVariableNames returns the allowlisted variable names, sorted.
+*/
+func QueryVariableNames() []string {
+	names := make([]string, 0, len(queryVariables))
+	for name := range queryVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+ParseQueryVariables parses a comma-separated list of allowlisted variable names, or "all" for every one.
+Function-level comment: a name outside the allowlist is an error, so no flag value reaches the server as SQL.
+*/
+func ParseQueryVariables(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "all" {
+		return QueryVariableNames(), nil
+	}
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@@")
+		if name == "" {
+			continue
+		}
+		if _, ok := queryVariables[name]; !ok {
+			return nil, fmt.Errorf("%q is not an allowed variable (allowed: %s, or all)", name, strings.Join(QueryVariableNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+/*
+readVariables reads each allowlisted variable with its own query, so a variable the server lacks (e.g. default_authentication_plugin, removed in 8.4) does not hide the others.
+Function-level comment: values are nil for SQL NULL; per-variable failures, including names outside the allowlist, are reported in errs.
+*/
+func readVariables(pc *mysqlclient.Conn, names []string) (map[string]*string, map[string]string) {
+	values := make(map[string]*string)
+	errs := make(map[string]string)
+	for _, name := range names {
+		stmt, ok := queryVariables[name]
+		if !ok {
+			errs[name] = "not an allowed variable"
+			continue
+		}
+		rows, err := pc.Query(stmt)
+		switch {
+		case err != nil:
+			errs[name] = err.Error()
+		case len(rows) != 1 || len(rows[0]) != 1:
+			errs[name] = fmt.Sprintf("unexpected result shape (%d rows)", len(rows))
+		default:
+			values[name] = rows[0][0]
+		}
+	}
+	if len(errs) == 0 {
+		errs = nil
+	}
+	return values, errs
+}
@@ -0,0 +1,50 @@
+package mysqlprobe
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+SanitizeText returns server-sent text safe to print anywhere results go (terminals, CSV, XML, syslog) and whether anything had to change.
+Function-level comment: printable UTF-8 is kept as is; bytes that are not valid UTF-8 become \xNN, and control characters and bidirectional-text controls (which can rewrite a terminal line or reorder what a reader sees) become \xNN or \uNNNN. The escaping is for display, not reversible; callers keep the raw bytes as hex when changed is true.
+*/
+func SanitizeText(b []byte) (clean string, changed bool) {
+	if printableASCII(b) {
+		return string(b), false
+	}
+	var sb strings.Builder
+	sb.Grow(len(b) + 8)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			fmt.Fprintf(&sb, `\x%02x`, b[0])
+			changed = true
+		case r < 0x80 && unicode.IsControl(r):
+			fmt.Fprintf(&sb, `\x%02x`, r)
+			changed = true
+		case unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r):
+			fmt.Fprintf(&sb, `\u%04x`, r)
+			changed = true
+		default:
+			sb.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return sb.String(), changed
+}
+
+/*
+printableASCII reports whether b is all printable ASCII, the case of every genuine server's version string, which needs no further look.
+*/
+func printableASCII(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
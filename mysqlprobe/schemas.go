@@ -0,0 +1,75 @@
+package mysqlprobe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// Schema redaction modes for Options.SchemaRedaction.
+const (
+	RedactNone  = "none"  // report schema names as listed
+	RedactHash  = "hash"  // replace user schema names with a short SHA-256 digest
+	RedactCount = "count" // report only the count
+)
+
+// systemSchemas are the schemas every server has; they are never hashed, since hashing them hides nothing.
+var systemSchemas = map[string]bool{
+	"information_schema": true,
+	"mysql":              true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+/*
+SchemaList is what SHOW DATABASES listed for the logged-in user, after redaction.
+*/
+type SchemaList struct {
+	Count     int      `json:"count"`
+	Names     []string `json:"names,omitempty"`
+	Redaction string   `json:"redaction,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+/*
+ValidateSchemaRedaction checks a redaction mode: none, hash, or count ("" means none).
+*/
+func ValidateSchemaRedaction(mode string) error {
+	switch mode {
+	case "", RedactNone, RedactHash, RedactCount:
+		return nil
+	}
+	return fmt.Errorf("want none, hash, or count, got %q", mode)
+}
+
+/*
+listSchemas runs SHOW DATABASES and redacts the names per mode.
+Function-level comment: the list only covers schemas the user has some privilege on. Hashed names are "sha256:" plus the first 12 hex digits of the name's digest: enough to match a schema across servers and scans without revealing it, though a guessed name can be confirmed.
+*/
+func listSchemas(pc *mysqlclient.Conn, mode string) *SchemaList {
+	rows, err := pc.Query("SHOW DATABASES")
+	if err != nil {
+		return &SchemaList{Error: err.Error()}
+	}
+	list := &SchemaList{Count: len(rows)}
+	if mode != RedactNone && mode != "" {
+		list.Redaction = mode
+	}
+	if mode == RedactCount {
+		return list
+	}
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == nil {
+			continue
+		}
+		name := *row[0]
+		if mode == RedactHash && !systemSchemas[name] {
+			sum := sha256.Sum256([]byte(name))
+			name = "sha256:" + hex.EncodeToString(sum[:6])
+		}
+		list.Names = append(list.Names, name)
+	}
+	return list
+}
@@ -0,0 +1,146 @@
+package mysqlprobe
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+OutputSink is a destination for scan results: a file, a stream, a database table, a queue.
+Write takes the record as the caller has it (the CLI passes its per-target result, library users the *Result from Scan or Probe), like Scanner.Scan returns any; sinks serialize it as they need, typically with encoding/json. Flush pushes buffered records through, and Close flushes and releases the destination.
+*/
+type OutputSink interface {
+	Write(result any) error
+	Flush() error
+	Close() error
+}
+
+/*
+SinkFactory opens a sink for a URL whose scheme it was registered under.
+*/
+type SinkFactory func(u *url.URL) (OutputSink, error)
+
+var (
+	sinkMu    sync.RWMutex
+	sinkTypes = make(map[string]SinkFactory)
+)
+
+/*
+RegisterSink makes a sink available for URLs with the given scheme.
+Function-level comment: as with RegisterModule, registering a scheme twice or a nil factory panics.
+*/
+func RegisterSink(scheme string, f SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	if f == nil {
+		panic("mysqlprobe: RegisterSink factory is nil")
+	}
+	if _, dup := sinkTypes[scheme]; dup {
+		panic("mysqlprobe: RegisterSink called twice for " + scheme)
+	}
+	sinkTypes[scheme] = f
+}
+
+/*
+SinkSchemes lists the registered sink schemes in sorted order.
+*/
+func SinkSchemes() []string {
+	sinkMu.RLock()
+	defer sinkMu.RUnlock()
+	schemes := make([]string, 0, len(sinkTypes))
+	for scheme := range sinkTypes {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+/*
+OpenSink opens the sink registered for rawURL's scheme.
+*/
+func OpenSink(rawURL string) (OutputSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("want a URL such as file:///path/results.ndjson, got %q", rawURL)
+	}
+	sinkMu.RLock()
+	f, ok := sinkTypes[u.Scheme]
+	sinkMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no sink registered for scheme %q (have %s)", u.Scheme, strings.Join(SinkSchemes(), ", "))
+	}
+	return f(u)
+}
+
+/*
+jsonLinesSink writes each result as one line of JSON through a buffer.
+*/
+type jsonLinesSink struct {
+	bw     *bufio.Writer
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+/*
+NewJSONLinesSink returns a sink writing NDJSON to w; Close also closes closer when it is not nil.
+*/
+func NewJSONLinesSink(w io.Writer, closer io.Closer) OutputSink {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &jsonLinesSink{bw: bw, enc: enc, closer: closer}
+}
+
+/*
+Write encodes result as one JSON line.
+*/
+func (s *jsonLinesSink) Write(result any) error {
+	return s.enc.Encode(result)
+}
+
+/*
+Flush writes buffered lines through.
+*/
+func (s *jsonLinesSink) Flush() error {
+	return s.bw.Flush()
+}
+
+/*
+Close flushes and closes the underlying destination.
+*/
+func (s *jsonLinesSink) Close() error {
+	err := s.bw.Flush()
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func init() {
+	RegisterSink("file", func(u *url.URL) (OutputSink, error) {
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("file sink needs a path, e.g. file:///var/scans/results.ndjson")
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewJSONLinesSink(f, f), nil
+	})
+	RegisterSink("stdout", func(*url.URL) (OutputSink, error) {
+		return NewJSONLinesSink(os.Stdout, nil), nil
+	})
+}
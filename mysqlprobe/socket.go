@@ -0,0 +1,166 @@
+package mysqlprobe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/*
+SocketOptions are IP- and TCP-level settings applied to every socket a probe opens.
+Zero values (and nil pointers) leave the Go and operating system defaults in place. KeepAlive follows net.Dialer: 0 uses Go's default period and a negative value disables keepalives. Linger is in seconds, as for net.TCPConn.SetLinger; 0 closes with a RST instead of a FIN. Interface names the network interface to egress through.
+FastOpen sends TCP Fast Open SYNs (Linux only). A TCP dial that times out is retried DialRetries times, waiting DialBackoff before the first retry and doubling the wait after each.
+*/
+type SocketOptions struct {
+	TTL       int
+	DSCP      int
+	KeepAlive time.Duration
+	Linger    *int
+	NoDelay   *bool
+	Interface string
+	FastOpen  bool
+
+	DialRetries int
+	DialBackoff time.Duration
+}
+
+/*
+Validate checks that the options are in range (TTL 0-255, DSCP 0-63, no negative linger or dial retries), that FastOpen is supported here, and that Interface, if set, exists.
+*/
+func (s SocketOptions) Validate() error {
+	if s.TTL < 0 || s.TTL > 255 {
+		return fmt.Errorf("TTL %d out of range 0-255", s.TTL)
+	}
+	if s.DSCP < 0 || s.DSCP > 63 {
+		return fmt.Errorf("DSCP %d out of range 0-63", s.DSCP)
+	}
+	if s.Linger != nil && *s.Linger < 0 {
+		return fmt.Errorf("linger %d must not be negative", *s.Linger)
+	}
+	if s.DialRetries < 0 {
+		return fmt.Errorf("dial retries %d must not be negative", s.DialRetries)
+	}
+	if s.DialBackoff < 0 {
+		return fmt.Errorf("dial backoff %v must not be negative", s.DialBackoff)
+	}
+	if s.FastOpen && !canFastOpen {
+		return errors.New("TCP Fast Open is only supported on Linux")
+	}
+	if s.Interface != "" {
+		if _, err := net.InterfaceByName(s.Interface); err != nil {
+			return fmt.Errorf("interface %s: %w", s.Interface, err)
+		}
+	}
+	return nil
+}
+
+/*
+Dialer returns a dialer with the given timeout and keepalive whose sockets get the TTL/DSCP options, and on Linux are bound to Interface, before connecting.
+Function-level comment: works for TCP and UDP; the options are set through the socket's raw control hook so they also cover the SYN. Use Dial to also get linger, nodelay, dial retries, the start of a deferred Fast Open handshake, and the interface fallback on other platforms.
+*/
+func (s SocketOptions) Dialer(timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: s.KeepAlive}
+	if s.TTL != 0 || s.DSCP != 0 || s.FastOpen || (s.Interface != "" && canBindToDevice) {
+		d.Control = s.control
+	}
+	return d
+}
+
+/*
+Dial connects with Dialer, retrying timed-out TCP dials per DialRetries and DialBackoff, and then applies the TCP-only settings (linger, nodelay) to the connection.
+Function-level comment: failing to apply a setting closes the connection and fails the dial, so a probe never runs with options other than those requested. With FastOpen, a connect Linux deferred for a cached cookie is started here, so its failure surfaces on the first read rather than from Dial. Where SO_BINDTODEVICE is unavailable, Interface is honored by binding to the interface's address of the target's family, which steers egress on hosts whose routing follows the source address.
+*/
+func (s SocketOptions) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	d := s.Dialer(timeout)
+	if s.Interface != "" && !canBindToDevice {
+		local, err := interfaceAddr(s.Interface, network, address)
+		if err != nil {
+			return nil, err
+		}
+		d.LocalAddr = local
+	}
+	conn, err := d.Dial(network, address)
+	var ne net.Error
+	for retry, wait := 0, s.DialBackoff; err != nil && retry < s.DialRetries && strings.HasPrefix(network, "tcp") && errors.As(err, &ne) && ne.Timeout(); retry++ {
+		time.Sleep(wait)
+		wait *= 2
+		conn, err = d.Dial(network, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return conn, nil
+	}
+	if s.FastOpen {
+		err = startFastOpen(tc)
+	}
+	if s.Linger != nil && err == nil {
+		err = tc.SetLinger(*s.Linger)
+	}
+	if s.NoDelay != nil && err == nil {
+		err = tc.SetNoDelay(*s.NoDelay)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+/*
+control binds the raw socket to Interface and sets the TTL/hop limit, DSCP, and TCP Fast Open; network ends in "6" for IPv6 sockets.
+*/
+func (s SocketOptions) control(network, _ string, c syscall.RawConn) error {
+	var serr error
+	err := c.Control(func(fd uintptr) {
+		if s.Interface != "" && canBindToDevice {
+			if serr = bindToDevice(fd, s.Interface); serr != nil {
+				return
+			}
+		}
+		if s.TTL != 0 || s.DSCP != 0 {
+			if serr = setIPOptions(fd, network[len(network)-1] == '6', s.TTL, s.DSCP); serr != nil {
+				return
+			}
+		}
+		if s.FastOpen && strings.HasPrefix(network, "tcp") {
+			serr = setFastOpen(fd)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+/*
+interfaceAddr returns a local address on the named interface with the same IP family as address, for use as a dialer's LocalAddr.
+*/
+func interfaceAddr(name, network, address string) (net.Addr, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(address)
+	want6 := strings.Contains(host, ":")
+	for _, a := range addrs {
+		ipn, ok := a.(*net.IPNet)
+		if !ok || (ipn.IP.To4() == nil) != want6 || ipn.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if strings.HasPrefix(network, "udp") {
+			return &net.UDPAddr{IP: ipn.IP}, nil
+		}
+		return &net.TCPAddr{IP: ipn.IP}, nil
+	}
+	return nil, fmt.Errorf("interface %s has no usable address for %s", name, host)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package mysqlprobe
+
+import "errors"
+
+/*
+setIPOptions is not implemented on this platform; setting any option fails the dial rather than silently sending unmarked traffic.
+*/
+func setIPOptions(fd uintptr, ipv6 bool, ttl, dscp int) error {
+	return errors.New("TTL/DSCP socket options are not supported on this platform")
+}
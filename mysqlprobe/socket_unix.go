@@ -0,0 +1,30 @@
+//go:build unix
+
+package mysqlprobe
+
+import (
+	"fmt"
+	"syscall"
+)
+
+/*
+setIPOptions sets the unicast TTL (hop limit) and the DSCP bits of the traffic class on fd.
+Function-level comment: DSCP occupies the upper six bits of the IPv4 TOS / IPv6 traffic class byte; the ECN bits are left zero.
+*/
+func setIPOptions(fd uintptr, ipv6 bool, ttl, dscp int) error {
+	level, ttlOpt, tosOpt := syscall.IPPROTO_IP, syscall.IP_TTL, syscall.IP_TOS
+	if ipv6 {
+		level, ttlOpt, tosOpt = syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, syscall.IPV6_TCLASS
+	}
+	if ttl != 0 {
+		if err := syscall.SetsockoptInt(int(fd), level, ttlOpt, ttl); err != nil {
+			return fmt.Errorf("set TTL: %w", err)
+		}
+	}
+	if dscp != 0 {
+		if err := syscall.SetsockoptInt(int(fd), level, tosOpt, dscp<<2); err != nil {
+			return fmt.Errorf("set DSCP: %w", err)
+		}
+	}
+	return nil
+}
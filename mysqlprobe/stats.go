@@ -0,0 +1,97 @@
+package mysqlprobe
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+/*
+ServerStats is what COM_PING and COM_STATISTICS report for an authenticated session.
+The counters come from the COM_STATISTICS status line (e.g. "Uptime: 3600  Threads: 2  Questions: 100 ... Queries per second avg: 0.027"); Status keeps the line as sent.
+*/
+type ServerStats struct {
+	PingOK           bool     `json:"ping_ok"`
+	PingMillis       *float64 `json:"ping_ms,omitempty"`
+	UptimeSeconds    int64    `json:"uptime_seconds,omitempty"`
+	Threads          int64    `json:"threads,omitempty"`
+	Questions        int64    `json:"questions,omitempty"`
+	SlowQueries      int64    `json:"slow_queries,omitempty"`
+	OpenTables       int64    `json:"open_tables,omitempty"`
+	QueriesPerSecond *float64 `json:"queries_per_second,omitempty"`
+	Status           string   `json:"status,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+/*
+collectStats issues COM_PING and COM_STATISTICS on an authenticated connection.
+Function-level comment: the ping round trip is timed; a failure stops the collection and is reported in ServerStats.Error with whatever was gathered.
+*/
+func collectStats(pc *mysqlclient.Conn) *ServerStats {
+	st := &ServerStats{}
+	start := time.Now()
+	if err := pc.Command(mysqlclient.ComPing); err != nil {
+		st.Error = "ping: " + err.Error()
+		return st
+	}
+	p, err := pc.ReadPacket()
+	if err != nil {
+		st.Error = "ping: " + err.Error()
+		return st
+	}
+	if p[0] != mysqlclient.HeaderOK {
+		st.Error = "ping: " + mysqlclient.ReplyError(p).Error()
+		return st
+	}
+	st.PingOK = true
+	ms := float64(time.Since(start).Microseconds()) / 1000
+	st.PingMillis = &ms
+
+	if err := pc.Command(mysqlclient.ComStatistics); err != nil {
+		st.Error = "statistics: " + err.Error()
+		return st
+	}
+	if p, err = pc.ReadPacket(); err != nil {
+		st.Error = "statistics: " + err.Error()
+		return st
+	}
+	if p[0] == mysqlclient.HeaderErr {
+		st.Error = "statistics: " + mysqlclient.ReplyError(p).Error()
+		return st
+	}
+	st.Status = string(p)
+	parseStatistics(st.Status, st)
+	return st
+}
+
+/*
+parseStatistics fills st's counters from a COM_STATISTICS line of "Name: value" pairs separated by two spaces; unknown or malformed pairs are ignored.
+*/
+func parseStatistics(line string, st *ServerStats) {
+	counters := map[string]*int64{
+		"Uptime":       &st.UptimeSeconds,
+		"Threads":      &st.Threads,
+		"Questions":    &st.Questions,
+		"Slow queries": &st.SlowQueries,
+		"Open tables":  &st.OpenTables,
+	}
+	for _, pair := range strings.Split(line, "  ") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), ": ")
+		if !ok {
+			continue
+		}
+		if name == "Queries per second avg" {
+			if qps, err := strconv.ParseFloat(value, 64); err == nil {
+				st.QueriesPerSecond = &qps
+			}
+			continue
+		}
+		if dst := counters[name]; dst != nil {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				*dst = n
+			}
+		}
+	}
+}
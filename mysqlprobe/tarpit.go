@@ -0,0 +1,103 @@
+package mysqlprobe
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// slowDripAfter is how long a first packet may take to arrive after its first byte before the server counts as a tarpit even though it completed it.
+const slowDripAfter = time.Second
+
+/*
+TarpitInfo is the evidence that a server accepted the connection but trickled its first packet, or stalled before finishing it.
+Seconds runs from the start of the read to the last byte (or to the stall); BytesExpected is the header's length plus 4, once the header arrived.
+*/
+type TarpitInfo struct {
+	BytesReceived  int     `json:"bytes_received"`
+	BytesExpected  int     `json:"bytes_expected,omitempty"`
+	Reads          int     `json:"reads"`
+	Seconds        float64 `json:"seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Complete       bool    `json:"complete"`
+	Evidence       string  `json:"evidence"`
+}
+
+/*
+readFirstPacket reads the initial MySQL packet like grabFirstPacket and also reports a tarpit: a server whose packet stalled after its first bytes, or dripped in over several reads for longer than slowDripAfter.
+Function-level comment: the header and then the payload are read in full through a frameReader (see there for the deadlines). A packet that ends early returns the bytes that did arrive with a *FrameError telling a closed connection from an idle timeout. A packet cut short by the server closing the connection is not a tarpit, and neither is silence, which client-first services answer with too. A payload larger than buf is read until buf is full; truncatedPacket tells such a packet apart from one cut short. Also returns when the first byte arrived, zero when none did.
+*/
+func readFirstPacket(conn net.Conn, timeout time.Duration, buf []byte) ([]byte, *TarpitInfo, time.Time, error) {
+	start := time.Now()
+	fr := frameReader{conn: conn, timeout: timeout}
+	want := 4
+	got, err := fr.readFull(buf[:4])
+	if err == nil {
+		payloadLen := int(buf[0]) | int(buf[1])<<8 | int(buf[2])<<16
+		if payloadLen == 0 {
+			return buf[:4], nil, fr.first, nil
+		}
+		want = min(4+payloadLen, len(buf))
+		var n int
+		n, err = fr.readFull(buf[4:want])
+		got += n
+	}
+	if err != nil {
+		ferr := &FrameError{Got: got, Want: want, Err: err}
+		var tarpit *TarpitInfo
+		if got > 0 && ferr.Timeout() {
+			tarpit = newTarpitInfo(got, want, fr.reads, time.Since(start), false)
+		}
+		return buf[:got], tarpit, fr.first, ferr
+	}
+	var tarpit *TarpitInfo
+	if fr.reads > 1 && fr.last.Sub(fr.first) >= slowDripAfter {
+		tarpit = newTarpitInfo(got, want, fr.reads, fr.last.Sub(start), true)
+	}
+	return buf[:got], tarpit, fr.first, nil
+}
+
+/*
+truncatedPacket reports whether first, as read into a buffer of capacity bytes, is a packet whose header announced more payload than fit, and the payload length it announced.
+*/
+func truncatedPacket(first []byte, capacity int) (advertised int, truncated bool) {
+	if len(first) < 4 || len(first) < capacity {
+		return 0, false
+	}
+	advertised = int(first[0]) | int(first[1])<<8 | int(first[2])<<16
+	return advertised, 4+advertised > len(first)
+}
+
+/*
+truncationError is the reason given for a truncated first packet, of which read payload bytes were read.
+*/
+func truncationError(advertised, read int) error {
+	return fmt.Errorf("first packet truncated: header announced %d payload bytes, read %d", advertised, read)
+}
+
+/*
+newTarpitInfo summarizes a slow or stalled read of got bytes (of want, when the header arrived) in reads reads over elapsed.
+*/
+func newTarpitInfo(got, want, reads int, elapsed time.Duration, complete bool) *TarpitInfo {
+	t := &TarpitInfo{BytesReceived: got, Reads: reads, Seconds: math.Round(elapsed.Seconds()*1000) / 1000, Complete: complete}
+	if got >= 4 {
+		t.BytesExpected = want
+	}
+	if elapsed > 0 {
+		t.BytesPerSecond = math.Round(float64(got)/elapsed.Seconds()*100) / 100
+	}
+	of := ""
+	if t.BytesExpected > 0 {
+		of = fmt.Sprintf(" of %d", t.BytesExpected)
+	}
+	plural := "s"
+	if reads == 1 {
+		plural = ""
+	}
+	t.Evidence = fmt.Sprintf("received %d%s bytes in %d read%s over %.1fs (%.1f B/s)", got, of, reads, plural, elapsed.Seconds(), t.BytesPerSecond)
+	if !complete {
+		t.Evidence += ", then stalled"
+	}
+	return t
+}
@@ -0,0 +1,51 @@
+package mysqlprobe
+
+import "time"
+
+// Phases of Result.Timing, in the order a probe goes through them.
+const (
+	PhaseDNS            = "dns"
+	PhaseConnect        = "connect"
+	PhaseFirstByte      = "first_byte"
+	PhaseBannerComplete = "banner_complete"
+	PhaseTLS            = "tls"
+)
+
+/*
+TimingPhase is one stage of a probe in Result.Timing: when it started and ended, in wall-clock time, and how long it took.
+Failed marks a stage that ended without completing, such as a dial that timed out or a read that got nothing.
+*/
+type TimingPhase struct {
+	Phase  string    `json:"phase"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Millis float64   `json:"ms"`
+	Failed bool      `json:"failed,omitempty"`
+}
+
+/*
+NewTimingPhase records a stage that ran from start to end.
+*/
+func NewTimingPhase(phase string, start, end time.Time, failed bool) TimingPhase {
+	return TimingPhase{
+		Phase:  phase,
+		Start:  start.UTC(),
+		End:    end.UTC(),
+		Millis: float64(end.Sub(start).Microseconds()) / 1000,
+		Failed: failed,
+	}
+}
+
+/*
+readPhases splits the read of the first packet, from start to end, into the wait for its first byte and the rest of the packet.
+Function-level comment: firstByte is zero when nothing arrived, which makes the whole read a failed first_byte stage.
+*/
+func readPhases(start, firstByte, end time.Time, err error) []TimingPhase {
+	if firstByte.IsZero() {
+		return []TimingPhase{NewTimingPhase(PhaseFirstByte, start, end, true)}
+	}
+	return []TimingPhase{
+		NewTimingPhase(PhaseFirstByte, start, firstByte, false),
+		NewTimingPhase(PhaseBannerComplete, firstByte, end, err != nil),
+	}
+}
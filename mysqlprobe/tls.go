@@ -0,0 +1,334 @@
+package mysqlprobe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// ticketWait bounds how long a TLS 1.3 session is read after the handshake for the server's session tickets, which arrive after it.
+const ticketWait = 250 * time.Millisecond
+
+/*
+TLSInfo describes the TLS session negotiated after an SSLRequest and the server's leaf certificate.
+*/
+type TLSInfo struct {
+	Version     string   `json:"version,omitempty"`
+	CipherSuite string   `json:"cipher_suite,omitempty"`
+	Subject     string   `json:"subject,omitempty"`
+	Issuer      string   `json:"issuer,omitempty"`
+	DNSNames    []string `json:"dns_names,omitempty"`
+	NotBefore   string   `json:"not_before,omitempty"`
+	NotAfter    string   `json:"not_after,omitempty"`
+	SelfSigned  bool     `json:"self_signed,omitempty"`
+	// ClientCertRequested is set when the server asked for a client certificate (mutual TLS); ClientCertSent when we answered with one.
+	ClientCertRequested bool `json:"client_cert_requested,omitempty"`
+	ClientCertSent      bool `json:"client_cert_sent,omitempty"`
+	// OfferedVersions and OfferedCiphers record a restricting TLSPolicy, so a failed attempt shows what the server refused.
+	OfferedVersions string   `json:"offered_versions,omitempty"`
+	OfferedCiphers  []string `json:"offered_ciphers,omitempty"`
+	// SNI is the server name sent in the ClientHello; ALPN the application protocol the server selected from TLSPolicy.ALPN.
+	SNI  string `json:"sni,omitempty"`
+	ALPN string `json:"alpn,omitempty"`
+	// SessionTicket is set when the server issued a session ticket, i.e. a client could resume the session.
+	SessionTicket bool `json:"session_ticket,omitempty"`
+	// Chain is every certificate the server sent, leaf first.
+	Chain []TLSCert `json:"chain,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+/*
+TLSCert describes one certificate of the server's chain.
+*/
+type TLSCert struct {
+	Subject   string `json:"subject"`
+	Issuer    string `json:"issuer"`
+	Serial    string `json:"serial"`
+	NotBefore string `json:"not_before"`
+	NotAfter  string `json:"not_after"`
+	IsCA      bool   `json:"is_ca,omitempty"`
+	SHA256    string `json:"sha256"`
+}
+
+/*
+TLSPolicy shapes the ClientHello of the TLS continuation: the protocol versions and cipher suites offered, e.g. to test whether a server still accepts TLS 1.0/1.1 or weak ciphers, and the SNI and ALPN values proxies terminating TLS route on.
+The zero value offers Go's defaults (TLS 1.2-1.3); a MaxVersion below 1.2 with no MinVersion offers from TLS 1.0. Cipher suites only apply up to TLS 1.2 (TLS 1.3 suites are not configurable), so a policy with CipherSuites and no MaxVersion stops at TLS 1.2.
+*/
+type TLSPolicy struct {
+	MinVersion   uint16
+	MaxVersion   uint16
+	CipherSuites []uint16
+	// ServerName is sent as SNI; "" sends none, as MySQL clients do by default.
+	ServerName string
+	// ALPN lists the application protocols offered; nil offers none.
+	ALPN []string
+}
+
+// tlsVersions maps the version names ParseTLSVersion accepts to their codes.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/*
+ParseTLSVersion parses a TLS version given as 1.0, 1.1, 1.2, or 1.3 (optionally prefixed with TLS); "" is 0, Go's default.
+*/
+func ParseTLSVersion(s string) (uint16, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[strings.TrimSpace(strings.TrimPrefix(strings.ToLower(s), "tls"))]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q (want 1.0, 1.1, 1.2, or 1.3)", s)
+	}
+	return v, nil
+}
+
+/*
+ParseCipherSuites parses a comma-separated list of cipher suite names as Go spells them (e.g. TLS_RSA_WITH_AES_128_CBC_SHA), including the insecure suites.
+Function-level comment: names are matched case-insensitively; an unknown name is an error.
+*/
+func ParseCipherSuites(list string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[strings.ToUpper(cs.Name)] = cs.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+/*
+config applies the policy to cfg and returns how a restricted version range and cipher list are described in TLSInfo; a zero policy leaves cfg alone and describes nothing.
+*/
+func (p TLSPolicy) config(cfg *tls.Config) (versions string, ciphers []string) {
+	cfg.MinVersion, cfg.MaxVersion = p.MinVersion, p.MaxVersion
+	cfg.ServerName, cfg.NextProtos = p.ServerName, p.ALPN
+	if cfg.MinVersion == 0 && cfg.MaxVersion != 0 && cfg.MaxVersion < tls.VersionTLS12 {
+		// Go's client floor is TLS 1.2; a lower ceiling means the old versions are what is being tested.
+		cfg.MinVersion = tls.VersionTLS10
+	}
+	if len(p.CipherSuites) > 0 {
+		cfg.CipherSuites = p.CipherSuites
+		if cfg.MaxVersion == 0 {
+			cfg.MaxVersion = tls.VersionTLS12
+		}
+		for _, id := range p.CipherSuites {
+			ciphers = append(ciphers, tls.CipherSuiteName(id))
+		}
+	}
+	if cfg.MinVersion != 0 || cfg.MaxVersion != 0 {
+		lo, hi := cfg.MinVersion, cfg.MaxVersion
+		if lo == 0 {
+			lo = tls.VersionTLS12
+		}
+		if hi == 0 {
+			hi = tls.VersionTLS13
+		}
+		versions = tls.VersionName(lo)
+		if hi != lo {
+			versions += "-" + tls.VersionName(hi)
+		}
+	}
+	return versions, ciphers
+}
+
+/*
+continueTLS upgrades conn to TLS the way a MySQL client would and records what was negotiated.
+Function-level comment: sends an SSLRequest, performs the TLS handshake without verifying the certificate (we are observing, not trusting), and summarizes the session; failures are reported in TLSInfo.Error. Only the versions and cipher suites policy allows are offered, with its SNI and ALPN. A TLS 1.3 session is read briefly after the handshake so session tickets, sent after it, are seen. When the server requests a client certificate, clientCert is presented if set (otherwise none is sent) and the request is recorded either way. The TLS connection is returned for authenticated mode to continue on, or nil when the handshake failed.
+*/
+func continueTLS(conn net.Conn, info *HandshakeInfo, timeout time.Duration, clientCert *tls.Certificate, policy TLSPolicy) (net.Conn, *TLSInfo) {
+	caps := uint32(ClientLongPassword|ClientProtocol41|ClientSecureConnection|ClientPluginAuth) & info.CapabilityFlags
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+	if err := mysqlclient.NewConn(conn, 1, timeout).WritePacket(mysqlclient.SSLRequest(caps)); err != nil {
+		return nil, &TLSInfo{Error: "ssl request: " + err.Error()}
+	}
+	return clientHandshake(conn, timeout, clientCert, policy)
+}
+
+/*
+clientHandshake runs a TLS client handshake on conn, whose deadline the caller has set, and summarizes it.
+Function-level comment: certificates are recorded, not verified. A TLS 1.3 server's session tickets are waited for briefly (see awaitTickets). On failure the returned conn is nil and TLSInfo.Error says why.
+*/
+func clientHandshake(conn net.Conn, timeout time.Duration, clientCert *tls.Certificate, policy TLSPolicy) (net.Conn, *TLSInfo) {
+	var requested, sent bool
+	cfg := &tls.Config{
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			requested = true
+			if clientCert == nil {
+				return &tls.Certificate{}, nil
+			}
+			sent = true
+			return clientCert, nil
+		},
+	}
+	versions, ciphers := policy.config(cfg)
+	tickets := &ticketCache{}
+	cfg.ClientSessionCache = tickets
+	tc := tls.Client(conn, cfg)
+	if err := tc.Handshake(); err != nil {
+		return nil, &TLSInfo{ClientCertRequested: requested, ClientCertSent: sent, OfferedVersions: versions, OfferedCiphers: ciphers, SNI: policy.ServerName, Error: "tls handshake: " + err.Error()}
+	}
+	var session net.Conn = tc
+	if tc.ConnectionState().Version == tls.VersionTLS13 && !tickets.issued {
+		wait := ticketWait
+		if timeout < wait {
+			wait = timeout
+		}
+		session = awaitTickets(conn, tc, tickets, wait)
+	}
+	ti := summarizeTLS(tc.ConnectionState())
+	ti.ClientCertRequested, ti.ClientCertSent = requested, sent
+	ti.OfferedVersions, ti.OfferedCiphers = versions, ciphers
+	ti.SNI, ti.SessionTicket = policy.ServerName, tickets.issued
+	return session, ti
+}
+
+/*
+ticketCache is a ClientSessionCache that only notes whether the server issued a session ticket; nothing is ever resumed.
+*/
+type ticketCache struct {
+	issued bool
+	onPut  func()
+}
+
+/*
+Get never finds a session, so every probe does a full handshake.
+*/
+func (c *ticketCache) Get(string) (*tls.ClientSessionState, bool) {
+	return nil, false
+}
+
+/*
+Put notes the ticket.
+*/
+func (c *ticketCache) Put(_ string, cs *tls.ClientSessionState) {
+	if cs == nil {
+		return
+	}
+	c.issued = true
+	if c.onPut != nil {
+		c.onPut()
+	}
+}
+
+/*
+awaitTickets reads tc for up to wait so TLS 1.3 session tickets, which follow the handshake, reach tickets; the read ends as soon as one arrives.
+Function-level comment: a MySQL server sends nothing more until the client logs in, but anything read is kept in front of the returned connection so authenticated mode sees it.
+*/
+func awaitTickets(conn net.Conn, tc *tls.Conn, tickets *ticketCache, wait time.Duration) net.Conn {
+	tickets.onPut = func() { _ = conn.SetReadDeadline(time.Now()) }
+	defer func() { tickets.onPut = nil }()
+	_ = conn.SetReadDeadline(time.Now().Add(wait))
+	buf := make([]byte, 1)
+	n, _ := tc.Read(buf)
+	if n == 0 {
+		return tc
+	}
+	return &prefixConn{Conn: tc, prefix: buf[:n]}
+}
+
+/*
+prefixConn is a connection whose first reads return bytes already read off it.
+*/
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+/*
+Read returns the remaining prefix before reading from the connection.
+*/
+func (c *prefixConn) Read(p []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(p, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
+
+/*
+summarizeTLS converts a negotiated connection state into TLSInfo.
+Function-level comment: the leaf certificate is summarized in the top-level fields; Chain lists every certificate sent.
+*/
+func summarizeTLS(cs tls.ConnectionState) *TLSInfo {
+	ti := &TLSInfo{
+		Version:     tls.VersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:        cs.NegotiatedProtocol,
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return ti
+	}
+	for _, cert := range cs.PeerCertificates {
+		ti.Chain = append(ti.Chain, describeCert(cert))
+	}
+	leaf := cs.PeerCertificates[0]
+	ti.Subject = leaf.Subject.String()
+	ti.Issuer = leaf.Issuer.String()
+	ti.DNSNames = leaf.DNSNames
+	ti.NotBefore = leaf.NotBefore.UTC().Format(time.RFC3339)
+	ti.NotAfter = leaf.NotAfter.UTC().Format(time.RFC3339)
+	ti.SelfSigned = bytes.Equal(leaf.RawIssuer, leaf.RawSubject)
+	return ti
+}
+
+/*
+describeCert summarizes one certificate.
+*/
+func describeCert(cert *x509.Certificate) TLSCert {
+	sum := sha256.Sum256(cert.Raw)
+	return TLSCert{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		Serial:    cert.SerialNumber.Text(16),
+		NotBefore: cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:  cert.NotAfter.UTC().Format(time.RFC3339),
+		IsCA:      cert.IsCA,
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+}
+
+/*
+CertMentions reports whether the certificate's subject, issuer, or DNS names contain needle (case-insensitive).
+Function-level comment: a nil or failed TLSInfo mentions nothing.
+*/
+func (ti *TLSInfo) CertMentions(needle string) bool {
+	if ti == nil || ti.Error != "" {
+		return false
+	}
+	needle = strings.ToLower(needle)
+	if strings.Contains(strings.ToLower(ti.Subject), needle) || strings.Contains(strings.ToLower(ti.Issuer), needle) {
+		return true
+	}
+	for _, n := range ti.DNSNames {
+		if strings.Contains(strings.ToLower(n), needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package mysqlprobe
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlclient"
+)
+
+// TLS requirements reported as tls_policy by Options.DetectTLSRequirement.
+const (
+	TLSOptional    = "optional"
+	TLSRequired    = "required"
+	TLSUnsupported = "unsupported"
+)
+
+// ErrSecureTransportRequired is ER_SECURE_TRANSPORT_REQUIRED, sent to plaintext logins under require_secure_transport.
+const ErrSecureTransportRequired = 3159
+
+// tlsCheckUser is the account a plaintext login without Credentials tries; it is not expected to exist.
+const tlsCheckUser = "mysqlprobe_tls_check"
+
+/*
+detectTLSRequirement classifies whether the server at addr requires TLS, from both continuation paths, and returns the class with the evidence for it.
+Function-level comment: a server without CLIENT_SSL, or one whose TLS handshake fails, is TLSUnsupported. Otherwise a plaintext login is tried: refused with ER_SECURE_TRANSPORT_REQUIRED (or a message about insecure transport), or hung up on, it is TLSRequired; answered by authentication it is TLSOptional. The paths res already took are reused and the rest use connections of their own. MySQL only checks require_secure_transport once the password is right, so an access-denied answer (always the case without Credentials) is an optional verdict that a proxy or managed service rejecting plaintext outright would not give, but a plain MySQL server with require_secure_transport would. An attempt that fails for another reason yields no class.
+*/
+func detectTLSRequirement(addr string, info *HandshakeInfo, res *Result, opts Options) (string, string) {
+	if info.CapabilityFlags&ClientSSL == 0 {
+		return TLSUnsupported, "server does not offer CLIENT_SSL"
+	}
+	ti := res.TLS
+	if ti == nil {
+		conn, hs, err := dialHandshake(addr, opts)
+		if err != nil {
+			return "", "tls attempt: " + err.Error()
+		}
+		var tc net.Conn
+		tc, ti = continueTLS(conn, hs, opts.Timeout, opts.ClientCert, opts.TLSPolicy)
+		if tc != nil {
+			tc.Close()
+		}
+		conn.Close()
+	}
+	if ti.Error != "" {
+		return TLSUnsupported, "offers CLIENT_SSL but the TLS handshake failed: " + ti.Error
+	}
+
+	ai := res.Auth
+	if ai == nil || res.TLS != nil {
+		conn, hs, err := dialHandshake(addr, opts)
+		if err != nil {
+			return "", "plaintext attempt: " + err.Error()
+		}
+		creds := opts.Credentials
+		if creds == nil {
+			creds = &Credentials{User: tlsCheckUser}
+		}
+		ai = authenticate(mysqlclient.NewConn(conn, 1, opts.Timeout), hs, false, creds)
+		conn.Close()
+	}
+	switch {
+	case ai.OK:
+		return TLSOptional, fmt.Sprintf("plaintext login as %q succeeded", ai.User)
+	case ai.ServerError != nil && insecureTransportError(ai.ServerError):
+		return TLSRequired, "plaintext login refused: " + ai.ServerError.Error()
+	case ai.ServerError != nil:
+		return TLSOptional, "plaintext login reached authentication: " + ai.ServerError.Error() + " (require_secure_transport is only enforced after a valid password; log in to confirm)"
+	case strings.Contains(ai.Error, "EOF") || strings.Contains(ai.Error, "connection reset"):
+		return TLSRequired, "server closed the connection on a plaintext login"
+	}
+	return "", "plaintext attempt: " + ai.Error
+}
+
+/*
+insecureTransportError reports whether a login was refused for not using TLS: ER_SECURE_TRANSPORT_REQUIRED, or a proxy's own message saying so.
+*/
+func insecureTransportError(e *ServerError) bool {
+	if e.Code == ErrSecureTransportRequired {
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	for _, needle := range []string{"secure transport", "insecure transport", "ssl is required", "ssl required", "tls is required", "tls required", "requires ssl", "requires tls"} {
+		if strings.Contains(msg, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+dialHandshake opens a new connection to addr and reads its handshake, for checks that need a session of their own.
+*/
+func dialHandshake(addr string, opts Options) (net.Conn, *HandshakeInfo, error) {
+	conn, err := opts.Connect("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := opts.Buffers.get()
+	defer opts.Buffers.put(buf)
+	first, err := grabFirstPacket(conn, opts.Timeout, *buf)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	info, err := ParseHandshake(first)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, info, nil
+}
@@ -0,0 +1,68 @@
+package mysqlprobe
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+/*
+tlsWrappedCandidate reports whether a first read looks like a port that speaks TLS from the first byte: the server sent a TLS record, or nothing at all until the read timed out, as a TLS server waiting for a ClientHello does.
+*/
+func tlsWrappedCandidate(first []byte, err error) bool {
+	if len(first) > 0 {
+		class := ClassifyFirstPacket(first)
+		return class == FirstPacketTLSAlert || class == FirstPacketTLSHandshake
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+/*
+probeTLSWrapped retries the probe of addr on a new connection that starts with a TLS handshake instead of waiting for the server, as for proxies that wrap MySQL in TLS without an SSLRequest.
+Function-level comment: ok is false when the TLS handshake fails, leaving the original result to stand. Otherwise the result has TLSWrapped set and the session's certificate, and the MySQL handshake inside the tunnel is parsed as usual (with Credentials, the login follows on the tunnel, which counts as secure); something else inside is classified like any first packet.
+*/
+func probeTLSWrapped(addr string, opts Options) (Result, bool) {
+	conn, err := opts.Connect("tcp", addr)
+	if err != nil {
+		return Result{}, false
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(opts.Timeout))
+	session, ti := clientHandshake(conn, opts.Timeout, opts.ClientCert, opts.TLSPolicy)
+	_ = conn.SetDeadline(time.Time{})
+	if session == nil {
+		return Result{}, false
+	}
+	defer session.Close()
+
+	buf := opts.Buffers.get()
+	defer opts.Buffers.put(buf)
+	first, _, _, err := readFirstPacket(session, opts.Timeout, *buf)
+	res := Result{OK: true, TLS: ti, TLSWrapped: true}
+	if err != nil || len(first) < 4 {
+		res.Reason = "no MySQL handshake inside TLS"
+		if err != nil {
+			res.Reason += ": " + err.Error()
+		}
+		return res, true
+	}
+	info, perr := ParseHandshake(first)
+	if advertised, truncated := truncatedPacket(first, len(*buf)); truncated {
+		perr = truncationError(advertised, len(first)-4)
+		res.Truncated, res.AdvertisedLength = true, advertised
+	}
+	var serr *ServerError
+	switch {
+	case errors.As(perr, &serr):
+		res.Reason, res.ServerError, res.ServerErrorClass, res.FirstPacketClass = serr.Error(), serr, serr.Class(), FirstPacketErr
+	case perr != nil:
+		res.Reason, res.FirstPacketClass = perr.Error(), ClassifyFirstPacket(first)
+	default:
+		res.MySQL, res.HandshakeInfo, res.Confidence = true, info, DetectionConfidence(first, info)
+		if opts.Credentials != nil {
+			res.Auth = authenticatedSession(session, 1, info, true, opts)
+		}
+	}
+	return res, true
+}
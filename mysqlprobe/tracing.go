@@ -0,0 +1,31 @@
+package mysqlprobe
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the probe's spans.
+const tracerName = "github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+
+/*
+startSpan starts a span named name as a child of the span in ctx, from that span's tracer provider.
+Function-level comment: the probe has no tracing setup of its own: a caller that passes a context carrying a recording span gets the dial, first-packet read, parse, and TLS stages as its children, and everyone else gets no-op spans at no cost.
+*/
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+/*
+endSpan records err, if any, as the span's error status and ends it.
+*/
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,43 @@
+package mysqlprobe
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mariaDBReplicationPrefix is prepended by MariaDB 10+ so old replicas don't mistake it for MySQL 10.
+const mariaDBReplicationPrefix = "5.5.5-"
+
+// serverVersionPattern captures the leading major[.minor[.patch]] and whatever follows it.
+var serverVersionPattern = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?(.*)$`)
+
+/*
+VersionInfo is the server version split into numeric components and the build suffix.
+Suffix holds everything after the numeric part without its leading separator (e.g. "log", "MariaDB-1:10.11.6+maria~ubu2204").
+*/
+type VersionInfo struct {
+	Major  int    `json:"major"`
+	Minor  int    `json:"minor"`
+	Patch  int    `json:"patch"`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+/*
+ParseServerVersion splits a handshake server version string into a VersionInfo.
+Function-level comment: strips MariaDB's "5.5.5-" replication prefix so the real release is reported; returns nil when the string does not start with a number.
+*/
+func ParseServerVersion(s string) *VersionInfo {
+	if strings.HasPrefix(s, mariaDBReplicationPrefix) && strings.Contains(s, "MariaDB") {
+		s = strings.TrimPrefix(s, mariaDBReplicationPrefix)
+	}
+	m := serverVersionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	v := &VersionInfo{Suffix: strings.TrimLeft(m[4], "-.+_ ")}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+nmapRun is the subset of nmap's -oX document needed to pick targets.
+*/
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+			Type string `xml:"type,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name string `xml:"name,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+/*
+loadNmapTargets reads nmap -oX output and returns its open TCP ports as probe targets.
+Function-level comment: when services is non-empty only ports nmap labeled with one of those service names are kept ("unknown" also matches ports nmap left unnamed). The IP address is dialed and the user-supplied hostname (else the first PTR name) is carried as the target's hostname; hosts nmap marked down are skipped.
+*/
+func loadNmapTargets(path string, services []string) ([]target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	wanted := make(map[string]bool, len(services))
+	for _, s := range services {
+		wanted[strings.ToLower(s)] = true
+	}
+
+	var targets []target
+	for _, h := range run.Hosts {
+		if h.Status.State == "down" {
+			continue
+		}
+		addr := ""
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				addr = a.Addr
+				break
+			}
+		}
+		if addr == "" {
+			continue
+		}
+		hostname := ""
+		for _, n := range h.Hostnames {
+			if hostname == "" || n.Type == "user" {
+				hostname = n.Name
+			}
+			if n.Type == "user" {
+				break
+			}
+		}
+		first := true
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			service := strings.ToLower(p.Service.Name)
+			if service == "" {
+				service = "unknown"
+			}
+			if len(wanted) > 0 && !wanted[service] {
+				continue
+			}
+			targets = append(targets, target{host: addr, port: p.PortID, runUDP: first, hostname: hostname})
+			first = false
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// nmapXMLVersion is the nmap output schema version the nmap-xml format claims to follow.
+const nmapXMLVersion = "1.05"
+
+/*
+nmapXMLHost is one <host> element of nmap's XML output.
+*/
+type nmapXMLHost struct {
+	XMLName   xml.Name `xml:"host"`
+	StartTime int64    `xml:"starttime,attr"`
+	EndTime   int64    `xml:"endtime,attr"`
+	Status    struct {
+		State  string `xml:"state,attr"`
+		Reason string `xml:"reason,attr"`
+	} `xml:"status"`
+	Address struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []nmapXMLHostname `xml:"hostnames>hostname,omitempty"`
+	Ports     []nmapXMLPort     `xml:"ports>port"`
+}
+
+/*
+nmapXMLHostname is a <hostname> element.
+*/
+type nmapXMLHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+/*
+nmapXMLPort is a <port> element with its state, service, and script results.
+*/
+type nmapXMLPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   int    `xml:"portid,attr"`
+	State    struct {
+		State  string `xml:"state,attr"`
+		Reason string `xml:"reason,attr"`
+	} `xml:"state"`
+	Service *nmapXMLService `xml:"service,omitempty"`
+	Scripts []nmapXMLScript `xml:"script,omitempty"`
+}
+
+/*
+nmapXMLService is a <service> element as nmap's version detection would write it.
+*/
+type nmapXMLService struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr,omitempty"`
+	Version   string `xml:"version,attr,omitempty"`
+	ExtraInfo string `xml:"extrainfo,attr,omitempty"`
+	Method    string `xml:"method,attr"`
+	Conf      int    `xml:"conf,attr"`
+}
+
+/*
+nmapXMLScript is a <script> element: the human-readable output plus structured <elem> children.
+*/
+type nmapXMLScript struct {
+	ID     string        `xml:"id,attr"`
+	Output string        `xml:"output,attr"`
+	Elems  []nmapXMLElem `xml:"elem"`
+}
+
+/*
+nmapXMLElem is one key/value <elem> of a script result.
+*/
+type nmapXMLElem struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+/*
+nmapXMLWriter streams results as an nmap XML document, one <host> element per probed target.
+Nmap groups a host's ports under one element; tools that import nmap XML merge repeated hosts by address, so streaming keeps memory flat.
+*/
+type nmapXMLWriter struct {
+	out     io.Writer
+	enc     *xml.Encoder
+	start   time.Time
+	started bool
+	up      int
+	down    int
+}
+
+/*
+newNmapXMLWriter returns an nmap XML writer on w; the document is opened on the first write.
+*/
+func newNmapXMLWriter(w io.Writer) *nmapXMLWriter {
+	return &nmapXMLWriter{out: w, enc: xml.NewEncoder(w), start: time.Now()}
+}
+
+/*
+begin writes the XML prolog and the opening <nmaprun> element.
+*/
+func (nw *nmapXMLWriter) begin() error {
+	nw.started = true
+	_, err := fmt.Fprintf(nw.out, "%s<!DOCTYPE nmaprun>\n<nmaprun scanner=\"mysql_scout\" args=\"%s\" start=\"%d\" startstr=\"%s\" version=\"1.0\" xmloutputversion=\"%s\">\n",
+		xml.Header, xmlEscape(strings.Join(os.Args, " ")), nw.start.Unix(), nw.start.Format(time.ANSIC), nmapXMLVersion)
+	return err
+}
+
+/*
+write appends res as a <host> element.
+Function-level comment: refused connections become closed ports, timeouts filtered ports, and anything that answered an open port; targets that were never contacted (excluded, unresolvable) are left out as nmap would.
+*/
+func (nw *nmapXMLWriter) write(res Result) error {
+	if !nw.started {
+		if err := nw.begin(); err != nil {
+			return err
+		}
+	}
+	status, _ := res.Status()
+	port := nmapXMLPort{Protocol: "tcp", PortID: res.Port}
+	hostState, hostReason := "up", "syn-ack"
+	switch status {
+	case mysqlprobe.StatusSuccess, mysqlprobe.StatusProtocolError, mysqlprobe.StatusApplicationError, mysqlprobe.StatusIOTimeout, mysqlprobe.StatusConnectionClosed:
+		port.State.State, port.State.Reason = "open", "syn-ack"
+	case mysqlprobe.StatusConnectionRefused:
+		port.State.State, port.State.Reason = "closed", "conn-refused"
+		hostReason = "conn-refused"
+	case mysqlprobe.StatusConnectionTimeout:
+		port.State.State, port.State.Reason = "filtered", "no-response"
+		hostState, hostReason = "unknown", "no-response"
+	default:
+		return nil
+	}
+	port.Service, port.Scripts = nmapService(res)
+
+	now := time.Now().Unix()
+	h := nmapXMLHost{StartTime: now, EndTime: now, Ports: []nmapXMLPort{port}}
+	h.Status.State, h.Status.Reason = hostState, hostReason
+	h.Address.Addr, h.Address.AddrType = res.Host, "ipv4"
+	if addr, err := netip.ParseAddr(res.Host); err == nil && addr.Is6() {
+		h.Address.AddrType = "ipv6"
+	} else if err != nil {
+		h.Hostnames = append(h.Hostnames, nmapXMLHostname{Name: res.Host, Type: "user"})
+	}
+	if len(res.Hostnames) > 0 {
+		for _, name := range res.Hostnames {
+			h.Hostnames = append(h.Hostnames, nmapXMLHostname{Name: name, Type: "user"})
+		}
+	} else if res.Hostname != "" {
+		h.Hostnames = append(h.Hostnames, nmapXMLHostname{Name: res.Hostname, Type: "user"})
+	}
+	if hostState == "up" {
+		nw.up++
+	} else {
+		nw.down++
+	}
+	if err := nw.enc.Encode(h); err != nil {
+		return err
+	}
+	_, err := io.WriteString(nw.out, "\n")
+	return err
+}
+
+/*
+flush writes <runstats> and closes the document.
+*/
+func (nw *nmapXMLWriter) flush() error {
+	if !nw.started {
+		if err := nw.begin(); err != nil {
+			return err
+		}
+	}
+	end := time.Now()
+	elapsed := end.Sub(nw.start).Seconds()
+	_, err := fmt.Fprintf(nw.out, "<runstats><finished time=\"%d\" timestr=\"%s\" elapsed=\"%.2f\" summary=\"mysql_scout done; %d hosts probed in %.2f seconds\" exit=\"success\"/><hosts up=\"%d\" down=\"%d\" total=\"%d\"/></runstats>\n</nmaprun>\n",
+		end.Unix(), end.Format(time.ANSIC), elapsed, nw.up+nw.down, elapsed, nw.up, nw.down, nw.up+nw.down)
+	return err
+}
+
+/*
+nmapService describes what answered on the port as nmap's version detection and NSE scripts would.
+Function-level comment: MySQL handshakes fill the service element and a mysql-info script shaped like nmap's own; a TLS continuation adds ssl-cert; a server refusing our host is reported as nmap does ("unauthorized"); other services get "unknown" plus a banner script when one was grabbed.
+*/
+func nmapService(res Result) (*nmapXMLService, []nmapXMLScript) {
+	if !res.OK {
+		return nil, nil
+	}
+	if res.ServerError != nil && res.ServerError.HostRefused() {
+		return &nmapXMLService{Name: "mysql", Product: "MySQL", ExtraInfo: "unauthorized", Method: "probed", Conf: 10}, nil
+	}
+	info := res.HandshakeInfo
+	if !res.MySQL || info == nil {
+		var scripts []nmapXMLScript
+		if res.GenericBanner != "" {
+			scripts = append(scripts, nmapXMLScript{ID: "banner", Output: res.GenericBanner})
+		}
+		return &nmapXMLService{Name: "unknown", Method: "table", Conf: 3}, scripts
+	}
+
+	svc := &nmapXMLService{Name: "mysql", Product: productName(info.ServerVersion), Version: info.ServerVersion, Method: "probed", Conf: 10}
+	if v := info.Version; v != nil {
+		svc.Version = fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+		svc.ExtraInfo = v.Suffix
+	}
+
+	elems := []nmapXMLElem{
+		{"Protocol", strconv.Itoa(int(info.ProtocolVersion))},
+		{"Version", info.ServerVersion},
+		{"Thread ID", strconv.FormatUint(uint64(info.ConnectionID), 10)},
+	}
+	if info.CapabilityFlags != 0 {
+		var caps []string
+		for _, f := range capabilityFlagNames {
+			if info.CapabilityFlags&f.bit != 0 {
+				caps = append(caps, f.name)
+			}
+		}
+		elems = append(elems,
+			nmapXMLElem{"Capabilities flags", strconv.FormatUint(uint64(info.CapabilityFlags), 10)},
+			nmapXMLElem{"Some Capabilities", strings.Join(caps, ", ")})
+	}
+	if info.StatusFlags != 0 {
+		var status []string
+		for _, f := range statusFlagNames {
+			if uint32(info.StatusFlags)&f.bit != 0 {
+				status = append(status, f.name)
+			}
+		}
+		elems = append(elems, nmapXMLElem{"Status", strings.Join(status, ", ")})
+	}
+	if info.AuthPluginName != "" {
+		elems = append(elems, nmapXMLElem{"Auth Plugin Name", info.AuthPluginName})
+	}
+	scripts := []nmapXMLScript{{ID: "mysql-info", Output: scriptOutput(elems), Elems: elems}}
+
+	if ti := res.TLS; ti != nil && ti.Error == "" {
+		certElems := []nmapXMLElem{
+			{"subject", ti.Subject},
+			{"issuer", ti.Issuer},
+			{"notBefore", ti.NotBefore},
+			{"notAfter", ti.NotAfter},
+		}
+		if len(ti.DNSNames) > 0 {
+			certElems = append(certElems, nmapXMLElem{"Subject Alternative Name", "DNS:" + strings.Join(ti.DNSNames, ", DNS:")})
+		}
+		scripts = append(scripts, nmapXMLScript{ID: "ssl-cert", Output: scriptOutput(certElems), Elems: certElems})
+	}
+	return svc, scripts
+}
+
+/*
+scriptOutput renders script elems as the indented "Key: value" lines nmap prints.
+*/
+func scriptOutput(elems []nmapXMLElem) string {
+	var sb strings.Builder
+	for _, e := range elems {
+		fmt.Fprintf(&sb, "\n  %s: %s", e.Key, e.Value)
+	}
+	return sb.String()
+}
+
+/*
+xmlEscape escapes s for use inside an XML attribute.
+*/
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
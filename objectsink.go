@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// defaultOutputChunkSize is how many bytes of NDJSON (before compression) go into one uploaded object.
+	defaultOutputChunkSize = 64 << 20
+	// defaultGCSUploadChunkSize is the resumable upload request size for gs:// output; a failed request resends only its own chunk.
+	defaultGCSUploadChunkSize = 16 << 20
+)
+
+/*
+objectPutter stores one finished object under key.
+*/
+type objectPutter func(ctx context.Context, key string, body []byte) error
+
+/*
+chunkUploader is the io.Writer results are written to for -output: it gzips NDJSON records into chunks and uploads each as its own object.
+Keys are time-partitioned by upload hour, <prefix>dt=YYYY-MM-DD/hour=HH/<run>-<seq>.ndjson.gz, where run is the scan's UTC start time plus a random suffix so concurrent scans (e.g. shards) never overwrite each other.
+*/
+type chunkUploader struct {
+	put       objectPutter
+	prefix    string
+	run       string
+	chunkSize int
+
+	seq     int
+	records int
+	raw     int
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+}
+
+/*
+newChunkUploader returns an uploader writing objects under prefix through put.
+*/
+func newChunkUploader(put objectPutter, prefix string, chunkSize int) *chunkUploader {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	u := &chunkUploader{
+		put:       put,
+		prefix:    prefix,
+		run:       time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		chunkSize: chunkSize,
+	}
+	u.gz = gzip.NewWriter(&u.buf)
+	return u
+}
+
+/*
+Write adds p, which must hold whole records, to the current chunk and uploads the chunk once it reaches the chunk size.
+Function-level comment: the JSON writer hands over one complete line per call, so chunks always end on a record boundary.
+*/
+func (u *chunkUploader) Write(p []byte) (int, error) {
+	n, err := u.gz.Write(p)
+	if err != nil {
+		return n, err
+	}
+	u.raw += n
+	u.records += bytes.Count(p, []byte{'\n'})
+	if u.raw >= u.chunkSize {
+		return n, u.upload()
+	}
+	return n, nil
+}
+
+/*
+Close uploads the final partial chunk, if any.
+*/
+func (u *chunkUploader) Close() error {
+	if u.raw == 0 {
+		return nil
+	}
+	return u.upload()
+}
+
+/*
+upload finishes the current chunk, stores it, and starts the next one.
+Function-level comment: a chunk whose upload fails (after the client's own retries) is dropped and the error names how many records were lost, so the rest of the scan still lands.
+*/
+func (u *chunkUploader) upload() error {
+	if err := u.gz.Close(); err != nil {
+		return err
+	}
+	u.seq++
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%sdt=%s/hour=%s/%s-%05d.ndjson.gz", u.prefix, now.Format("2006-01-02"), now.Format("15"), u.run, u.seq)
+	err := u.put(context.Background(), key, u.buf.Bytes())
+	if err != nil {
+		err = fmt.Errorf("upload %s (%d records lost): %w", key, u.records, err)
+	}
+	u.buf.Reset()
+	u.gz.Reset(&u.buf)
+	u.raw, u.records = 0, 0
+	return err
+}
+
+/*
+newObjectSink parses an -output URL and returns the uploader for it.
+Function-level comment: s3://bucket/prefix/ uses the standard AWS credential chain and region settings; an endpoint query parameter (s3://bucket/prefix/?endpoint=http://minio:9000) targets S3-compatible stores with path-style addressing. gs://bucket/prefix/ uses Application Default Credentials and resumable uploads in requests of gcsChunkSize bytes.
+*/
+func newObjectSink(ctx context.Context, spec string, chunkSize, gcsChunkSize int) (*chunkUploader, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("want s3://bucket/prefix/ or gs://bucket/prefix/, got %q", spec)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	switch u.Scheme {
+	case "s3":
+		put, err := s3Putter(ctx, u.Host, u.Query().Get("endpoint"))
+		if err != nil {
+			return nil, err
+		}
+		return newChunkUploader(put, prefix, chunkSize), nil
+	case "gs":
+		put, err := gcsPutter(ctx, u.Host, gcsChunkSize)
+		if err != nil {
+			return nil, err
+		}
+		return newChunkUploader(put, prefix, chunkSize), nil
+	}
+	return nil, fmt.Errorf("unsupported output scheme %q (want s3 or gs)", u.Scheme)
+}
+
+/*
+s3Putter returns a putter storing objects in bucket, optionally at a custom endpoint.
+*/
+func s3Putter(ctx context.Context, bucket, endpoint string) (objectPutter, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return func(ctx context.Context, key string, body []byte) error {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:          aws.String(bucket),
+			Key:             aws.String(key),
+			Body:            bytes.NewReader(body),
+			ContentType:     aws.String("application/x-ndjson"),
+			ContentEncoding: aws.String("gzip"),
+		})
+		return err
+	}, nil
+}
+
+/*
+gcsPutter returns a putter storing objects in a Google Cloud Storage bucket with resumable uploads of chunkSize-byte requests.
+*/
+func gcsPutter(ctx context.Context, bucket string, chunkSize int) (objectPutter, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create GCS client: %w", err)
+	}
+	b := client.Bucket(bucket)
+	return func(ctx context.Context, key string, body []byte) error {
+		w := b.Object(key).NewWriter(ctx)
+		w.ChunkSize = chunkSize
+		w.ContentType = "application/x-ndjson"
+		w.ContentEncoding = "gzip"
+		if _, err := w.Write(body); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}, nil
+}
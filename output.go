@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultCSVFields are the CSV columns used when -fields is not given.
+var defaultCSVFields = []string{
+	"host", "port", "ok", "mysql", "server_version", "protocol", "connection_id",
+	"auth_plugin", "tls.version", "provider", "eol", "error",
+}
+
+// verboseProbeFields are the result fields only a verbose probe fills in, at the cost of extra work per connection.
+var verboseProbeFields = []string{"timing", "first_bytes_hex", "reason"}
+
+/*
+needsVerboseProbe reports whether output in format, projected to fields (none: the format's own columns), shows any of verboseProbeFields.
+Function-level comment: pretty and parquet output always show reason; table, zgrab2, and nmap-xml never show any of them.
+*/
+func needsVerboseProbe(format string, fields []string) bool {
+	switch format {
+	case "pretty", "parquet":
+		return true
+	case "csv":
+		if len(fields) == 0 {
+			fields = defaultCSVFields
+		}
+	case "table", "zgrab2", "nmap-xml":
+		return false
+	}
+	for _, f := range fields {
+		top, _, _ := strings.Cut(f, ".")
+		if slices.Contains(verboseProbeFields, top) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+resultWriter renders results in one output format.
+Streaming implementations write each record through immediately so an interrupted scan never loses rows it already checkpointed; buffering ones (table, parquet) and those that close a document (nmap-xml) also implement flush, which only runs once the scan ends, so they cannot be used with -checkpoint.
+*/
+type resultWriter interface {
+	write(res Result) error
+}
+
+/*
+flushWriter finishes a writer that buffers output (such as the table format) once the scan is over.
+Function-level comment: writers that stream every record need no flush and are left alone.
+*/
+func flushWriter(w resultWriter) error {
+	if f, ok := w.(interface{ flush() error }); ok {
+		return f.flush()
+	}
+	return nil
+}
+
+/*
+formatSink is the OutputSink for the CLI's own formats: a resultWriter over a stream, with the writers beneath it (compressor, uploader) closed in order once the output is finished.
+*/
+type formatSink struct {
+	out     resultWriter
+	closers []io.Closer
+}
+
+/*
+Write renders a Result in the sink's format.
+*/
+func (s *formatSink) Write(result any) error {
+	res, ok := result.(Result)
+	if !ok {
+		return fmt.Errorf("unexpected result type %T", result)
+	}
+	return s.out.write(res)
+}
+
+/*
+Flush is a no-op: streaming formats write every record through, and buffering ones can only be finished once, by Close.
+*/
+func (s *formatSink) Flush() error {
+	return nil
+}
+
+/*
+Close finishes the format (see flushWriter) and closes the writers beneath it, reporting the first error.
+*/
+func (s *formatSink) Close() error {
+	err := flushWriter(s.out)
+	for _, c := range s.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+/*
+newResultWriter returns the writer for -format, projected onto fields when any are given.
+Function-level comment: fields are JSON output names, with dots reaching into nested objects (e.g. version.major, tls.version); the pretty, table, parquet, zgrab2, and nmap-xml formats ignore them.
+*/
+func newResultWriter(format string, fields []string, w io.Writer) (resultWriter, error) {
+	switch format {
+	case "", "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonWriter{enc: enc, out: w, fields: fields}, nil
+	case "csv":
+		if len(fields) == 0 {
+			fields = defaultCSVFields
+		}
+		return &csvWriter{w: csv.NewWriter(w), fields: fields}, nil
+	case "pretty":
+		if f, ok := w.(*os.File); ok {
+			return newPrettyWriter(f), nil
+		}
+		return &prettyWriter{out: w}, nil
+	case "table":
+		return &tableWriter{out: w}, nil
+	case "parquet":
+		return newParquetWriter(w), nil
+	case "zgrab2":
+		return newZgrab2Writer(w), nil
+	case "nmap-xml":
+		return newNmapXMLWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown format %q (want json, csv, pretty, table, parquet, zgrab2, or nmap-xml)", format)
+}
+
+/*
+resultFields converts a result to its generic JSON object form.
+Function-level comment: going through encoding/json keeps field names and omission rules identical to the JSON output.
+*/
+func resultFields(res Result) (map[string]any, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+/*
+fieldValue looks up a dotted field path in a result's JSON object, returning nil when any step is missing.
+*/
+func fieldValue(fields map[string]any, path string) any {
+	return filterField{strings.Split(path, ".")}.eval(fields)
+}
+
+/*
+jsonWriter prints one JSON object per line, optionally keeping only selected fields.
+*/
+type jsonWriter struct {
+	enc    *json.Encoder
+	out    io.Writer
+	fields []string
+}
+
+/*
+write encodes res in full, or as an object holding just the selected fields in the order given.
+Function-level comment: selected fields that are absent from the result are omitted rather than printed as null.
+*/
+func (jw *jsonWriter) write(res Result) error {
+	if len(jw.fields) == 0 {
+		return jw.enc.Encode(res)
+	}
+	fields, err := resultFields(res)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, name := range jw.fields {
+		v := fieldValue(fields, name)
+		if v == nil {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		key, _ := json.Marshal(name)
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteString("}\n")
+	_, err = jw.out.Write(buf.Bytes())
+	return err
+}
+
+/*
+csvWriter prints a header row followed by one row per result.
+*/
+type csvWriter struct {
+	w           *csv.Writer
+	fields      []string
+	wroteHeader bool
+}
+
+/*
+write emits the header on first use, then res as one row.
+Function-level comment: scalars are printed plainly, nested objects and arrays as compact JSON, and missing fields as empty cells.
+*/
+func (cw *csvWriter) write(res Result) error {
+	if !cw.wroteHeader {
+		if err := cw.w.Write(cw.fields); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+	fields, err := resultFields(res)
+	if err != nil {
+		return err
+	}
+	row := make([]string, len(cw.fields))
+	for i, name := range cw.fields {
+		row[i] = csvCell(fieldValue(fields, name))
+	}
+	if err := cw.w.Write(row); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+/*
+csvCell renders one decoded JSON value as a CSV cell.
+*/
+func csvCell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+/*
+parquetRow is the typed, flattened schema of the parquet format.
+Nested JSON objects become prefixed columns (version_major, tls_version, ...) and every field a target may lack is optional.
+*/
+type parquetRow struct {
+	Host            string            `parquet:"host"`
+	Hostname        *string           `parquet:"hostname,optional"`
+	Hostnames       []string          `parquet:"hostnames,list"`
+	TargetHost      *string           `parquet:"target_host,optional"`
+	TargetIP        *string           `parquet:"target_ip,optional"`
+	Source          *string           `parquet:"source,optional"`
+	Label           *string           `parquet:"label,optional"`
+	Labels          map[string]string `parquet:"labels,optional"`
+	ScanID          *string           `parquet:"scan_id,optional"`
+	RunLabel        *string           `parquet:"run_label,optional"`
+	Port            int32             `parquet:"port"`
+	OK              bool              `parquet:"ok"`
+	MySQL           bool              `parquet:"mysql"`
+	Error           *string           `parquet:"error,optional"`
+	Reason          *string           `parquet:"reason,optional"`
+	GenericBanner   *string           `parquet:"generic_banner,optional"`
+	Protocol        *int32            `parquet:"protocol,optional"`
+	ServerVersion   *string           `parquet:"server_version,optional"`
+	VersionMajor    *int32            `parquet:"version_major,optional"`
+	VersionMinor    *int32            `parquet:"version_minor,optional"`
+	VersionPatch    *int32            `parquet:"version_patch,optional"`
+	VersionSuffix   *string           `parquet:"version_suffix,optional"`
+	ConnectionID    *int64            `parquet:"connection_id,optional"`
+	CapabilityFlags *int64            `parquet:"capability_flags,optional"`
+	CharacterSet    *int32            `parquet:"character_set,optional"`
+	StatusFlags     *int32            `parquet:"status_flags,optional"`
+	AuthPlugin      *string           `parquet:"auth_plugin,optional"`
+	AuthPluginData  *string           `parquet:"auth_plugin_data,optional"`
+	SaltEntropy     *float64          `parquet:"salt_entropy,optional"`
+	TLSVersion      *string           `parquet:"tls_version,optional"`
+	TLSCipherSuite  *string           `parquet:"tls_cipher_suite,optional"`
+	TLSSubject      *string           `parquet:"tls_subject,optional"`
+	TLSIssuer       *string           `parquet:"tls_issuer,optional"`
+	TLSError        *string           `parquet:"tls_error,optional"`
+	EOL             *bool             `parquet:"eol,optional"`
+	EOLDate         *string           `parquet:"eol_date,optional"`
+	BuildVariant    *string           `parquet:"build_variant,optional"`
+	PlatformGuess   *string           `parquet:"platform_guess,optional"`
+	Provider        *string           `parquet:"provider,optional"`
+	WeakAuth        bool              `parquet:"weak_auth"`
+	WeakAuthReason  *string           `parquet:"weak_auth_reason,optional"`
+}
+
+/*
+parquetWriter buffers rows into a parquet file; the footer is only written on flush, so the file is unreadable until the scan ends.
+*/
+type parquetWriter struct {
+	w *parquet.GenericWriter[parquetRow]
+}
+
+/*
+newParquetWriter starts a parquet file on out.
+*/
+func newParquetWriter(out io.Writer) *parquetWriter {
+	return &parquetWriter{w: parquet.NewGenericWriter[parquetRow](out)}
+}
+
+/*
+optional returns a pointer to v, or nil for the zero value so empty fields are stored as nulls.
+*/
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+/*
+write converts res to a parquetRow and appends it.
+Function-level comment: handshake numbers are only set when MySQL was detected so a 0 never masquerades as a parsed value.
+*/
+func (pw *parquetWriter) write(res Result) error {
+	row := parquetRow{
+		Host:           res.Host,
+		Hostname:       optional(res.Hostname),
+		Hostnames:      res.Hostnames,
+		TargetHost:     optional(res.TargetHost),
+		TargetIP:       optional(res.TargetIP),
+		Source:         optional(res.Source),
+		Label:          optional(res.Label),
+		Labels:         res.Labels,
+		ScanID:         optional(res.ScanID),
+		RunLabel:       optional(res.RunLabel),
+		Port:           int32(res.Port),
+		OK:             res.OK,
+		MySQL:          res.MySQL,
+		Error:          optional(res.Error),
+		Reason:         optional(res.Reason),
+		GenericBanner:  optional(res.GenericBanner),
+		EOL:            res.EOL,
+		EOLDate:        optional(res.EOLDate),
+		BuildVariant:   optional(res.BuildVariant),
+		PlatformGuess:  optional(res.PlatformGuess),
+		Provider:       optional(res.Provider),
+		WeakAuth:       res.WeakAuth,
+		WeakAuthReason: optional(res.WeakAuthReason),
+	}
+	if info := res.HandshakeInfo; info != nil {
+		protocol, connID := int32(info.ProtocolVersion), int64(info.ConnectionID)
+		caps, charset, status := int64(info.CapabilityFlags), int32(info.CharacterSet), int32(info.StatusFlags)
+		row.Protocol, row.ConnectionID = &protocol, &connID
+		row.CapabilityFlags, row.CharacterSet, row.StatusFlags = &caps, &charset, &status
+		row.ServerVersion = &info.ServerVersion
+		row.AuthPlugin = optional(info.AuthPluginName)
+		row.AuthPluginData = optional(info.AuthPluginData)
+		row.SaltEntropy = info.SaltEntropy
+		if v := info.Version; v != nil {
+			major, minor, patch := int32(v.Major), int32(v.Minor), int32(v.Patch)
+			row.VersionMajor, row.VersionMinor, row.VersionPatch = &major, &minor, &patch
+			row.VersionSuffix = optional(v.Suffix)
+		}
+	}
+	if t := res.TLS; t != nil {
+		row.TLSVersion = optional(t.Version)
+		row.TLSCipherSuite = optional(t.CipherSuite)
+		row.TLSSubject = optional(t.Subject)
+		row.TLSIssuer = optional(t.Issuer)
+		row.TLSError = optional(t.Error)
+	}
+	_, err := pw.w.Write([]parquetRow{row})
+	return err
+}
+
+/*
+flush writes the remaining row group and the file footer.
+*/
+func (pw *parquetWriter) flush() error {
+	return pw.w.Close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["parse"] = runParse
+}
+
+/*
+runParse is the parse subcommand.
+Function-level comment: runs the MySQL prober over hex-encoded first packets given as arguments, or one per line on stdin, and prints the JSON result for each as a scan of that server would, without any network access; useful for bytes captured by tcpdump or another scanner.
+*/
+func runParse(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "Full handshake detail, as with the scan's -v")
+	bannerFallback := fs.Bool("banner-fallback", false, "Report a non-MySQL packet as a generic banner, as with the scan's -banner-fallback")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout parse [-v] [-banner-fallback] [hex...]   (hex lines on stdin when none are given)")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	packets := fs.Args()
+	if len(packets) == 0 {
+		sc := bufio.NewScanner(os.Stdin)
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				packets = append(packets, line)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "parse: %v\n", err)
+			return 1
+		}
+	}
+
+	opts := mysqlprobe.Options{Timeout: time.Second, Verbose: true, BannerFallback: *bannerFallback}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	status := 0
+	for i, packet := range packets {
+		packet = strings.Join(strings.Fields(packet), "")
+		res, err := replaySession(recordedSession{Responses: []string{packet}, Closed: true}, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse packet %d: %v\n", i+1, err)
+			status = 1
+			continue
+		}
+		if !*verbose && res.HandshakeInfo != nil {
+			res.HandshakeInfo = res.HandshakeInfo.Basic()
+		}
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintf(os.Stderr, "parse: %v\n", err)
+			return 1
+		}
+	}
+	return status
+}
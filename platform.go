@@ -0,0 +1,216 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// Platforms reported as platform_guess, before any release suffix (e.g. ubuntu-22.04).
+const (
+	platformUbuntu  = "ubuntu"
+	platformDebian  = "debian"
+	platformWindows = "windows"
+	platformMacOS   = "macos"
+)
+
+// platformConfidenceThreshold is the score a platform needs before it is guessed.
+const platformConfidenceThreshold = 2
+
+// collationUTF8MB4General is utf8mb4_general_ci, the server default Debian-family MariaDB packaging configures in place of upstream's latin1.
+const collationUTF8MB4General = 45
+
+// Release numbers in version suffixes: Ubuntu package revisions (0ubuntu0.22.04.1), Debian ones (+deb12u1, 1debian11), and MariaDB repository packages (+maria~ubu2204, +maria~deb12, or a release codename such as +maria~jammy).
+var (
+	ubuntuPackageRelease = regexp.MustCompile(`ubuntu\d*\.(\d+\.\d+)`)
+	debianPackageRelease = regexp.MustCompile(`\+deb(\d+)|debian(\d+)`)
+	mariaRepoUbuntu      = regexp.MustCompile(`\+maria~ubu(\d\d)(\d\d)`)
+	mariaRepoDebian      = regexp.MustCompile(`\+maria~deb(\d+)`)
+	mariaRepoCodename    = regexp.MustCompile(`\+maria~([a-z]+)`)
+)
+
+// releaseCodenames maps the Ubuntu and Debian codenames MariaDB's repositories use to the platform and release.
+var releaseCodenames = map[string][2]string{
+	"xenial":   {platformUbuntu, "16.04"},
+	"bionic":   {platformUbuntu, "18.04"},
+	"focal":    {platformUbuntu, "20.04"},
+	"jammy":    {platformUbuntu, "22.04"},
+	"noble":    {platformUbuntu, "24.04"},
+	"stretch":  {platformDebian, "9"},
+	"buster":   {platformDebian, "10"},
+	"bullseye": {platformDebian, "11"},
+	"bookworm": {platformDebian, "12"},
+	"trixie":   {platformDebian, "13"},
+}
+
+// windowsDefaultHostname matches the computer names Windows Setup generates, e.g. WIN-3K9F0QJ2P1A, as they appear in self-issued certificates.
+var windowsDefaultHostname = regexp.MustCompile(`(?i)\bCN=WIN-[A-Z0-9]{11}\b`)
+
+/*
+platformObservation is what a platform guess is made from: the handshake, the TLS certificate, version_comment when authenticated mode read it, and whether the target was a named pipe.
+*/
+type platformObservation struct {
+	info           *mysqlprobe.HandshakeInfo
+	tls            *mysqlprobe.TLSInfo
+	versionComment string
+	pipe           bool
+}
+
+/*
+platformSignal is one heuristic pointing at the operating system a server runs on.
+Strong signals (weight 2) are enough on their own; weak signals (weight 1) must be corroborated.
+*/
+type platformSignal struct {
+	platform string
+	weight   int
+	evidence string
+	match    func(o platformObservation) bool
+}
+
+var platformSignals = []platformSignal{
+	{platformUbuntu, 2, "version suffix is an Ubuntu package revision", func(o platformObservation) bool {
+		return strings.Contains(strings.ToLower(versionSuffix(o.info)), "ubuntu")
+	}},
+	{platformUbuntu, 2, "version suffix is a MariaDB repository package for Ubuntu, as the official mariadb Docker image also runs", func(o platformObservation) bool {
+		return mariaRepoPlatform(o.info) == platformUbuntu
+	}},
+	{platformUbuntu, 2, "version_comment names Ubuntu", func(o platformObservation) bool {
+		return strings.Contains(o.versionComment, "Ubuntu")
+	}},
+	{platformUbuntu, 1, "default collation utf8mb4_general_ci, as Debian-family MariaDB packaging configures", func(o platformObservation) bool {
+		return debianFamilyCharset(o.info)
+	}},
+	{platformDebian, 2, "version suffix is a Debian package revision", func(o platformObservation) bool {
+		return debianPackageSuffix.MatchString(strings.ToLower(versionSuffix(o.info)))
+	}},
+	{platformDebian, 2, "version suffix is a MariaDB repository package for Debian", func(o platformObservation) bool {
+		return mariaRepoPlatform(o.info) == platformDebian
+	}},
+	{platformDebian, 2, "version_comment names Debian", func(o platformObservation) bool {
+		return strings.Contains(o.versionComment, "Debian")
+	}},
+	{platformDebian, 1, "default collation utf8mb4_general_ci, as Debian-family MariaDB packaging configures", func(o platformObservation) bool {
+		return debianFamilyCharset(o.info)
+	}},
+	{platformWindows, 2, "probed over a Windows named pipe", func(o platformObservation) bool {
+		return o.pipe
+	}},
+	{platformWindows, 1, "certificate names a Windows-generated computer name (WIN-...)", func(o platformObservation) bool {
+		return o.tls != nil && o.tls.Error == "" && (windowsDefaultHostname.MatchString(o.tls.Subject) || windowsDefaultHostname.MatchString(o.tls.Issuer))
+	}},
+	{platformWindows, 1, "certificate issued by an Active Directory CA (DC= in the issuer)", func(o platformObservation) bool {
+		return o.tls != nil && o.tls.Error == "" && strings.Contains(o.tls.Issuer, "DC=")
+	}},
+	{platformMacOS, 2, "version_comment names Homebrew", func(o platformObservation) bool {
+		return strings.Contains(o.versionComment, "Homebrew")
+	}},
+}
+
+/*
+classifyPlatform guesses the operating system a server runs on, with the evidence, by scoring platformSignals the way classifyProvider scores providers.
+Function-level comment: returns "" when no platform reaches platformConfidenceThreshold or two tie. Ubuntu and Debian guesses carry the release when the version suffix gives it, e.g. ubuntu-22.04 or debian-12. Containers are named by the distribution they were built from: the official mariadb Docker image reports MariaDB's Ubuntu repository packages, the official mysql image nothing that tells it from Oracle's own binaries.
+*/
+func classifyPlatform(o platformObservation) (string, []string) {
+	if o.info == nil {
+		return "", nil
+	}
+	scores := make(map[string]int)
+	evidence := make(map[string][]string)
+	for _, s := range platformSignals {
+		if s.match(o) {
+			scores[s.platform] += s.weight
+			evidence[s.platform] = append(evidence[s.platform], s.evidence)
+		}
+	}
+	best, bestScore, tied := "", 0, false
+	for p, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = p, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied || bestScore < platformConfidenceThreshold {
+		return "", nil
+	}
+	if release := platformRelease(best, o.info); release != "" {
+		return best + "-" + release, evidence[best]
+	}
+	return best, evidence[best]
+}
+
+/*
+newPlatformObservation collects what classifyPlatform looks at from a result.
+*/
+func newPlatformObservation(res *Result, pipe bool) platformObservation {
+	o := platformObservation{info: res.HandshakeInfo, tls: res.TLS, pipe: pipe}
+	if res.Auth != nil {
+		if v := res.Auth.Variables["version_comment"]; v != nil {
+			o.versionComment = *v
+		}
+	}
+	return o
+}
+
+/*
+versionSuffix returns the build suffix of the server version, or "" when the version did not parse.
+*/
+func versionSuffix(info *mysqlprobe.HandshakeInfo) string {
+	if info.Version == nil {
+		return ""
+	}
+	return info.Version.Suffix
+}
+
+/*
+mariaRepoPlatform returns the platform a MariaDB repository package (+maria~...) was built for, or "".
+*/
+func mariaRepoPlatform(info *mysqlprobe.HandshakeInfo) string {
+	switch v := info.ServerVersion; {
+	case mariaRepoUbuntu.MatchString(v):
+		return platformUbuntu
+	case mariaRepoDebian.MatchString(v):
+		return platformDebian
+	case mariaRepoCodename.MatchString(v):
+		return releaseCodenames[mariaRepoCodename.FindStringSubmatch(v)[1]][0]
+	}
+	return ""
+}
+
+/*
+debianFamilyCharset reports whether a MariaDB server defaults to utf8mb4_general_ci, which Debian, Ubuntu, and MariaDB's own Debian-family packages configure while upstream builds keep latin1.
+*/
+func debianFamilyCharset(info *mysqlprobe.HandshakeInfo) bool {
+	return info.CharacterSet == collationUTF8MB4General && strings.Contains(strings.ToLower(info.ServerVersion), "mariadb")
+}
+
+/*
+platformRelease returns the Ubuntu or Debian release named by the version suffix, or "".
+*/
+func platformRelease(platform string, info *mysqlprobe.HandshakeInfo) string {
+	v := info.ServerVersion
+	switch platform {
+	case platformUbuntu:
+		if m := ubuntuPackageRelease.FindStringSubmatch(v); m != nil {
+			return m[1]
+		}
+		if m := mariaRepoUbuntu.FindStringSubmatch(v); m != nil {
+			return m[1] + "." + m[2]
+		}
+	case platformDebian:
+		if m := debianPackageRelease.FindStringSubmatch(v); m != nil {
+			return m[1] + m[2]
+		}
+		if m := mariaRepoDebian.FindStringSubmatch(v); m != nil {
+			return m[1]
+		}
+	}
+	if m := mariaRepoCodename.FindStringSubmatch(v); m != nil {
+		if r, ok := releaseCodenames[m[1]]; ok && r[0] == platform {
+			return r[1]
+		}
+	}
+	return ""
+}
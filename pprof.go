@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+/*
+startPprof serves net/http/pprof's profiling endpoints under /debug/pprof/ on addr for the life of the process.
+Function-level comment: the listener is opened before returning so a bad or busy address fails the run up front; the handlers go on a private mux so no other server in the process exposes them. Returns the bound address (useful with port 0).
+*/
+func startPprof(addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	go http.Serve(ln, mux)
+	return ln.Addr().String(), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ANSI colors used by the pretty format.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+// prettyAddrWidth is the column width reserved for host:port so summaries line up.
+const prettyAddrWidth = 28
+
+/*
+prettyWriter prints one aligned, human-oriented summary line per target.
+Colors are only used when writing to a terminal (and NO_COLOR is unset).
+*/
+type prettyWriter struct {
+	out   io.Writer
+	color bool
+}
+
+/*
+isTerminal reports whether f is attached to a character device such as a TTY.
+*/
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+/*
+newPrettyWriter returns a pretty writer for out, enabling color only for terminals.
+*/
+func newPrettyWriter(out *os.File) *prettyWriter {
+	return &prettyWriter{out: out, color: isTerminal(out) && os.Getenv("NO_COLOR") == ""}
+}
+
+/*
+paint wraps s in the given color when color output is enabled.
+*/
+func (pw *prettyWriter) paint(color, s string) string {
+	if !pw.color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+/*
+write prints res as: status tag, address, then the most useful details for that outcome.
+Function-level comment: green marks detected MySQL, red marks errors, and yellow marks anomalies (something answered but not as MySQL, or a failed TLS continuation).
+*/
+func (pw *prettyWriter) write(res Result) error {
+	addr := net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+	var tag, color string
+	var details []string
+	switch {
+	case !res.OK:
+		tag, color = "ERROR", ansiRed
+		details = append(details, res.Error)
+	case !res.MySQL:
+		tag, color = "OTHER", ansiYellow
+		if res.FirstPacketClass != "" {
+			details = append(details, res.FirstPacketClass)
+		}
+		if res.Reason != "" {
+			details = append(details, res.Reason)
+		}
+		if res.GenericBanner != "" {
+			details = append(details, strconv.Quote(res.GenericBanner))
+		}
+	default:
+		tag, color = "MYSQL", ansiGreen
+		details = append(details, res.ServerVersion)
+		if res.AuthPluginName != "" {
+			details = append(details, res.AuthPluginName)
+		}
+		if res.TLS != nil {
+			if res.TLS.Error != "" {
+				color = ansiYellow
+				details = append(details, "tls error: "+res.TLS.Error)
+			} else {
+				details = append(details, "tls "+res.TLS.Version)
+				if res.TLSWrapped {
+					details = append(details, "tls-wrapped")
+				}
+			}
+		}
+		if res.BuildVariant != "" {
+			details = append(details, "build "+res.BuildVariant)
+		}
+		if res.Provider != "" {
+			details = append(details, "provider "+res.Provider)
+		}
+		if res.EOL != nil && *res.EOL {
+			details = append(details, pw.paint(ansiYellow, "EOL since "+res.EOLDate))
+		}
+		if res.WeakAuth {
+			details = append(details, pw.paint(ansiYellow, "weak auth"))
+		}
+		if res.Tarpit != nil {
+			details = append(details, pw.paint(ansiYellow, "slow drip"))
+		}
+		if n := len(res.AdditionalPackets); n == 1 {
+			details = append(details, pw.paint(ansiYellow, "1 unprompted packet"))
+		} else if n > 1 {
+			details = append(details, pw.paint(ansiYellow, fmt.Sprintf("%d unprompted packets", n)))
+		}
+		if res.Downgrade != nil && res.Downgrade.LegacyAccepted {
+			details = append(details, pw.paint(ansiYellow, "legacy login accepted"))
+		}
+	}
+	_, err := fmt.Fprintf(pw.out, "%s  %-*s  %s\n", pw.paint(color, tag), prettyAddrWidth, addr, strings.Join(details, "  "))
+	return err
+}
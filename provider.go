@@ -0,0 +1,120 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// providerConfidenceThreshold is the score a provider needs before it is reported.
+const providerConfidenceThreshold = 2
+
+/*
+providerSignal is one heuristic pointing at a managed MySQL offering.
+Strong signals (weight 2) are enough on their own; weak signals (weight 1) must be corroborated.
+*/
+type providerSignal struct {
+	provider string
+	weight   int
+	evidence string
+	match    func(host string, info *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool
+}
+
+// vitessMissingCapabilities are CLIENT_COMPRESS and CLIENT_LOCAL_FILES, which MySQL and MariaDB servers offer but Vitess's vtgate, the front end of PlanetScale, never does.
+const vitessMissingCapabilities = 1<<5 | 1<<7
+
+// azureGatewayVersion matches the four-part versions (e.g. 5.7.32.0) reported by Azure Database for MySQL gateways.
+var azureGatewayVersion = regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+$`)
+
+var providerSignals = []providerSignal{
+	{"aws_aurora", 2, "server version contains mysql_aurora", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return strings.Contains(info.ServerVersion, "mysql_aurora")
+	}},
+	{"aws_rds", 2, "certificate issued by Amazon RDS CA", func(_ string, _ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+		return ti != nil && ti.Error == "" && strings.Contains(ti.Issuer, "Amazon RDS")
+	}},
+	{"aws_rds", 2, "hostname under rds.amazonaws.com", func(host string, _ *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return hostUnder(host, "rds.amazonaws.com")
+	}},
+	{"aws_rds", 1, "MySQL 8.0 defaults to mysql_native_password, as RDS and Aurora parameter groups set it", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return nativeAuthMySQL80(info)
+	}},
+	{"aws_aurora", 1, "MySQL 8.0 defaults to mysql_native_password, as RDS and Aurora parameter groups set it", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return nativeAuthMySQL80(info)
+	}},
+	{"gcp_cloudsql", 2, "server version has -google suffix", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return strings.HasSuffix(info.ServerVersion, "-google")
+	}},
+	{"gcp_cloudsql", 2, "certificate issued by Google Cloud SQL CA", func(_ string, _ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+		return ti != nil && ti.Error == "" && strings.Contains(ti.Issuer, "Google Cloud SQL")
+	}},
+	{"azure_mysql", 2, "hostname under mysql.database.azure.com", func(host string, _ *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return hostUnder(host, "mysql.database.azure.com")
+	}},
+	{"azure_mysql", 2, "certificate names *.mysql.database.azure.com", func(_ string, _ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+		return ti.CertMentions("mysql.database.azure.com")
+	}},
+	{"azure_mysql", 1, "certificate issued by a Microsoft CA", func(_ string, _ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+		return ti != nil && ti.Error == "" && strings.Contains(ti.Issuer, "Microsoft")
+	}},
+	{"azure_mysql", 1, "four-part gateway version string", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return azureGatewayVersion.MatchString(info.ServerVersion)
+	}},
+	{"planetscale", 2, "hostname under psdb.cloud", func(host string, _ *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return hostUnder(host, "psdb.cloud")
+	}},
+	{"planetscale", 2, "certificate names psdb.cloud", func(_ string, _ *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) bool {
+		return ti.CertMentions("psdb.cloud")
+	}},
+	{"planetscale", 1, "server version reports Vitess", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return strings.Contains(strings.ToLower(info.ServerVersion), "vitess")
+	}},
+	{"planetscale", 1, "capability flags lack CLIENT_COMPRESS and CLIENT_LOCAL_FILES, as Vitess's vtgate advertises them", func(_ string, info *mysqlprobe.HandshakeInfo, _ *mysqlprobe.TLSInfo) bool {
+		return info.CapabilityFlags&mysqlprobe.ClientProtocol41 != 0 && info.CapabilityFlags&vitessMissingCapabilities == 0
+	}},
+}
+
+/*
+nativeAuthMySQL80 reports whether a MySQL (not MariaDB) 8.0 server announces mysql_native_password, where stock builds default to caching_sha2_password.
+*/
+func nativeAuthMySQL80(info *mysqlprobe.HandshakeInfo) bool {
+	v := info.Version
+	return v != nil && v.Major == 8 && v.Minor == 0 && !strings.Contains(info.ServerVersion, "MariaDB") && info.AuthPluginName == "mysql_native_password"
+}
+
+/*
+hostUnder reports whether host equals domain or is a subdomain of it.
+*/
+func hostUnder(host, domain string) bool {
+	host = normalizeHostname(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+/*
+classifyProvider scores each managed-MySQL provider from the target hostname, the handshake (version string, auth plugin, and capability flags), and the TLS certificate.
+Function-level comment: returns the highest-scoring provider and the evidence behind it, or "" when no provider reaches providerConfidenceThreshold; a tie between providers is treated as not confident.
+*/
+func classifyProvider(host string, info *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) (string, []string) {
+	scores := make(map[string]int)
+	evidence := make(map[string][]string)
+	for _, s := range providerSignals {
+		if s.match(host, info, ti) {
+			scores[s.provider] += s.weight
+			evidence[s.provider] = append(evidence[s.provider], s.evidence)
+		}
+	}
+	best, bestScore, tied := "", 0, false
+	for p, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = p, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied || bestScore < providerConfidenceThreshold {
+		return "", nil
+	}
+	return best, evidence[best]
+}
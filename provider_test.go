@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func TestClassifyProvider(t *testing.T) {
+	const stockCaps = mysqlprobe.ClientLongPassword | 1<<5 | 1<<7 | mysqlprobe.ClientProtocol41 | mysqlprobe.ClientSecureConnection | mysqlprobe.ClientPluginAuth
+	const vitessCaps = stockCaps &^ vitessMissingCapabilities
+	info := func(version, plugin string, caps uint32) *mysqlprobe.HandshakeInfo {
+		return &mysqlprobe.HandshakeInfo{ServerVersion: version, Version: mysqlprobe.ParseServerVersion(version), AuthPluginName: plugin, CapabilityFlags: caps}
+	}
+	tests := []struct {
+		name     string
+		host     string
+		info     *mysqlprobe.HandshakeInfo
+		want     string
+		evidence string
+	}{
+		{"aurora version", "10.0.0.1", info("8.0.32-mysql_aurora.3.05.2", "mysql_native_password", stockCaps), "aws_aurora", "server version contains mysql_aurora"},
+		{"rds host and native auth", "db.abc.us-east-1.rds.amazonaws.com", info("8.0.35", "mysql_native_password", stockCaps), "aws_rds", "MySQL 8.0 defaults to mysql_native_password, as RDS and Aurora parameter groups set it"},
+		{"native auth alone", "10.0.0.1", info("8.0.35", "mysql_native_password", stockCaps), "", ""},
+		{"native auth on 8.4", "10.0.0.1", info("8.4.0", "mysql_native_password", stockCaps), "", ""},
+		{"stock 8.0", "10.0.0.1", info("8.0.35", "caching_sha2_password", stockCaps), "", ""},
+		{"vitess version and caps", "10.0.0.1", info("8.0.23-vitess", "mysql_native_password", vitessCaps), "planetscale", "capability flags lack CLIENT_COMPRESS and CLIENT_LOCAL_FILES, as Vitess's vtgate advertises them"},
+		{"vitess caps alone", "10.0.0.1", info("5.7.9", "mysql_native_password", vitessCaps), "", ""},
+		{"vitess caps tied with native auth", "10.0.0.1", info("8.0.40", "mysql_native_password", vitessCaps), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, evidence := classifyProvider(tt.host, tt.info, nil)
+			if got != tt.want {
+				t.Fatalf("provider = %q, want %q (evidence %q)", got, tt.want, evidence)
+			}
+			if tt.evidence != "" && !slices.Contains(evidence, tt.evidence) {
+				t.Errorf("evidence %q lacks %q", evidence, tt.evidence)
+			}
+		})
+	}
+}
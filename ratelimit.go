@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subnetV6Bits is the prefix IPv6 destinations are grouped by for -subnet-rate; the flag's prefix applies to IPv4.
+const subnetV6Bits = 64
+
+/*
+subnetRateSpec is a parsed -subnet-rate value.
+Exactly one of perSecond (new connections per second) or concurrent (simultaneous connections) is set.
+*/
+type subnetRateSpec struct {
+	bits       int
+	perSecond  float64
+	concurrent int
+}
+
+/*
+parseSubnetRate parses "<prefix>:<n>/s" (rate) or "<prefix>:<n>" (concurrency), e.g. "24:2/s".
+Function-level comment: an empty spec disables the limit; the prefix must be a valid IPv4 prefix length.
+*/
+func parseSubnetRate(spec string) (subnetRateSpec, error) {
+	if spec == "" {
+		return subnetRateSpec{}, nil
+	}
+	bitsStr, limit, ok := strings.Cut(spec, ":")
+	bits, err := strconv.Atoi(bitsStr)
+	if !ok || err != nil || bits < 0 || bits > 32 {
+		return subnetRateSpec{}, fmt.Errorf("invalid subnet rate %q (want prefix:N/s or prefix:N, e.g. 24:2/s)", spec)
+	}
+	if n, isRate := strings.CutSuffix(limit, "/s"); isRate {
+		rate, err := strconv.ParseFloat(n, 64)
+		if err != nil || rate <= 0 {
+			return subnetRateSpec{}, fmt.Errorf("invalid subnet rate %q: rate must be a positive number", spec)
+		}
+		return subnetRateSpec{bits: bits, perSecond: rate}, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return subnetRateSpec{}, fmt.Errorf("invalid subnet rate %q: concurrency must be a positive integer", spec)
+	}
+	return subnetRateSpec{bits: bits, concurrent: n}, nil
+}
+
+/*
+subnetLimiter paces or bounds connections into each destination subnet.
+*/
+type subnetLimiter struct {
+	spec     subnetRateSpec
+	interval time.Duration
+	conc     *hostLimiter
+
+	mu   sync.Mutex
+	next map[netip.Prefix]time.Time
+}
+
+/*
+newSubnetLimiter builds the limiter for spec; it returns nil when spec disables limiting.
+*/
+func newSubnetLimiter(spec subnetRateSpec) *subnetLimiter {
+	switch {
+	case spec.perSecond > 0:
+		return &subnetLimiter{
+			spec:     spec,
+			interval: time.Duration(float64(time.Second) / spec.perSecond),
+			next:     make(map[netip.Prefix]time.Time),
+		}
+	case spec.concurrent > 0:
+		return &subnetLimiter{spec: spec, conc: newHostLimiter(spec.concurrent)}
+	}
+	return nil
+}
+
+/*
+subnetOf maps a destination address to the subnet it is limited under.
+*/
+func (l *subnetLimiter) subnetOf(addr netip.Addr) netip.Prefix {
+	bits := l.spec.bits
+	if addr.Is6() {
+		bits = subnetV6Bits
+	}
+	p, _ := addr.Prefix(bits)
+	return p
+}
+
+/*
+acquire waits until a connection into addr's subnet is allowed and returns the release func.
+Function-level comment: in rate mode each subnet gets evenly spaced start slots and release is a no-op; in concurrency mode it reuses the per-host semaphore keyed by subnet. A nil limiter never blocks.
+*/
+func (l *subnetLimiter) acquire(addr netip.Addr) func() {
+	if l == nil {
+		return func() {}
+	}
+	subnet := l.subnetOf(addr)
+	if l.conc != nil {
+		return l.conc.acquire(subnet.String())
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	slot := now
+	if n, ok := l.next[subnet]; ok && n.After(now) {
+		slot = n
+	}
+	l.next[subnet] = slot.Add(l.interval)
+	if len(l.next) > 4096 {
+		l.pruneLocked(now)
+	}
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+	return func() {}
+}
+
+/*
+pruneLocked drops subnets whose next slot is already in the past; they behave the same as unseen subnets.
+Function-level comment: caller must hold l.mu.
+*/
+func (l *subnetLimiter) pruneLocked(now time.Time) {
+	for subnet, n := range l.next {
+		if !n.After(now) {
+			delete(l.next, subnet)
+		}
+	}
+}
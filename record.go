@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["replay"] = runReplay
+}
+
+// sessionSuffix names recorded session files.
+const sessionSuffix = ".session.json"
+
+/*
+recordedSession is what -record saves for one target's probe connection: the server's bytes, split at each of our writes so a replay can hand them out in the same order.
+Responses[0] is what the server sent before we wrote anything, Responses[i] what followed our i-th write (Sent[i-1]). Bytes after a TLS upgrade are ciphertext and are kept only for completeness.
+*/
+type recordedSession struct {
+	Host       string    `json:"host"`
+	IP         string    `json:"ip"`
+	Port       int       `json:"port"`
+	Protocol   string    `json:"protocol"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Responses  []string  `json:"responses"`
+	Sent       []string  `json:"sent,omitempty"`
+	// Closed is set when the server closed the connection (we read EOF) rather than us hanging up.
+	Closed bool `json:"closed,omitempty"`
+}
+
+/*
+sessionRecorder saves every main probe connection's traffic under dir, one file per target; a nil recorder records nothing.
+*/
+type sessionRecorder struct {
+	dir      string
+	protocol string
+}
+
+/*
+newSessionRecorder creates dir if needed and returns a recorder writing into it.
+*/
+func newSessionRecorder(dir, protocol string) (*sessionRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &sessionRecorder{dir: dir, protocol: protocol}, nil
+}
+
+/*
+wrap returns opts with a Dial that records the connection to ip:port, saved when the probe closes it.
+Function-level comment: a retried target's file holds its last attempt.
+*/
+func (r *sessionRecorder) wrap(opts mysqlprobe.Options, host, ip string, port int) mysqlprobe.Options {
+	if r == nil {
+		return opts
+	}
+	connect := opts.Connect
+	// connect already sends any PROXY protocol header; the wrapped Dial must not send another.
+	opts.ProxyProtocol = 0
+	opts.Dial = func(network, address string, _ time.Duration) (net.Conn, error) {
+		conn, err := connect(network, address)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.NewReplacer(":", "_", "%", "_").Replace(ip) + "_" + fmt.Sprint(port) + sessionSuffix
+		return &recordingConn{
+			Conn:      conn,
+			path:      filepath.Join(r.dir, name),
+			session:   recordedSession{Host: host, IP: ip, Port: port, Protocol: r.protocol, RecordedAt: time.Now().UTC()},
+			responses: [][]byte{nil},
+		}, nil
+	}
+	return opts
+}
+
+/*
+recordingConn copies everything read and written on a connection into a recordedSession, written out on Close.
+*/
+type recordingConn struct {
+	net.Conn
+	path      string
+	session   recordedSession
+	responses [][]byte
+	sent      [][]byte
+	closed    bool
+}
+
+/*
+Read records the bytes read into the current response.
+*/
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	last := len(c.responses) - 1
+	c.responses[last] = append(c.responses[last], p[:n]...)
+	if err == io.EOF {
+		c.session.Closed = true
+	}
+	return n, err
+}
+
+/*
+Write records the bytes sent and starts a new response.
+*/
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.sent = append(c.sent, append([]byte(nil), p...))
+	c.responses = append(c.responses, nil)
+	return c.Conn.Write(p)
+}
+
+/*
+Close closes the connection and saves the session; a failure to save is reported on stderr, since the probe's result is unaffected.
+*/
+func (c *recordingConn) Close() error {
+	err := c.Conn.Close()
+	if c.closed {
+		return err
+	}
+	c.closed = true
+	for _, r := range c.responses {
+		c.session.Responses = append(c.session.Responses, hex.EncodeToString(r))
+	}
+	for _, s := range c.sent {
+		c.session.Sent = append(c.session.Sent, hex.EncodeToString(s))
+	}
+	data, merr := json.MarshalIndent(c.session, "", "  ")
+	if merr == nil {
+		merr = os.WriteFile(c.path, append(data, '\n'), 0o644)
+	}
+	if merr != nil {
+		fmt.Fprintf(os.Stderr, "record %s: %v\n", c.path, merr)
+	}
+	return err
+}
+
+/*
+replayConn serves a recorded session's responses back in order, releasing Responses[i] only after the i-th write, and discards what is written.
+A read with nothing left to hand out fails with EOF when the server had closed the connection and with a deadline error otherwise, as the live read would have timed out.
+*/
+type replayConn struct {
+	responses [][]byte
+	closed    bool
+	cur       int
+	writes    int
+}
+
+/*
+Read copies the next bytes of the current response into p, moving on to the next response once a write has released it.
+*/
+func (c *replayConn) Read(p []byte) (int, error) {
+	for len(c.responses[c.cur]) == 0 {
+		if c.cur+1 >= len(c.responses) {
+			if c.closed {
+				return 0, io.EOF
+			}
+			return 0, os.ErrDeadlineExceeded
+		}
+		if c.cur >= c.writes {
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.cur++
+	}
+	n := copy(p, c.responses[c.cur])
+	c.responses[c.cur] = c.responses[c.cur][n:]
+	return n, nil
+}
+
+/*
+Write counts the write, releasing the next response.
+*/
+func (c *replayConn) Write(p []byte) (int, error) {
+	c.writes++
+	return len(p), nil
+}
+
+/*
+Close is a no-op.
+*/
+func (c *replayConn) Close() error {
+	return nil
+}
+
+/*
+LocalAddr returns a placeholder loopback address.
+*/
+func (c *replayConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+/*
+RemoteAddr returns a placeholder loopback address.
+*/
+func (c *replayConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+/*
+SetDeadline is a no-op; replayed reads never block.
+*/
+func (c *replayConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+/*
+SetReadDeadline is a no-op; replayed reads never block.
+*/
+func (c *replayConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+/*
+SetWriteDeadline is a no-op; writes are discarded.
+*/
+func (c *replayConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+/*
+replaySession runs the session's prober over its recorded bytes and returns the result as a scan would have.
+Function-level comment: TLS and login are not replayed (their traffic is encrypted or depends on our secrets), so only the handshake, ERR, and banner paths are re-run.
+*/
+func replaySession(s recordedSession, opts mysqlprobe.Options) (Result, error) {
+	protocol := s.Protocol
+	if protocol == "" {
+		protocol = mysqlprobe.ModuleName
+	}
+	newProber, ok := mysqlprobe.LookupProber(protocol)
+	if !ok {
+		return Result{}, fmt.Errorf("no prober registered for protocol %q", protocol)
+	}
+	conn := &replayConn{closed: s.Closed}
+	for _, r := range s.Responses {
+		b, err := hex.DecodeString(r)
+		if err != nil {
+			return Result{}, fmt.Errorf("response %d: %w", len(conn.responses), err)
+		}
+		conn.responses = append(conn.responses, b)
+	}
+	if len(conn.responses) == 0 {
+		conn.responses = [][]byte{nil}
+	}
+	opts.TLS, opts.TLSWrapped, opts.Credentials, opts.DetectTLSRequirement, opts.DowngradeTest, opts.EnumAuthPlugins = false, false, nil, false, false, false
+	r, err := newProber(opts).Probe(context.Background(), conn)
+	if err != nil && r.Error == "" {
+		r.Error = err.Error()
+	}
+	return Result{Host: s.Host, Port: s.Port, TargetHost: s.Host, TargetIP: s.IP, Result: r}, nil
+}
+
+/*
+runReplay is the replay subcommand.
+Function-level comment: re-parses every session recorded with -record in the given directories (or single files) and prints one JSON result per session, so parser bugs seen on unreachable networks can be reproduced from the recording.
+*/
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "Full handshake detail, as with the scan's -v")
+	bannerFallback := fs.Bool("banner-fallback", false, "Re-run the generic banner fallback for sessions recorded with it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout replay [-v] [-banner-fallback] dir/|file"+sessionSuffix+"...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	var paths []string
+	for _, arg := range fs.Args() {
+		st, err := os.Stat(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 1
+		}
+		if !st.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, _ := filepath.Glob(filepath.Join(arg, "*"+sessionSuffix))
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	opts := mysqlprobe.Options{Timeout: time.Second, Verbose: true, BannerFallback: *bannerFallback}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	status := 0
+	for _, path := range paths {
+		var s recordedSession
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &s)
+		}
+		var res Result
+		if err == nil {
+			res, err = replaySession(s, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "replay %s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		res.Source = path
+		if !*verbose && res.HandshakeInfo != nil {
+			res.HandshakeInfo = res.HandshakeInfo.Basic()
+		}
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			return 1
+		}
+	}
+	return status
+}
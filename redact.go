@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactDropKeys are the fields -redact removes outright: raw packet bytes, the auth salt, and the schema names a login could see. server_version_hex goes too, since it holds the unescaped version and could carry whatever the escaping hid.
+var redactDropKeys = map[string]bool{
+	"first_bytes_hex":    true,
+	"preview_hex":        true,
+	"auth_plugin_data":   true,
+	"hex":                true,
+	"response_hex":       true,
+	"server_version_hex": true,
+	"schemas":            true,
+}
+
+// redactNameKeys are the fields holding names of hosts, certificates, or pipes, which -redact replaces with hashes. host is one too when it is not an IP address.
+var redactNameKeys = map[string]bool{
+	"hostname":      true,
+	"hostnames":     true,
+	"target_host":   true,
+	"source":        true,
+	"pipe":          true,
+	"sni":           true,
+	"subject":       true,
+	"dns_names":     true,
+	"external_name": true,
+	"server_name":   true,
+}
+
+// redactIPv4 and redactIPv6 find address candidates in free text; candidates are confirmed with netip before being masked.
+var (
+	redactIPv4 = regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`)
+	redactIPv6 = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f]*:[0-9A-Fa-f:.]*`)
+)
+
+/*
+redactWriter is the -redact wrapper around another format: every result is redacted (see redactResult) before the format sees it.
+It sits beneath -dedupe, so results are merged and sorted by their real addresses.
+*/
+type redactWriter struct {
+	next resultWriter
+}
+
+/*
+write redacts res and hands it on.
+*/
+func (rw redactWriter) write(res Result) error {
+	red, err := redactResult(res)
+	if err != nil {
+		return err
+	}
+	return rw.next.write(red)
+}
+
+/*
+flush finishes the wrapped format.
+*/
+func (rw redactWriter) flush() error {
+	return flushWriter(rw.next)
+}
+
+/*
+redactResult returns the copy of res that -redact writes: IP addresses masked wherever they appear (the last octet of IPv4, everything past the /64 of IPv6), names of hosts and certificates replaced with hashes, also where they appear in error messages, and raw bytes dropped.
+Function-level comment: works on the JSON form so every nested object (TLS chain, cluster, sampling, UDP, Db2) is covered without knowing its type. Name hashes are unsalted and stable, so redacted files from different runs still join on them, but a guessed name can be confirmed; they are for sharing topology-free results, not for hiding names from someone who already has a list of candidates.
+*/
+func redactResult(res Result) (Result, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return Result{}, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Result{}, err
+	}
+	names := make(map[string]string)
+	collectNames(m, "", names)
+	redactValue(m, "", redactNameReplacer(names))
+	if data, err = json.Marshal(m); err != nil {
+		return Result{}, err
+	}
+	var out Result
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Result{}, err
+	}
+	out.span = res.span
+	return out, nil
+}
+
+/*
+collectNames records every name held in a name field of v, mapped to its hash, so the names can also be replaced where they appear in free text.
+*/
+func collectNames(v any, key string, names map[string]string) {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			collectNames(e, k, names)
+		}
+	case []any:
+		for _, e := range x {
+			collectNames(e, key, names)
+		}
+	case string:
+		if isRedactedName(key, x) {
+			names[x] = redactName(x)
+		}
+	}
+}
+
+/*
+redactNameReplacer replaces the collected names of four or more characters in free text, longest first so a name is not half-replaced by one of its suffixes.
+*/
+func redactNameReplacer(names map[string]string) *strings.Replacer {
+	keys := make([]string, 0, len(names))
+	for name := range names {
+		// Very short names would mangle unrelated words.
+		if len(name) >= 4 {
+			keys = append(keys, name)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	pairs := make([]string, 0, 2*len(keys))
+	for _, name := range keys {
+		pairs = append(pairs, name, names[name])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+/*
+redactValue redacts the JSON value v, found under key, in place, and returns it.
+*/
+func redactValue(v any, key string, names *strings.Replacer) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			if redactDropKeys[k] {
+				delete(x, k)
+				continue
+			}
+			x[k] = redactValue(e, k, names)
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = redactValue(e, key, names)
+		}
+	case string:
+		if isRedactedName(key, x) {
+			return redactName(x)
+		}
+		return maskAddrs(names.Replace(x))
+	}
+	return v
+}
+
+/*
+isRedactedName reports whether the string s under key is a name to hash: a value of a name field, or of host, that is not an IP address (addresses are masked instead).
+*/
+func isRedactedName(key, s string) bool {
+	if s == "" || (key != "host" && !redactNameKeys[key]) {
+		return false
+	}
+	_, err := netip.ParseAddr(s)
+	return err != nil
+}
+
+/*
+redactName hashes a name case-insensitively, e.g. "h:3f1a9c0b2e7d".
+*/
+func redactName(name string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSuffix(name, "."))))
+	return "h:" + hex.EncodeToString(sum[:6])
+}
+
+/*
+maskAddrs masks every IP address in s: 10.1.2.3 becomes 10.1.2.x and 2001:db8:1:2:a:b:c:d becomes 2001:db8:1:2::x.
+*/
+func maskAddrs(s string) string {
+	s = redactIPv4.ReplaceAllStringFunc(s, func(m string) string {
+		if _, err := netip.ParseAddr(m); err != nil {
+			return m
+		}
+		return m[:strings.LastIndexByte(m, '.')] + ".x"
+	})
+	return redactIPv6.ReplaceAllStringFunc(s, func(m string) string {
+		addr, err := netip.ParseAddr(m)
+		if err != nil || !addr.Is6() || addr.Is4In6() {
+			return m
+		}
+		return netip.PrefixFrom(addr, 64).Masked().Addr().String() + "x"
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func TestRedactResult(t *testing.T) {
+	var res Result
+	res.Host, res.Port, res.Hostname = "10.1.2.3", 3306, "db.internal.example"
+	res.HandshakeInfo = &mysqlprobe.HandshakeInfo{
+		ServerVersion:    `8.0.36-db\x0a10.9.8.7`,
+		ServerVersionHex: "382e302e33362d64620a31302e392e382e37",
+		AuthPluginData:   "3031323334353637383961626364656667686970",
+	}
+	red, err := redactResult(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if red.Host != "10.1.2.x" {
+		t.Errorf("host = %q, want 10.1.2.x", red.Host)
+	}
+	if red.Hostname == res.Hostname || red.Hostname == "" {
+		t.Errorf("hostname = %q, want a hash", red.Hostname)
+	}
+	info := red.HandshakeInfo
+	if info.ServerVersionHex != "" {
+		t.Errorf("server_version_hex = %q, want it dropped", info.ServerVersionHex)
+	}
+	if info.AuthPluginData != "" {
+		t.Errorf("auth_plugin_data = %q, want it dropped", info.AuthPluginData)
+	}
+}
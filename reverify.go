@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["reverify"] = runReverify
+}
+
+// reverifyFields are the observations reverify compares between the old and new result of a target, with how each is read from a result.
+var reverifyFields = []struct {
+	name string
+	get  func(Result) string
+}{
+	{"mysql", func(r Result) string { return strconv.FormatBool(r.MySQL) }},
+	{"status", func(r Result) string { status, _ := r.Status(); return string(status) }},
+	{"server_version", func(r Result) string {
+		return r.handshakeField(func(h *mysqlprobe.HandshakeInfo) string { return h.ServerVersion })
+	}},
+	{"auth_plugin", func(r Result) string {
+		return r.handshakeField(func(h *mysqlprobe.HandshakeInfo) string { return h.AuthPluginName })
+	}},
+	{"capability_flags", func(r Result) string {
+		return r.handshakeField(func(h *mysqlprobe.HandshakeInfo) string { return nonzero(uint64(h.CapabilityFlags)) })
+	}},
+	{"character_set", func(r Result) string {
+		return r.handshakeField(func(h *mysqlprobe.HandshakeInfo) string { return nonzero(uint64(h.CharacterSet)) })
+	}},
+	{"handshake_fingerprint", func(r Result) string {
+		return r.handshakeField(func(h *mysqlprobe.HandshakeInfo) string { return h.Fingerprint })
+	}},
+	{"tls", tlsPosture},
+	{"eol", func(r Result) string {
+		if r.EOL == nil {
+			return ""
+		}
+		return strconv.FormatBool(*r.EOL)
+	}},
+	{"build_variant", func(r Result) string { return r.BuildVariant }},
+	{"platform_guess", func(r Result) string { return r.PlatformGuess }},
+	{"provider", func(r Result) string { return r.Provider }},
+}
+
+/*
+reverifyRecord is the joined output line for one target: its result from the old file, its result now, and which observations differ.
+*/
+type reverifyRecord struct {
+	Host       string          `json:"host"`
+	Hostname   string          `json:"hostname,omitempty"`
+	Port       int             `json:"port"`
+	Old        *Result         `json:"old"`
+	New        *Result         `json:"new"`
+	Changed    map[string]bool `json:"changed"`
+	AnyChanged bool            `json:"any_changed"`
+}
+
+/*
+handshakeField reads one handshake value of r, or "" when no handshake was parsed.
+*/
+func (r Result) handshakeField(get func(*mysqlprobe.HandshakeInfo) string) string {
+	if r.HandshakeInfo == nil {
+		return ""
+	}
+	return get(r.HandshakeInfo)
+}
+
+/*
+nonzero formats n, or "" for 0, which is what results trimmed without -v hold for fields they did not keep.
+*/
+func nonzero(n uint64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+/*
+runReverify is the reverify subcommand.
+Function-level comment: reads the results of an earlier scan (NDJSON, optionally gzip or zstd compressed), probes each host:port in them again, and prints one joined record per target with the old and new result and a changed flag for every field in reverifyFields; targets first probed with -tls are probed with TLS again, and new handshakes are trimmed to the detail the old file recorded (without -v, the basic fields), so only real differences count. Lines without a host and port are skipped; with -changed-only, unchanged targets are not printed. Exits 1 if a file cannot be read.
+*/
+func runReverify(args []string) int {
+	fs := flag.NewFlagSet("reverify", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 3*time.Second, "Dial/read timeout")
+	concurrency := fs.Int("concurrency", 50, "Maximum targets probed at once")
+	retries := fs.Int("retries", 0, "Retry a target this many times after a timeout or dropped connection")
+	changedOnly := fs.Bool("changed-only", false, "Print only targets where some field changed")
+	outPath := fs.String("o", "", "Write joined records to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout reverify [-timeout D] [-concurrency N] [-retries N] [-changed-only] [-o file] old-results.ndjson...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if *concurrency < 1 || *retries < 0 || *timeout <= 0 {
+		fmt.Fprintln(os.Stderr, "invalid -concurrency/-retries/-timeout: need at least 1 worker, no negative retries, and a positive timeout")
+		return 2
+	}
+
+	old := make(map[string]*Result)
+	var targets []target
+	skipped := 0
+	for _, path := range fs.Args() {
+		if err := readReverifyFile(path, func(res Result) {
+			if res.Host == "" || res.Port == 0 {
+				skipped++
+				return
+			}
+			key := net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+			if _, dup := old[key]; dup {
+				return
+			}
+			old[key] = &res
+			t := target{host: res.Host, port: res.Port, hostname: res.Hostname, source: res.Source}
+			if res.TLS != nil || res.Label != "" || len(res.Labels) > 0 {
+				t.opts = &targetOptions{Label: res.Label, Labels: res.Labels}
+				if res.TLS != nil {
+					tls := true
+					t.opts.TLS = &tls
+				}
+			}
+			targets = append(targets, t)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "reverify %s: %v\n", path, err)
+			return 1
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "reverify: skipped %d lines without host and port\n", skipped)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reverify: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	bw := bufio.NewWriter(out)
+	defer bw.Flush()
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	cfg := scanConfig{
+		concurrency: *concurrency,
+		prober:      mysqlprobe.NewMySQLProber,
+		probe:       mysqlprobe.Options{Timeout: *timeout, Verbose: true},
+		retries:     *retries,
+	}
+	changed := 0
+	runScan(slices.Values(targets), cfg, func(t target, res Result) {
+		prev := old[net.JoinHostPort(t.host, strconv.Itoa(t.port))]
+		// Old results written without -v carry only the basic handshake; trim the new one alike so uncollected fields do not count as changes.
+		if prev.HandshakeInfo != nil && prev.CapabilityFlags == 0 && res.HandshakeInfo != nil {
+			res.HandshakeInfo = res.HandshakeInfo.Basic()
+		}
+		rec := joinReverify(prev, &res)
+		if rec.AnyChanged {
+			changed++
+		} else if *changedOnly {
+			return
+		}
+		if err := enc.Encode(rec); err != nil {
+			fmt.Fprintf(os.Stderr, "write result: %v\n", err)
+		}
+	})
+	fmt.Fprintf(os.Stderr, "reverify: %d targets, %d changed\n", len(targets), changed)
+	return 0
+}
+
+/*
+joinReverify builds the joined record of a target's old and new result.
+*/
+func joinReverify(old, cur *Result) reverifyRecord {
+	rec := reverifyRecord{Host: cur.Host, Hostname: cur.Hostname, Port: cur.Port, Old: old, New: cur, Changed: make(map[string]bool, len(reverifyFields))}
+	for _, f := range reverifyFields {
+		diff := f.get(*old) != f.get(*cur)
+		rec.Changed[f.name] = diff
+		rec.AnyChanged = rec.AnyChanged || diff
+	}
+	return rec
+}
+
+/*
+readReverifyFile calls add with every result in the file at path ("-" for stdin), decompressing gzip and zstd input and ignoring lines that do not decode.
+*/
+func readReverifyFile(path string, add func(Result)) error {
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r, err := decompressedReader(in)
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxResultLine)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var res Result
+		if json.Unmarshal([]byte(line), &res) == nil {
+			add(res)
+		}
+	}
+	return sc.Err()
+}
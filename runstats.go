@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// slowestShown is how many outstanding targets a stats dump lists.
+const slowestShown = 10
+
+/*
+runtimeStats tracks a running scan for the on-demand stats dump: targets queued and finished, failures by status, the targets currently being probed with their start times, and the output queue between the probes and the sink.
+*/
+type runtimeStats struct {
+	mu        sync.Mutex
+	start     time.Time
+	queued    int
+	done      int
+	errors    map[string]int
+	nextID    int
+	inFlight  map[int]inFlightTarget
+	output    chan scanned
+	queuePeak int
+	dropped   int
+	adaptive  *concurrencyController
+}
+
+/*
+inFlightTarget is a target a worker has picked up but not finished.
+*/
+type inFlightTarget struct {
+	addr  string
+	start time.Time
+}
+
+/*
+newRuntimeStats starts tracking with the rate measured from now.
+*/
+func newRuntimeStats() *runtimeStats {
+	return &runtimeStats{start: time.Now(), errors: make(map[string]int), inFlight: make(map[int]inFlightTarget)}
+}
+
+/*
+add counts n more targets read for probing; a nil tracker ignores it, as it does every other call.
+*/
+func (s *runtimeStats) add(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.queued += n
+	s.mu.Unlock()
+}
+
+/*
+begin marks t as in flight and returns the func that marks it finished with its result.
+*/
+func (s *runtimeStats) begin(t target) func(Result) {
+	if s == nil {
+		return func(Result) {}
+	}
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.inFlight[id] = inFlightTarget{addr: net.JoinHostPort(t.host, strconv.Itoa(t.port)), start: time.Now()}
+	s.mu.Unlock()
+	return func(res Result) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.inFlight, id)
+		s.done++
+		if status, _ := res.Status(); status != mysqlprobe.StatusSuccess {
+			s.errors[string(status)]++
+		}
+	}
+}
+
+/*
+trackQueue makes q the output queue the dump reports on.
+*/
+func (s *runtimeStats) trackQueue(q chan scanned) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.output = q
+	s.mu.Unlock()
+}
+
+/*
+queueDepth records how many results are still queued as one leaves the output queue, keeping the peak.
+*/
+func (s *runtimeStats) queueDepth(n int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.queuePeak = max(s.queuePeak, n)
+	s.mu.Unlock()
+}
+
+/*
+drop counts a result dropped because the output queue was full.
+*/
+func (s *runtimeStats) drop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+}
+
+/*
+outputCounts returns the output queue's peak depth and the results dropped, for the summary.
+*/
+func (s *runtimeStats) outputCounts() (peak, dropped int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queuePeak, s.dropped
+}
+
+/*
+dump writes the current counters and the longest-running outstanding targets to w.
+Function-level comment: in-flight targets include those waiting for a host, destination, or subnet slot, so a stall behind a limiter shows up as old entries here.
+*/
+func (s *runtimeStats) dump(w io.Writer) {
+	s.mu.Lock()
+	elapsed := time.Since(s.start)
+	done, queued := s.done, s.queued
+	output, peak, dropped, adaptive := s.output, s.queuePeak, s.dropped, s.adaptive
+	errs := make([]string, 0, len(s.errors))
+	for status, n := range s.errors {
+		errs = append(errs, fmt.Sprintf("%s=%d", status, n))
+	}
+	slowest := make([]inFlightTarget, 0, len(s.inFlight))
+	for _, t := range s.inFlight {
+		slowest = append(slowest, t)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(errs)
+	sort.Slice(slowest, func(i, j int) bool { return slowest[i].start.Before(slowest[j].start) })
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+	}
+	fmt.Fprintf(w, "stats: %d of %d targets done, %d in flight, %.1f targets/s over %s\n", done, queued, len(slowest), rate, elapsed.Round(time.Second))
+	if len(errs) > 0 {
+		fmt.Fprintf(w, "  errors:  %s\n", strings.Join(errs, "  "))
+	}
+	if output != nil {
+		fmt.Fprintf(w, "  output:  %d of %d queued for the sink, peak %d, %d dropped\n", len(output), cap(output), peak, dropped)
+	}
+	if adaptive != nil {
+		fmt.Fprintf(w, "  concurrency: %s\n", adaptive)
+	}
+	now := time.Now()
+	for i, t := range slowest {
+		if i == slowestShown {
+			fmt.Fprintf(w, "  ... and %d more in flight\n", len(slowest)-slowestShown)
+			break
+		}
+		fmt.Fprintf(w, "  slowest: %s for %s\n", t.addr, now.Sub(t.start).Round(time.Millisecond))
+	}
+}
+
+/*
+dumpOnSignal prints the stats to stderr each time the process receives the stats signal (SIGUSR1 where the platform has it), until stop is closed.
+*/
+func (s *runtimeStats) dumpOnSignal(stop <-chan struct{}) {
+	sig := make(chan os.Signal, 1)
+	if !notifyStatsSignal(sig) {
+		return
+	}
+	defer signal.Stop(sig)
+	for {
+		select {
+		case <-sig:
+			s.dump(os.Stderr)
+		case <-stop:
+			return
+		}
+	}
+}
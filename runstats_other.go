@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+/*
+notifyStatsSignal reports that this platform has no SIGUSR1, so stats are not dumped on demand.
+*/
+func notifyStatsSignal(c chan<- os.Signal) bool {
+	return false
+}
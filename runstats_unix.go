@@ -0,0 +1,17 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+/*
+notifyStatsSignal relays SIGUSR1 to c.
+*/
+func notifyStatsSignal(c chan<- os.Signal) bool {
+	signal.Notify(c, syscall.SIGUSR1)
+	return true
+}
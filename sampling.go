@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/hex"
+	"math"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// connectionIDWrap is how close to 2^32 a connection ID must be for a smaller next ID to count as wraparound rather than a restart.
+const connectionIDWrap = 1 << 28
+
+/*
+samplingResult describes a target's repeated handshakes under -samples: the connection IDs the server handed out, how far apart they were, and what that says about the server.
+ChurnPerSecond estimates other clients' connections per second: each step between our samples is one ID for our own connection, and the rest went to someone else.
+*/
+type samplingResult struct {
+	Samples         int      `json:"samples"`
+	Succeeded       int      `json:"succeeded"`
+	ConnectionIDs   []uint32 `json:"connection_ids,omitempty"`
+	Deltas          []int64  `json:"deltas,omitempty"`
+	DurationMillis  float64  `json:"duration_ms"`
+	ChurnPerSecond  *float64 `json:"churn_per_second,omitempty"`
+	RestartDetected bool     `json:"restart_detected,omitempty"`
+	// MultipleBackends is set when the handshakes differ or the IDs interleave several counters, as behind a TCP load balancer; Fingerprints lists the distinct handshakes seen.
+	Latency          *sampleLatency       `json:"latency,omitempty"`
+	MultipleBackends bool                 `json:"multiple_backends,omitempty"`
+	Fingerprints     []backendFingerprint `json:"fingerprints,omitempty"`
+	Errors           []string             `json:"errors,omitempty"`
+}
+
+/*
+sampleLatency is the spread of the samples' TCP connect times and of the time from connect to the handshake (banner), in milliseconds.
+*/
+type sampleLatency struct {
+	Connect latencyStats `json:"connect_ms"`
+	Banner  latencyStats `json:"banner_ms"`
+}
+
+/*
+latencyStats summarizes a set of latencies in milliseconds; p95 is the nearest-rank percentile.
+*/
+type latencyStats struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+}
+
+/*
+summarizeLatency computes latencyStats over d, which must not be empty.
+*/
+func summarizeLatency(d []time.Duration) latencyStats {
+	sorted := slices.Clone(d)
+	slices.Sort(sorted)
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	p95 := sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	return latencyStats{Min: ms(sorted[0]), Median: ms(median), P95: ms(p95)}
+}
+
+/*
+backendFingerprint is the part of a handshake that stays fixed for one server process, and how many of the target's handshakes showed it.
+SaltHighBit records whether the scramble had bytes of 0x80 or more: MySQL and MariaDB never generate them, while implementations drawing 20 random bytes almost always do.
+*/
+type backendFingerprint struct {
+	ServerVersion   string `json:"server_version"`
+	CapabilityFlags uint32 `json:"capability_flags"`
+	CharacterSet    uint8  `json:"character_set"`
+	AuthPlugin      string `json:"auth_plugin,omitempty"`
+	SaltHighBit     bool   `json:"salt_high_bit"`
+	Count           int    `json:"count"`
+}
+
+/*
+fingerprintOf extracts info's backendFingerprint, with a zero count.
+*/
+func fingerprintOf(info *mysqlprobe.HandshakeInfo) backendFingerprint {
+	salt, _ := hex.DecodeString(info.AuthPluginData)
+	var highBit bool
+	for _, c := range salt {
+		if c >= 0x80 {
+			highBit = true
+			break
+		}
+	}
+	return backendFingerprint{
+		ServerVersion:   info.ServerVersion,
+		CapabilityFlags: info.CapabilityFlags,
+		CharacterSet:    info.CharacterSet,
+		AuthPlugin:      info.AuthPluginName,
+		SaltHighBit:     highBit,
+	}
+}
+
+/*
+connSample is one sampling connection's handshake and when it was taken, relative to the first.
+*/
+type connSample struct {
+	info   *mysqlprobe.HandshakeInfo
+	offset time.Duration
+}
+
+/*
+sampleTarget opens n more connections to ip:port, interval apart, and compares their handshakes with each other and with first, the main probe's.
+Function-level comment: the sample connections stop at the handshake (no TLS, no login) and wait for the destination and subnet limits like the main probe; each one's connect time and time to the handshake feed the latency summary. Differing fingerprints, or connection IDs dropping more than once (short of 32-bit wraparound), mean several backends answer behind one address; a single drop from one backend means the server restarted between samples.
+*/
+func sampleTarget(ip string, addr netip.Addr, port, n int, interval time.Duration, first *mysqlprobe.HandshakeInfo, opts mysqlprobe.Options, dests *hostLimiter, subnets *subnetLimiter) *samplingResult {
+	opts.TLS, opts.Credentials = false, nil
+	connect := opts.Connect
+	// connect already sends any PROXY protocol header; the wrapped Dial must not send another.
+	opts.ProxyProtocol = 0
+	var connectTime time.Duration
+	opts.Dial = func(network, address string, _ time.Duration) (net.Conn, error) {
+		t0 := time.Now()
+		conn, err := connect(network, address)
+		connectTime = time.Since(t0)
+		return conn, err
+	}
+	hostPort := net.JoinHostPort(ip, strconv.Itoa(port))
+	sr := &samplingResult{Samples: n}
+	var samples []connSample
+	var connects, banners []time.Duration
+	start := time.Now()
+	for i := range n {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		offset := time.Since(start)
+		var elapsed time.Duration
+		r := func() mysqlprobe.Result {
+			defer dests.acquire(ip)()
+			defer subnets.acquire(addr)()
+			t0 := time.Now()
+			defer func() { elapsed = time.Since(t0) }()
+			return mysqlprobe.Probe(hostPort, opts)
+		}()
+		if !r.MySQL {
+			msg := r.Error
+			if msg == "" {
+				msg = "no MySQL handshake"
+			}
+			sr.Errors = append(sr.Errors, msg)
+			continue
+		}
+		connects, banners = append(connects, connectTime), append(banners, elapsed-connectTime)
+		samples = append(samples, connSample{info: r.HandshakeInfo, offset: offset})
+		sr.ConnectionIDs = append(sr.ConnectionIDs, r.HandshakeInfo.ConnectionID)
+	}
+	sr.Succeeded = len(samples)
+	sr.DurationMillis = float64(time.Since(start).Microseconds()) / 1000
+	if len(samples) > 0 {
+		sr.Latency = &sampleLatency{Connect: summarizeLatency(connects), Banner: summarizeLatency(banners)}
+	}
+
+	var drops int
+	var others int64
+	var span time.Duration
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1].info.ConnectionID, samples[i].info.ConnectionID
+		delta := int64(cur) - int64(prev)
+		if delta < 0 && prev >= math.MaxUint32-connectionIDWrap && cur < connectionIDWrap {
+			delta += math.MaxUint32 + 1
+		}
+		sr.Deltas = append(sr.Deltas, delta)
+		if delta < 0 {
+			drops++
+			continue
+		}
+		others += max(delta-1, 0)
+		span += samples[i].offset - samples[i-1].offset
+	}
+	if span > 0 {
+		churn := math.Round(float64(others)/span.Seconds()*1000) / 1000
+		sr.ChurnPerSecond = &churn
+	}
+
+	seen := make(map[backendFingerprint]int)
+	for _, info := range append([]*mysqlprobe.HandshakeInfo{first}, sampleInfos(samples)...) {
+		fp := fingerprintOf(info)
+		if i, ok := seen[fp]; ok {
+			sr.Fingerprints[i].Count++
+			continue
+		}
+		seen[fp] = len(sr.Fingerprints)
+		fp.Count = 1
+		sr.Fingerprints = append(sr.Fingerprints, fp)
+	}
+	sr.MultipleBackends = len(sr.Fingerprints) > 1 || drops > 1
+	sr.RestartDetected = drops == 1 && !sr.MultipleBackends
+	if sr.MultipleBackends {
+		// Steps between different backends' counters say nothing about churn.
+		sr.ChurnPerSecond = nil
+	} else {
+		sr.Fingerprints = nil
+	}
+	return sr
+}
+
+/*
+sampleInfos returns the samples' handshakes.
+*/
+func sampleInfos(samples []connSample) []*mysqlprobe.HandshakeInfo {
+	infos := make([]*mysqlprobe.HandshakeInfo, len(samples))
+	for i, s := range samples {
+		infos[i] = s.info
+	}
+	return infos
+}
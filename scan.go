@@ -0,0 +1,547 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net"
+	"net/netip"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+target is one host:port pair queued for probing.
+runUDP marks the single target per host that also carries the host-level probes (UDP, X Protocol, DRDA); hostname is the name an imported or expanded address was known by (e.g. from nmap or DNS), and source the -host spec a DNS expansion came from. Both are carried through to the result. opts holds per-target overrides from -targets-file, nil when there are none.
+*/
+type target struct {
+	host     string
+	port     int
+	runUDP   bool
+	hostname string
+	source   string
+	opts     *targetOptions
+	// protocol is the prober a URI-style spec (mysqlx://host) selected; empty means -protocol.
+	protocol string
+	// pipe marks host as a Windows named pipe path (-pipe) rather than a network host.
+	pipe bool
+}
+
+/*
+name is the name the target was given by: the hostname an imported or expanded address was known by, else the host as given.
+*/
+func (t target) name() string {
+	if t.hostname != "" {
+		return t.hostname
+	}
+	return t.host
+}
+
+/*
+scanConfig holds the engine-level settings that apply across all targets.
+*/
+type scanConfig struct {
+	concurrency     int
+	hostParallelism int
+	perIPLimit      int
+	prober          mysqlprobe.ProberFactory
+	recorder        *sessionRecorder
+	stats           *runtimeStats
+	statsd          *statsdClient
+	deadline        time.Time
+	dns             *dnsCache
+	probe           mysqlprobe.Options
+	retries         int
+	secondPass      bool
+	udpProbes       []string
+	mysqlxPort      int
+	db2Port         int
+	clusterChecks   bool
+	middleware      bool
+	samples         int
+	sampleInterval  time.Duration
+	timingDiffDelay time.Duration
+	adaptive        *concurrencyController
+	credentials     *credentialStore
+	exclusions      *exclusionList
+	subnetRate      subnetRateSpec
+	blocks          *blockTracker
+	noRecover       bool
+	execProbes      []execProbe
+	execTimeout     time.Duration
+	outputQueue     int
+	dropResults     bool
+}
+
+/*
+splitList splits a comma-separated flag value into trimmed, non-empty items.
+Function-level comment: returns nil for an empty or all-blank spec.
+*/
+func splitList(spec string) []string {
+	var out []string
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+/*
+parsePortList parses a comma-separated list of TCP ports.
+Function-level comment: rejects anything that is not an integer in 1-65535 and drops duplicates while keeping the given order.
+*/
+func parsePortList(spec string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, item := range splitList(spec) {
+		p, err := strconv.Atoi(item)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %q", item)
+		}
+		if !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+/*
+buildTargets expands hosts x ports into the probe queue.
+Function-level comment: iterates port-major so consecutive targets usually hit different hosts, which keeps workers from piling up behind one host's parallelism limit; the first port of each host carries its UDP probes.
+*/
+func buildTargets(hosts []string, ports []int) []target {
+	targets := make([]target, 0, len(hosts)*len(ports))
+	for i, port := range ports {
+		for _, host := range hosts {
+			targets = append(targets, target{host: host, port: port, runUDP: i == 0})
+		}
+	}
+	return targets
+}
+
+/*
+hostSlot is the connection semaphore for one host plus the number of workers holding or waiting on it.
+*/
+type hostSlot struct {
+	sem  chan struct{}
+	refs int
+}
+
+/*
+hostLimiter bounds simultaneous connections to the same key (a target host, or a resolved destination IP), independently of global concurrency.
+Slots are created on demand and dropped once no worker references them, so memory tracks in-flight hosts only.
+*/
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]*hostSlot
+}
+
+/*
+newHostLimiter returns a limiter allowing limit connections per key; limit <= 0 disables it.
+*/
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]*hostSlot)}
+}
+
+/*
+acquire blocks until a connection slot for host (any limiter key) is free.
+Function-level comment: returns the matching release func, which must be called exactly once.
+*/
+func (l *hostLimiter) acquire(host string) func() {
+	if l.limit <= 0 {
+		return func() {}
+	}
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.limit)}
+		l.slots[host] = slot
+	}
+	slot.refs++
+	l.mu.Unlock()
+
+	slot.sem <- struct{}{}
+	return func() {
+		<-slot.sem
+		l.mu.Lock()
+		if slot.refs--; slot.refs == 0 {
+			delete(l.slots, host)
+		}
+		l.mu.Unlock()
+	}
+}
+
+/*
+scanTarget probes a single target and stamps its address on the result.
+Function-level comment: after the main probe, the result is classified and the enabled extra checks run; every connection waits for the destination-IP and subnet limits.
+*/
+func scanTarget(ctx context.Context, t target, cfg scanConfig, dests *hostLimiter, subnets *subnetLimiter) Result {
+	opts, retries := cfg.probe, cfg.retries
+	if o := t.opts; o != nil {
+		if o.Timeout > 0 {
+			opts.Timeout = o.Timeout
+		}
+		if o.TLS != nil {
+			opts.TLS = *o.TLS
+		}
+		if o.Retries != nil {
+			retries = *o.Retries
+		}
+	}
+	res := Result{Host: t.host, Hostname: t.hostname, Port: t.port, Probe: t.protocol, Source: t.source, Label: t.opts.label(), Labels: t.opts.labels(), TargetHost: t.name()}
+	// A named pipe target (-pipe) is probed over the pipe and classified, and nothing else.
+	if t.pipe {
+		res.Host, res.Pipe, res.TargetHost = "", t.host, ""
+		// A named pipe reaches the server itself, with no load balancer to read a PROXY protocol header.
+		opts.ProxyProtocol = 0
+		opts.Dial = func(_, path string, timeout time.Duration) (net.Conn, error) {
+			return mysqlprobe.DialPipe(path, timeout)
+		}
+		credErr := cfg.credentials.apply(ctx, &opts, t.host, "", 0)
+		res.Result = mysqlprobe.ProbeWith(ctx, cfg.prober(opts), t.host, opts)
+		credentialFailure(&res, credErr)
+		classifyHandshake(&res, t)
+		return res
+	}
+	resolveStart := time.Now()
+	addr, err := resolveTarget(ctx, t.host, cfg.exclusions, cfg.dns)
+	var dnsPhase []mysqlprobe.TimingPhase
+	if _, perr := netip.ParseAddr(t.host); perr != nil && opts.Verbose {
+		dnsPhase = []mysqlprobe.TimingPhase{mysqlprobe.NewTimingPhase(mysqlprobe.PhaseDNS, resolveStart, time.Now(), err != nil)}
+	}
+	if err != nil {
+		res.Error, res.Timing = err.Error(), dnsPhase
+		return res
+	}
+	ip := addr.String()
+	res.TargetIP = ip
+	credErr := cfg.credentials.apply(ctx, &opts, t.host, ip, t.port)
+	hostPort := net.JoinHostPort(ip, strconv.Itoa(t.port))
+	// The follow-up checks run below, once the probe's connection and its slots are released.
+	probeOpts := opts
+	probeOpts.DetectTLSRequirement, probeOpts.DowngradeTest, probeOpts.EnumAuthPlugins = false, false, false
+	// Networks throttled for refusing our host are skipped or slowed; transient failures are retried.
+	for attempt := 0; ; attempt++ {
+		unblock, err := cfg.blocks.admit(addr)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		// Release through defer so a panicking probe (recovered by safeScanTarget) does not leak destination or subnet slots.
+		res.Result = func() mysqlprobe.Result {
+			defer unblock()
+			defer dests.acquire(ip)()
+			defer subnets.acquire(addr)()
+			// A URI-style spec (mysqlx://host) picks its own prober over -protocol.
+			newProber := cfg.prober
+			if f, ok := mysqlprobe.LookupProber(t.protocol); ok {
+				newProber = f
+			}
+			return mysqlprobe.ProbeWith(ctx, newProber(probeOpts), hostPort, cfg.recorder.wrap(probeOpts, t.host, ip, t.port))
+		}()
+		cfg.blocks.record(addr, res)
+		if attempt >= retries || !transientFailure(res) {
+			if attempt > 0 {
+				res.Attempts = attempt + 1
+			}
+			break
+		}
+	}
+	if res.MySQL {
+		mysqlprobe.FollowUp(hostPort, &res.Result, limitConns(opts, ip, addr, cfg.blocks, dests, subnets))
+	}
+	if dnsPhase != nil {
+		res.Timing = append(dnsPhase, res.Timing...)
+	}
+	credentialFailure(&res, credErr)
+	if res.XProtocol != nil {
+		res.MySQLX = res.XProtocol
+	}
+	classifyHandshake(&res, t)
+	if cfg.samples > 0 && res.MySQL {
+		res.Sampling = sampleTarget(ip, addr, t.port, cfg.samples, cfg.sampleInterval, res.HandshakeInfo, opts, dests, subnets)
+	}
+	if cfg.timingDiffDelay > 0 && res.MySQL {
+		res.TimingDiff, res.InlineSuspected, res.InlineEvidence = timingDiff(ip, addr, t.port, cfg.timingDiffDelay, res.Result, opts, dests, subnets)
+	}
+	mw := newMiddlewareObservation(&res, t.port)
+	if t.runUDP && cfg.middleware {
+		probeMiddlewareAdmin(&mw, ip, addr, opts, dests, subnets)
+	}
+	// A target that sent nothing is asked again behind a PROXY protocol header.
+	if cfg.middleware && opts.ProxyProtocol == 0 && unanswered(&res) {
+		mw.proxyHeader = func() bool {
+			defer dests.acquire(ip)()
+			defer subnets.acquire(addr)()
+			return proxyHeaderAnswer(net.JoinHostPort(ip, strconv.Itoa(t.port)), opts)
+		}()
+	}
+	res.Middleware, res.MiddlewareEvidence, res.Intermediary = classifyMiddleware(mw)
+	// The host-level probes run once per host, on its designated target.
+	if t.runUDP && cfg.mysqlxPort != 0 {
+		res.MySQLX = func() *mysqlprobe.XInfo {
+			defer dests.acquire(ip)()
+			defer subnets.acquire(addr)()
+			return mysqlprobe.ProbeX(net.JoinHostPort(ip, strconv.Itoa(cfg.mysqlxPort)), opts)
+		}()
+	}
+	if t.runUDP && cfg.db2Port != 0 {
+		res.DB2 = func() *DRDAResult {
+			defer dests.acquire(ip)()
+			defer subnets.acquire(addr)()
+			return runDRDAProbe(ip, cfg.db2Port, opts)
+		}()
+	}
+	if t.runUDP && cfg.clusterChecks {
+		res.Cluster = checkCluster(ip, addr, res, opts, dests, subnets)
+	}
+	if t.runUDP {
+		for _, name := range cfg.udpProbes {
+			res.UDP = append(res.UDP, runUDPProbe(ip, udpProbes[name], opts))
+		}
+	}
+	if res.OK && len(cfg.execProbes) > 0 {
+		in := execProbeInput{Host: t.host, IP: ip, Port: t.port, Hostname: t.hostname, Timeout: opts.Timeout.Seconds(), Result: &res}
+		external := make(externalResults, len(cfg.execProbes))
+		for _, p := range cfg.execProbes {
+			external[p.name] = func() []byte {
+				defer dests.acquire(ip)()
+				defer subnets.acquire(addr)()
+				return p.run(ctx, in, cfg.execTimeout)
+			}()
+		}
+		res.External = external
+	}
+	return res
+}
+
+/*
+limitConns returns opts with a Dial that admits each connection through the block tracker and holds a destination and a subnet slot until the connection is closed.
+*/
+func limitConns(opts mysqlprobe.Options, ip string, addr netip.Addr, blocks *blockTracker, dests *hostLimiter, subnets *subnetLimiter) mysqlprobe.Options {
+	connect := opts.Connect
+	// connect already sends any PROXY protocol header; the wrapped Dial must not send another.
+	opts.ProxyProtocol = 0
+	opts.Dial = func(network, address string, _ time.Duration) (net.Conn, error) {
+		unblock, err := blocks.admit(addr)
+		if err != nil {
+			return nil, err
+		}
+		releaseDest := dests.acquire(ip)
+		releaseSubnet := subnets.acquire(addr)
+		release := func() {
+			releaseSubnet()
+			releaseDest()
+			unblock()
+		}
+		conn, err := connect(network, address)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, release: sync.OnceFunc(release)}, nil
+	}
+	return opts
+}
+
+/*
+limitedConn releases its limitConns slots when it is closed.
+*/
+type limitedConn struct {
+	net.Conn
+	release func()
+}
+
+/*
+Close closes the connection and releases its slots.
+*/
+func (c *limitedConn) Close() error {
+	defer c.release()
+	return c.Conn.Close()
+}
+
+/*
+classifyHandshake fills in what a parsed handshake tells about the server: managed provider (by the imported hostname when there is one), EOL status, weak authentication, client compatibility, build variant, and platform.
+*/
+func classifyHandshake(res *Result, t target) {
+	if res.HandshakeInfo == nil {
+		return
+	}
+	res.Provider, res.ProviderEvidence = classifyProvider(t.name(), res.HandshakeInfo, res.TLS)
+	if eol, date, ok := lookupEOL(res.HandshakeInfo, time.Now()); ok {
+		res.EOL, res.EOLDate = &eol, date
+	}
+	res.WeakAuth, res.WeakAuthReason = classifyWeakAuth(res.HandshakeInfo, res.Auth)
+	res.CompatWarnings = classifyCompat(res.HandshakeInfo, res.TLS)
+	res.BuildVariant, res.BuildEvidence = classifyBuildVariant(res.HandshakeInfo)
+	res.PlatformGuess, res.PlatformEvidence = classifyPlatform(newPlatformObservation(res, t.pipe))
+}
+
+/*
+safeScanTarget runs scanTarget, turning a panic into an internal_error result carrying the stack instead of crashing the scan.
+Function-level comment: with cfg.noRecover (-no-recover) panics propagate, for debugging. Every result is stamped with the scanner's build metadata, and the target is traced under a span of its own that ends with the outcome.
+*/
+func safeScanTarget(t target, cfg scanConfig, dests *hostLimiter, subnets *subnetLimiter) (res Result) {
+	ctx, span := startTargetSpan(t)
+	defer func() {
+		res.Scanner, res.span = scannerBuild, span.SpanContext()
+		endTargetSpan(span, res)
+	}()
+	if cfg.noRecover {
+		return scanTarget(ctx, t, cfg, dests, subnets)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			res = Result{Host: t.host, Hostname: t.hostname, Port: t.port, Source: t.source, Label: t.opts.label(), Labels: t.opts.labels()}
+			if !t.pipe {
+				res.TargetHost = t.name()
+			}
+			res.Error = fmt.Sprintf("internal error: %v", r)
+			res.ErrorType = "internal_error"
+			res.Stack = string(debug.Stack())
+		}
+	}()
+	return scanTarget(ctx, t, cfg, dests, subnets)
+}
+
+/*
+transientFailure reports whether a failed probe is worth retrying: timeouts and dropped connections, not refusals or non-MySQL answers.
+*/
+func transientFailure(res Result) bool {
+	switch status, _ := res.Status(); status {
+	case mysqlprobe.StatusConnectionTimeout, mysqlprobe.StatusIOTimeout, mysqlprobe.StatusConnectionClosed:
+		return true
+	}
+	return false
+}
+
+/*
+scanned pairs a finished target with its result on the way to the emitter.
+*/
+type scanned struct {
+	target target
+	result Result
+}
+
+/*
+runScan probes every target with a bounded worker pool and hands results to emit.
+Function-level comment: emit is called from a single goroutine, so output never interleaves. Returns once every target has been reported, with the number never started because cfg.deadline (-max-runtime) passed.
+*/
+func runScan(targets iter.Seq[target], cfg scanConfig, emit func(target, Result)) (skipped int) {
+	workers := max(cfg.concurrency, 1)
+	limiter := newHostLimiter(cfg.hostParallelism)
+	dests := newHostLimiter(cfg.perIPLimit)
+	subnets := newSubnetLimiter(cfg.subnetRate)
+	queue := make(chan target)
+	// A full result queue blocks the workers, so a slow sink slows the scan instead of results piling up in memory.
+	depth := cfg.outputQueue
+	if depth <= 0 {
+		depth = workers
+	}
+	results := make(chan scanned, depth)
+	cfg.stats.trackQueue(results)
+	var expired <-chan time.Time
+	if !cfg.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(cfg.deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				finish := cfg.stats.begin(t)
+				start := time.Now()
+				release := limiter.acquire(t.host)
+				cfg.adaptive.acquire()
+				res := safeScanTarget(t, cfg, dests, subnets)
+				cfg.adaptive.release(res)
+				release()
+				finish(res)
+				cfg.statsd.target(res, time.Since(start))
+				if !cfg.dropResults {
+					results <- scanned{t, res}
+					continue
+				}
+				// -output-queue-policy drop: the result is counted instead, and -resume probes its target again.
+				select {
+				case results <- scanned{t, res}:
+				default:
+					cfg.stats.drop()
+					cfg.statsd.dropped()
+				}
+			}
+		}()
+	}
+	// targets is read as the workers take them, so a streamed -targets-file is never held in memory.
+	// Past the deadline no more targets start; the rest are read through to count them.
+	go func() {
+		late := false
+		for t := range targets {
+			if late {
+				skipped++
+				continue
+			}
+			cfg.stats.add(1)
+			select {
+			case queue <- t:
+			case <-expired:
+				late = true
+				skipped++
+			}
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	// With cfg.secondPass, transient failures are held back and probed again after the sweep; only the second result is emitted.
+	var requeue []scanned
+	for r := range results {
+		cfg.stats.queueDepth(len(results))
+		cfg.statsd.queueDepth(len(results))
+		if cfg.secondPass && transientFailure(r.result) {
+			requeue = append(requeue, r)
+			continue
+		}
+		emit(r.target, r.result)
+	}
+	if len(requeue) == 0 {
+		return skipped
+	}
+	// Past the deadline, held-back failures are emitted without their second pass.
+	if cfg.deadlinePassed() {
+		for _, r := range requeue {
+			emit(r.target, r.result)
+		}
+		return skipped
+	}
+	retry := make([]target, len(requeue))
+	for i, r := range requeue {
+		retry[i] = r.target
+	}
+	cfg.secondPass = false
+	return skipped + runScan(slices.Values(retry), cfg, func(t target, res Result) {
+		res.SecondPass = true
+		emit(t, res)
+	})
+}
+
+/*
+deadlinePassed reports whether the -max-runtime budget is used up.
+*/
+func (cfg scanConfig) deadlinePassed() bool {
+	return !cfg.deadline.IsZero() && !time.Now().Before(cfg.deadline)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+/*
+newScanID returns a random (version 4) UUID identifying one scan invocation.
+Function-level comment: it is stamped on every result and the summary as scan_id, so results of concurrent or repeated runs stay apart after ingestion.
+*/
+func newScanID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
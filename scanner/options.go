@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+Option configures a Scanner in New.
+*/
+type Option func(*Scanner)
+
+/*
+Enricher adds to a finished result, e.g. a classification of its handshake or a lookup in an inventory; it runs on the worker that probed the target, after any retries.
+*/
+type Enricher func(ctx context.Context, res *Result)
+
+/*
+WithConcurrency sets how many targets ScanAll probes at once (default 50); values below 1 mean 1.
+*/
+func WithConcurrency(n int) Option {
+	return func(s *Scanner) {
+		s.concurrency = max(n, 1)
+	}
+}
+
+/*
+WithHostParallelism caps the simultaneous connections to any one host, however many of its ports are queued (0, the default, leaves only the concurrency limit).
+*/
+func WithHostParallelism(n int) Option {
+	return func(s *Scanner) {
+		s.hostParallelism = n
+	}
+}
+
+/*
+WithRetries retries a target this many times after a timeout or dropped connection (default 0); refusals and non-MySQL answers are not retried.
+*/
+func WithRetries(n int) Option {
+	return func(s *Scanner) {
+		s.retries = max(n, 0)
+	}
+}
+
+/*
+WithTimeout sets the dial and read timeout of each probe (default 3s).
+*/
+func WithTimeout(d time.Duration) Option {
+	return func(s *Scanner) {
+		s.opts.Timeout = d
+	}
+}
+
+/*
+WithProbeOptions replaces the probe settings wholesale: TLS, credentials, socket options, the extra probes (DetectTLSRequirement, DowngradeTest, EnumAuthPlugins), and the rest of mysqlprobe.Options.
+Function-level comment: a zero Timeout in opts keeps the scanner's timeout, so the order of WithTimeout and WithProbeOptions does not matter.
+*/
+func WithProbeOptions(opts mysqlprobe.Options) Option {
+	return func(s *Scanner) {
+		if opts.Timeout == 0 {
+			opts.Timeout = s.opts.Timeout
+		}
+		s.opts = opts
+	}
+}
+
+/*
+WithProtocol probes targets that name no protocol with the prober registered under name (see mysqlprobe.RegisterProber); the default is mysql. An unknown name makes New fail.
+*/
+func WithProtocol(name string) Option {
+	return func(s *Scanner) {
+		s.protocol = name
+	}
+}
+
+/*
+WithEnricher adds an enrichment step run on every result; enrichers run in the order given.
+*/
+func WithEnricher(e Enricher) Option {
+	return func(s *Scanner) {
+		s.enrichers = append(s.enrichers, e)
+	}
+}
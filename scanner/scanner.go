@@ -0,0 +1,239 @@
+/*
+Package scanner runs the probe pipeline of mysql_scout from Go: a worker pool with per-host limits, retries of transient failures, the probes configured through mysqlprobe.Options, and enrichment steps, for programs that would otherwise shell out to the binary and parse its NDJSON.
+*/
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// Defaults of a Scanner built without options.
+const (
+	defaultConcurrency = 50
+	defaultTimeout     = 3 * time.Second
+)
+
+/*
+Target is one service to probe: a host name or IP address and a port, with the prober to use and labels to copy into its result.
+A zero Port means the prober's default port (3306 for mysql); an empty Protocol means the scanner's (see WithProtocol).
+*/
+type Target struct {
+	Host     string
+	Port     int
+	Protocol string
+	Labels   map[string]string
+}
+
+/*
+Result is what scanning one target produced: the target, the probe's result, and whatever enrichers added.
+It marshals like a mysql_scout output line for the fields the two share.
+*/
+type Result struct {
+	Host   string            `json:"host"`
+	Port   int               `json:"port"`
+	Probe  string            `json:"probe,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	mysqlprobe.Result
+	Attempts   int            `json:"attempts,omitempty"`
+	ErrorType  string         `json:"error_type,omitempty"`
+	Stack      string         `json:"stack,omitempty"`
+	Enrichment map[string]any `json:"enrichment,omitempty"`
+}
+
+/*
+SetEnrichment stores an enricher's finding under key, creating the map on first use.
+*/
+func (r *Result) SetEnrichment(key string, value any) {
+	if r.Enrichment == nil {
+		r.Enrichment = make(map[string]any)
+	}
+	r.Enrichment[key] = value
+}
+
+/*
+Scanner probes targets with one configuration; it is safe for concurrent use, and one Scanner may run several ScanAll calls at once, which then share its per-host limit.
+*/
+type Scanner struct {
+	concurrency     int
+	hostParallelism int
+	retries         int
+	protocol        string
+	opts            mysqlprobe.Options
+	enrichers       []Enricher
+
+	hosts *hostLimiter
+}
+
+/*
+New returns a Scanner configured by opts.
+Function-level comment: without options it probes MySQL with a 3s timeout, 50 targets at once, and no retries; it fails when WithProtocol names a prober that is not registered.
+*/
+func New(opts ...Option) (*Scanner, error) {
+	s := &Scanner{
+		concurrency: defaultConcurrency,
+		protocol:    mysqlprobe.ModuleName,
+		opts:        mysqlprobe.Options{Timeout: defaultTimeout},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if _, ok := mysqlprobe.LookupProber(s.protocol); !ok {
+		return nil, fmt.Errorf("scanner: unknown protocol %q (registered: %v)", s.protocol, mysqlprobe.ProberNames())
+	}
+	s.hosts = newHostLimiter(s.hostParallelism)
+	return s, nil
+}
+
+/*
+ScanTarget probes one target and returns its enriched result.
+Function-level comment: failures are reported inside the Result, as the CLI reports them, rather than returned; a panic in a prober or enricher becomes an internal_error result carrying the stack. Cancelling ctx interrupts the probe.
+*/
+func (s *Scanner) ScanTarget(ctx context.Context, t Target) (res Result) {
+	newProber, ok := mysqlprobe.LookupProber(s.protocol)
+	if t.Protocol != "" {
+		newProber, ok = mysqlprobe.LookupProber(t.Protocol)
+	}
+	res = Result{Host: t.Host, Port: t.Port, Probe: t.Protocol, Labels: t.Labels}
+	if !ok {
+		res.Error = fmt.Sprintf("unknown protocol %q", t.Protocol)
+		return res
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			res = Result{Host: t.Host, Port: t.Port, Probe: t.Protocol, Labels: t.Labels}
+			res.Error = fmt.Sprintf("internal error: %v", r)
+			res.ErrorType = "internal_error"
+			res.Stack = string(debug.Stack())
+		}
+	}()
+	prober := newProber(s.opts)
+	if res.Port == 0 {
+		res.Port = prober.DefaultPort()
+	}
+	release := s.hosts.acquire(t.Host)
+	defer release()
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(res.Port))
+	for attempt := 0; ; attempt++ {
+		res.Result = mysqlprobe.ProbeWith(ctx, prober, addr, s.opts)
+		if attempt >= s.retries || !transientFailure(res.Result) || ctx.Err() != nil {
+			if attempt > 0 {
+				res.Attempts = attempt + 1
+			}
+			break
+		}
+	}
+	for _, e := range s.enrichers {
+		e(ctx, &res)
+	}
+	return res
+}
+
+/*
+ScanAll probes every target from targets with the scanner's worker pool and calls resultFn with each result.
+Function-level comment: targets is read as workers free up, so it may be a stream far larger than memory; resultFn is called from a single goroutine, never concurrently, in completion order. When ctx is cancelled no further targets are started, probes in flight are interrupted and still reported, and ctx's error is returned; otherwise ScanAll returns nil once every target has been reported.
+*/
+func (s *Scanner) ScanAll(ctx context.Context, targets iter.Seq[Target], resultFn func(Result)) error {
+	queue := make(chan Target)
+	results := make(chan Result, s.concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				results <- s.ScanTarget(ctx, t)
+			}
+		}()
+	}
+	go func() {
+		defer func() {
+			close(queue)
+			wg.Wait()
+			close(results)
+		}()
+		for t := range targets {
+			select {
+			case queue <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for r := range results {
+		resultFn(r)
+	}
+	return ctx.Err()
+}
+
+/*
+transientFailure reports whether a failed probe is worth retrying: timeouts and dropped connections, not refusals or non-MySQL answers.
+*/
+func transientFailure(res mysqlprobe.Result) bool {
+	switch status, _ := res.Status(); status {
+	case mysqlprobe.StatusConnectionTimeout, mysqlprobe.StatusIOTimeout, mysqlprobe.StatusConnectionClosed:
+		return true
+	}
+	return false
+}
+
+/*
+hostLimiter bounds simultaneous connections to the same host, independently of the worker count.
+Slots are created on demand and dropped once no worker references them, so memory tracks in-flight hosts only.
+*/
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]*hostSlot
+}
+
+/*
+hostSlot is one host's semaphore and the number of workers holding or waiting on it.
+*/
+type hostSlot struct {
+	sem  chan struct{}
+	refs int
+}
+
+/*
+newHostLimiter returns a limiter allowing limit connections per host; limit <= 0 disables it.
+*/
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]*hostSlot)}
+}
+
+/*
+acquire blocks until a connection slot for host is free.
+Function-level comment: returns the matching release func, which must be called exactly once.
+*/
+func (l *hostLimiter) acquire(host string) func() {
+	if l.limit <= 0 {
+		return func() {}
+	}
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.limit)}
+		l.slots[host] = slot
+	}
+	slot.refs++
+	l.mu.Unlock()
+
+	slot.sem <- struct{}{}
+	return func() {
+		<-slot.sem
+		l.mu.Lock()
+		if slot.refs--; slot.refs == 0 {
+			delete(l.slots, host)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	subcommands["schema"] = runSchema
+}
+
+// jsonSchemaDialect is the JSON Schema draft the schema subcommand emits.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaTypes are the output records the schema subcommand can describe.
+var schemaTypes = map[string]struct {
+	typ   reflect.Type
+	title string
+}{
+	"result":  {reflect.TypeFor[Result](), "mysql_scout result: one NDJSON line per target"},
+	"summary": {reflect.TypeFor[scanSummary](), "mysql_scout scan summary (-summary)"},
+}
+
+/*
+schemaBuilder turns Go types into JSON Schema, following encoding/json's rules so the schema describes exactly what is marshaled.
+Named struct types become $defs entries referenced with $ref, so generated code gets one type per Go struct.
+*/
+type schemaBuilder struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+}
+
+/*
+runSchema is the schema subcommand.
+Function-level comment: prints the JSON Schema (draft 2020-12) for a result line, or with -type summary for the scan summary, generated from the Go structs by reflection so it cannot drift from the output.
+*/
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	kind := fs.String("type", "result", "Record to describe: result or summary")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout schema [-type result|summary]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	st, ok := schemaTypes[*kind]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "invalid -type: %q (want result or summary)\n", *kind)
+		return 2
+	}
+	b := &schemaBuilder{defs: make(map[string]any), names: make(map[reflect.Type]string)}
+	root := b.structSchema(st.typ)
+	root["$schema"] = jsonSchemaDialect
+	root["title"] = st.title
+	if len(b.defs) > 0 {
+		root["$defs"] = b.defs
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		fmt.Fprintf(os.Stderr, "schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+/*
+schema returns the schema for a value of type t.
+Function-level comment: nullable says the value can be marshaled as null (a nil pointer, slice, or map without omitempty).
+*/
+func (b *schemaBuilder) schema(t reflect.Type, nullable bool) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		return b.schema(t.Elem(), nullable)
+	}
+	var s map[string]any
+	switch {
+	case t == reflect.TypeFor[time.Time]():
+		s = map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeFor[json.RawMessage]():
+		// Any JSON value, e.g. a -probe-exec program's output.
+		return map[string]any{}
+	case t.Kind() == reflect.Struct:
+		s = map[string]any{"$ref": "#/$defs/" + b.define(t)}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = map[string]any{"type": "string", "contentEncoding": "base64"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = map[string]any{"type": "array", "items": b.schema(t.Elem(), false)}
+	case t.Kind() == reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem(), false)}
+	case t.Kind() == reflect.Interface:
+		return map[string]any{}
+	case t.Kind() == reflect.String:
+		s = map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		s = map[string]any{"type": "integer"}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		s = map[string]any{"type": "integer", "minimum": 0}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+	if nullable {
+		return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+	}
+	return s
+}
+
+/*
+define adds the named struct t to $defs, once, and returns its name; a name taken by another package's type is qualified with the package name.
+*/
+func (b *schemaBuilder) define(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := b.defs[name]; taken || name == "" {
+		name = path.Base(t.PkgPath()) + "." + t.Name()
+	}
+	b.names[t] = name
+	b.defs[name] = nil // reserve the name before recursing, for self-referencing types
+	b.defs[name] = b.structSchema(t)
+	return name
+}
+
+/*
+schemaField is one JSON member of a struct, as encoding/json would marshal it.
+*/
+type schemaField struct {
+	name      string
+	typ       reflect.Type
+	omitEmpty bool
+	tagged    bool
+	depth     int
+	optional  bool // promoted through an embedded pointer, so absent when that pointer is nil
+}
+
+/*
+structSchema returns the object schema for struct t: its properties, with the fields marshaled even when empty listed as required.
+*/
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for _, f := range jsonFields(t) {
+		nullable := !f.omitEmpty && (f.typ.Kind() == reflect.Pointer || f.typ.Kind() == reflect.Slice || f.typ.Kind() == reflect.Map)
+		props[f.name] = b.schema(f.typ, nullable)
+		if !f.omitEmpty && !f.optional {
+			required = append(required, f.name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+/*
+jsonFields lists the members encoding/json marshals for struct t, resolving embedded structs the same way: a shallower field hides deeper ones of the same name, and at equal depth a tagged field wins or, if none or several are tagged, the name is dropped.
+*/
+func jsonFields(t reflect.Type) []schemaField {
+	var all []schemaField
+	var walk func(t reflect.Type, depth int, optional bool)
+	walk = func(t reflect.Type, depth int, optional bool) {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			ft := sf.Type
+			if sf.Anonymous && name == "" {
+				et, viaPointer := ft, false
+				if et.Kind() == reflect.Pointer {
+					et, viaPointer = et.Elem(), true
+				}
+				if et.Kind() == reflect.Struct {
+					walk(et, depth+1, optional || viaPointer)
+					continue
+				}
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = sf.Name
+			}
+			all = append(all, schemaField{name: name, typ: ft, omitEmpty: strings.Contains(","+opts+",", ",omitempty,"), tagged: tagged, depth: depth, optional: optional})
+		}
+	}
+	walk(t, 0, false)
+
+	byName := make(map[string][]schemaField)
+	var order []string
+	for _, f := range all {
+		if _, seen := byName[f.name]; !seen {
+			order = append(order, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []schemaField
+	for _, name := range order {
+		if f, ok := dominantField(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+/*
+dominantField picks the field encoding/json marshals among those sharing a name, if any.
+*/
+func dominantField(fs []schemaField) (schemaField, bool) {
+	minDepth := fs[0].depth
+	for _, f := range fs {
+		minDepth = min(minDepth, f.depth)
+	}
+	var shallow, tagged []schemaField
+	for _, f := range fs {
+		if f.depth == minDepth {
+			shallow = append(shallow, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	switch {
+	case len(shallow) == 1:
+		return shallow[0], true
+	case len(tagged) == 1:
+		return tagged[0], true
+	}
+	return schemaField{}, false
+}
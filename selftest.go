@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+func init() {
+	subcommands["selftest"] = runSelftest
+}
+
+/*
+selftestCase is one canned server for the selftest subcommand and the checks its scan result must pass.
+*/
+type selftestCase struct {
+	name   string
+	first  []byte
+	verify func(res *Result) error
+}
+
+// selftestCases cover the handshakes a deployment most needs to get right: current and old MySQL, MariaDB behind its replication prefix, a malformed handshake the anomaly checks must flag, and a host refused with ERR.
+var selftestCases = []selftestCase{
+	{"mysql-5.7", mysqlprobe.FakeHandshake("5.7.44-log", 0xc1ffffff, 33, "mysql_native_password"), func(res *Result) error {
+		if err := verifyHandshake(res, "5.7.44-log", 5, 7, 44, "mysql_native_password"); err != nil {
+			return err
+		}
+		if res.EOL == nil || !*res.EOL {
+			return fmt.Errorf("5.7 not reported end-of-life")
+		}
+		return nil
+	}},
+	{"mysql-8.0", mysqlprobe.FakeHandshake("8.0.36", 0xdfffffff, 255, "caching_sha2_password"), func(res *Result) error {
+		return verifyHandshake(res, "8.0.36", 8, 0, 36, "caching_sha2_password")
+	}},
+	{"mariadb-10.11", mysqlprobe.FakeHandshake("5.5.5-10.11.6-MariaDB-0+deb12u1", 0xa0fff7fe, 45, "mysql_native_password"), func(res *Result) error {
+		return verifyHandshake(res, "5.5.5-10.11.6-MariaDB-0+deb12u1", 10, 11, 6, "mysql_native_password")
+	}},
+	{"anomalous", anomalousHandshake(), func(res *Result) error {
+		if !res.MySQL || res.HandshakeInfo == nil {
+			return fmt.Errorf("no MySQL handshake detected: %s", res.Error)
+		}
+		return verifyAnomalies(res.HandshakeInfo.Anomalies, map[string]int{
+			mysqlprobe.AnomalySequenceNonzero: 3,
+			mysqlprobe.AnomalyFillerNonzero:   anomalousFillerOffset,
+		})
+	}},
+	{"err-first", mysqlprobe.FakeErrPacket(mysqlprobe.ErrHostNotPrivileged, "Host '127.0.0.1' is not allowed to connect to this MySQL server"), func(res *Result) error {
+		if res.MySQL || res.ServerError == nil {
+			return fmt.Errorf("want an ERR result, got mysql=%t error=%q", res.MySQL, res.Error)
+		}
+		if res.ServerError.Code != mysqlprobe.ErrHostNotPrivileged || !res.ServerError.HostRefused() {
+			return fmt.Errorf("want host-refused error %d, got %d", mysqlprobe.ErrHostNotPrivileged, res.ServerError.Code)
+		}
+		if status, _ := res.Status(); status != mysqlprobe.StatusApplicationError {
+			return fmt.Errorf("status %s, want %s", status, mysqlprobe.StatusApplicationError)
+		}
+		return nil
+	}},
+}
+
+/*
+verifyHandshake checks that res is a clean MySQL detection with the given version string, parsed version, and auth plugin.
+*/
+func verifyHandshake(res *Result, version string, major, minor, patch int, plugin string) error {
+	if !res.MySQL || res.HandshakeInfo == nil {
+		return fmt.Errorf("no MySQL handshake detected: %s", res.Error)
+	}
+	info := res.HandshakeInfo
+	switch {
+	case info.ServerVersion != version:
+		return fmt.Errorf("server version %q, want %q", info.ServerVersion, version)
+	case info.Version == nil || info.Version.Major != major || info.Version.Minor != minor || info.Version.Patch != patch:
+		return fmt.Errorf("parsed version %+v, want %d.%d.%d", info.Version, major, minor, patch)
+	case info.AuthPluginName != plugin:
+		return fmt.Errorf("auth plugin %q, want %q", info.AuthPluginName, plugin)
+	case info.ConnectionID != 11:
+		return fmt.Errorf("connection id %d, want 11", info.ConnectionID)
+	case info.Fingerprint == "":
+		return fmt.Errorf("no handshake fingerprint")
+	case len(info.Anomalies) > 0:
+		return fmt.Errorf("unexpected anomaly %s: %s", info.Anomalies[0].Code, info.Anomalies[0].Description)
+	}
+	if status, err := res.Status(); status != mysqlprobe.StatusSuccess {
+		return fmt.Errorf("status %s: %v", status, err)
+	}
+	return nil
+}
+
+// anomalousVersion is the version string of the anomalous case; anomalousFillerOffset is where its filler byte sits (header, protocol, version and NUL, connection ID, first scramble part).
+const (
+	anomalousVersion      = "8.0.36"
+	anomalousFillerOffset = 4 + 1 + len(anomalousVersion) + 1 + 4 + 8
+)
+
+/*
+anomalousHandshake returns a MySQL 8.0 handshake sent with sequence ID 1 and a nonzero filler byte, as a sloppy emulation might.
+*/
+func anomalousHandshake() []byte {
+	p := mysqlprobe.FakeHandshake(anomalousVersion, 0xdfffffff, 255, "caching_sha2_password")
+	p[3] = 1
+	p[anomalousFillerOffset] = 0x2a
+	return p
+}
+
+/*
+verifyAnomalies checks that got holds exactly the anomaly codes in want, each at its offset.
+*/
+func verifyAnomalies(got []mysqlprobe.Anomaly, want map[string]int) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("%d anomalies, want %d: %+v", len(got), len(want), got)
+	}
+	for _, a := range got {
+		offset, ok := want[a.Code]
+		switch {
+		case !ok:
+			return fmt.Errorf("unexpected anomaly %s: %s", a.Code, a.Description)
+		case a.Offset == nil || *a.Offset != offset:
+			return fmt.Errorf("anomaly %s at offset %v, want %d", a.Code, a.Offset, offset)
+		case a.Severity == "":
+			return fmt.Errorf("anomaly %s has no severity", a.Code)
+		}
+	}
+	return nil
+}
+
+/*
+runSelftest is the selftest subcommand.
+Function-level comment: serves each canned handshake from an in-process fake server on loopback, scans it through the same pipeline as a real scan (probe, version parsing, provider and EOL classification), and checks the result; prints one line per case and exits 1 if any failed, so operators can confirm a deployed binary works without a MySQL server at hand.
+*/
+func runSelftest(args []string) int {
+	fs := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "Also print each case's scan result as JSON")
+	timeout := fs.Duration("timeout", 2*time.Second, "Per-case probe timeout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout selftest [-v] [-timeout D]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg := scanConfig{prober: mysqlprobe.NewMySQLProber, probe: mysqlprobe.Options{Timeout: *timeout, Verbose: true}}
+	dests := newHostLimiter(0)
+	failed := 0
+	for _, c := range selftestCases {
+		err := runSelftestCase(c, cfg, dests, *verbose)
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", c.name)
+	}
+	if failed > 0 {
+		fmt.Printf("selftest: %d of %d cases failed\n", failed, len(selftestCases))
+		return 1
+	}
+	fmt.Printf("selftest: all %d cases passed\n", len(selftestCases))
+	return 0
+}
+
+/*
+runSelftestCase starts c's fake server, scans it, and verifies the result.
+*/
+func runSelftestCase(c selftestCase, cfg scanConfig, dests *hostLimiter, verbose bool) error {
+	srv, err := mysqlprobe.StartFakeServer(c.first)
+	if err != nil {
+		return fmt.Errorf("start fake server: %w", err)
+	}
+	defer srv.Close()
+	host, portStr, _ := net.SplitHostPort(srv.Addr())
+	port, _ := strconv.Atoi(portStr)
+	res := safeScanTarget(target{host: host, port: port}, cfg, dests, nil)
+	if verbose {
+		if err := json.NewEncoder(os.Stdout).Encode(res); err != nil {
+			return err
+		}
+	}
+	return c.verify(&res)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// signatureSuffix is appended to the -o path to name its detached signature.
+const signatureSuffix = ".sig"
+
+/*
+outputSignature is the detached signature -sign-key writes next to the output file: what was signed (the file's name, size, and SHA-256, and the scan it came from), the public half of the key, and the signature.
+The signature covers the JSON encoding of every other field, so none of them can be changed without invalidating it.
+*/
+type outputSignature struct {
+	Version   int       `json:"version"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	ScanID    string    `json:"scan_id,omitempty"`
+	SignedAt  time.Time `json:"signed_at"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature,omitempty"`
+}
+
+/*
+digestWriter passes writes through to the output file while hashing them, so the signature covers exactly the bytes that reached the file, after any compression.
+*/
+type digestWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+/*
+newDigestWriter wraps w.
+*/
+func newDigestWriter(w io.Writer) *digestWriter {
+	return &digestWriter{w: w, hash: sha256.New()}
+}
+
+/*
+Write writes p to the file and adds what was written to the digest.
+*/
+func (d *digestWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.hash.Write(p[:n])
+	d.size += int64(n)
+	return n, err
+}
+
+/*
+absorb adds what r holds to the digest without writing it, so the signature of an output that -resume appended to also covers the results written before the interruption.
+*/
+func (d *digestWriter) absorb(r io.Reader) error {
+	n, err := io.Copy(d.hash, r)
+	d.size += n
+	return err
+}
+
+/*
+loadSigningKey reads an unencrypted PEM private key: Ed25519, ECDSA, or RSA, in PKCS#8 or the older EC and PKCS#1 encodings.
+*/
+func loadSigningKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q (want an unencrypted private key)", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey, *rsa.PrivateKey:
+		return k.(crypto.Signer), nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, key)
+}
+
+/*
+keyAlgorithm names the signature scheme used with a public key.
+*/
+func keyAlgorithm(pub crypto.PublicKey) string {
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return "ed25519"
+	case *ecdsa.PublicKey:
+		return "ecdsa-sha256"
+	case *rsa.PublicKey:
+		return "rsa-pkcs1v15-sha256"
+	}
+	return ""
+}
+
+/*
+signedBytes is the message a signature covers: sig's JSON without the signature itself.
+*/
+func (sig outputSignature) signedBytes() ([]byte, error) {
+	sig.Signature = ""
+	return json.Marshal(sig)
+}
+
+/*
+writeOutputSignature signs the output file at path, as hashed by d, and writes the signature to path.sig.
+*/
+func writeOutputSignature(path string, d *digestWriter, key crypto.Signer, scanID string) error {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	sig := outputSignature{
+		Version:   1,
+		File:      filepath.Base(path),
+		Size:      d.size,
+		SHA256:    hex.EncodeToString(d.hash.Sum(nil)),
+		ScanID:    scanID,
+		SignedAt:  time.Now().UTC(),
+		Algorithm: keyAlgorithm(key.Public()),
+		PublicKey: base64.StdEncoding.EncodeToString(der),
+	}
+	msg, err := sig.signedBytes()
+	if err != nil {
+		return err
+	}
+	var raw []byte
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		raw, err = key.Sign(rand.Reader, msg, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(msg)
+		raw, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return err
+	}
+	sig.Signature = base64.StdEncoding.EncodeToString(raw)
+	out, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+signatureSuffix, append(out, '\n'), 0o644)
+}
+
+/*
+verifySignature checks sig's signature with the public key embedded in it and returns that key.
+*/
+func verifySignature(sig outputSignature) (crypto.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+	if alg := keyAlgorithm(pub); alg == "" || alg != sig.Algorithm {
+		return nil, fmt.Errorf("algorithm %q does not match the %T public key", sig.Algorithm, pub)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	msg, err := sig.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(msg)
+	ok := false
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, msg, raw)
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, digest[:], raw)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], raw) == nil
+	}
+	if !ok {
+		return nil, errors.New("signature does not match")
+	}
+	return pub, nil
+}
+
+/*
+keyFingerprint is the SHA-256 of a public key's PKIX encoding, hex-encoded, for telling keys apart in messages.
+*/
+func keyFingerprint(pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
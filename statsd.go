@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsdTagReplacer makes a tag value safe for the DogStatsD line format, where ',', '|', '#', and ':' (after the key) are syntax.
+var statsdTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", ":", "_", " ", "_")
+
+/*
+statsdClient sends scan metrics to a statsd or DogStatsD agent over UDP, one metric per datagram, tagged in the DogStatsD format.
+Sends are fire-and-forget: a missing agent never slows or fails the scan. A nil client ignores every call, so call sites need no -statsd check.
+*/
+type statsdClient struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	prefix string
+	tags   []string
+}
+
+/*
+newStatsdClient returns a client sending to addr (host:port) with every metric name prefixed by prefix and tagged with tags.
+*/
+func newStatsdClient(addr, prefix string, tags []string) (*statsdClient, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdClient{conn: conn, prefix: prefix, tags: tags}, nil
+}
+
+/*
+parseStatsdTags parses -statsd-tags: comma-separated key:value (or bare key) tags.
+*/
+func parseStatsdTags(spec string) ([]string, error) {
+	var tags []string
+	for _, tag := range splitList(spec) {
+		key, value, _ := strings.Cut(tag, ":")
+		if key == "" || strings.ContainsAny(key, "|#") || strings.ContainsAny(value, "|#") {
+			return nil, fmt.Errorf("invalid tag %q (want key:value)", tag)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+/*
+target records one probed target: a count tagged with its status and whether MySQL answered, and the time it took.
+Function-level comment: detected servers also count toward mysql.servers, tagged with product and version series like the summary's versions.
+*/
+func (c *statsdClient) target(res Result, elapsed time.Duration) {
+	if c == nil {
+		return
+	}
+	status, _ := res.Status()
+	tags := []string{"status:" + string(status), "mysql:" + strconv.FormatBool(res.MySQL)}
+	c.send("targets", "1", "c", tags...)
+	c.send("target.duration", strconv.FormatInt(elapsed.Milliseconds(), 10), "ms", tags...)
+	if info := res.HandshakeInfo; res.MySQL && info != nil {
+		series := "unknown"
+		if v := info.Version; v != nil {
+			series = fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		}
+		c.send("mysql.servers", "1", "c", "product:"+productName(info.ServerVersion), "series:"+series)
+	}
+}
+
+/*
+queueDepth records how many results are still queued between the probes and the sink as one leaves for it, as a gauge.
+*/
+func (c *statsdClient) queueDepth(n int) {
+	if c == nil {
+		return
+	}
+	c.send("output.queue_depth", strconv.Itoa(n), "g")
+}
+
+/*
+dropped counts a result dropped because the output queue was full.
+*/
+func (c *statsdClient) dropped() {
+	if c == nil {
+		return
+	}
+	c.send("output.dropped", "1", "c")
+}
+
+/*
+scanDone records the end of a scan: its duration, the targets -max-runtime left unprobed, and the results the output queue dropped.
+*/
+func (c *statsdClient) scanDone(summary *scanSummary) {
+	if c == nil {
+		return
+	}
+	c.send("scan.duration", strconv.FormatInt(int64(summary.DurationSeconds*1000), 10), "ms")
+	c.send("scan.skipped_deadline", strconv.Itoa(summary.SkippedDeadline), "g")
+	c.send("scan.dropped_results", strconv.Itoa(summary.DroppedResults), "g")
+}
+
+/*
+send writes one metric line, name:value|kind|#tags, with the prefix and the global tags added. Per-metric tag values are lowercased and made safe; global tags were validated by parseStatsdTags.
+*/
+func (c *statsdClient) send(name, value, kind string, tags ...string) {
+	all := append([]string(nil), c.tags...)
+	for _, tag := range tags {
+		key, val, _ := strings.Cut(tag, ":")
+		all = append(all, key+":"+statsdTagReplacer.Replace(strings.ToLower(val)))
+	}
+	line := c.prefix + name + ":" + value + "|" + kind
+	if len(all) > 0 {
+		line += "|#" + strings.Join(all, ",")
+	}
+	c.mu.Lock()
+	_, _ = c.conn.Write([]byte(line))
+	c.mu.Unlock()
+}
+
+/*
+close releases the socket; a nil client has none.
+*/
+func (c *statsdClient) close() {
+	if c != nil {
+		c.conn.Close()
+	}
+}
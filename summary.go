@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+scanSummary aggregates results into the end-of-scan overview.
+Versions are keyed by product and release series (e.g. "MySQL 8.0"), errors by the zgrab2-style status of failed targets.
+*/
+type scanSummary struct {
+	ScanID         string         `json:"scan_id"`
+	RunLabel       string         `json:"run_label,omitempty"`
+	Targets        int            `json:"targets"`
+	MySQL          int            `json:"mysql"`
+	Versions       map[string]int `json:"versions,omitempty"`
+	AuthPlugins    map[string]int `json:"auth_plugins,omitempty"`
+	Errors         map[string]int `json:"errors,omitempty"`
+	DNSCacheHits   int64          `json:"dns_cache_hits,omitempty"`
+	DNSCacheMisses int64          `json:"dns_cache_misses,omitempty"`
+	// SkippedDeadline counts targets never probed because -max-runtime ran out.
+	SkippedDeadline int `json:"skipped_deadline,omitempty"`
+	// OutputQueuePeak is the most results ever waiting for the sink; DroppedResults counts those -output-queue-policy drop discarded.
+	OutputQueuePeak int `json:"output_queue_peak,omitempty"`
+	DroppedResults  int `json:"dropped_results,omitempty"`
+	// AdaptiveConcurrency is set with -adaptive-concurrency: where its limit ended and peaked, and how often it backed off.
+	AdaptiveConcurrency *adaptiveSummary `json:"adaptive_concurrency,omitempty"`
+	DurationSeconds     float64          `json:"duration_seconds"`
+
+	start time.Time
+}
+
+/*
+newScanSummary starts a summary whose duration is measured from now.
+*/
+func newScanSummary() *scanSummary {
+	return &scanSummary{
+		Versions:    make(map[string]int),
+		AuthPlugins: make(map[string]int),
+		Errors:      make(map[string]int),
+		start:       time.Now(),
+	}
+}
+
+/*
+add counts one result.
+Function-level comment: must see the full handshake (before non-verbose trimming) so auth plugins are known.
+*/
+func (s *scanSummary) add(res Result) {
+	s.Targets++
+	if status, _ := res.Status(); res.MySQL {
+		s.MySQL++
+	} else if status != mysqlprobe.StatusSuccess {
+		s.Errors[string(status)]++
+	}
+	if info := res.HandshakeInfo; info != nil {
+		series := "unknown"
+		if v := info.Version; v != nil {
+			series = fmt.Sprintf("%d.%d", v.Major, v.Minor)
+		}
+		s.Versions[productName(info.ServerVersion)+" "+series]++
+		if info.AuthPluginName != "" {
+			s.AuthPlugins[info.AuthPluginName]++
+		}
+	}
+}
+
+/*
+finish stamps the total duration; call it once the scan is over.
+*/
+func (s *scanSummary) finish() {
+	s.DurationSeconds = time.Since(s.start).Round(time.Millisecond).Seconds()
+}
+
+/*
+writeFile stores the summary as indented JSON at path.
+*/
+func (s *scanSummary) writeFile(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+/*
+writeText prints the summary as a short human-readable block.
+*/
+func (s *scanSummary) writeText(w io.Writer) error {
+	run := s.ScanID
+	if s.RunLabel != "" {
+		run += " (" + s.RunLabel + ")"
+	}
+	_, err := fmt.Fprintf(w, "scan summary: %d targets in %.1fs, %d MySQL\n  scan id:      %s\n  versions:     %s\n  auth plugins: %s\n  errors:       %s\n",
+		s.Targets, s.DurationSeconds, s.MySQL, run, countList(s.Versions), countList(s.AuthPlugins), countList(s.Errors))
+	if err == nil && s.DNSCacheHits+s.DNSCacheMisses > 0 {
+		_, err = fmt.Fprintf(w, "  dns cache:    %d hits, %d misses\n", s.DNSCacheHits, s.DNSCacheMisses)
+	}
+	if err == nil && s.SkippedDeadline > 0 {
+		_, err = fmt.Fprintf(w, "  skipped_deadline: %d targets not probed within -max-runtime\n", s.SkippedDeadline)
+	}
+	if err == nil && s.DroppedResults > 0 {
+		_, err = fmt.Fprintf(w, "  dropped_results: %d results discarded because the output queue was full\n", s.DroppedResults)
+	}
+	if a := s.AdaptiveConcurrency; err == nil && a != nil {
+		_, err = fmt.Fprintf(w, "  concurrency:  adaptive %d-%d, ended at %d, peak %d, %d backoffs\n", a.Min, a.Max, a.Final, a.Peak, a.Backoffs)
+	}
+	return err
+}
+
+/*
+countList renders counts as "key=n" pairs, most frequent first (ties by key), or "-" when empty.
+*/
+func countList(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	keys := sortedByCount(counts)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%d", k, counts[k])
+	}
+	return strings.Join(pairs, "  ")
+}
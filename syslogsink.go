@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// syslogSDID is the structured-data element carrying a detection's key fields; 32473 is the private enterprise number RFC 5612 reserves for documentation, since the scanner has none of its own.
+const syslogSDID = "mysql@32473"
+
+// Syslog severities (RFC 5424 section 6.2.1) of detection messages: notice for a server found, warning when it is end-of-life or accepts weak authentication.
+const (
+	syslogWarning = 4
+	syslogNotice  = 5
+)
+
+// syslogFacilities maps the facility names the sink accepts to their codes.
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3, "auth": 4, "security": 13, "audit": 13,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogTimestamp is RFC 3339 with the microsecond precision RFC 5424 allows at most.
+const syslogTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogParamEscaper escapes the characters RFC 5424 requires escaped in structured-data parameter values.
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+/*
+syslogSink sends one RFC 5424 message per MySQL detection to a syslog collector over UDP, TCP, or TLS.
+Results that are not detections are dropped. Over TCP and TLS messages are octet-counted (RFC 6587, RFC 5425), and a broken connection is redialed once per message.
+*/
+type syslogSink struct {
+	network  string
+	addr     string
+	tls      *tls.Config
+	timeout  time.Duration
+	conn     net.Conn
+	facility int
+	hostname string
+	app      string
+}
+
+func init() {
+	mysqlprobe.RegisterSink("syslog", openSyslogSink)
+}
+
+/*
+openSyslogSink opens the sink for a syslog://host[:port] URL.
+Function-level comment: query parameters pick the transport (transport=udp, the default, tcp, or tls), facility (default local0), app name (app, default mysql_scout), and for TLS a CA bundle (ca=/path.pem, default the system roots). The port defaults to 514, or 6514 for TLS.
+*/
+func openSyslogSink(u *url.URL) (mysqlprobe.OutputSink, error) {
+	q := u.Query()
+	s := &syslogSink{network: q.Get("transport"), timeout: 10 * time.Second, app: q.Get("app")}
+	if s.network == "" {
+		s.network = "udp"
+	}
+	port := "514"
+	switch s.network {
+	case "udp", "tcp":
+	case "tls":
+		port = "6514"
+		s.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+		if ca := q.Get("ca"); ca != "" {
+			pem, err := os.ReadFile(ca)
+			if err != nil {
+				return nil, err
+			}
+			s.tls.RootCAs = x509.NewCertPool()
+			if !s.tls.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in %s", ca)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid syslog transport %q (want udp, tcp, or tls)", s.network)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("syslog sink needs a host, e.g. syslog://siem.example.com:514")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	s.addr = net.JoinHostPort(u.Hostname(), port)
+
+	facility := q.Get("facility")
+	if facility == "" {
+		facility = "local0"
+	}
+	var ok bool
+	if s.facility, ok = syslogFacilities[facility]; !ok {
+		return nil, fmt.Errorf("invalid syslog facility %q", facility)
+	}
+	if s.app == "" {
+		s.app = "mysql_scout"
+	} else if len(s.app) > 48 || strings.ContainsAny(s.app, " \t\n") {
+		return nil, fmt.Errorf("invalid syslog app %q (at most 48 characters, no spaces)", s.app)
+	}
+	if s.hostname, _ = os.Hostname(); s.hostname == "" {
+		s.hostname = "-"
+	}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+dial (re)connects to the collector.
+*/
+func (s *syslogSink) dial() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	var conn net.Conn
+	var err error
+	if s.tls != nil {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: s.timeout}, "tcp", s.addr, s.tls)
+	} else {
+		conn, err = net.DialTimeout(s.network, s.addr, s.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("syslog %s: %w", s.addr, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+/*
+Write sends result as one message if it is a MySQL detection.
+*/
+func (s *syslogSink) Write(result any) error {
+	var res *Result
+	switch r := result.(type) {
+	case Result:
+		res = &r
+	case *Result:
+		res = r
+	default:
+		return fmt.Errorf("syslog sink: unsupported record %T", result)
+	}
+	if !res.MySQL {
+		return nil
+	}
+	msg, err := s.format(res, time.Now())
+	if err != nil {
+		return err
+	}
+	if s.network != "udp" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	err = s.send(msg)
+	if err != nil && s.network != "udp" {
+		if err = s.dial(); err == nil {
+			err = s.send(msg)
+		}
+	}
+	return err
+}
+
+/*
+send writes one framed message within the timeout.
+*/
+func (s *syslogSink) send(msg []byte) error {
+	if s.conn == nil {
+		return fmt.Errorf("syslog %s: not connected", s.addr)
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+/*
+format renders res as an RFC 5424 message: the detection's key fields as structured data, and the whole result as JSON in the message body.
+*/
+func (s *syslogSink) format(res *Result, now time.Time) ([]byte, error) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	severity := syslogNotice
+	if (res.EOL != nil && *res.EOL) || res.WeakAuth {
+		severity = syslogWarning
+	}
+	params := [][2]string{{"host", res.Host}, {"port", strconv.Itoa(res.Port)}}
+	for _, p := range [][2]string{
+		{"hostname", res.Hostname},
+		{"server_version", res.ServerVersion},
+		{"auth_plugin", res.AuthPluginName},
+		{"tls", tlsPosture(*res)},
+		{"eol", boolParam(res.EOL)},
+		{"provider", res.Provider},
+		{"scan_id", res.ScanID},
+	} {
+		if p[1] != "" {
+			params = append(params, p)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d detection [%s", s.facility*8+severity, now.UTC().Format(syslogTimestamp), s.hostname, s.app, os.Getpid(), syslogSDID)
+	for _, p := range params {
+		fmt.Fprintf(&b, ` %s="%s"`, p[0], syslogParamEscaper.Replace(p[1]))
+	}
+	b.WriteString("] ")
+	b.Write(body)
+	return []byte(b.String()), nil
+}
+
+/*
+boolParam formats an optional flag, "" when unset.
+*/
+func boolParam(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
+
+/*
+Flush is a no-op: every message is written as it comes.
+*/
+func (s *syslogSink) Flush() error {
+	return nil
+}
+
+/*
+Close closes the connection to the collector.
+*/
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// tableColumns are the headers of the table format.
+var tableColumns = []string{"HOST", "PORT", "STATUS", "PRODUCT", "VERSION", "AUTH PLUGIN", "TLS"}
+
+/*
+tableWriter collects results and renders them as one fixed-width table when flushed.
+Buffering is what lets every column be sized to its widest cell, so nothing is printed until the scan ends.
+*/
+type tableWriter struct {
+	out  io.Writer
+	rows [][]string
+}
+
+/*
+write buffers res as a table row.
+*/
+func (tw *tableWriter) write(res Result) error {
+	row := []string{res.Host, strconv.Itoa(res.Port), "error", "-", "-", "-", "-"}
+	switch {
+	case !res.OK:
+	case !res.MySQL:
+		row[2] = "other"
+	default:
+		row[2] = "mysql"
+		row[3] = productName(res.ServerVersion)
+		row[4] = res.ServerVersion
+		if res.AuthPluginName != "" {
+			row[5] = res.AuthPluginName
+		}
+		row[6] = tlsColumn(res)
+	}
+	tw.rows = append(tw.rows, row)
+	return nil
+}
+
+/*
+flush renders the buffered rows under a header and a separator line.
+*/
+func (tw *tableWriter) flush() error {
+	w := tabwriter.NewWriter(tw.out, 0, 0, 2, ' ', 0)
+	sep := make([]string, len(tableColumns))
+	for i, c := range tableColumns {
+		sep[i] = strings.Repeat("-", len(c))
+	}
+	for _, row := range append([][]string{tableColumns, sep}, tw.rows...) {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+/*
+productName is the display name of the product family announced in the version string.
+*/
+func productName(serverVersion string) string {
+	if versionProduct(serverVersion) == "mariadb" {
+		return "MariaDB"
+	}
+	return "MySQL"
+}
+
+/*
+tlsColumn summarizes TLS for the table: the negotiated version when -tls ran, otherwise whether the server offers it.
+*/
+func tlsColumn(res Result) string {
+	switch {
+	case res.TLS != nil && res.TLS.Error != "":
+		return "error"
+	case res.TLS != nil:
+		return res.TLS.Version
+	case res.CapabilityFlags&mysqlprobe.ClientSSL != 0:
+		return "offered"
+	}
+	return "no"
+}
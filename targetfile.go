@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+targetOptions are per-target overrides of the global scan settings, read from a -targets-file line.
+Unset fields (zero timeout, nil pointers) fall back to the command-line values.
+*/
+type targetOptions struct {
+	Timeout time.Duration     `json:"timeout,omitempty"`
+	Retries *int              `json:"retries,omitempty"`
+	TLS     *bool             `json:"tls,omitempty"`
+	Label   string            `json:"label,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+/*
+label returns the target's label; a nil options set has none.
+*/
+func (o *targetOptions) label() string {
+	if o == nil {
+		return ""
+	}
+	return o.Label
+}
+
+/*
+labels returns the target's key=value labels; a nil options set has none.
+*/
+func (o *targetOptions) labels() map[string]string {
+	if o == nil {
+		return nil
+	}
+	return o.Labels
+}
+
+/*
+set applies one key=value column.
+Function-level comment: timeout (duration), retries (count), tls (bool), and label override scan settings; any other key becomes a label copied into the result's labels.
+*/
+func (o *targetOptions) set(key, value string) error {
+	switch key {
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout %q", value)
+		}
+		o.Timeout = d
+	case "retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid retries %q", value)
+		}
+		o.Retries = &n
+	case "tls":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid tls %q", value)
+		}
+		o.TLS = &b
+	case "label":
+		o.Label = value
+	default:
+		if key == "" {
+			return fmt.Errorf("empty key in %q", key+"="+value)
+		}
+		if o.Labels == nil {
+			o.Labels = make(map[string]string)
+		}
+		o.Labels[key] = value
+	}
+	return nil
+}
+
+/*
+targetSpec is the JSON form of a -targets-file line.
+*/
+type targetSpec struct {
+	Host    string            `json:"host"`
+	Port    int               `json:"port,omitempty"`
+	Timeout string            `json:"timeout,omitempty"`
+	Retries *int              `json:"retries,omitempty"`
+	TLS     *bool             `json:"tls,omitempty"`
+	Label   string            `json:"label,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// targetUDPMemory bounds the hosts a -targets-file read remembers as already carrying UDP probes; past it the memory is cleared, so a host listed again far apart may get its UDP probes twice rather than the set growing with the file.
+const targetUDPMemory = 1 << 16
+
+/*
+loadTargetsFile reads targets with optional per-target overrides, one per line, into memory.
+Function-level comment: a line is either `spec [key=value ...]` (e.g. `10.2.3.4:3307 timeout=10s retries=3 label=prod-db env=prod`) or a JSON object with host, port, the same option keys, and a labels object. The spec (or JSON host) takes every form parseTargetSpec accepts: a name, IP, or CIDR, with optional ports or a mysql:// / mysqlx:// scheme, and brace expressions (see expandBraces); without a port the target gets every port in ports. Path "-" reads stdin. Gzip and zstd input is decompressed. Blank lines and # comments are skipped, and the first target of each host carries its UDP probes. Files too big to hold in memory are read with openTargetStream instead.
+*/
+func loadTargetsFile(path string, ports []int) ([]target, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+	var targets []target
+	err := readTargets(f, path, ports, func(t target) bool {
+		targets = append(targets, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+/*
+readTargets parses a -targets-file from r, calling yield with each target as its line is read, until yield returns false.
+Function-level comment: only the current line, and a CIDR's current address, are held at a time; path names the input in errors.
+*/
+func readTargets(r io.Reader, path string, ports []int, yield func(target) bool) error {
+	dr, err := decompressedReader(r)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if c, ok := dr.(io.Closer); ok {
+		defer c.Close()
+	}
+	hasUDP := make(map[string]bool)
+	sc := bufio.NewScanner(dr)
+	stopped := false
+	for n := 1; !stopped && sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parsed, err := parseTargetLine(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		for _, spec := range parsed.addrs {
+			linePorts := ports
+			if len(spec.ports) > 0 {
+				linePorts = spec.ports
+			}
+			err = expandHost(spec.host, func(h string) bool {
+				if len(hasUDP) >= targetUDPMemory {
+					clear(hasUDP)
+				}
+				for _, p := range linePorts {
+					if !yield(target{host: h, port: p, protocol: spec.protocol, runUDP: !hasUDP[h], opts: parsed.opts}) {
+						stopped = true
+						return false
+					}
+					hasUDP[h] = true
+				}
+				return true
+			})
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			if stopped {
+				break
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return nil
+}
+
+/*
+targetStream is a -targets-file read from disk as the scan goes rather than loaded up front, so memory stays flat however many targets it lists.
+Each pass over all opens the file again, which is what lets -watch rounds reread it.
+*/
+type targetStream struct {
+	path  string
+	ports []int
+	count int
+
+	mu      sync.Mutex
+	readErr error
+}
+
+/*
+openTargetStream checks the whole file at path once, so a bad line is reported before the scan starts, and counts its targets.
+*/
+func openTargetStream(path string, ports []int) (*targetStream, error) {
+	s := &targetStream{path: path, ports: ports}
+	for range s.all {
+		s.count++
+	}
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+all yields every target in the file, in order; an error ends the pass early and is kept for err.
+*/
+func (s *targetStream) all(yield func(target) bool) {
+	f, err := os.Open(s.path)
+	if err == nil {
+		err = readTargets(f, s.path, s.ports, yield)
+		f.Close()
+	}
+	s.mu.Lock()
+	s.readErr = err
+	s.mu.Unlock()
+}
+
+/*
+err returns the error that ended the last pass, or nil if it read the whole file (or was stopped by its consumer).
+*/
+func (s *targetStream) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readErr
+}
+
+/*
+parsedTargetLine is one -targets-file line before CIDR expansion: the targets its spec stands for after brace expansion, and its options.
+*/
+type parsedTargetLine struct {
+	addrs []targetAddr
+	opts  *targetOptions
+}
+
+/*
+parseTargetLine parses a single plain or JSON -targets-file line.
+*/
+func parseTargetLine(line string) (parsedTargetLine, error) {
+	var out parsedTargetLine
+	opts := &targetOptions{}
+	if strings.HasPrefix(line, "{") {
+		var spec targetSpec
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&spec); err != nil {
+			return out, fmt.Errorf("invalid JSON target: %w", err)
+		}
+		if spec.Host == "" {
+			return out, fmt.Errorf("JSON target has no host")
+		}
+		addrs, err := parseTargetSpecs(spec.Host)
+		if err != nil {
+			return out, err
+		}
+		if spec.Port < 0 || spec.Port > 65535 {
+			return out, fmt.Errorf("invalid port %d", spec.Port)
+		}
+		if out.addrs = addrs; spec.Port != 0 {
+			for i := range out.addrs {
+				out.addrs[i].ports = []int{spec.Port}
+			}
+		}
+		if spec.Timeout != "" {
+			if err := opts.set("timeout", spec.Timeout); err != nil {
+				return out, err
+			}
+		}
+		opts.Retries, opts.TLS, opts.Label, opts.Labels = spec.Retries, spec.TLS, spec.Label, spec.Labels
+	} else {
+		fields := strings.Fields(line)
+		addrs, err := parseTargetSpecs(fields[0])
+		if err != nil {
+			return out, err
+		}
+		out.addrs = addrs
+		for _, kv := range fields[1:] {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return out, fmt.Errorf("expected key=value, got %q", kv)
+			}
+			if err := opts.set(key, value); err != nil {
+				return out, err
+			}
+		}
+	}
+	if opts.Retries != nil && *opts.Retries < 0 {
+		return out, fmt.Errorf("invalid retries %d", *opts.Retries)
+	}
+	if opts.Timeout != 0 || opts.Retries != nil || opts.TLS != nil || opts.Label != "" || len(opts.Labels) > 0 {
+		out.opts = opts
+	}
+	return out, nil
+}
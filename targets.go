@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"iter"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxCIDRHosts bounds how many addresses a single CIDR target may expand to.
+const maxCIDRHosts = 1 << 24
+
+/*
+expandHosts turns the -host entries into individual hosts, expanding CIDR blocks into every address they contain.
+Function-level comment: hostnames and single IPs pass through unchanged; blocks larger than maxCIDRHosts are rejected rather than silently truncated.
+*/
+func expandHosts(specs []string) ([]string, error) {
+	var hosts []string
+	for _, spec := range specs {
+		if err := expandHost(spec, func(h string) bool {
+			hosts = append(hosts, h)
+			return true
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return hosts, nil
+}
+
+/*
+splitPrefixZone splits the zone out of a link-local CIDR written with one after the address, e.g. fe80::%eth0/120, which netip.ParsePrefix refuses; the zone is "" when there is none.
+*/
+func splitPrefixZone(spec string) (string, string) {
+	addr, bits, ok := strings.Cut(spec, "/")
+	if !ok {
+		return spec, ""
+	}
+	addr, zone, _ := strings.Cut(addr, "%")
+	return addr + "/" + bits, zone
+}
+
+/*
+expandHost calls yield with each host spec stands for, one at a time, so a large block is never held in memory; it stops early when yield returns false.
+*/
+func expandHost(spec string, yield func(string) bool) error {
+	if !strings.Contains(spec, "/") {
+		yield(spec)
+		return nil
+	}
+	cidr, zone := splitPrefixZone(spec)
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q: %w", spec, err)
+	}
+	prefix = prefix.Masked()
+	if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits > 24 {
+		return fmt.Errorf("CIDR %q expands to more than %d addresses", spec, maxCIDRHosts)
+	}
+	for a := prefix.Addr(); prefix.Contains(a); a = a.Next() {
+		if !yield(a.WithZone(zone).String()) {
+			break
+		}
+	}
+	return nil
+}
+
+/*
+exclusionList holds networks and hostnames that must never be contacted.
+Hostnames in the list are also resolved at load time so their addresses are blocked when targeted directly.
+*/
+type exclusionList struct {
+	prefixes []netip.Prefix
+	names    map[string]bool
+}
+
+/*
+normalizeHostname lowercases a hostname and drops a trailing root dot for comparisons.
+*/
+func normalizeHostname(h string) string {
+	return strings.TrimSuffix(strings.ToLower(h), ".")
+}
+
+/*
+loadExclusions reads an exclusion file: one CIDR, IP, or hostname per line, with # comments.
+Function-level comment: hostnames that fail to resolve are still excluded by name; any unparsable line is an error so a typo can't quietly widen scope.
+*/
+func loadExclusions(path string) (*exclusionList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ex := &exclusionList{names: make(map[string]bool)}
+	sc := bufio.NewScanner(f)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if err := ex.add(line, true); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return ex, nil
+}
+
+/*
+add parses one exclusion entry (CIDR, IP, or hostname) into the list.
+Function-level comment: when resolve is set, hostnames are also looked up and their addresses excluded; a failed lookup still excludes the name itself.
+*/
+func (ex *exclusionList) add(entry string, resolve bool) error {
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		ex.prefixes = append(ex.prefixes, prefix.Masked())
+		return nil
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		ex.prefixes = append(ex.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		return nil
+	}
+	if strings.ContainsAny(entry, "/ \t") {
+		return fmt.Errorf("invalid exclusion %q", entry)
+	}
+	ex.names[normalizeHostname(entry)] = true
+	if resolve {
+		addrs, _ := net.DefaultResolver.LookupNetIP(context.Background(), "ip", entry)
+		for _, a := range addrs {
+			ex.prefixes = append(ex.prefixes, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+		}
+	}
+	return nil
+}
+
+/*
+entries returns the list as exclusion-file entries (networks first, then hostnames).
+Function-level comment: used to hand the coordinator's exclusions to workers; resolved hostname addresses are included as networks so workers need not re-resolve them.
+*/
+func (ex *exclusionList) entries() []string {
+	if ex == nil {
+		return nil
+	}
+	out := make([]string, 0, len(ex.prefixes)+len(ex.names))
+	for _, p := range ex.prefixes {
+		out = append(out, p.String())
+	}
+	for name := range ex.names {
+		out = append(out, name)
+	}
+	return out
+}
+
+/*
+mergeExclusions returns a list holding base's entries plus extra ones.
+Function-level comment: base is left untouched; extra entries are taken verbatim without DNS lookups.
+*/
+func mergeExclusions(base *exclusionList, extra []string) (*exclusionList, error) {
+	if base == nil && len(extra) == 0 {
+		return nil, nil
+	}
+	merged := &exclusionList{names: make(map[string]bool)}
+	if base != nil {
+		merged.prefixes = append(merged.prefixes, base.prefixes...)
+		for name := range base.names {
+			merged.names[name] = true
+		}
+	}
+	for _, e := range extra {
+		if err := merged.add(e, false); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+/*
+excludesAddr reports whether addr falls inside any excluded network.
+Function-level comment: a nil list excludes nothing.
+*/
+func (ex *exclusionList) excludesAddr(addr netip.Addr) bool {
+	if ex == nil {
+		return false
+	}
+	// A zoned address (fe80::1%eth0) is contained in no prefix, so match it without its zone.
+	addr = addr.Unmap().WithZone("")
+	for _, p := range ex.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+excludesHost reports whether a target host (name or literal IP) is excluded before any resolution.
+*/
+func (ex *exclusionList) excludesHost(host string) bool {
+	if ex == nil {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return ex.excludesAddr(addr)
+	}
+	return ex.names[normalizeHostname(host)]
+}
+
+/*
+filterExcluded drops targets whose host (or imported hostname) is excluded by name or literal address.
+Function-level comment: each pass over the result counts the targets it dropped in *dropped; hostnames that resolve into excluded networks are caught later, at dial time.
+*/
+func filterExcluded(targets iter.Seq[target], ex *exclusionList, dropped *int) iter.Seq[target] {
+	return func(yield func(target) bool) {
+		*dropped = 0
+		for t := range targets {
+			if ex != nil && (ex.excludesHost(t.host) || (t.hostname != "" && ex.excludesHost(t.hostname))) {
+				*dropped++
+				continue
+			}
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+/*
+resolveTarget picks the address to dial for host, honoring the exclusion list.
+Function-level comment: literal IPs are used as-is; hostnames are resolved (through dns when it is not nil) and the first non-excluded address wins, so the address that was checked is exactly the one contacted.
+*/
+func resolveTarget(ctx context.Context, host string, ex *exclusionList, dns *dnsCache) (netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if ex.excludesAddr(addr) {
+			return netip.Addr{}, fmt.Errorf("excluded: %s matches exclusion list", addr)
+		}
+		return addr, nil
+	}
+	addrs, err := dns.lookup(ctx, host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("resolve failed: %w", err)
+	}
+	for _, a := range addrs {
+		if !ex.excludesAddr(a) {
+			return a.Unmap(), nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("excluded: every address of %s matches exclusion list", host)
+}
+
+/*
+shardSpec selects one deterministic slice of the target space: index k of n shards.
+*/
+type shardSpec struct {
+	index int
+	total int
+}
+
+/*
+parseShard parses a -shard value of the form "k/n" (0 <= k < n).
+Function-level comment: an empty spec means a single shard covering everything.
+*/
+func parseShard(spec string) (shardSpec, error) {
+	if spec == "" {
+		return shardSpec{index: 0, total: 1}, nil
+	}
+	k, n, ok := strings.Cut(spec, "/")
+	index, err1 := strconv.Atoi(k)
+	total, err2 := strconv.Atoi(n)
+	if !ok || err1 != nil || err2 != nil || total < 1 || index < 0 || index >= total {
+		return shardSpec{}, fmt.Errorf("invalid shard %q (want k/n with 0 <= k < n)", spec)
+	}
+	return shardSpec{index: index, total: total}, nil
+}
+
+/*
+applyShard keeps only the targets that belong to this shard.
+Function-level comment: assignment hashes each target's host:port key, so every instance given the same target spec agrees on the split regardless of input order.
+*/
+func applyShard(targets iter.Seq[target], s shardSpec) iter.Seq[target] {
+	if s.total <= 1 {
+		return targets
+	}
+	return func(yield func(target) bool) {
+		for t := range targets {
+			h := fnv.New64a()
+			h.Write([]byte(targetKey(t)))
+			// Fold the high bits in: FNV's low bits barely move between keys differing only in a trailing digit.
+			sum := h.Sum64()
+			sum ^= sum >> 32
+			if int(sum%uint64(s.total)) == s.index && !yield(t) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+targetAddr is one parsed target spec: a host (name, IP, or CIDR), the ports it names, and the protocol its URI scheme selects.
+No ports means the scan's -port/-ports; an empty protocol means -protocol.
+*/
+type targetAddr struct {
+	host     string
+	ports    []int
+	protocol string
+}
+
+/*
+parseTargetSpec parses a target as written in -host, a -targets-file line, or stdin.
+Function-level comment: accepts a bare host, IP, or CIDR; host:port or [IPv6]:port, where the port may be a comma-separated list (db1:3306,3307); and a URI whose scheme names a registered prober (mysql://db1.example.com:3307, mysqlx://db1), which defaults to that protocol's port. Link-local IPv6 addresses may carry a zone in every form: fe80::1%eth0, fe80::1%eth0:3306, [fe80::1%eth0]:3306, mysql://[fe80::1%eth0]:3306 (or %25eth0, as RFC 6874 escapes it), and fe80::%eth0/120 for a range.
+*/
+func parseTargetSpec(spec string) (targetAddr, error) {
+	if scheme, _, ok := strings.Cut(spec, "://"); ok {
+		spec = escapeURIZone(spec)
+		newProber, known := mysqlprobe.LookupProber(scheme)
+		if !known {
+			return targetAddr{}, fmt.Errorf("unknown scheme %q in %q (have %s)", scheme, spec, strings.Join(mysqlprobe.ProberNames(), ", "))
+		}
+		u, err := url.Parse(spec)
+		if err != nil {
+			return targetAddr{}, fmt.Errorf("invalid target %q: %w", spec, err)
+		}
+		if u.Hostname() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return targetAddr{}, fmt.Errorf("invalid target %q: want %s://host[:port]", spec, scheme)
+		}
+		t := targetAddr{host: u.Hostname(), protocol: scheme, ports: []int{newProber(mysqlprobe.Options{}).DefaultPort()}}
+		if p := u.Port(); p != "" {
+			if t.ports, err = parsePortList(p); err != nil {
+				return targetAddr{}, err
+			}
+		}
+		return t, nil
+	}
+	// Bare IPv6 addresses and prefixes contain colons but no port.
+	if addr, err := netip.ParseAddr(spec); err == nil {
+		// A zone never contains a colon, so fe80::1%eth0:3306 is a zoned address and its port.
+		if !strings.Contains(addr.Zone(), ":") {
+			return targetAddr{host: spec}, nil
+		}
+		i := strings.LastIndexByte(spec, ':')
+		spec = "[" + spec[:i] + "]" + spec[i:]
+	}
+	// A CIDR, zoned or not, has no port; its colons would confuse SplitHostPort.
+	if cidr, _ := splitPrefixZone(spec); strings.Contains(spec, "/") {
+		if _, err := netip.ParsePrefix(cidr); err == nil {
+			return targetAddr{host: spec}, nil
+		}
+	}
+	host, portSpec, err := net.SplitHostPort(spec)
+	if err != nil {
+		if strings.Contains(spec, ":") {
+			return targetAddr{}, fmt.Errorf("invalid target %q: %w", spec, err)
+		}
+		return targetAddr{host: spec}, nil
+	}
+	ports, err := parsePortList(portSpec)
+	if err != nil || len(ports) == 0 {
+		return targetAddr{}, fmt.Errorf("invalid ports in %q", spec)
+	}
+	return targetAddr{host: host, ports: ports}, nil
+}
+
+/*
+parseTargetSpecs expands the brace expressions in spec and parses each spec they stand for.
+*/
+func parseTargetSpecs(spec string) ([]targetAddr, error) {
+	specs, err := expandBraces(spec)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]targetAddr, 0, len(specs))
+	for _, s := range specs {
+		a, err := parseTargetSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, a)
+	}
+	return addrs, nil
+}
+
+/*
+splitTargetList splits a comma-separated -host value into target specs, keeping a port list with its host, "db1:3306,3307,db2" is db1:3306,3307 and db2, and a brace list with its spec, "db-{a,b},db3" is db-{a,b} and db3.
+*/
+func splitTargetList(list string) []string {
+	var specs []string
+	for _, item := range splitBraceAware(list) {
+		if n := len(specs); n > 0 && isDigits(item) && strings.Contains(specs[n-1], ":") {
+			specs[n-1] += "," + item
+			continue
+		}
+		specs = append(specs, item)
+	}
+	return specs
+}
+
+/*
+isDigits reports whether s is a non-empty run of ASCII digits.
+*/
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+/*
+specTargets builds targets from -host specs.
+Function-level comment: brace expressions are expanded first (see expandBraces). Plain hosts (and CIDRs) without a port or scheme are then crossed with ports through buildTargets, keeping its port-major order; specs naming ports or a protocol follow, each host's first target carrying the host-level probes.
+*/
+func specTargets(specs []string, ports []int) ([]target, error) {
+	var plain []string
+	var addrs []targetAddr
+	for _, spec := range specs {
+		expanded, err := parseTargetSpecs(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range expanded {
+			if len(a.ports) == 0 && a.protocol == "" {
+				plain = append(plain, a.host)
+				continue
+			}
+			addrs = append(addrs, a)
+		}
+	}
+	hosts, err := expandHosts(plain)
+	if err != nil {
+		return nil, err
+	}
+	targets := buildTargets(hosts, ports)
+	hasUDP := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		hasUDP[h] = true
+	}
+	for _, a := range addrs {
+		expanded, err := expandHosts([]string{a.host})
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range expanded {
+			for _, p := range a.ports {
+				targets = append(targets, target{host: h, port: p, protocol: a.protocol, runUDP: !hasUDP[h]})
+				hasUDP[h] = true
+			}
+		}
+	}
+	return targets, nil
+}
+
+/*
+escapeURIZone escapes a zone written as-is in a URI's bracketed IPv6 host, mysql://[fe80::1%eth0]:3306, to the %25 form url.Parse requires; a zone already escaped is left alone.
+*/
+func escapeURIZone(uri string) string {
+	open := strings.Index(uri, "[")
+	end := strings.Index(uri, "]")
+	if open < 0 || end < open {
+		return uri
+	}
+	i := strings.Index(uri[open:end], "%")
+	if i < 0 || strings.HasPrefix(uri[open+i:], "%25") {
+		return uri
+	}
+	return uri[:open+i] + "%25" + uri[open+i+1:]
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTargetSpec(t *testing.T) {
+	tests := []struct {
+		spec  string
+		host  string
+		ports []int
+	}{
+		{"db1.example.com", "db1.example.com", nil},
+		{"db1:3306,3307", "db1", []int{3306, 3307}},
+		{"10.0.0.0/24", "10.0.0.0/24", nil},
+		{"2001:db8::1", "2001:db8::1", nil},
+		{"[2001:db8::1]:3307", "2001:db8::1", []int{3307}},
+		{"2001:db8::/126", "2001:db8::/126", nil},
+		{"fe80::1%eth0:3306", "fe80::1%eth0", []int{3306}},
+		{"fe80::%eth0/120", "fe80::%eth0/120", nil},
+	}
+	for _, tt := range tests {
+		got, err := parseTargetSpec(tt.spec)
+		if err != nil {
+			t.Errorf("parseTargetSpec(%q): %v", tt.spec, err)
+			continue
+		}
+		if got.host != tt.host || !slices.Equal(got.ports, tt.ports) {
+			t.Errorf("parseTargetSpec(%q) = %q %v, want %q %v", tt.spec, got.host, got.ports, tt.host, tt.ports)
+		}
+	}
+}
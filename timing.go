@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// inlineConfidenceThreshold is the score the timing signals need before an inline device is suspected.
+const inlineConfidenceThreshold = 2
+
+// A banner slower than inlineBannerRatio times the TCP connect, and by at least inlineBannerMinMillis, came from further away than the peer that completed the connect.
+const (
+	inlineBannerRatio     = 3
+	inlineBannerMinMillis = 20
+)
+
+/*
+timingDiffResult is what -timing-diff measured: one connection reading the banner as soon as it arrives and one staying silent for DelayMillis first.
+*/
+type timingDiffResult struct {
+	DelayMillis float64       `json:"delay_ms"`
+	Immediate   timingPattern `json:"immediate"`
+	Delayed     timingPattern `json:"delayed"`
+}
+
+/*
+timingPattern is one -timing-diff connection: how long the TCP connect took, how long the banner took to arrive after it (after the wait, for the delayed pattern), and how the probe ended.
+*/
+type timingPattern struct {
+	ConnectMillis float64               `json:"connect_ms"`
+	BannerMillis  *float64              `json:"banner_ms,omitempty"`
+	Status        mysqlprobe.ScanStatus `json:"status"`
+	Fingerprint   string                `json:"handshake_fingerprint,omitempty"`
+	Error         string                `json:"error,omitempty"`
+}
+
+/*
+inlineObservation is what an inline device guess is made from: the main probe's result and the two -timing-diff patterns.
+*/
+type inlineObservation struct {
+	first     mysqlprobe.Result
+	immediate timingPattern
+	delayed   timingPattern
+}
+
+/*
+inlineSignal is one way a firewall, IDS/IPS, or rate limiter in the path shows in the timing patterns.
+Strong signals (weight 2) are enough on their own; weak signals (weight 1) must be corroborated.
+*/
+type inlineSignal struct {
+	weight   int
+	evidence string
+	match    func(o inlineObservation) bool
+}
+
+var inlineSignals = []inlineSignal{
+	{2, "immediate connection was refused or timed out right after the main probe got a handshake, as rate-based blocking does", func(o inlineObservation) bool {
+		return o.first.MySQL && blocked(o.immediate.Status)
+	}},
+	{2, "delayed connection was refused or timed out right after the immediate one got a handshake, as rate-based blocking does", func(o inlineObservation) bool {
+		return o.immediate.Status == mysqlprobe.StatusSuccess && blocked(o.delayed.Status)
+	}},
+	{2, "delayed connection was closed while silent, before its banner was read, though the immediate one got a handshake", func(o inlineObservation) bool {
+		return o.immediate.Status == mysqlprobe.StatusSuccess && o.delayed.Status == mysqlprobe.StatusConnectionClosed
+	}},
+	{1, "delayed connection got a different handshake than the immediate one", func(o inlineObservation) bool {
+		return o.immediate.Fingerprint != "" && o.delayed.Fingerprint != "" && o.immediate.Fingerprint != o.delayed.Fingerprint
+	}},
+	{1, "TCP connect completed much faster than the banner followed, as when a device in the path accepts the connection before the server sees it", func(o inlineObservation) bool {
+		return slowBanner(o.immediate)
+	}},
+	{1, "banner was not waiting after the silent delay but took as long as an immediate read, as when a device in the path holds the server's data", func(o inlineObservation) bool {
+		return o.immediate.BannerMillis != nil && o.delayed.BannerMillis != nil && *o.immediate.BannerMillis >= inlineBannerMinMillis && *o.delayed.BannerMillis >= *o.immediate.BannerMillis/2
+	}},
+}
+
+/*
+timingDiff probes ip:port twice more with -timing-diff, once reading the banner at once and once only after delay, and infers from those and from first, the main probe's result, whether a device inspects or rate-limits the path.
+Function-level comment: both connections stop at the handshake (no TLS, no login) and wait for the destination and subnet limits like the main probe. Returns the measurements and, when the signals reach inlineConfidenceThreshold, the evidence for inline_device_suspected.
+*/
+func timingDiff(ip string, addr netip.Addr, port int, delay time.Duration, first mysqlprobe.Result, opts mysqlprobe.Options, dests *hostLimiter, subnets *subnetLimiter) (*timingDiffResult, bool, []string) {
+	hostPort := net.JoinHostPort(ip, strconv.Itoa(port))
+	td := &timingDiffResult{
+		DelayMillis: millis(delay),
+		Immediate:   timingProbe(hostPort, ip, addr, 0, opts, dests, subnets),
+		Delayed:     timingProbe(hostPort, ip, addr, delay, opts, dests, subnets),
+	}
+	o := inlineObservation{first: first, immediate: td.Immediate, delayed: td.Delayed}
+	score := 0
+	var evidence []string
+	for _, s := range inlineSignals {
+		if s.match(o) {
+			score += s.weight
+			evidence = append(evidence, s.evidence)
+		}
+	}
+	if score < inlineConfidenceThreshold {
+		return td, false, nil
+	}
+	return td, true, evidence
+}
+
+/*
+timingProbe runs one timing pattern: connects, stays silent for delay, then reads the handshake, timing the connect and the banner separately.
+*/
+func timingProbe(hostPort, ip string, addr netip.Addr, delay time.Duration, opts mysqlprobe.Options, dests *hostLimiter, subnets *subnetLimiter) timingPattern {
+	opts.TLS, opts.Credentials = false, nil
+	connect := opts.Connect
+	// connect already sends any PROXY protocol header; the wrapped Dial must not send another.
+	opts.ProxyProtocol = 0
+	var connectTime time.Duration
+	opts.Dial = func(network, address string, _ time.Duration) (net.Conn, error) {
+		t0 := time.Now()
+		conn, err := connect(network, address)
+		connectTime = time.Since(t0)
+		if err == nil {
+			time.Sleep(delay)
+		}
+		return conn, err
+	}
+	var elapsed time.Duration
+	r := func() mysqlprobe.Result {
+		defer dests.acquire(ip)()
+		defer subnets.acquire(addr)()
+		t0 := time.Now()
+		defer func() { elapsed = time.Since(t0) }()
+		return mysqlprobe.Probe(hostPort, opts)
+	}()
+	status, _ := r.Status()
+	p := timingPattern{ConnectMillis: millis(connectTime), Status: status, Error: r.Error}
+	if r.OK {
+		banner := millis(elapsed - connectTime - delay)
+		p.BannerMillis = &banner
+	}
+	if r.HandshakeInfo != nil {
+		p.Fingerprint = r.HandshakeInfo.Fingerprint
+	}
+	return p
+}
+
+/*
+blocked reports whether a status is a connection that never got through: refused, or timed out connecting.
+*/
+func blocked(s mysqlprobe.ScanStatus) bool {
+	return s == mysqlprobe.StatusConnectionRefused || s == mysqlprobe.StatusConnectionTimeout
+}
+
+/*
+slowBanner reports whether a pattern's banner lagged its TCP connect by more than a direct path explains: a server's handshake arrives about one round trip after the connect completes, which the connect itself took.
+*/
+func slowBanner(p timingPattern) bool {
+	if p.BannerMillis == nil {
+		return false
+	}
+	return *p.BannerMillis >= inlineBannerMinMillis && *p.BannerMillis > inlineBannerRatio*p.ConnectMillis
+}
+
+/*
+millis converts d to milliseconds with microsecond precision, as the JSON reports latencies.
+*/
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the scanner's own spans; the probe's spans use mysqlprobe's.
+const tracerName = "github.com/hadimalik12/censys_take_home_exercise_data_internship"
+
+// tracer starts the scanner's spans; until setupTracing installs a provider it is a no-op.
+var tracer = otel.Tracer(tracerName)
+
+/*
+setupTracing installs a tracer provider exporting spans over OTLP/HTTP to endpoint (e.g. http://collector:4318), sampling sampleRatio of the targets.
+Function-level comment: the returned func flushes the spans still batched and shuts the exporter down; call it before exiting. The usual OTEL_EXPORTER_OTLP_* variables (headers, timeout, certificates) apply on top of endpoint.
+*/
+func setupTracing(ctx context.Context, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("mysql_scout"), semconv.ServiceVersion(version)))
+	if err != nil {
+		return nil, fmt.Errorf("resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	tracer = provider.Tracer(tracerName)
+	return provider.Shutdown, nil
+}
+
+/*
+startTargetSpan starts the root span of one target's trace, under which the probe's dial, read, parse, and TLS spans and the output span nest.
+*/
+func startTargetSpan(t target) (context.Context, trace.Span) {
+	return tracer.Start(context.Background(), "mysql_scout.target", trace.WithAttributes(
+		attribute.String("server.address", net.JoinHostPort(t.host, strconv.Itoa(t.port))),
+		attribute.String("mysql_scout.hostname", t.hostname),
+	))
+}
+
+/*
+endTargetSpan records the target's outcome on span and ends it.
+*/
+func endTargetSpan(span trace.Span, res Result) {
+	status, err := res.Status()
+	span.SetAttributes(attribute.String("mysql_scout.status", string(status)), attribute.Bool("mysql_scout.mysql", res.MySQL))
+	if res.HandshakeInfo != nil {
+		span.SetAttributes(attribute.String("mysql.server_version", res.ServerVersion))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+/*
+startOutputSpan starts the span for writing res, as a child of its target's span.
+*/
+func startOutputSpan(res Result) trace.Span {
+	_, span := tracer.Start(trace.ContextWithSpanContext(context.Background(), res.span), "mysql_scout.output")
+	return span
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// maxUDPResponse is the largest datagram we read back from a UDP probe.
+const maxUDPResponse = 4096
+
+/*
+udpProbe describes a single-datagram request to a service commonly co-hosted with MySQL.
+parse turns the reply into a short human-readable summary, or reports why it isn't the expected service.
+*/
+type udpProbe struct {
+	name    string
+	port    int
+	payload []byte
+	parse   func(resp []byte) (string, error)
+}
+
+/*
+UDPResult is the JSON record for one UDP probe sent alongside the MySQL check.
+*/
+type UDPResult struct {
+	Probe       string `json:"probe"`
+	Port        int    `json:"port"`
+	Responded   bool   `json:"responded"`
+	Summary     string `json:"summary,omitempty"`
+	ResponseHex string `json:"response_hex,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// dnsVersionBindID is the transaction ID used for the version.bind query.
+const dnsVersionBindID = 0x4d53
+
+var udpProbes = map[string]udpProbe{
+	"memcached": {
+		name: "memcached",
+		port: 11211,
+		// 8-byte UDP frame header: request id, sequence number, datagram count, reserved.
+		payload: append([]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00}, "stats\r\n"...),
+		parse:   parseMemcachedStats,
+	},
+	"dns": {
+		name:    "dns",
+		port:    53,
+		payload: buildVersionBindQuery(),
+		parse:   parseVersionBindReply,
+	},
+}
+
+/*
+parseUDPProbeList validates the -udp flag value.
+Function-level comment: splits the comma-separated list, trims blanks, and rejects unknown probe names; returns the names in the order given.
+*/
+func parseUDPProbeList(spec string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := udpProbes[name]; !ok {
+			known := make([]string, 0, len(udpProbes))
+			for k := range udpProbes {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown UDP probe %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+/*
+runUDPProbe sends one probe datagram to host and records the reply.
+Function-level comment: dials UDP, writes the payload, waits up to the probe timeout for a single datagram, and summarizes it with the probe's parser; a silent port is reported as not responded rather than an error, since UDP gives no other signal.
+*/
+func runUDPProbe(host string, p udpProbe, opts mysqlprobe.Options) UDPResult {
+	res := UDPResult{Probe: p.name, Port: p.port}
+	conn, err := opts.Socket.Dial("udp", net.JoinHostPort(host, strconv.Itoa(p.port)), opts.Timeout)
+	if err != nil {
+		res.Error = "dial failed: " + err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(p.payload); err != nil {
+		res.Error = "write failed: " + err.Error()
+		return res
+	}
+	buf := make([]byte, maxUDPResponse)
+	n, err := mysqlprobe.ReadWithDeadline(conn, buf, opts.Timeout)
+	if err != nil {
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			res.Error = "read failed: " + err.Error()
+		}
+		return res
+	}
+	resp := buf[:n]
+	res.Responded = true
+	if opts.Verbose {
+		res.ResponseHex = hex.EncodeToString(resp[:min(len(resp), 64)])
+	}
+	summary, err := p.parse(resp)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Summary = summary
+	return res
+}
+
+/*
+parseMemcachedStats summarizes a memcached UDP "stats" reply.
+Function-level comment: strips the 8-byte UDP frame header and pulls the version out of the STAT lines.
+*/
+func parseMemcachedStats(resp []byte) (string, error) {
+	if len(resp) < 8 {
+		return "", errors.New("short memcached frame")
+	}
+	body := resp[8:]
+	if !bytes.HasPrefix(body, []byte("STAT ")) {
+		return "", errors.New("not a memcached stats reply")
+	}
+	for _, line := range strings.Split(string(body), "\r\n") {
+		if v, ok := strings.CutPrefix(line, "STAT version "); ok {
+			return "memcached " + v, nil
+		}
+	}
+	return "memcached", nil
+}
+
+/*
+buildVersionBindQuery builds the DNS CHAOS TXT query for version.bind.
+Function-level comment: a fixed header with one question and no recursion desired, followed by the encoded name, QTYPE=TXT and QCLASS=CH.
+*/
+func buildVersionBindQuery() []byte {
+	q := make([]byte, 12, 12+len("version.bind")+6)
+	binary.BigEndian.PutUint16(q[0:2], dnsVersionBindID)
+	binary.BigEndian.PutUint16(q[4:6], 1) // QDCOUNT
+	for _, label := range []string{"version", "bind"} {
+		q = append(q, byte(len(label)))
+		q = append(q, label...)
+	}
+	q = append(q, 0x00)
+	q = append(q, 0x00, 0x10) // TXT
+	q = append(q, 0x00, 0x03) // CH
+	return q
+}
+
+/*
+skipDNSName advances past a (possibly compressed) domain name starting at off.
+Function-level comment: walks length-prefixed labels until the root label or a compression pointer; returns the offset just after the name.
+*/
+func skipDNSName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errors.New("truncated DNS name")
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+}
+
+/*
+parseVersionBindReply extracts the TXT string from a version.bind answer.
+Function-level comment: checks the transaction ID and response code, skips the question, then returns the first TXT record's strings joined by spaces.
+*/
+func parseVersionBindReply(resp []byte) (string, error) {
+	if len(resp) < 12 {
+		return "", errors.New("short DNS reply")
+	}
+	if binary.BigEndian.Uint16(resp[0:2]) != dnsVersionBindID || resp[2]&0x80 == 0 {
+		return "", errors.New("not a DNS reply to our query")
+	}
+	if rcode := resp[3] & 0x0f; rcode != 0 {
+		return "", fmt.Errorf("DNS server answered rcode %d", rcode)
+	}
+	qd := int(binary.BigEndian.Uint16(resp[4:6]))
+	an := int(binary.BigEndian.Uint16(resp[6:8]))
+	off := 12
+	var err error
+	for i := 0; i < qd; i++ {
+		if off, err = skipDNSName(resp, off); err != nil {
+			return "", err
+		}
+		off += 4
+	}
+	for i := 0; i < an; i++ {
+		if off, err = skipDNSName(resp, off); err != nil {
+			return "", err
+		}
+		if off+10 > len(resp) {
+			return "", errors.New("truncated DNS answer")
+		}
+		rtype := binary.BigEndian.Uint16(resp[off : off+2])
+		rdlen := int(binary.BigEndian.Uint16(resp[off+8 : off+10]))
+		off += 10
+		if off+rdlen > len(resp) {
+			return "", errors.New("truncated DNS rdata")
+		}
+		if rtype != 16 {
+			off += rdlen
+			continue
+		}
+		var parts []string
+		for rd := resp[off : off+rdlen]; len(rd) > 0; {
+			l := int(rd[0])
+			if 1+l > len(rd) {
+				break
+			}
+			parts = append(parts, mysqlprobe.PrintableBanner(rd[1:1+l]))
+			rd = rd[1+l:]
+		}
+		return strings.Join(parts, " "), nil
+	}
+	return "", errors.New("no TXT answer for version.bind")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	subcommands["verify"] = runVerify
+}
+
+/*
+runVerify is the verify subcommand.
+Function-level comment: checks a results file against the detached signature -sign-key wrote for it (file.sig unless -sig says otherwise): the signature must name the file, the file's size and SHA-256 must match what was signed, the signature must verify, and the signing key must be the trusted one, given as -pub or pinned by its -fingerprint. One of them is required, since the key embedded in the signature file only shows that whoever signed the file held it. Exits 0 when the file verifies, 1 when it does not, and 2 on invalid arguments.
+*/
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	sigPath := fs.String("sig", "", "Signature file (default: the results file with .sig appended)")
+	pubPath := fs.String("pub", "", "PEM public key the file must have been signed with")
+	fingerprint := fs.String("fingerprint", "", "Instead of -pub, SHA-256 fingerprint of that public key, as verify prints it")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout verify [-sig file.sig] -pub key.pub.pem|-fingerprint hex results-file")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	path := fs.Arg(0)
+	if *sigPath == "" {
+		*sigPath = path + signatureSuffix
+	}
+	wantFingerprint := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(*fingerprint), ":", ""))
+	switch {
+	case *pubPath == "" && wantFingerprint == "":
+		fmt.Fprintln(os.Stderr, "verify needs -pub or -fingerprint: the key inside the signature file only shows that whoever signed the file held it")
+		return 2
+	case *pubPath != "" && wantFingerprint != "":
+		fmt.Fprintln(os.Stderr, "-pub and -fingerprint are mutually exclusive")
+		return 2
+	}
+	var want crypto.PublicKey
+	if *pubPath != "" {
+		var err error
+		if want, err = loadPublicKey(*pubPath); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -pub: %v\n", err)
+			return 2
+		}
+	}
+
+	data, err := os.ReadFile(*sigPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %v\n", err)
+		return 1
+	}
+	var sig outputSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %s: %v\n", *sigPath, err)
+		return 1
+	}
+	fail := func(format string, a ...any) int {
+		fmt.Printf("FAILED %s: %s\n", path, fmt.Sprintf(format, a...))
+		return 1
+	}
+	pub, err := verifySignature(sig)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if want != nil && !publicKeysEqual(pub, want) {
+		return fail("signed with key %s, not the -pub key %s", keyFingerprint(pub), keyFingerprint(want))
+	}
+	if wantFingerprint != "" && keyFingerprint(pub) != wantFingerprint {
+		return fail("signed with key %s, not the -fingerprint key %s", keyFingerprint(pub), wantFingerprint)
+	}
+	if sig.File != filepath.Base(path) {
+		return fail("signature is for %s, not %s", sig.File, filepath.Base(path))
+	}
+	size, sum, err := hashFile(path)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if size != sig.Size || sum != sig.SHA256 {
+		return fail("contents changed since signing (%d bytes, sha256 %s; signed %d bytes, sha256 %s)", size, sum, sig.Size, sig.SHA256)
+	}
+	fmt.Printf("OK %s: %d bytes, sha256 %s, signed %s with %s key %s\n", path, size, sum, sig.SignedAt.Format("2006-01-02T15:04:05Z07:00"), sig.Algorithm, keyFingerprint(pub))
+	return 0
+}
+
+/*
+loadPublicKey reads a PEM public key (PKIX "PUBLIC KEY"), or the public half of a PEM private key.
+*/
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	if block.Type == "PUBLIC KEY" {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return pub, nil
+	}
+	key, err := loadSigningKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.Public(), nil
+}
+
+/*
+publicKeysEqual compares two public keys of any supported type.
+*/
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+/*
+hashFile returns the size and hex SHA-256 of the file at path.
+*/
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
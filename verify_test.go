@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// signTestFile writes data to dir/name signed with key, as a scan with -sign-key does.
+func signTestFile(t *testing.T, dir, name, data string, key crypto.Signer) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	d := newDigestWriter(io.Discard)
+	d.Write([]byte(data))
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeOutputSignature(path, d, key, "scan-1"); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	_, trusted, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKIXPublicKey(trusted.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := filepath.Join(dir, "trusted.pub.pem")
+	if err := os.WriteFile(pub, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	good := signTestFile(t, dir, "good.ndjson", "{}\n", trusted)
+	forged := signTestFile(t, dir, "forged.ndjson", "{}\n", other)
+	tampered := signTestFile(t, dir, "tampered.ndjson", "{}\n", trusted)
+	if err := os.WriteFile(tampered, []byte("{\"x\":1}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	renamed := filepath.Join(dir, "renamed.ndjson")
+	os.WriteFile(renamed, []byte("{}\n"), 0o644)
+	sig, _ := os.ReadFile(good + signatureSuffix)
+	os.WriteFile(renamed+signatureSuffix, sig, 0o644)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"trusted key", []string{"-pub", pub, good}, 0},
+		{"pinned fingerprint", []string{"-fingerprint", keyFingerprint(trusted.Public()), good}, 0},
+		{"no trusted key", []string{good}, 2},
+		{"both keys", []string{"-pub", pub, "-fingerprint", keyFingerprint(trusted.Public()), good}, 2},
+		{"signed by another key", []string{"-pub", pub, forged}, 1},
+		{"fingerprint of another key", []string{"-fingerprint", keyFingerprint(trusted.Public()), forged}, 1},
+		{"changed contents", []string{"-pub", pub, tampered}, 1},
+		{"signature of another file", []string{"-pub", pub, renamed}, 1},
+	}
+	for _, tt := range tests {
+		if got := runVerify(tt.args); got != tt.want {
+			t.Errorf("%s: verify exited %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
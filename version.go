@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// Set at link time, e.g. go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"; empty values fall back to the binary's embedded build info.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+func init() {
+	subcommands["version"] = runVersion
+}
+
+/*
+buildMetadata identifies the scanner build that produced a result.
+*/
+type buildMetadata struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// scannerBuild is this binary's build metadata, stamped on every result.
+var scannerBuild = readBuildMetadata()
+
+/*
+readBuildMetadata combines the -ldflags values with debug.ReadBuildInfo.
+Function-level comment: the linker values win; otherwise the module version and the VCS revision and commit time recorded by go build are used, with "-dirty" marking uncommitted changes. Without either the version is "devel".
+*/
+func readBuildMetadata() *buildMetadata {
+	m := &buildMetadata{Version: version, Commit: commit, BuildDate: buildDate}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		if m.Version == "" {
+			m.Version = "devel"
+		}
+		return m
+	}
+	m.GoVersion = bi.GoVersion
+	if m.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		m.Version = bi.Main.Version
+	}
+	if m.Version == "" {
+		m.Version = "devel"
+	}
+	var revision, vcsTime string
+	var modified bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.time":
+			vcsTime = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if m.Commit == "" && revision != "" {
+		m.Commit = revision
+		if modified {
+			m.Commit += "-dirty"
+		}
+	}
+	if m.BuildDate == "" {
+		m.BuildDate = vcsTime
+	}
+	return m
+}
+
+/*
+String renders the metadata as the one line -version prints.
+*/
+func (m *buildMetadata) String() string {
+	s := "mysql_scout " + m.Version
+	if m.Commit != "" {
+		s += " commit " + m.Commit
+	}
+	if m.BuildDate != "" {
+		s += " built " + m.BuildDate
+	}
+	if m.GoVersion != "" {
+		s += " " + m.GoVersion
+	}
+	return s
+}
+
+/*
+runVersion is the version subcommand.
+Function-level comment: prints the build line, or the metadata as JSON with -json.
+*/
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the build metadata as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mysql_scout version [-json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(scannerBuild); err != nil {
+			fmt.Fprintf(os.Stderr, "version: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Println(scannerBuild)
+	return 0
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"iter"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+watchState is what watch mode remembers about a target between rounds.
+*/
+type watchState struct {
+	version    string
+	tls        string
+	authPlugin string
+}
+
+/*
+watchChange is one observed change of a watched attribute; it is also the data -alert-template renders.
+*/
+type watchChange struct {
+	Target string    `json:"target"`
+	Field  string    `json:"field"`
+	Old    string    `json:"old"`
+	New    string    `json:"new"`
+	Time   time.Time `json:"time"`
+	Result *Result   `json:"-"`
+}
+
+/*
+watcher compares each round's results with the last MySQL answer seen from the same target.
+Targets that fail to answer keep their previous state, so an outage followed by recovery is not reported as a change.
+*/
+type watcher struct {
+	mu     sync.Mutex
+	states map[string]watchState
+}
+
+/*
+newWatcher returns a watcher with no history.
+*/
+func newWatcher() *watcher {
+	return &watcher{states: make(map[string]watchState)}
+}
+
+/*
+observe records res and returns the changes since the target's previous MySQL answer.
+Function-level comment: the first answer from a target only establishes its baseline; a nil watcher (no -watch) reports nothing. Needs the full handshake (before non-verbose trimming) so the auth plugin is known.
+*/
+func (w *watcher) observe(res Result) []watchChange {
+	if w == nil || !res.MySQL || res.HandshakeInfo == nil {
+		return nil
+	}
+	cur := watchState{version: res.ServerVersion, tls: tlsPosture(res), authPlugin: res.AuthPluginName}
+	key := net.JoinHostPort(res.Host, strconv.Itoa(res.Port))
+	w.mu.Lock()
+	prev, seen := w.states[key]
+	w.states[key] = cur
+	w.mu.Unlock()
+	if !seen {
+		return nil
+	}
+	now := time.Now().UTC()
+	var changes []watchChange
+	for _, f := range []struct{ name, old, new string }{
+		{"server_version", prev.version, cur.version},
+		{"tls", prev.tls, cur.tls},
+		{"auth_plugin", prev.authPlugin, cur.authPlugin},
+	} {
+		if f.old != f.new {
+			changes = append(changes, watchChange{Target: key, Field: f.name, Old: f.old, New: f.new, Time: now, Result: &res})
+		}
+	}
+	return changes
+}
+
+/*
+runWatch rescans targets every interval, for count rounds (0 = until interrupted).
+Function-level comment: each round is a full runScan through emit, reading targets afresh, so a streamed -targets-file is read again each round. An interrupt ends the watch once the current round finishes, or at once while waiting for the next round; so does reaching cfg.deadline (-max-runtime), which also cuts the current round short. Returns the targets the last round skipped for the deadline.
+*/
+func runWatch(targets iter.Seq[target], cfg scanConfig, emit func(target, Result), interval time.Duration, count int) int {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	var expired <-chan time.Time
+	if !cfg.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(cfg.deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for round := 1; ; round++ {
+		start := time.Now()
+		if skipped := runScan(targets, cfg, emit); skipped > 0 || cfg.deadlinePassed() {
+			return skipped
+		}
+		if count > 0 && round >= count {
+			return 0
+		}
+		select {
+		case <-stop:
+			return 0
+		case <-expired:
+			return 0
+		case <-time.After(time.Until(start.Add(interval))):
+		}
+		fmt.Fprintf(os.Stderr, "watch: starting round %d\n", round+1)
+	}
+}
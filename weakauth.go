@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// weakAuthPlugins explains why each weak or deprecated auth plugin is flagged.
+var weakAuthPlugins = map[string]string{
+	"mysql_old_password":   "pre-4.1 password hash, broken and trivially reversible",
+	"mysql_clear_password": "password sent in cleartext, readable by anyone on the path without TLS",
+	"sha256_password":      "deprecated since MySQL 8.0 in favor of caching_sha2_password and removed in 9.0",
+}
+
+/*
+classifyWeakAuth reports whether the server uses or accepts a weak or deprecated auth plugin, and why.
+Function-level comment: the handshake's default plugin is checked, then (with -user) the plugin the login ended up on after any auth switch; a pre-4.1 server without secure connection support only has the old password hash. The first finding is reported.
+*/
+func classifyWeakAuth(info *mysqlprobe.HandshakeInfo, auth *mysqlprobe.AuthInfo) (bool, string) {
+	if info == nil {
+		return false, ""
+	}
+	if why, ok := weakAuthPlugins[info.AuthPluginName]; ok {
+		return true, "default auth plugin " + info.AuthPluginName + ": " + why
+	}
+	if info.AuthPluginName == "" && info.CapabilityFlags&mysqlprobe.ClientSecureConnection == 0 {
+		return true, "no secure connection support, so only mysql_old_password: " + weakAuthPlugins["mysql_old_password"]
+	}
+	if auth != nil {
+		if why, ok := weakAuthPlugins[auth.Plugin]; ok {
+			return true, "login switched to " + auth.Plugin + ": " + why
+		}
+	}
+	return false, ""
+}
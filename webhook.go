@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// webhookAttempts is how many times a webhook delivery is tried before it is dropped.
+	webhookAttempts = 4
+	// webhookQueueSize bounds the deliveries waiting to be posted; emit blocks once it is full.
+	webhookQueueSize = 256
+)
+
+/*
+webhookEvent is the JSON body posted to -webhook.
+*/
+type webhookEvent struct {
+	Event     string       `json:"event"`
+	Timestamp time.Time    `json:"timestamp"`
+	Result    *Result      `json:"result,omitempty"`
+	Change    *watchChange `json:"change,omitempty"`
+}
+
+/*
+webhookNotifier posts events to a URL from a background goroutine, so slow endpoints do not hold up the scan's output.
+*/
+type webhookNotifier struct {
+	url    string
+	http   *http.Client
+	queue  chan []byte
+	done   chan struct{}
+	errors io.Writer
+}
+
+/*
+newWebhookNotifier starts the delivery goroutine for url; failures are reported on errOut.
+*/
+func newWebhookNotifier(url string, errOut io.Writer) *webhookNotifier {
+	wn := &webhookNotifier{
+		url:    url,
+		http:   &http.Client{Timeout: 30 * time.Second},
+		queue:  make(chan []byte, webhookQueueSize),
+		done:   make(chan struct{}),
+		errors: errOut,
+	}
+	go wn.run()
+	return wn
+}
+
+/*
+notify queues ev for delivery.
+*/
+func (wn *webhookNotifier) notify(ev webhookEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		fmt.Fprintf(wn.errors, "webhook: %v\n", err)
+		return
+	}
+	wn.queue <- body
+}
+
+/*
+close waits for queued deliveries to finish; notify must not be called afterwards.
+*/
+func (wn *webhookNotifier) close() {
+	close(wn.queue)
+	<-wn.done
+}
+
+/*
+run delivers queued events in order until the queue is closed.
+*/
+func (wn *webhookNotifier) run() {
+	defer close(wn.done)
+	for body := range wn.queue {
+		if err := wn.post(body); err != nil {
+			fmt.Fprintf(wn.errors, "webhook: %v (event dropped)\n", err)
+		}
+	}
+}
+
+/*
+post sends one event, retrying network errors, 429s, and 5xx responses with exponential backoff.
+Function-level comment: any 2xx is success; other 4xx responses are not retried since resending the same body will not change them.
+*/
+func (wn *webhookNotifier) post(body []byte) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := wn.http.Post(wn.url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			switch {
+			case resp.StatusCode >= 200 && resp.StatusCode < 300:
+				return nil
+			case resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+				return fmt.Errorf("post %s: %s", wn.url, resp.Status)
+			}
+			err = fmt.Errorf("post %s: %s", wn.url, resp.Status)
+		}
+		if attempt == webhookAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
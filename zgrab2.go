@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+/*
+namedFlag names one bit of a MySQL flags field, using the names zgrab2 reports.
+*/
+type namedFlag struct {
+	bit  uint32
+	name string
+}
+
+// capabilityFlagNames are the server capability bits, in protocol order.
+var capabilityFlagNames = []namedFlag{
+	{1 << 0, "CLIENT_LONG_PASSWORD"},
+	{1 << 1, "CLIENT_FOUND_ROWS"},
+	{1 << 2, "CLIENT_LONG_FLAG"},
+	{1 << 3, "CLIENT_CONNECT_WITH_DB"},
+	{1 << 4, "CLIENT_NO_SCHEMA"},
+	{1 << 5, "CLIENT_COMPRESS"},
+	{1 << 6, "CLIENT_ODBC"},
+	{1 << 7, "CLIENT_LOCAL_FILES"},
+	{1 << 8, "CLIENT_IGNORE_SPACE"},
+	{1 << 9, "CLIENT_PROTOCOL_41"},
+	{1 << 10, "CLIENT_INTERACTIVE"},
+	{1 << 11, "CLIENT_SSL"},
+	{1 << 12, "CLIENT_IGNORE_SIGPIPE"},
+	{1 << 13, "CLIENT_TRANSACTIONS"},
+	{1 << 14, "CLIENT_RESERVED"},
+	{1 << 15, "CLIENT_SECURE_CONNECTION"},
+	{1 << 16, "CLIENT_MULTI_STATEMENTS"},
+	{1 << 17, "CLIENT_MULTI_RESULTS"},
+	{1 << 18, "CLIENT_PS_MULTI_RESULTS"},
+	{1 << 19, "CLIENT_PLUGIN_AUTH"},
+	{1 << 20, "CLIENT_CONNECT_ATTRS"},
+	{1 << 21, "CLIENT_PLUGIN_AUTH_LEN_ENC_CLIENT_DATA"},
+	{1 << 22, "CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS"},
+	{1 << 23, "CLIENT_SESSION_TRACK"},
+	{1 << 24, "CLIENT_DEPRECATED_EOF"},
+}
+
+// statusFlagNames are the server status bits sent in the handshake.
+var statusFlagNames = []namedFlag{
+	{0x0001, "SERVER_STATUS_IN_TRANS"},
+	{0x0002, "SERVER_STATUS_AUTOCOMMIT"},
+	{0x0008, "SERVER_MORE_RESULTS_EXISTS"},
+	{0x0010, "SERVER_QUERY_NO_GOOD_INDEX_USED"},
+	{0x0020, "SERVER_QUERY_NO_INDEX_USED"},
+	{0x0040, "SERVER_STATUS_CURSOR_EXISTS"},
+	{0x0080, "SERVER_STATUS_LAST_ROW_SENT"},
+	{0x0100, "SERVER_STATUS_DB_DROPPED"},
+	{0x0200, "SERVER_STATUS_NO_BACKSLASH_ESCAPES"},
+	{0x0400, "SERVER_STATUS_METADATA_CHANGED"},
+	{0x0800, "SERVER_QUERY_WAS_SLOW"},
+	{0x1000, "SERVER_PS_OUT_PARAMS"},
+	{0x2000, "SERVER_STATUS_IN_TRANS_READONLY"},
+	{0x4000, "SERVER_SESSION_STATE_CHANGED"},
+}
+
+/*
+zgrab2Record is one line of zgrab2 output: the target plus a response per module.
+Port is not part of zgrab2's envelope; it is added so multi-port scans stay unambiguous and is ignored by zgrab2 consumers.
+*/
+type zgrab2Record struct {
+	IP     string                    `json:"ip,omitempty"`
+	Domain string                    `json:"domain,omitempty"`
+	Port   int                       `json:"port,omitempty"`
+	Data   map[string]zgrab2Response `json:"data"`
+}
+
+/*
+zgrab2Response mirrors zgrab2's per-module ScanResponse.
+*/
+type zgrab2Response struct {
+	Status    mysqlprobe.ScanStatus `json:"status"`
+	Protocol  string                `json:"protocol"`
+	Result    *zgrab2MySQLResult    `json:"result,omitempty"`
+	Timestamp string                `json:"timestamp"`
+	Error     string                `json:"error,omitempty"`
+}
+
+/*
+zgrab2MySQLResult mirrors the result object of zgrab2's mysql module.
+*/
+type zgrab2MySQLResult struct {
+	ProtocolVersion uint8           `json:"protocol_version"`
+	ServerVersion   string          `json:"server_version,omitempty"`
+	ConnectionID    uint32          `json:"connection_id,omitempty"`
+	AuthPluginData  []byte          `json:"auth_plugin_data,omitempty"`
+	CapabilityFlags map[string]bool `json:"capability_flags,omitempty"`
+	CharacterSet    uint8           `json:"character_set,omitempty"`
+	StatusFlags     map[string]bool `json:"status_flags,omitempty"`
+	AuthPluginName  string          `json:"auth_plugin_name,omitempty"`
+	TLSLog          *zgrab2TLSLog   `json:"tls,omitempty"`
+}
+
+/*
+zgrab2TLSLog is the subset of zgrab2's TLS handshake log we can fill from TLSInfo, keeping zgrab2's nesting.
+*/
+type zgrab2TLSLog struct {
+	HandshakeLog struct {
+		ServerHello struct {
+			Version     zgrab2Named `json:"version"`
+			CipherSuite zgrab2Named `json:"cipher_suite"`
+		} `json:"server_hello"`
+		ServerCertificates struct {
+			Certificate struct {
+				Parsed struct {
+					SubjectDN string   `json:"subject_dn,omitempty"`
+					IssuerDN  string   `json:"issuer_dn,omitempty"`
+					Names     []string `json:"names,omitempty"`
+					Validity  struct {
+						Start string `json:"start,omitempty"`
+						End   string `json:"end,omitempty"`
+					} `json:"validity"`
+				} `json:"parsed"`
+			} `json:"certificate"`
+		} `json:"server_certificates"`
+	} `json:"handshake_log"`
+}
+
+/*
+zgrab2Named is a {"name": ...} object as zgrab2 uses for enumerated TLS values.
+*/
+type zgrab2Named struct {
+	Name string `json:"name,omitempty"`
+}
+
+/*
+zgrab2Writer prints one zgrab2-style envelope per line.
+*/
+type zgrab2Writer struct {
+	enc *json.Encoder
+}
+
+/*
+newZgrab2Writer returns a zgrab2 envelope writer on w.
+*/
+func newZgrab2Writer(w io.Writer) *zgrab2Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &zgrab2Writer{enc: enc}
+}
+
+/*
+write wraps res in zgrab2's envelope under data.mysql.
+Function-level comment: the status comes from mysqlprobe.Result.Status; IP literals go in "ip" and names in "domain"; the timestamp is when the record is written, which trails the probe by at most the write queue.
+*/
+func (zw *zgrab2Writer) write(res Result) error {
+	rec := zgrab2Record{Port: res.Port, Data: map[string]zgrab2Response{}}
+	if _, err := netip.ParseAddr(res.Host); err == nil {
+		rec.IP = res.Host
+	} else {
+		rec.Domain = res.Host
+	}
+	status, err := res.Status()
+	resp := zgrab2Response{
+		Status:    status,
+		Protocol:  mysqlprobe.ModuleName,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	if res.HandshakeInfo != nil {
+		resp.Result = zgrab2MySQL(res.HandshakeInfo, res.TLS)
+	}
+	rec.Data[mysqlprobe.ModuleName] = resp
+	return zw.enc.Encode(rec)
+}
+
+/*
+zgrab2MySQL converts the handshake (and TLS summary, if any) into zgrab2's mysql result.
+Function-level comment: flags are expanded into maps of the bits that are set, and auth-plugin-data is carried as raw bytes (base64 in JSON) as zgrab2 does.
+*/
+func zgrab2MySQL(info *mysqlprobe.HandshakeInfo, ti *mysqlprobe.TLSInfo) *zgrab2MySQLResult {
+	out := &zgrab2MySQLResult{
+		ProtocolVersion: info.ProtocolVersion,
+		ServerVersion:   info.ServerVersion,
+		ConnectionID:    info.ConnectionID,
+		CapabilityFlags: flagMap(info.CapabilityFlags, capabilityFlagNames),
+		CharacterSet:    info.CharacterSet,
+		StatusFlags:     flagMap(uint32(info.StatusFlags), statusFlagNames),
+		AuthPluginName:  info.AuthPluginName,
+	}
+	out.AuthPluginData, _ = hex.DecodeString(info.AuthPluginData)
+	if ti != nil && ti.Error == "" {
+		log := &zgrab2TLSLog{}
+		hello := &log.HandshakeLog.ServerHello
+		hello.Version.Name = strings.Replace(ti.Version, "TLS ", "TLSv", 1)
+		hello.CipherSuite.Name = ti.CipherSuite
+		cert := &log.HandshakeLog.ServerCertificates.Certificate.Parsed
+		cert.SubjectDN, cert.IssuerDN, cert.Names = ti.Subject, ti.Issuer, ti.DNSNames
+		cert.Validity.Start, cert.Validity.End = ti.NotBefore, ti.NotAfter
+		out.TLSLog = log
+	}
+	return out
+}
+
+/*
+flagMap expands a flags field into a map of the names of the set bits, or nil when none are known.
+*/
+func flagMap(flags uint32, names []namedFlag) map[string]bool {
+	var m map[string]bool
+	for _, f := range names {
+		if flags&f.bit != 0 {
+			if m == nil {
+				m = make(map[string]bool)
+			}
+			m[f.name] = true
+		}
+	}
+	return m
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hadimalik12/censys_take_home_exercise_data_internship/mysqlprobe"
+)
+
+// testInfo is the handshake the capability benchmarks decode.
+var testInfo = &mysqlprobe.HandshakeInfo{
+	ProtocolVersion: 10,
+	ServerVersion:   "8.0.36",
+	CapabilityFlags: 0xdfffffff,
+	CharacterSet:    255,
+	StatusFlags:     2,
+	AuthPluginName:  "caching_sha2_password",
+	AuthPluginData:  "1f2e3d4c5b6a79081122334455667708192a3b4c",
+}
+
+/*
+BenchmarkCapabilityFlagMap decodes capability and status bits into name maps.
+*/
+func BenchmarkCapabilityFlagMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if len(flagMap(testInfo.CapabilityFlags, capabilityFlagNames)) == 0 || len(flagMap(uint32(testInfo.StatusFlags), statusFlagNames)) == 0 {
+			b.Fatal("no flags decoded")
+		}
+	}
+}
+
+/*
+BenchmarkZgrab2MySQL converts a handshake into the zgrab2 result object, the per-record cost of -format zgrab2.
+*/
+func BenchmarkZgrab2MySQL(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if zgrab2MySQL(testInfo, nil) == nil {
+			b.Fatal("nil result")
+		}
+	}
+}